@@ -14,11 +14,19 @@ import (
 	"task-planner/internal/db"
 	"task-planner/internal/email"
 	"task-planner/internal/goal"
+	"task-planner/internal/inbox"
+	"task-planner/internal/llm"
 	"task-planner/internal/motivation"
+	"task-planner/internal/note"
+	"task-planner/internal/refill"
 	"task-planner/internal/schedule"
+	"task-planner/internal/search"
+	"task-planner/internal/tag"
+	"task-planner/internal/usage"
 	"task-planner/internal/user"
 	"task-planner/migration"
 	"task-planner/pkg/config"
+	"task-planner/pkg/storage"
 	"time"
 )
 
@@ -60,18 +68,51 @@ func main() {
 
 	rateLimiter := auth.NewRateLimiter(1*time.Minute, 60)
 
+	tagRepo := tag.NewRepository(database)
+	tagService := tag.NewService(tagRepo)
+	tagHandler := tag.NewHandler(tagService)
+
+	llmClient, err := llm.New(cfg.LLM)
+	if err != nil {
+		log.Fatalf("Failed to init LLM client: %v", err)
+	}
+	usageRepo := usage.NewRepository(database)
+	llmClient = usage.NewMeter(llmClient, usageRepo, cfg.LLM)
+	usageService := usage.NewService(usageRepo, cfg.LLM)
+	usageHandler := usage.NewHandler(usageService)
+
+	fileStorage, err := storage.NewLocalStorage(cfg.Files.Dir)
+	if err != nil {
+		log.Fatalf("Failed to init file storage: %v", err)
+	}
+
 	goalRepo := goal.NewRepository(database)
-	goalService := goal.NewService(goalRepo, database, os.Getenv("OPENAI_API_KEY"))
+	goalService := goal.NewService(goalRepo, tagService, database, llmClient, fileStorage, cfg.Goal, cfg.LLM)
 	goalHandler := goal.NewHandler(goalService)
 
+	inboxRepo := inbox.NewRepository(database)
+	inboxService := inbox.NewService(inboxRepo, goalRepo)
+	inboxHandler := inbox.NewHandler(inboxService)
+
 	scheduleRepo := schedule.NewRepository(database)
-	scheduleService := schedule.NewService(database, scheduleRepo, goalRepo)
+	scheduleService := schedule.NewService(database, scheduleRepo, goalRepo, inboxRepo)
 	scheduleHandler := schedule.NewHandler(scheduleService)
 
+	searchRepo := search.NewRepository(database)
+	searchService := search.NewService(searchRepo)
+	searchHandler := search.NewHandler(searchService)
+
+	noteRepo := note.NewRepository(database)
+	noteService := note.NewService(noteRepo, fileStorage, cfg.Files)
+	noteHandler := note.NewHandler(noteService, int64(cfg.Files.MaxSizeMB)<<20)
+
 	motivationRepo := motivation.NewRepository(database)
-	motivationService := motivation.NewService(motivationRepo, goalRepo, os.Getenv("OPENAI_API_KEY"))
+	motivationService := motivation.NewService(motivationRepo, goalRepo, llmClient, cfg.LLM.Motivation)
 	motivationHandler := motivation.NewHandler(motivationService)
-	//refillWorker := refill.NewWorker(goalRepo, goalService, scheduleService)
+	refillRepo := refill.NewRepository(database)
+	refillWorker := refill.NewWorker(goalRepo, goalService, scheduleService, refillRepo, cfg.Refill)
+	refillService := refill.NewService(refillRepo, goalRepo, refillWorker)
+	refillHandler := refill.NewHandler(refillService)
 
 	c := cron.New()
 	c.AddFunc("0 7 * * *", func() {
@@ -80,11 +121,38 @@ func main() {
 		}
 	})
 
-	//c.AddFunc("0 */4 * * *", func() {
-	//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
-	//	defer cancel()
-	//	refillWorker.Tick(ctx)
-	//})
+	c.AddFunc("0 0 3 * * *", func() {
+		if purged, err := goalService.PurgeDeletedGoals(context.Background()); err != nil {
+			log.Printf("PurgeDeletedGoals error: %v", err)
+		} else {
+			log.Printf("PurgeDeletedGoals: purged %d goals", purged)
+		}
+
+		if drafts, err := goalService.PurgeExpiredDrafts(context.Background()); err != nil {
+			log.Printf("PurgeExpiredDrafts error: %v", err)
+		} else {
+			log.Printf("PurgeExpiredDrafts: purged %d drafts", drafts)
+		}
+
+		if runs, err := refillWorker.PurgeRuns(context.Background()); err != nil {
+			log.Printf("PurgeRefillRuns error: %v", err)
+		} else {
+			log.Printf("PurgeRefillRuns: purged %d runs", runs)
+		}
+	})
+
+	if cfg.Refill.Enabled {
+		if err := c.AddFunc(cfg.Refill.Schedule, func() {
+			refillWorker.Tick(context.Background())
+		}); err != nil {
+			log.Fatalf("invalid REFILL_SCHEDULE: %v", err)
+		}
+		c.AddFunc("0 */15 * * * *", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			defer cancel()
+			refillWorker.StartPhases(ctx)
+		})
+	}
 
 	c.Start()
 
@@ -111,6 +179,8 @@ func main() {
 	r.Route("/api/users", func(r chi.Router) {
 		r.With(auth.JWTAuthMiddleware(cfg.JWT.AccessSecret)).
 			Get("/me", authHandler.GetMe)
+		r.With(auth.JWTAuthMiddleware(cfg.JWT.AccessSecret)).
+			Patch("/me/locale", authHandler.SetLocale)
 	})
 
 	r.Group(func(r chi.Router) {
@@ -118,10 +188,47 @@ func main() {
 
 		r.Route("/api/goals", func(r chi.Router) {
 			r.Post("/generate", goalHandler.GenerateGoal)
+			r.Post("/generate/stream", goalHandler.GenerateGoalStream)
+			r.Post("/drafts", goalHandler.CreateDraft)
+			r.Get("/drafts/{draft_id}", goalHandler.GetDraft)
+			r.Delete("/drafts/{draft_id}", goalHandler.DeleteDraft)
+			r.Post("/drafts/{draft_id}/refine", goalHandler.RefineDraft)
+			r.Post("/drafts/{draft_id}/commit", goalHandler.CommitDraft)
 			r.Post("/", goalHandler.CreateGoal)
 			r.Get("/", goalHandler.ListGoals)
 			r.Get("/{id}", goalHandler.GetGoal)
 			r.Delete("/{id}", goalHandler.DeleteGoal)
+			r.Post("/{id}/restore", goalHandler.RestoreGoal)
+			r.Post("/{id}/archive", goalHandler.ArchiveGoal)
+			r.Post("/{id}/unarchive", goalHandler.UnarchiveGoal)
+			r.Put("/{id}/tags", goalHandler.SetGoalTags)
+			r.Patch("/{id}/progress_strategy", goalHandler.UpdateGoalProgressStrategy)
+			r.Post("/{id}/template", goalHandler.SaveGoalAsTemplate)
+			r.Post("/{id}/duplicate", goalHandler.DuplicateGoal)
+			r.Get("/{id}/activity", goalHandler.ListActivity)
+			r.Get("/{id}/refill", refillHandler.GetRefill)
+			r.Put("/{id}/refill", refillHandler.SetRefill)
+			r.Post("/{id}/refill/run", refillHandler.RunRefill)
+		})
+
+		r.Route("/api/phases/{phase_id}/milestone", func(r chi.Router) {
+			r.Get("/", goalHandler.GetMilestone)
+			r.Put("/", goalHandler.SetMilestone)
+			r.Delete("/", goalHandler.DeleteMilestone)
+			r.Patch("/criteria/{criterion_id}", goalHandler.ConfirmCriterion)
+		})
+
+		r.Route("/api/estimates", func(r chi.Router) {
+			r.Get("/accuracy", goalHandler.GetEstimateAccuracy)
+			r.Put("/calibration", goalHandler.SetCalibration)
+		})
+
+		r.Route("/api/templates", func(r chi.Router) {
+			r.Get("/", goalHandler.ListTemplates)
+			r.Get("/{template_id}", goalHandler.GetTemplate)
+			r.Patch("/{template_id}", goalHandler.UpdateTemplate)
+			r.Delete("/{template_id}", goalHandler.DeleteTemplate)
+			r.Post("/{template_id}/instantiate", goalHandler.InstantiateTemplate)
 		})
 
 		r.Route("/api/availability/{goal_id}", func(r chi.Router) {
@@ -135,10 +242,62 @@ func main() {
 		})
 
 		r.Get("/api/tasks/upcoming", scheduleHandler.GetUpcomingTasks)
+		r.Post("/api/tasks/batch", goalHandler.BatchTasks)
+
+		r.Route("/api/tasks/{task_id}", func(r chi.Router) {
+			r.Patch("/status", goalHandler.UpdateTaskStatus)
+			r.Patch("/priority", goalHandler.UpdateTaskPriority)
+			r.Patch("/progress_mode", goalHandler.UpdateTaskProgressMode)
+			r.Get("/checklist", goalHandler.ListChecklist)
+			r.Post("/checklist", goalHandler.AddChecklistItem)
+			r.Put("/checklist/order", goalHandler.ReorderChecklist)
+			r.Patch("/checklist/{item_id}", goalHandler.UpdateChecklistItem)
+			r.Delete("/checklist/{item_id}", goalHandler.DeleteChecklistItem)
+			r.Put("/tags", goalHandler.SetTaskTags)
+			r.Put("/recurrence", goalHandler.UpdateTaskRecurrence)
+			r.Get("/occurrences", goalHandler.ListOccurrences)
+			r.Patch("/occurrences/{date}", goalHandler.UpdateOccurrence)
+			r.Get("/streak", goalHandler.GetStreak)
+		})
+
+		r.Route("/api/inbox", func(r chi.Router) {
+			r.Get("/", inboxHandler.ListItems)
+			r.Post("/", inboxHandler.CreateItem)
+			r.Post("/schedule", scheduleHandler.ScheduleInbox)
+			r.Patch("/{item_id}", inboxHandler.UpdateItem)
+			r.Delete("/{item_id}", inboxHandler.DeleteItem)
+			r.Post("/{item_id}/triage", inboxHandler.TriageItem)
+		})
+
+		r.Route("/api/tags", func(r chi.Router) {
+			r.Get("/", tagHandler.ListTags)
+			r.Post("/", tagHandler.CreateTag)
+			r.Patch("/{tag_id}", tagHandler.UpdateTag)
+			r.Delete("/{tag_id}", tagHandler.DeleteTag)
+		})
 
 		r.Get("/api/stats", scheduleHandler.GetStats)
+		r.Get("/api/usage", usageHandler.GetUsage)
+		r.Get("/api/stats/tags", scheduleHandler.GetTagStats)
+
+		r.Get("/api/search", searchHandler.Search)
+
+		r.Route("/api/notes", func(r chi.Router) {
+			r.Get("/", noteHandler.ListNotes)
+			r.Post("/", noteHandler.CreateNote)
+			r.Patch("/{note_id}", noteHandler.UpdateNote)
+			r.Delete("/{note_id}", noteHandler.DeleteNote)
+		})
+
+		r.Route("/api/attachments", func(r chi.Router) {
+			r.Get("/", noteHandler.ListAttachments)
+			r.Post("/", noteHandler.UploadAttachment)
+			r.Get("/{attachment_id}/download", noteHandler.DownloadAttachment)
+			r.Delete("/{attachment_id}", noteHandler.DeleteAttachment)
+		})
 
 		r.Patch("/api/scheduled_tasks/{id}", scheduleHandler.ToggleInterval)
+		r.Post("/api/scheduled_tasks/batch", scheduleHandler.BatchIntervals)
 
 		r.Group(func(r chi.Router) {
 			r.Use(auth.JWTAuthMiddleware(cfg.JWT.AccessSecret))
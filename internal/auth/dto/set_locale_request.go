@@ -0,0 +1,5 @@
+package dto
+
+type SetLocaleRequest struct {
+	Locale string `json:"locale"`
+}
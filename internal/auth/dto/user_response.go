@@ -1,7 +1,8 @@
 package dto
 
 type UserResponse struct {
-	Email string `json:"email"`
-	Name  string `json:"name"`
-	Id    int64  `json:"id"`
+	Email  string `json:"email"`
+	Name   string `json:"name"`
+	Id     int64  `json:"id"`
+	Locale string `json:"locale"`
 }
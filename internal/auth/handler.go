@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"task-planner/internal/auth/dto"
+	"task-planner/internal/user"
 	"task-planner/pkg/response"
 )
 
@@ -85,9 +86,10 @@ func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		User: dto.UserResponse{
-			Email: usr.Email,
-			Name:  usr.Name,
-			Id:    usr.ID,
+			Email:  usr.Email,
+			Name:   usr.Name,
+			Id:     usr.ID,
+			Locale: usr.Locale,
 		},
 	}
 
@@ -126,9 +128,10 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		User: dto.UserResponse{
-			Email: usr.Email,
-			Name:  usr.Name,
-			Id:    usr.ID,
+			Email:  usr.Email,
+			Name:   usr.Name,
+			Id:     usr.ID,
+			Locale: usr.Locale,
 		},
 	}
 
@@ -252,14 +255,51 @@ func (h *Handler) GetMe(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp := dto.UserResponse{
-		Email: usr.Email,
-		Name:  usr.Name,
-		Id:    usr.ID,
+		Email:  usr.Email,
+		Name:   usr.Name,
+		Id:     usr.ID,
+		Locale: usr.Locale,
 	}
 
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
+// @Summary      Сменить язык пользователя
+// @Description  Язык, на котором формулируются запросы к модели: планы, задачи и мотивационные сообщения генерируются на нём. Поддерживаются en и ru
+// @Tags         Auth
+// @Accept       json
+// @Security     ApiKeyAuth
+// @Param        body  body      dto.SetLocaleRequest  true  "Язык"
+// @Success      204   {string}  string  "No Content"
+// @Failure      400   {object}  response.ErrorResponse
+// @Failure      401   {object}  response.ErrorResponse
+// @Router       /api/users/me/locale [patch]
+func (h *Handler) SetLocale(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetUserFromContext(r.Context())
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var req dto.SetLocaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, ErrInvalidRequest.Error())
+		return
+	}
+
+	err = h.service.userService.SetLocale(r.Context(), claims.UserID, req.Locale)
+	if errors.Is(err, user.ErrUnsupportedLocale) {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to set locale: %v", err)
+		response.Error(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *Handler) GoogleLogin(w http.ResponseWriter, r *http.Request) {
 	var req dto.GoogleLoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -278,9 +318,10 @@ func (h *Handler) GoogleLogin(w http.ResponseWriter, r *http.Request) {
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		User: dto.UserResponse{
-			Email: usr.Email,
-			Name:  usr.Name,
-			Id:    usr.ID,
+			Email:  usr.Email,
+			Name:   usr.Name,
+			Id:     usr.ID,
+			Locale: usr.Locale,
 		},
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,80 @@
+package goal
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"task-planner/internal/auth"
+	"time"
+)
+
+// Entity types of activity entries.
+const (
+	EntityGoal          = "goal"
+	EntityPhase         = "phase"
+	EntityTask          = "task"
+	EntityChecklistItem = "checklist_item"
+	EntityOccurrence    = "occurrence"
+	EntityInterval      = "interval"
+	EntityMilestone     = "milestone"
+)
+
+// Activity actions.
+const (
+	ActionCreated         = "created"
+	ActionUpdated         = "updated"
+	ActionDeleted         = "deleted"
+	ActionStatusChanged   = "status_changed"
+	ActionArchived        = "archived"
+	ActionUnarchived      = "unarchived"
+	ActionRestored        = "restored"
+	ActionDuplicated      = "duplicated"
+	ActionIntervalToggled = "interval_toggled"
+	ActionAIRefill        = "ai_refill"
+	ActionAIPhaseTasks    = "ai_phase_tasks"
+)
+
+// Activity is one entry of a goal's append-only change log. ActorID is nil
+// for changes made by background jobs.
+type Activity struct {
+	ID         uuid.UUID              `json:"id"`
+	GoalID     uuid.UUID              `json:"goal_id"`
+	ActorID    *int64                 `json:"actor_id,omitempty"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   uuid.UUID              `json:"entity_id"`
+	Action     string                 `json:"action"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// RecordActivity appends an entry to the goal's log. Call it with the same
+// context as the change it describes so both land in one transaction. The
+// actor is the authenticated user of the request, if there is one.
+func RecordActivity(
+	ctx context.Context, repo ActivityRepository,
+	goalID uuid.UUID, entityType string, entityID uuid.UUID, action string, details map[string]interface{},
+) error {
+	a := &Activity{
+		ID:         uuid.New(),
+		GoalID:     goalID,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		Details:    details,
+		CreatedAt:  time.Now(),
+	}
+	if claims, err := auth.GetUserFromContext(ctx); err == nil {
+		id := claims.UserID
+		a.ActorID = &id
+	}
+	return repo.AppendActivity(ctx, a)
+}
+
+// change describes an edited field for the activity details.
+func change(field string, from, to interface{}) map[string]interface{} {
+	return map[string]interface{}{"field": field, "from": from, "to": to}
+}
+
+// statusChange describes a status transition for the activity details.
+func statusChange(from, to string) map[string]interface{} {
+	return map[string]interface{}{"from": from, "to": to}
+}
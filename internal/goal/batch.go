@@ -0,0 +1,65 @@
+package goal
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"task-planner/internal/goal/dto"
+)
+
+// MaxBatchSize caps the number of items in one batch request.
+const MaxBatchSize = 200
+
+// RunBatch applies fn to every id and reports a result per item. Errors for
+// which isItemErr holds are recorded against their item; if any item fails,
+// RunBatch returns ErrBatchFailed together with the results so the
+// surrounding transaction rolls back. Other errors abort the batch.
+func RunBatch(ids []uuid.UUID, fn func(id uuid.UUID) error, isItemErr func(error) bool) (*dto.BatchResponse, error) {
+	resp := &dto.BatchResponse{Results: make([]dto.BatchItemResult, 0, len(ids))}
+	failed := false
+	for _, id := range ids {
+		res := dto.BatchItemResult{ID: id, OK: true}
+		if err := fn(id); err != nil {
+			if !isItemErr(err) {
+				return nil, err
+			}
+			res.OK, res.Error = false, err.Error()
+			failed = true
+		}
+		resp.Results = append(resp.Results, res)
+	}
+	if failed {
+		return resp, ErrBatchFailed
+	}
+	resp.Applied = true
+	return resp, nil
+}
+
+// ProgressBatch collects the goals and phases touched by a batch of changes
+// so that each goal is recomputed once at the end rather than per item.
+type ProgressBatch map[uuid.UUID]map[uuid.UUID]bool
+
+// Touch marks the goal and, when set, its phase for recomputation.
+func (b ProgressBatch) Touch(goalID uuid.UUID, phaseID *uuid.UUID) {
+	phases, ok := b[goalID]
+	if !ok {
+		phases = make(map[uuid.UUID]bool)
+		b[goalID] = phases
+	}
+	if phaseID != nil {
+		phases[*phaseID] = true
+	}
+}
+
+// Recalc recomputes every touched goal together with its touched phases.
+func (b ProgressBatch) Recalc(ctx context.Context, repo RepositoryAggregator) error {
+	for goalID, phases := range b {
+		ids := make([]uuid.UUID, 0, len(phases))
+		for id := range phases {
+			ids = append(ids, id)
+		}
+		if err := RecalcGoalPhases(ctx, repo, goalID, ids...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
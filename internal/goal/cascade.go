@@ -0,0 +1,199 @@
+package goal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+)
+
+// cascadeAttempts bounds how often a cascade step re-reads a row that a
+// concurrent request changed between the step's read and its write.
+const cascadeAttempts = 3
+
+// retryCascade runs a read-compute-write step of a cascade again when its
+// versioned write lost to a concurrent change. Under READ COMMITTED the
+// re-read sees the other request's commit, so the step simply recomputes
+// from it. The conflict is internal: the client sent no precondition, so
+// it never surfaces as ErrVersionConflict.
+func retryCascade(step func() error) error {
+	var err error
+	for i := 0; i < cascadeAttempts; i++ {
+		if err = step(); !errors.Is(err, ErrVersionConflict) {
+			return err
+		}
+	}
+	return fmt.Errorf("progress cascade kept conflicting with concurrent changes: %v", err)
+}
+
+// RecalcTaskCascade re-derives the status of a task after its tracked time,
+// checklist or status changed, then rolls progress up into its phase and
+// goal using the goal's progress strategy.
+func RecalcTaskCascade(ctx context.Context, repo RepositoryAggregator, taskID uuid.UUID) error {
+	t, err := RecalcTaskStatus(ctx, repo, taskID)
+	if err != nil {
+		return err
+	}
+	return RecalcGoalProgress(ctx, repo, t.GoalId, t.PhaseId)
+}
+
+// RecalcTaskStatus re-derives and stores the status of a task without
+// rolling progress up; batches use it and recompute each goal once.
+func RecalcTaskStatus(ctx context.Context, repo RepositoryAggregator, taskID uuid.UUID) (*Task, error) {
+	var (
+		t    *Task
+		prev string
+	)
+	err := retryCascade(func() error {
+		var err error
+		t, err = repo.GetTaskByID(ctx, taskID)
+		if err != nil {
+			return fmt.Errorf("failed to get task: %w", err)
+		}
+		items, err := repo.ListChecklistItemsByTaskIDs(ctx, []uuid.UUID{t.ID})
+		if err != nil {
+			return err
+		}
+		t.Checklist = items
+		prev = t.Status
+		t.DeriveStatus()
+		if err := repo.UpdateTask(ctx, t); err != nil {
+			return fmt.Errorf("failed to update task: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if t.Status != prev {
+		if err := RecordActivity(ctx, repo, t.GoalId, EntityTask, t.ID, ActionStatusChanged, statusChange(prev, t.Status)); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// RecalcGoalProgress recomputes and stores the progress of a goal and,
+// when phaseID is set, of that phase.
+func RecalcGoalProgress(ctx context.Context, repo RepositoryAggregator, goalID uuid.UUID, phaseID *uuid.UUID) error {
+	if phaseID == nil {
+		return RecalcGoalPhases(ctx, repo, goalID)
+	}
+	return RecalcGoalPhases(ctx, repo, goalID, *phaseID)
+}
+
+// RecalcGoalPhases recomputes and stores the progress of the given phases
+// and then of their goal, loading the goal's tasks only once. Phases and
+// goals with an open milestone do not complete.
+func RecalcGoalPhases(ctx context.Context, repo RepositoryAggregator, goalID uuid.UUID, phaseIDs ...uuid.UUID) error {
+	g, err := repo.GetGoalByID(ctx, goalID)
+	if err != nil {
+		return fmt.Errorf("failed to get goal: %w", err)
+	}
+	if g == nil {
+		return ErrGoalNotFound
+	}
+
+	tasks, err := repo.ListTasksByGoalID(ctx, goalID)
+	if err != nil {
+		return err
+	}
+	if err := loadChecklists(ctx, repo, tasks); err != nil {
+		return err
+	}
+	gates, err := LoadGates(ctx, repo, goalID)
+	if err != nil {
+		return err
+	}
+
+	for _, phaseID := range phaseIDs {
+		var (
+			ph   *Phase
+			prev string
+		)
+		err := retryCascade(func() error {
+			var err error
+			ph, err = repo.GetPhaseByID(ctx, phaseID)
+			if err != nil {
+				return fmt.Errorf("failed to get phase: %w", err)
+			}
+			prev = ph.Status
+			ph.ApplyProgress(ph.CalculateProgress(tasks, g.Strategy()), gates.Open(ph.ID))
+			return repo.UpdatePhase(ctx, ph)
+		})
+		if err != nil {
+			return err
+		}
+		if ph.Status != prev {
+			if err := RecordActivity(ctx, repo, goalID, EntityPhase, ph.ID, ActionStatusChanged, statusChange(prev, ph.Status)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The goal is read again for the write: the phases above may have
+	// taken a while, and a retry needs the current version anyway.
+	var prev string
+	err = retryCascade(func() error {
+		var err error
+		if g, err = repo.GetGoalByID(ctx, goalID); err != nil {
+			return fmt.Errorf("failed to get goal: %w", err)
+		}
+		if g == nil {
+			return ErrGoalNotFound
+		}
+		prev = g.Status
+		g.Progress = g.CalculateProgress(tasks)
+		if g.Progress == 100 && gates.AllOpen() {
+			g.Status = "completed"
+		} else {
+			g.Status = "active"
+		}
+		return repo.UpdateGoal(ctx, g)
+	})
+	if err != nil {
+		return err
+	}
+	if g.Status != prev {
+		return RecordActivity(ctx, repo, goalID, EntityGoal, goalID, ActionStatusChanged, statusChange(prev, g.Status))
+	}
+	return nil
+}
+
+func loadChecklists(ctx context.Context, repo ChecklistRepository, tasks []Task) error {
+	ids := make([]uuid.UUID, 0, len(tasks))
+	for _, t := range tasks {
+		ids = append(ids, t.ID)
+	}
+	items, err := repo.ListChecklistItemsByTaskIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byTask := make(map[uuid.UUID][]ChecklistItem)
+	for _, it := range items {
+		byTask[it.TaskID] = append(byTask[it.TaskID], it)
+	}
+	for i := range tasks {
+		tasks[i].Checklist = byTask[tasks[i].ID]
+	}
+	return nil
+}
+
+// RecordOccurrenceTime stores the minutes tracked against one instance of a
+// recurring task. The task row itself is left untouched, so each instance
+// completes on its own.
+func RecordOccurrenceTime(ctx context.Context, repo RepositoryAggregator, occurrenceID uuid.UUID, spent int) error {
+	o, err := repo.GetOccurrenceByID(ctx, occurrenceID)
+	if err != nil {
+		return err
+	}
+	if o == nil {
+		return ErrInvalidOccurrence
+	}
+	t, err := repo.GetTaskByID(ctx, o.TaskID)
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	o.ApplyTimeSpent(spent, t.OccurrenceDuration())
+	return repo.UpdateOccurrence(ctx, o)
+}
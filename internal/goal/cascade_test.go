@@ -0,0 +1,48 @@
+package goal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRetryCascade(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		name      string
+		results   []error
+		wantCalls int
+		wantErr   error
+		conflict  bool
+	}{
+		{name: "first attempt succeeds", results: []error{nil}, wantCalls: 1},
+		{name: "succeeds after a conflict", results: []error{ErrVersionConflict, nil}, wantCalls: 2},
+		{name: "other errors are not retried", results: []error{boom}, wantCalls: 1, wantErr: boom},
+		{
+			name:      "gives up without reporting a conflict",
+			results:   []error{ErrVersionConflict, ErrVersionConflict, ErrVersionConflict},
+			wantCalls: cascadeAttempts,
+			conflict:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := retryCascade(func() error {
+				err := tt.results[calls]
+				calls++
+				return err
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			switch {
+			case tt.conflict:
+				if err == nil || errors.Is(err, ErrVersionConflict) {
+					t.Errorf("err = %v, want an internal error", err)
+				}
+			case !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil):
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
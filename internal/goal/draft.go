@@ -0,0 +1,206 @@
+package goal
+
+import (
+	"github.com/google/uuid"
+	"strconv"
+	"strings"
+	"task-planner/internal/goal/dto/create"
+	"task-planner/internal/goal/dto/generate"
+	"task-planner/internal/llm"
+	"task-planner/internal/prompt"
+	"time"
+)
+
+const (
+	// draftTTL is how long an untouched draft is kept.
+	draftTTL = 7 * 24 * time.Hour
+	// maxDraftTurns caps the refinements of one draft; every turn resends
+	// the whole conversation to the model.
+	maxDraftTurns = 20
+	// maxInstructionLength caps a single refinement instruction.
+	maxInstructionLength = 2000
+)
+
+// PlanDraft is a generated plan being refined before it is saved as a
+// goal. Messages is the conversation with the model: the generation
+// prompt, then the model's plans alternating with the user's instructions
+// as they were typed. Draft is the latest plan with the user's calibration
+// applied.
+type PlanDraft struct {
+	ID        uuid.UUID                     `json:"id"`
+	UserID    int64                         `json:"user_id"`
+	Draft     generate.GeneratedGoalPreview `json:"draft"`
+	Messages  []llm.Message                 `json:"messages"`
+	Turns     int                           `json:"turns"`
+	Version   int                           `json:"version"`
+	CreatedAt time.Time                     `json:"created_at"`
+	UpdatedAt time.Time                     `json:"updated_at"`
+}
+
+// Expired reports whether the draft was left untouched for too long.
+func (d *PlanDraft) Expired(now time.Time) bool {
+	return now.Sub(d.UpdatedAt) > draftTTL
+}
+
+// Instructions returns the user's refinement instructions in order.
+func (d *PlanDraft) Instructions() []string {
+	out := make([]string, 0, d.Turns)
+	for i, m := range d.Messages {
+		if i > 0 && m.Role == llm.RoleUser {
+			out = append(out, m.Content)
+		}
+	}
+	return out
+}
+
+// conversation returns the messages to send for a new instruction, with
+// every instruction wrapped in the refinement prompt for the locale, and
+// the version of that prompt.
+func (d *PlanDraft) conversation(locale, instruction string) ([]llm.Message, string, error) {
+	var version string
+	wrap := func(instruction string) (string, error) {
+		r, err := prompt.Render(prompt.Refine, locale, prompt.RefineData{Instruction: strings.TrimSpace(instruction)})
+		version = r.Version
+		return r.Text, err
+	}
+	msgs := make([]llm.Message, 0, len(d.Messages)+1)
+	for i, m := range d.Messages {
+		if i > 0 && m.Role == llm.RoleUser {
+			text, err := wrap(m.Content)
+			if err != nil {
+				return nil, "", err
+			}
+			m.Content = text
+		}
+		msgs = append(msgs, m)
+	}
+	text, err := wrap(instruction)
+	if err != nil {
+		return nil, "", err
+	}
+	return append(msgs, llm.Message{Role: llm.RoleUser, Content: text}), version, nil
+}
+
+// DiffPlans lists what changed from one plan to the next. Phases are
+// matched by title, and tasks by title within a matched phase, so a
+// renamed phase or task shows up as removed and added. Repeated titles
+// are paired up in order: the second "Review" matches the second "Review".
+func DiffPlans(from, to *generate.GeneratedGoalPreview) generate.PlanDiff {
+	var diff generate.PlanDiff
+	diff.Goal = appendChange(diff.Goal, "title", from.Title, to.Title)
+	diff.Goal = appendChange(diff.Goal, "description", from.Description, to.Description)
+	diff.Goal = appendChange(diff.Goal, "hours_per_week", from.HoursPerWeek, to.HoursPerWeek)
+	diff.Goal = appendChange(diff.Goal, "estimated_time", from.EstimatedTime, to.EstimatedTime)
+
+	phaseTitle := func(ph generate.GeneratedPhaseDraft) string { return ph.Title }
+	fromKeys, toKeys := draftKeys(from.Phases, phaseTitle), draftKeys(to.Phases, phaseTitle)
+	oldPhases := make(map[string]*generate.GeneratedPhaseDraft, len(from.Phases))
+	for i := range from.Phases {
+		oldPhases[fromKeys[i]] = &from.Phases[i]
+	}
+	for i := range to.Phases {
+		ph := &to.Phases[i]
+		old, ok := oldPhases[toKeys[i]]
+		if !ok {
+			diff.Phases = append(diff.Phases, generate.PhaseDiff{Title: ph.Title, Change: generate.ChangeAdded})
+			continue
+		}
+		delete(oldPhases, toKeys[i])
+		pd := generate.PhaseDiff{Title: ph.Title, Change: generate.ChangeChanged}
+		pd.Fields = appendChange(pd.Fields, "description", old.Description, ph.Description)
+		pd.Fields = appendChange(pd.Fields, "estimated_time", old.EstimatedTime, ph.EstimatedTime)
+		pd.Fields = appendChange(pd.Fields, "order", old.Order, ph.Order)
+		pd.Tasks = diffTasks(old.Tasks, ph.Tasks)
+		if len(pd.Fields) > 0 || len(pd.Tasks) > 0 {
+			diff.Phases = append(diff.Phases, pd)
+		}
+	}
+	for i, ph := range from.Phases {
+		if _, ok := oldPhases[fromKeys[i]]; ok {
+			diff.Phases = append(diff.Phases, generate.PhaseDiff{Title: ph.Title, Change: generate.ChangeRemoved})
+		}
+	}
+	return diff
+}
+
+func diffTasks(from, to []generate.GeneratedTaskDraft) []generate.TaskDiff {
+	taskTitle := func(t generate.GeneratedTaskDraft) string { return t.Title }
+	fromKeys, toKeys := draftKeys(from, taskTitle), draftKeys(to, taskTitle)
+	old := make(map[string]*generate.GeneratedTaskDraft, len(from))
+	for i := range from {
+		old[fromKeys[i]] = &from[i]
+	}
+	var out []generate.TaskDiff
+	for i, t := range to {
+		prev, ok := old[toKeys[i]]
+		if !ok {
+			out = append(out, generate.TaskDiff{Title: t.Title, Change: generate.ChangeAdded})
+			continue
+		}
+		delete(old, toKeys[i])
+		td := generate.TaskDiff{Title: t.Title, Change: generate.ChangeChanged}
+		td.Fields = appendChange(td.Fields, "description", prev.Description, t.Description)
+		td.Fields = appendChange(td.Fields, "estimated_time", prev.EstimatedTime, t.EstimatedTime)
+		if len(td.Fields) > 0 {
+			out = append(out, td)
+		}
+	}
+	for i, t := range from {
+		if _, ok := old[fromKeys[i]]; ok {
+			out = append(out, generate.TaskDiff{Title: t.Title, Change: generate.ChangeRemoved})
+		}
+	}
+	return out
+}
+
+func appendChange[T comparable](changes []generate.FieldChange, field string, from, to T) []generate.FieldChange {
+	if from == to {
+		return changes
+	}
+	return append(changes, generate.FieldChange{Field: field, From: from, To: to})
+}
+
+// draftKeys keys every item by its normalised title and how many items
+// with that title came before it, so duplicates do not collide.
+func draftKeys[T any](items []T, title func(T) string) []string {
+	seen := make(map[string]int, len(items))
+	keys := make([]string, len(items))
+	for i, it := range items {
+		t := strings.ToLower(strings.TrimSpace(title(it)))
+		keys[i] = t + "#" + strconv.Itoa(seen[t])
+		seen[t]++
+	}
+	return keys
+}
+
+// createRequest turns the draft into the request that saves it as a goal.
+func (d *PlanDraft) createRequest() create.CreateGoalRequest {
+	p := d.Draft
+	req := create.CreateGoalRequest{
+		Title:         p.Title,
+		Description:   p.Description,
+		HoursPerWeek:  p.HoursPerWeek,
+		EstimatedTime: p.EstimatedTime,
+		Phases:        make([]create.CreatePhaseRequest, 0, len(p.Phases)),
+		PromptVersion: p.PromptVersion,
+	}
+	for _, ph := range p.Phases {
+		pr := create.CreatePhaseRequest{
+			Title:         ph.Title,
+			Description:   ph.Description,
+			Order:         ph.Order,
+			EstimatedTime: ph.EstimatedTime,
+		}
+		for _, t := range ph.Tasks {
+			pr.Tasks = append(pr.Tasks, create.CreateTaskRequest{
+				Title:              t.Title,
+				Description:        t.Description,
+				EstimatedTime:      t.EstimatedTime,
+				EstimateCalibrated: t.EstimateCalibrated,
+				Generated:          t.Generated,
+			})
+		}
+		req.Phases = append(req.Phases, pr)
+	}
+	return req
+}
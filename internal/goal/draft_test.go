@@ -0,0 +1,111 @@
+package goal
+
+import (
+	"strings"
+	"task-planner/internal/goal/dto/generate"
+	"task-planner/internal/llm"
+	"testing"
+)
+
+func TestDiffPlans(t *testing.T) {
+	from := &generate.GeneratedGoalPreview{
+		Title: "Learn Go",
+		Phases: []generate.GeneratedPhaseDraft{
+			{Title: "Basics", EstimatedTime: 6, Order: 1, Tasks: []generate.GeneratedTaskDraft{
+				{Title: "Tour of Go", EstimatedTime: 3},
+				{Title: "Write a CLI", EstimatedTime: 3},
+			}},
+			{Title: "Concurrency", EstimatedTime: 14, Order: 2},
+		},
+	}
+	to := &generate.GeneratedGoalPreview{
+		Title: "Learn Go",
+		Phases: []generate.GeneratedPhaseDraft{
+			{Title: "basics", EstimatedTime: 4, Order: 1, Tasks: []generate.GeneratedTaskDraft{
+				{Title: "Tour of Go", EstimatedTime: 4},
+			}},
+			{Title: "Testing", EstimatedTime: 5, Order: 2},
+		},
+	}
+
+	diff := DiffPlans(from, to)
+	if len(diff.Goal) != 0 {
+		t.Errorf("goal changes = %+v, want none", diff.Goal)
+	}
+	if len(diff.Phases) != 3 {
+		t.Fatalf("phase changes = %+v, want 3", diff.Phases)
+	}
+
+	basics := diff.Phases[0]
+	if basics.Change != generate.ChangeChanged || len(basics.Fields) != 1 || basics.Fields[0].Field != "estimated_time" {
+		t.Errorf("basics = %+v, want estimated_time change", basics)
+	}
+	if len(basics.Tasks) != 2 || basics.Tasks[0].Change != generate.ChangeChanged || basics.Tasks[1].Change != generate.ChangeRemoved {
+		t.Errorf("basics tasks = %+v, want changed Tour of Go and removed Write a CLI", basics.Tasks)
+	}
+	if diff.Phases[1].Title != "Testing" || diff.Phases[1].Change != generate.ChangeAdded {
+		t.Errorf("second change = %+v, want Testing added", diff.Phases[1])
+	}
+	if diff.Phases[2].Title != "Concurrency" || diff.Phases[2].Change != generate.ChangeRemoved {
+		t.Errorf("third change = %+v, want Concurrency removed", diff.Phases[2])
+	}
+}
+
+func TestDiffPlansDuplicateTitles(t *testing.T) {
+	from := &generate.GeneratedGoalPreview{
+		Phases: []generate.GeneratedPhaseDraft{
+			{Title: "Practice", EstimatedTime: 2, Tasks: []generate.GeneratedTaskDraft{
+				{Title: "Review", EstimatedTime: 1},
+				{Title: "Review", EstimatedTime: 1},
+			}},
+			{Title: "Practice", EstimatedTime: 3},
+		},
+	}
+	to := &generate.GeneratedGoalPreview{
+		Phases: []generate.GeneratedPhaseDraft{
+			{Title: "Practice", EstimatedTime: 2, Tasks: []generate.GeneratedTaskDraft{
+				{Title: "Review", EstimatedTime: 1},
+				{Title: "Review", EstimatedTime: 2},
+				{Title: "Review", EstimatedTime: 1},
+			}},
+		},
+	}
+
+	diff := DiffPlans(from, to)
+	if len(diff.Phases) != 2 {
+		t.Fatalf("phase changes = %+v, want 2", diff.Phases)
+	}
+	tasks := diff.Phases[0].Tasks
+	if len(tasks) != 2 || tasks[0].Change != generate.ChangeChanged || tasks[1].Change != generate.ChangeAdded {
+		t.Errorf("tasks = %+v, want the second Review changed and a third added", tasks)
+	}
+	if diff.Phases[1].Change != generate.ChangeRemoved || diff.Phases[1].Title != "Practice" {
+		t.Errorf("second change = %+v, want the second Practice removed", diff.Phases[1])
+	}
+}
+
+func TestDraftConversationWrapsInstructions(t *testing.T) {
+	d := &PlanDraft{Messages: []llm.Message{
+		{Role: llm.RoleUser, Content: "decomposition prompt"},
+		{Role: llm.RoleAssistant, Content: "plan 1"},
+		{Role: llm.RoleUser, Content: "Fewer phases"},
+		{Role: llm.RoleAssistant, Content: "plan 2"},
+	}}
+
+	msgs, version, err := d.conversation("en", "  Shorter tasks ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "refine.v1.en" {
+		t.Errorf("version = %q", version)
+	}
+	if len(msgs) != 5 || msgs[0].Content != "decomposition prompt" || msgs[1].Content != "plan 1" {
+		t.Fatalf("messages = %+v", msgs)
+	}
+	if !strings.HasSuffix(msgs[2].Content, "request: Fewer phases") || !strings.HasSuffix(msgs[4].Content, "request: Shorter tasks") {
+		t.Errorf("instructions are not wrapped: %q, %q", msgs[2].Content, msgs[4].Content)
+	}
+	if d.Messages[2].Content != "Fewer phases" {
+		t.Errorf("stored instruction changed to %q", d.Messages[2].Content)
+	}
+}
@@ -0,0 +1,17 @@
+package dto
+
+import "github.com/google/uuid"
+
+// BatchResponse reports the outcome of every item of a batch. A batch is
+// applied as a whole: when any item fails, Applied is false and nothing
+// was changed.
+type BatchResponse struct {
+	Applied bool              `json:"applied"`
+	Results []BatchItemResult `json:"results"`
+}
+
+type BatchItemResult struct {
+	ID    uuid.UUID `json:"id"`
+	OK    bool      `json:"ok"`
+	Error string    `json:"error,omitempty"`
+}
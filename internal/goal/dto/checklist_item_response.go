@@ -0,0 +1,17 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type ChecklistItemResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	TaskID      uuid.UUID  `json:"task_id"`
+	Title       string     `json:"title"`
+	Done        bool       `json:"done"`
+	Order       int        `json:"order"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
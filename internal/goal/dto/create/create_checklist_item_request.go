@@ -0,0 +1,5 @@
+package create
+
+type CreateChecklistItemRequest struct {
+	Title string `json:"title" validate:"required,max=255"`
+}
@@ -1,9 +1,15 @@
 package create
 
 type CreateGoalRequest struct {
-	Title         string               `json:"title" validate:"required,max=255"`
-	Description   string               `json:"description,omitempty"`
-	HoursPerWeek  int                  `json:"hours_per_week" validate:"required,min=1"`
-	EstimatedTime int                  `json:"estimated_time"`
-	Phases        []CreatePhaseRequest `json:"phases,omitempty"`
+	Title            string               `json:"title" validate:"required,max=255"`
+	Description      string               `json:"description,omitempty"`
+	HoursPerWeek     int                  `json:"hours_per_week" validate:"required,min=1"`
+	EstimatedTime    int                  `json:"estimated_time"`
+	ProgressStrategy string               `json:"progress_strategy,omitempty" validate:"omitempty,oneof=time count weighted manual"`
+	Kind             string               `json:"kind,omitempty" validate:"omitempty,oneof=project habit"`
+	Deadline         string               `json:"deadline,omitempty"` // YYYY-MM-DD
+	Phases           []CreatePhaseRequest `json:"phases,omitempty"`
+	// PromptVersion comes from a generated preview and is recorded on the
+	// goal and on its tasks marked Generated.
+	PromptVersion string `json:"prompt_version,omitempty" validate:"max=64"`
 }
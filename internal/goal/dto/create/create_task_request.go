@@ -1,7 +1,18 @@
 package create
 
 type CreateTaskRequest struct {
-	Title         string `json:"title" validate:"required,max=255"`
-	Description   string `json:"description,omitempty"`
-	EstimatedTime int    `json:"estimated_time"`
+	Title         string                       `json:"title" validate:"required,max=255"`
+	Description   string                       `json:"description,omitempty"`
+	EstimatedTime int                          `json:"estimated_time"`
+	ProgressMode  string                       `json:"progress_mode,omitempty" validate:"omitempty,oneof=time checklist"`
+	Priority      string                       `json:"priority,omitempty" validate:"omitempty,oneof=low normal high"`
+	Recurrence    string                       `json:"recurrence_rule,omitempty"`
+	OccurrenceMin *int                         `json:"occurrence_minutes,omitempty" validate:"omitempty,min=1"`
+	Checklist     []CreateChecklistItemRequest `json:"checklist,omitempty"`
+	// EstimateCalibrated comes from a generated preview whose estimates
+	// already include the user's calibration factor.
+	EstimateCalibrated bool `json:"estimate_calibrated,omitempty"`
+	// Generated comes from a generated preview; clear it when the user
+	// writes or edits the task.
+	Generated bool `json:"generated,omitempty"`
 }
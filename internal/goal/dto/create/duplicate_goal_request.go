@@ -0,0 +1,9 @@
+package create
+
+// DuplicateGoalRequest controls what a goal copy takes over. Progress and
+// statuses are always reset.
+type DuplicateGoalRequest struct {
+	Title               string `json:"title,omitempty" validate:"omitempty,max=255"`
+	IncludeAvailability bool   `json:"include_availability"`
+	KeepCompletedTasks  bool   `json:"keep_completed_tasks"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+import "github.com/google/uuid"
+
+// EstimateAccuracyResponse compares estimated and tracked time of the
+// user's completed tasks. A ratio above 1 means tasks took longer than
+// estimated.
+type EstimateAccuracyResponse struct {
+	Days              int                    `json:"days"`
+	Overall           EstimateAccuracyItem   `json:"overall"`
+	ByGoal            []EstimateAccuracyItem `json:"by_goal"`
+	ByTag             []EstimateAccuracyItem `json:"by_tag"`
+	CalibrationFactor *float64               `json:"calibration_factor,omitempty"`
+	SuggestedFactor   *float64               `json:"suggested_factor,omitempty"`
+}
+
+type EstimateAccuracyItem struct {
+	ID               *uuid.UUID `json:"id,omitempty"`
+	Name             string     `json:"name,omitempty"`
+	Tasks            int        `json:"tasks"`
+	EstimatedMinutes int        `json:"estimated_minutes"`
+	ActualMinutes    int        `json:"actual_minutes"`
+	Ratio            float64    `json:"ratio"`
+}
+
+type CalibrationResponse struct {
+	Factor *float64 `json:"factor"`
+}
@@ -0,0 +1,57 @@
+package generate
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type RefineDraftRequest struct {
+	Instruction string `json:"instruction" validate:"required,max=2000"`
+}
+
+type DraftResponse struct {
+	ID    uuid.UUID            `json:"id"`
+	Draft GeneratedGoalPreview `json:"draft"`
+	// Instructions are the refinements applied so far, oldest first.
+	Instructions []string  `json:"instructions"`
+	Version      int       `json:"version"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type RefineDraftResponse struct {
+	Draft DraftResponse `json:"draft"`
+	Diff  PlanDiff      `json:"diff"`
+}
+
+// Kinds of change in a PlanDiff.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// PlanDiff lists what a refinement changed in the plan.
+type PlanDiff struct {
+	Goal   []FieldChange `json:"goal,omitempty"`
+	Phases []PhaseDiff   `json:"phases,omitempty"`
+}
+
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type PhaseDiff struct {
+	Title  string        `json:"title"`
+	Change string        `json:"change"`
+	Fields []FieldChange `json:"fields,omitempty"`
+	Tasks  []TaskDiff    `json:"tasks,omitempty"`
+}
+
+type TaskDiff struct {
+	Title  string        `json:"title"`
+	Change string        `json:"change"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
@@ -6,6 +6,9 @@ type GeneratedGoalPreview struct {
 	HoursPerWeek  int                   `json:"hours_per_week"`
 	EstimatedTime int                   `json:"estimated_time"`
 	Phases        []GeneratedPhaseDraft `json:"phases"`
+	// PromptVersion names the prompt that generated the plan; pass it back
+	// unchanged when creating the goal.
+	PromptVersion string `json:"prompt_version,omitempty"`
 }
 
 type GeneratedPhaseDraft struct {
@@ -20,4 +23,10 @@ type GeneratedTaskDraft struct {
 	Title         string `json:"title"`
 	Description   string `json:"description,omitempty"`
 	EstimatedTime int    `json:"estimated_time"`
+	// EstimateCalibrated is set when the user's calibration factor was
+	// applied; pass it back unchanged when creating the goal.
+	EstimateCalibrated bool `json:"estimate_calibrated"`
+	// Generated marks the task as written by the model; pass it back when
+	// creating the goal unless the user changed the task.
+	Generated bool `json:"generated"`
 }
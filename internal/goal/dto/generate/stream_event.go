@@ -0,0 +1,32 @@
+package generate
+
+// Events of the streamed goal decomposition. phase and task events may be
+// sent again after a retry event; the done event carries the final plan.
+const (
+	EventPhase = "phase"
+	EventTask  = "task"
+	EventRetry = "retry"
+	EventDone  = "done"
+	EventError = "error"
+)
+
+type PhaseEvent struct {
+	Index int                 `json:"index"`
+	Phase GeneratedPhaseDraft `json:"phase"`
+}
+
+type TaskEvent struct {
+	PhaseIndex int                `json:"phase_index"`
+	Index      int                `json:"index"`
+	Task       GeneratedTaskDraft `json:"task"`
+}
+
+// RetryEvent means the model's reply was rejected and a corrected one is
+// being streamed: phases and tasks received so far are void.
+type RetryEvent struct {
+	Attempt int `json:"attempt"`
+}
+
+type ErrorEvent struct {
+	Message string `json:"message"`
+}
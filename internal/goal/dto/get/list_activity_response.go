@@ -0,0 +1,25 @@
+package get
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type ListActivityResponse struct {
+	Items []ActivityItem `json:"items"`
+	Meta  struct {
+		Total  int `json:"total"`
+		Limit  int `json:"limit"`
+		Offset int `json:"offset"`
+	} `json:"meta"`
+}
+
+type ActivityItem struct {
+	ID         uuid.UUID              `json:"id"`
+	ActorID    *int64                 `json:"actor_id,omitempty"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   uuid.UUID              `json:"entity_id"`
+	Action     string                 `json:"action"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
@@ -1,7 +1,22 @@
 package get
 
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
 type ListGoalsRequest struct {
-	Limit  int    `json:"limit"  validate:"required,min=1"`
-	Offset int    `json:"offset" validate:"required,min=0"`
-	Status string `json:"status" validate:"omitempty,oneof=planning active completed paused"`
+	Limit    int         `json:"limit"  validate:"required,min=1"`
+	Offset   int         `json:"offset" validate:"required,min=0"`
+	Status   string      `json:"status" validate:"omitempty,oneof=planning active completed paused"`
+	Kind     string      `json:"kind" validate:"omitempty,oneof=project habit"`
+	Archived bool        `json:"archived"`
+	TagIDs   []uuid.UUID `json:"tag_ids,omitempty"`
+
+	Query     string     `json:"q,omitempty"`
+	Sort      string     `json:"sort,omitempty" validate:"omitempty,oneof=updated_at created_at progress deadline"`
+	Order     string     `json:"order,omitempty" validate:"omitempty,oneof=asc desc"`
+	DateField string     `json:"date_field,omitempty" validate:"omitempty,oneof=updated_at created_at deadline"`
+	From      *time.Time `json:"from,omitempty"`
+	To        *time.Time `json:"to,omitempty"`
 }
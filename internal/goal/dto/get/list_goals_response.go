@@ -2,6 +2,7 @@ package get
 
 import (
 	"github.com/google/uuid"
+	tagdto "task-planner/internal/tag/dto"
 	"time"
 )
 
@@ -15,13 +16,18 @@ type ListGoalsResponse struct {
 }
 
 type ListGoalItem struct {
-	ID           uuid.UUID `json:"id"`
-	Title        string    `json:"title"`
-	Description  string    `json:"description"`
-	Status       string    `json:"status"`
-	Progress     int       `json:"progress"`
-	HoursPerWeek int       `json:"hours_per_week"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           uuid.UUID            `json:"id"`
+	Title        string               `json:"title"`
+	Description  string               `json:"description"`
+	Status       string               `json:"status"`
+	Kind         string               `json:"kind"`
+	Progress     int                  `json:"progress"`
+	HoursPerWeek int                  `json:"hours_per_week"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+	ArchivedAt   *time.Time           `json:"archived_at,omitempty"`
+	Deadline     *string              `json:"deadline,omitempty"`
+	Version      int                  `json:"version"`
+	Tags         []tagdto.TagResponse `json:"tags,omitempty"`
 	NextTask     *struct {
 		ID      uuid.UUID  `json:"id"`
 		Title   string     `json:"title"`
@@ -2,19 +2,28 @@ package dto
 
 import (
 	"github.com/google/uuid"
+	tagdto "task-planner/internal/tag/dto"
 	"time"
 )
 
 type GoalResponse struct {
-	ID            uuid.UUID       `json:"id"`
-	UserID        int64           `json:"user_id"`
-	Title         string          `json:"title"`
-	Description   string          `json:"description"`
-	Status        string          `json:"status"`
-	HoursPerWeek  int             `json:"hours_per_week"`
-	EstimatedTime int             `json:"estimated_time"`
-	Progress      int             `json:"progress"`
-	CreatedAt     time.Time       `json:"created_at"`
-	UpdatedAt     time.Time       `json:"updated_at"`
-	Phases        []PhaseResponse `json:"phases,omitempty"`
+	ID               uuid.UUID            `json:"id"`
+	UserID           int64                `json:"user_id"`
+	Title            string               `json:"title"`
+	Description      string               `json:"description"`
+	Status           string               `json:"status"`
+	HoursPerWeek     int                  `json:"hours_per_week"`
+	EstimatedTime    int                  `json:"estimated_time"`
+	Progress         int                  `json:"progress"`
+	ProgressStrategy string               `json:"progress_strategy"`
+	ManualProgress   *int                 `json:"manual_progress,omitempty"`
+	Kind             string               `json:"kind"`
+	CreatedAt        time.Time            `json:"created_at"`
+	UpdatedAt        time.Time            `json:"updated_at"`
+	ArchivedAt       *time.Time           `json:"archived_at,omitempty"`
+	Deadline         *string              `json:"deadline,omitempty"`
+	Version          int                  `json:"version"`
+	PromptVersion    string               `json:"prompt_version,omitempty"`
+	Tags             []tagdto.TagResponse `json:"tags,omitempty"`
+	Phases           []PhaseResponse      `json:"phases,omitempty"`
 }
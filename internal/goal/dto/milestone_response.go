@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type MilestoneResponse struct {
+	ID          uuid.UUID           `json:"id"`
+	PhaseID     uuid.UUID           `json:"phase_id"`
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	Achieved    bool                `json:"achieved"`
+	AchievedAt  *time.Time          `json:"achieved_at,omitempty"`
+	Criteria    []CriterionResponse `json:"criteria"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+type CriterionResponse struct {
+	ID    uuid.UUID  `json:"id"`
+	Title string     `json:"title"`
+	Met   bool       `json:"met"`
+	MetAt *time.Time `json:"met_at,omitempty"`
+	Order int        `json:"order"`
+}
@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// OccurrenceResponse is one expected date of a recurring task. ID is empty
+// for dates nothing has been recorded for yet.
+type OccurrenceResponse struct {
+	ID          *uuid.UUID `json:"id,omitempty"`
+	TaskID      uuid.UUID  `json:"task_id"`
+	Date        string     `json:"date"`
+	Status      string     `json:"status"`
+	TimeSpent   int        `json:"time_spent"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+type StreakResponse struct {
+	TaskID        uuid.UUID `json:"task_id"`
+	Rule          string    `json:"rule"`
+	Current       int       `json:"current"`
+	Longest       int       `json:"longest"`
+	Completed     int       `json:"completed"`
+	Missed        int       `json:"missed"`
+	LastCompleted *string   `json:"last_completed,omitempty"`
+}
@@ -6,14 +6,19 @@ import (
 )
 
 type PhaseResponse struct {
-	ID          uuid.UUID      `json:"id"`
-	GoalID      uuid.UUID      `json:"goal_id"`
-	Title       string         `json:"title"`
-	Description string         `json:"description"`
-	Status      string         `json:"status"`
-	Progress    int            `json:"progress"`
-	Order       int            `json:"order"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	Tasks       []TaskResponse `json:"tasks,omitempty"`
+	ID          uuid.UUID `json:"id"`
+	GoalID      uuid.UUID `json:"goal_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Status      string    `json:"status"`
+	Progress    int       `json:"progress"`
+	Order       int       `json:"order"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Version     int       `json:"version"`
+	// Locked is set while a milestone of an earlier phase is not achieved;
+	// the scheduler does not plan tasks of locked phases.
+	Locked    bool               `json:"locked"`
+	Milestone *MilestoneResponse `json:"milestone,omitempty"`
+	Tasks     []TaskResponse     `json:"tasks,omitempty"`
 }
@@ -2,18 +2,32 @@ package dto
 
 import (
 	"github.com/google/uuid"
+	tagdto "task-planner/internal/tag/dto"
 	"time"
 )
 
 type TaskResponse struct {
-	ID            uuid.UUID  `json:"id"`
-	GoalID        uuid.UUID  `json:"goal_id"`
-	PhaseID       *uuid.UUID `json:"phase_id,omitempty"`
-	Title         string     `json:"title"`
-	Description   string     `json:"description"`
-	Status        string     `json:"status"`
-	EstimatedTime int        `json:"estimated_time"`
-	CompletedAt   *time.Time `json:"completed_at,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	ID            uuid.UUID               `json:"id"`
+	GoalID        uuid.UUID               `json:"goal_id"`
+	PhaseID       *uuid.UUID              `json:"phase_id,omitempty"`
+	Title         string                  `json:"title"`
+	Description   string                  `json:"description"`
+	Status        string                  `json:"status"`
+	BlockedReason *string                 `json:"blocked_reason,omitempty"`
+	Priority      string                  `json:"priority"`
+	EstimatedTime int                     `json:"estimated_time"`
+	ProgressMode  string                  `json:"progress_mode"`
+	Progress      int                     `json:"progress"`
+	Recurrence    *string                 `json:"recurrence_rule,omitempty"`
+	OccurrenceMin *int                    `json:"occurrence_minutes,omitempty"`
+	CompletedAt   *time.Time              `json:"completed_at,omitempty"`
+	CreatedAt     time.Time               `json:"created_at"`
+	UpdatedAt     time.Time               `json:"updated_at"`
+	Version       int                     `json:"version"`
+	PromptVersion string                  `json:"prompt_version,omitempty"`
+	Tags          []tagdto.TagResponse    `json:"tags,omitempty"`
+	Checklist     []ChecklistItemResponse `json:"checklist,omitempty"`
+	// EstimateCalibrated is set when the estimate already includes the
+	// user's calibration factor.
+	EstimateCalibrated bool `json:"estimate_calibrated"`
 }
@@ -0,0 +1,23 @@
+package template
+
+// SaveTemplateRequest turns a goal into a template. Empty fields fall back
+// to the goal's own title and description.
+type SaveTemplateRequest struct {
+	Title       string `json:"title,omitempty" validate:"omitempty,max=255"`
+	Description string `json:"description,omitempty"`
+	IsPublic    bool   `json:"is_public"`
+}
+
+type UpdateTemplateRequest struct {
+	Title       *string `json:"title,omitempty" validate:"omitempty,max=255"`
+	Description *string `json:"description,omitempty"`
+	IsPublic    *bool   `json:"is_public,omitempty"`
+}
+
+// InstantiateTemplateRequest creates a goal from a template. Zero values
+// keep the template's own settings.
+type InstantiateTemplateRequest struct {
+	Title        string `json:"title,omitempty" validate:"omitempty,max=255"`
+	Description  string `json:"description,omitempty"`
+	HoursPerWeek int    `json:"hours_per_week,omitempty" validate:"omitempty,min=1"`
+}
@@ -0,0 +1,28 @@
+package template
+
+import (
+	"github.com/google/uuid"
+	"task-planner/internal/goal/dto/create"
+	"time"
+)
+
+type TemplateResponse struct {
+	ID               uuid.UUID                   `json:"id"`
+	Title            string                      `json:"title"`
+	Description      string                      `json:"description"`
+	HoursPerWeek     int                         `json:"hours_per_week"`
+	EstimatedTime    int                         `json:"estimated_time"`
+	Kind             string                      `json:"kind"`
+	ProgressStrategy string                      `json:"progress_strategy"`
+	IsPublic         bool                        `json:"is_public"`
+	IsOwner          bool                        `json:"is_owner"`
+	PhaseCount       int                         `json:"phase_count"`
+	TaskCount        int                         `json:"task_count"`
+	Phases           []create.CreatePhaseRequest `json:"phases,omitempty"`
+	CreatedAt        time.Time                   `json:"created_at"`
+	UpdatedAt        time.Time                   `json:"updated_at"`
+}
+
+type ListTemplatesResponse struct {
+	Templates []TemplateResponse `json:"templates"`
+}
@@ -0,0 +1,13 @@
+package update
+
+import "github.com/google/uuid"
+
+// BatchTasksRequest applies one action to many tasks. Status and Reason are
+// used by the status action, PhaseID by move.
+type BatchTasksRequest struct {
+	Action  string      `json:"action" validate:"required,oneof=status move delete"`
+	TaskIDs []uuid.UUID `json:"task_ids" validate:"required,min=1,max=200"`
+	Status  string      `json:"status,omitempty" validate:"omitempty,oneof=todo in_progress blocked completed"`
+	Reason  string      `json:"reason,omitempty"`
+	PhaseID *uuid.UUID  `json:"phase_id,omitempty"`
+}
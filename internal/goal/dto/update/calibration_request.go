@@ -0,0 +1,9 @@
+package update
+
+// SetCalibrationRequest sets the factor applied to new estimates. A null
+// factor turns calibration off; use_suggested takes the factor suggested
+// by the accuracy of recently completed tasks instead.
+type SetCalibrationRequest struct {
+	Factor       *float64 `json:"factor" validate:"omitempty,min=0.25,max=5"`
+	UseSuggested bool     `json:"use_suggested"`
+}
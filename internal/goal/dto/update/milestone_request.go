@@ -0,0 +1,13 @@
+package update
+
+// SetMilestoneRequest creates the phase's milestone or replaces it. A
+// criterion whose title is unchanged keeps its confirmation.
+type SetMilestoneRequest struct {
+	Title       string   `json:"title" validate:"required,max=255"`
+	Description string   `json:"description"`
+	Criteria    []string `json:"criteria" validate:"required,min=1"`
+}
+
+type ConfirmCriterionRequest struct {
+	Met bool `json:"met"`
+}
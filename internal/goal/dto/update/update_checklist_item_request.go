@@ -0,0 +1,12 @@
+package update
+
+import "github.com/google/uuid"
+
+type UpdateChecklistItemRequest struct {
+	Title *string `json:"title,omitempty" validate:"omitempty,max=255"`
+	Done  *bool   `json:"done,omitempty"`
+}
+
+type ReorderChecklistRequest struct {
+	ItemIDs []uuid.UUID `json:"item_ids" validate:"required"`
+}
@@ -0,0 +1,5 @@
+package update
+
+type UpdateProgressModeRequest struct {
+	Mode string `json:"mode" validate:"required,oneof=time checklist"`
+}
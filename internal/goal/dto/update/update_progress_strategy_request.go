@@ -0,0 +1,6 @@
+package update
+
+type UpdateProgressStrategyRequest struct {
+	Strategy       string `json:"strategy" validate:"required,oneof=time count weighted manual"`
+	ManualProgress *int   `json:"manual_progress,omitempty" validate:"omitempty,min=0,max=100"`
+}
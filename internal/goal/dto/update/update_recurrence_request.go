@@ -0,0 +1,11 @@
+package update
+
+// UpdateRecurrenceRequest sets or clears (empty rule) the recurrence of a task.
+type UpdateRecurrenceRequest struct {
+	Rule              string `json:"rule"`
+	OccurrenceMinutes *int   `json:"occurrence_minutes,omitempty" validate:"omitempty,min=1"`
+}
+
+type UpdateOccurrenceRequest struct {
+	Status string `json:"status" validate:"required,oneof=pending completed skipped"`
+}
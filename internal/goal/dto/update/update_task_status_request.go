@@ -0,0 +1,10 @@
+package update
+
+type UpdateTaskStatusRequest struct {
+	Status string `json:"status" validate:"required,oneof=todo in_progress blocked completed"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type UpdateTaskPriorityRequest struct {
+	Priority string `json:"priority" validate:"required,oneof=low normal high"`
+}
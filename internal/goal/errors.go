@@ -0,0 +1,50 @@
+package goal
+
+import "errors"
+
+var (
+	ErrGoalNotFound      = errors.New("goal not found")
+	ErrGoalNotRestorable = errors.New("goal not found or restore window has expired")
+	ErrVersionConflict   = errors.New("the resource was changed by another request")
+
+	ErrInvalidProgressStrategy = errors.New("progress strategy must be one of time, count, weighted, manual")
+	ErrInvalidManualProgress   = errors.New("manual_progress must be between 0 and 100 and is only allowed for the manual strategy")
+
+	ErrTaskNotFound          = errors.New("task not found")
+	ErrInvalidProgressMode   = errors.New("invalid progress mode")
+	ErrChecklistItemNotFound = errors.New("checklist item not found")
+	ErrInvalidChecklistItem  = errors.New("checklist item title is required")
+	ErrInvalidChecklistOrder = errors.New("item_ids must list every checklist item of the task exactly once")
+
+	ErrInvalidStatusTransition = errors.New("invalid task status transition")
+	ErrBlockedReasonRequired   = errors.New("a reason is required to block a task")
+	ErrInvalidPriority         = errors.New("priority must be one of low, normal, high")
+
+	ErrInvalidGoalKind         = errors.New("goal kind must be project or habit")
+	ErrInvalidDeadline         = errors.New("deadline must be a date in YYYY-MM-DD format")
+	ErrInvalidPromptVersion    = errors.New("prompt_version must be one returned with a generated preview")
+	ErrInvalidGoalListQuery    = errors.New("sort must be updated_at, created_at, progress or deadline, order asc or desc, date_field updated_at, created_at or deadline")
+	ErrInvalidRecurrence       = errors.New("invalid recurrence rule")
+	ErrTaskNotRecurring        = errors.New("task is not recurring")
+	ErrInvalidOccurrence       = errors.New("date is not an occurrence of the task")
+	ErrInvalidOccurrenceStatus = errors.New("occurrence status must be pending, completed or skipped")
+
+	ErrPhaseNotFound     = errors.New("phase not found")
+	ErrMilestoneNotFound = errors.New("phase has no milestone")
+	ErrInvalidMilestone  = errors.New("milestone needs a title and at least one acceptance criterion, each at most 255 characters")
+	ErrCriterionNotFound = errors.New("acceptance criterion not found")
+	ErrPhaseOutsideGoal  = errors.New("phase belongs to another goal")
+	ErrInvalidBatch      = errors.New("batch must name a known action and between 1 and 200 items")
+	ErrBatchFailed       = errors.New("batch was not applied")
+
+	ErrInvalidCalibration   = errors.New("calibration factor must be between 0.25 and 5")
+	ErrNotEnoughEstimates   = errors.New("not enough completed tasks with tracked time to suggest a calibration factor")
+	ErrInvalidEstimateQuery = errors.New("days must be a positive number")
+
+	ErrDraftNotFound      = errors.New("plan draft not found")
+	ErrInvalidInstruction = errors.New("instruction is required and must be at most 2000 characters")
+	ErrDraftTurnLimit     = errors.New("the draft cannot be refined any further, save it or start a new one")
+
+	ErrTemplateNotFound     = errors.New("template not found")
+	ErrInvalidTemplateScope = errors.New("scope must be mine or public")
+)
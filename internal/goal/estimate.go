@@ -0,0 +1,120 @@
+package goal
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"math"
+	"time"
+)
+
+// Calibration factor bounds. A factor scales new estimates: 1.5 means the
+// user's tasks take half again as long as estimated.
+const (
+	MinCalibrationFactor = 0.25
+	MaxCalibrationFactor = 5.0
+
+	// minCalibrationSamples completed tasks are needed before a factor is
+	// suggested; a couple of outliers say little about a user's estimates.
+	minCalibrationSamples = 5
+
+	defaultEstimateDays = 90
+	maxEstimateDays     = 365
+)
+
+// Accuracy compares estimated and tracked time over a set of completed
+// tasks. ID and Name identify the goal or tag the set is grouped by.
+type Accuracy struct {
+	ID               uuid.UUID
+	Name             string
+	Tasks            int
+	EstimatedMinutes int
+	ActualMinutes    int
+}
+
+// Ratio is tracked over estimated time; above 1 means the tasks ran over.
+func (a Accuracy) Ratio() float64 {
+	if a.EstimatedMinutes <= 0 {
+		return 0
+	}
+	return roundFactor(float64(a.ActualMinutes) / float64(a.EstimatedMinutes))
+}
+
+// SuggestedFactor is the calibration factor a's ratio calls for, or false
+// when there are too few tasks to tell.
+func (a Accuracy) SuggestedFactor() (float64, bool) {
+	if a.Tasks < minCalibrationSamples || a.EstimatedMinutes <= 0 {
+		return 0, false
+	}
+	f := math.Min(math.Max(a.Ratio(), MinCalibrationFactor), MaxCalibrationFactor)
+	return f, true
+}
+
+// EstimateStats holds the accuracy of a user's completed tasks. Raw only
+// counts tasks whose estimate was not calibrated, so a suggested factor is
+// not derived from estimates that already had one applied.
+type EstimateStats struct {
+	Overall Accuracy
+	Raw     Accuracy
+	ByGoal  []Accuracy
+	ByTag   []Accuracy
+}
+
+// ValidCalibrationFactor reports whether f may be stored as a factor.
+func ValidCalibrationFactor(f float64) bool {
+	return f >= MinCalibrationFactor && f <= MaxCalibrationFactor
+}
+
+// CalibrateMinutes scales an estimate in minutes by the factor.
+func CalibrateMinutes(minutes int, factor float64) int {
+	if factor <= 0 || minutes <= 0 {
+		return minutes
+	}
+	return int(math.Round(float64(minutes) * factor))
+}
+
+// MinutesToHours converts minutes to the whole hours Task.EstimatedTime is
+// stored in, rounding up so that short tasks keep a non-zero estimate.
+func MinutesToHours(minutes int) int {
+	if minutes <= 0 {
+		return 0
+	}
+	return (minutes + 59) / 60
+}
+
+// PlannedMinutes is the time the scheduler reserves for the task: its
+// estimate scaled by factor, unless that already happened at creation.
+func (t *Task) PlannedMinutes(factor float64) int {
+	minutes := t.EstimatedTime * 60
+	if t.EstimateCalibrated {
+		return minutes
+	}
+	return CalibrateMinutes(minutes, factor)
+}
+
+// CalibrationFactor returns the user's calibration factor, or 1 when the
+// user has none.
+func CalibrationFactor(ctx context.Context, repo EstimateRepository, userID int64) (float64, error) {
+	f, err := repo.GetCalibration(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if f == nil {
+		return 1, nil
+	}
+	return *f, nil
+}
+
+// estimateWindow bounds the period the accuracy is computed over.
+func estimateWindow(days int) (int, time.Time) {
+	if days <= 0 {
+		days = defaultEstimateDays
+	}
+	if days > maxEstimateDays {
+		days = maxEstimateDays
+	}
+	return days, time.Now().AddDate(0, 0, -days)
+}
+
+func roundFactor(f float64) float64 {
+	return math.Round(f*100) / 100
+}
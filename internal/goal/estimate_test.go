@@ -0,0 +1,133 @@
+package goal
+
+import (
+	"reflect"
+	"testing"
+
+	"task-planner/internal/goal/dto/generate"
+)
+
+func TestMinutesToHours(t *testing.T) {
+	tests := []struct {
+		minutes int
+		want    int
+	}{
+		{minutes: -30, want: 0},
+		{minutes: 0, want: 0},
+		{minutes: 1, want: 1},
+		{minutes: 59, want: 1},
+		{minutes: 60, want: 1},
+		{minutes: 61, want: 2},
+		{minutes: 150, want: 3},
+	}
+	for _, tt := range tests {
+		if got := MinutesToHours(tt.minutes); got != tt.want {
+			t.Errorf("MinutesToHours(%d) = %d, want %d", tt.minutes, got, tt.want)
+		}
+	}
+}
+
+func TestCalibrateMinutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		minutes int
+		factor  float64
+		want    int
+	}{
+		{name: "scales up", minutes: 60, factor: 1.5, want: 90},
+		{name: "scales down", minutes: 120, factor: 0.5, want: 60},
+		{name: "rounds to the nearest minute", minutes: 10, factor: 1.25, want: 13},
+		{name: "factor of one", minutes: 45, factor: 1, want: 45},
+		{name: "zero factor leaves the estimate", minutes: 60, factor: 0, want: 60},
+		{name: "negative factor leaves the estimate", minutes: 60, factor: -2, want: 60},
+		{name: "zero minutes", minutes: 0, factor: 2, want: 0},
+		{name: "negative minutes", minutes: -10, factor: 2, want: -10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalibrateMinutes(tt.minutes, tt.factor); got != tt.want {
+				t.Errorf("CalibrateMinutes(%d, %v) = %d, want %d", tt.minutes, tt.factor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlannedMinutes(t *testing.T) {
+	tests := []struct {
+		name   string
+		task   Task
+		factor float64
+		want   int
+	}{
+		{name: "estimate hours become minutes", task: Task{EstimatedTime: 2}, factor: 1, want: 120},
+		{name: "uncalibrated estimate is scaled", task: Task{EstimatedTime: 2}, factor: 1.5, want: 180},
+		{name: "calibrated estimate is not scaled again", task: Task{EstimatedTime: 2, EstimateCalibrated: true}, factor: 1.5, want: 120},
+		{name: "zero factor keeps the estimate", task: Task{EstimatedTime: 3}, factor: 0, want: 180},
+		{name: "no estimate", task: Task{}, factor: 2, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.task.PlannedMinutes(tt.factor); got != tt.want {
+				t.Errorf("PlannedMinutes(%v) = %d, want %d", tt.factor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestedFactor(t *testing.T) {
+	tests := []struct {
+		name   string
+		acc    Accuracy
+		want   float64
+		wantOK bool
+	}{
+		{name: "too few tasks", acc: Accuracy{Tasks: minCalibrationSamples - 1, EstimatedMinutes: 600, ActualMinutes: 900}},
+		{name: "no estimated time", acc: Accuracy{Tasks: 10, ActualMinutes: 900}},
+		{name: "over estimate", acc: Accuracy{Tasks: 5, EstimatedMinutes: 600, ActualMinutes: 900}, want: 1.5, wantOK: true},
+		{name: "under estimate", acc: Accuracy{Tasks: 5, EstimatedMinutes: 600, ActualMinutes: 300}, want: 0.5, wantOK: true},
+		{name: "rounded to cents", acc: Accuracy{Tasks: 5, EstimatedMinutes: 300, ActualMinutes: 100}, want: 0.33, wantOK: true},
+		{name: "clamped above", acc: Accuracy{Tasks: 5, EstimatedMinutes: 60, ActualMinutes: 6000}, want: MaxCalibrationFactor, wantOK: true},
+		{name: "clamped below", acc: Accuracy{Tasks: 5, EstimatedMinutes: 6000, ActualMinutes: 60}, want: MinCalibrationFactor, wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.acc.SuggestedFactor()
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("SuggestedFactor() = %v, %v; want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCalibratePreview(t *testing.T) {
+	tests := []struct {
+		name   string
+		factor float64
+		want   [3]int // goal, phase and task hours
+	}{
+		{name: "factor of one keeps whole hours", factor: 1, want: [3]int{10, 4, 2}},
+		{name: "scaled hours round up", factor: 1.2, want: [3]int{12, 5, 3}},
+		{name: "scaled down", factor: 0.5, want: [3]int{5, 2, 1}},
+		{name: "zero factor keeps the estimates", factor: 0, want: [3]int{10, 4, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &generate.GeneratedGoalPreview{
+				EstimatedTime: 10,
+				Phases: []generate.GeneratedPhaseDraft{{
+					EstimatedTime: 4,
+					Tasks:         []generate.GeneratedTaskDraft{{EstimatedTime: 2}},
+				}},
+			}
+			calibratePreview(p, tt.factor)
+
+			got := [3]int{p.EstimatedTime, p.Phases[0].EstimatedTime, p.Phases[0].Tasks[0].EstimatedTime}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hours = %v, want %v", got, tt.want)
+			}
+			if !p.Phases[0].Tasks[0].EstimateCalibrated {
+				t.Error("task is not marked as calibrated")
+			}
+		})
+	}
+}
@@ -1,12 +1,24 @@
 package goal
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"task-planner/internal/goal/dto/create"
 	"task-planner/internal/goal/dto/generate"
 	"task-planner/internal/goal/dto/get"
+	tpldto "task-planner/internal/goal/dto/template"
+	"task-planner/internal/goal/dto/update"
+	"task-planner/internal/llm"
+	"task-planner/internal/tag"
+	tagdto "task-planner/internal/tag/dto"
+	"task-planner/internal/usage"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -31,7 +43,9 @@ func NewHandler(service Service) *Handler {
 // @Success      200                  {object}  generate.GenerateGoalResponse
 // @Failure      400                  {object}  response.ErrorResponse       "Invalid request body"
 // @Failure      401                  {object}  response.ErrorResponse       "Unauthorized"
+// @Failure      429                  {object}  response.ErrorResponse       "LLM usage quota exceeded"
 // @Failure      500                  {object}  response.ErrorResponse       "Failed to generate goal"
+// @Failure      502                  {object}  response.ErrorResponse       "The model did not return a usable plan"
 // @Router       /api/goals/generate [post]
 func (h *Handler) GenerateGoal(w http.ResponseWriter, r *http.Request) {
 	log.Println("[GOAL] GenerateGoal request")
@@ -49,6 +63,15 @@ func (h *Handler) GenerateGoal(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp, err := h.service.GenerateGoalDecomposition(r.Context(), claims.UserID, req)
+	if errors.Is(err, usage.ErrQuotaExceeded) {
+		usage.WriteQuotaError(w, err)
+		return
+	}
+	if errors.Is(err, llm.ErrInvalidOutput) {
+		log.Printf("[GOAL] Failed to generate: %v", err)
+		http.Error(w, "The model did not return a usable plan, please try again", http.StatusBadGateway)
+		return
+	}
 	if err != nil {
 		log.Printf("[GOAL] Failed to generate: %v", err)
 		http.Error(w, "Failed to generate goal", http.StatusInternalServerError)
@@ -59,6 +82,78 @@ func (h *Handler) GenerateGoal(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// @Summary      Потоковая генерация разбивки цели
+// @Description  То же, что /api/goals/generate, но через Server-Sent Events: фазы (event: phase) и задачи (event: task) приходят по мере того, как модель их пишет. event: retry означает, что ответ модели отклонён и полученное ранее недействительно. Последнее событие — done с полной проверенной разбивкой или error. Генерация прерывается, когда клиент отключается
+// @Tags         Goal
+// @Accept       json
+// @Produce      text/event-stream
+// @Security     ApiKeyAuth
+// @Param        GenerateGoalRequest  body      generate.GenerateGoalRequest  true  "Данные для генерации цели"
+// @Success      200                  {object}  generate.GenerateGoalResponse  "Данные события done"
+// @Failure      400                  {object}  response.ErrorResponse       "Invalid request body"
+// @Failure      401                  {object}  response.ErrorResponse       "Unauthorized"
+// @Failure      429                  {object}  response.ErrorResponse       "LLM usage quota exceeded"
+// @Router       /api/goals/generate/stream [post]
+func (h *Handler) GenerateGoalStream(w http.ResponseWriter, r *http.Request) {
+	var req generate.GenerateGoalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// The stream starts with the first event, so a request that is refused
+	// before the model is called (over quota) still gets a plain status.
+	started := false
+	emit := func(event string, data interface{}) error {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+			w.Header().Set("X-Accel-Buffering", "no")
+			w.WriteHeader(http.StatusOK)
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	err = h.service.StreamGoalDecomposition(r.Context(), claims.UserID, req, emit)
+	if err == nil {
+		return
+	}
+	if r.Context().Err() != nil {
+		log.Printf("[GOAL] GenerateGoalStream: client went away: %v", err)
+		return
+	}
+	if !started && errors.Is(err, usage.ErrQuotaExceeded) {
+		usage.WriteQuotaError(w, err)
+		return
+	}
+	log.Printf("[GOAL] Failed to stream generation: %v", err)
+	msg := "Failed to generate goal"
+	if errors.Is(err, llm.ErrInvalidOutput) {
+		msg = "The model did not return a usable plan, please try again"
+	}
+	_ = emit(generate.EventError, generate.ErrorEvent{Message: msg})
+}
+
 // @Summary      Создание цели
 // @Description  Сохраняет новую цель вместе с фазами и задачами в базе
 // @Tags         Goal
@@ -87,6 +182,12 @@ func (h *Handler) CreateGoal(w http.ResponseWriter, r *http.Request) {
 	}
 
 	result, err := h.service.CreateGoal(r.Context(), claims.UserID, req)
+	if errors.Is(err, ErrInvalidPriority) || errors.Is(err, ErrInvalidProgressStrategy) ||
+		errors.Is(err, ErrInvalidGoalKind) || errors.Is(err, ErrInvalidRecurrence) ||
+		errors.Is(err, ErrInvalidDeadline) || errors.Is(err, ErrInvalidPromptVersion) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		log.Printf("[GOAL] failed to create full goal: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -98,15 +199,25 @@ func (h *Handler) CreateGoal(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary      Список целей
-// @Description  Возвращает постраничный список целей пользователя с фильтром по статусу
+// @Description  Возвращает постраничный список целей пользователя с фильтрами, полнотекстовым поиском и сортировкой
 // @Tags         Goal
 // @Accept       json
 // @Produce      json
 // @Security     ApiKeyAuth
 // @Param        status  query     string  false  "Фильтр по статусу (planning,in_progress,completed)"
-// @Param        limit   query     int     false  "Максимальное число элементов" default(10)
+// @Param        archived  query   bool    false  "Показать архивные цели вместо активных"
+// @Param        kind    query     string  false  "Тип цели (project, habit)"
+// @Param        tags    query     string  false  "UUID тегов через запятую: цели с любым из тегов (у цели или её задач)"
+// @Param        q       query     string  false  "Поиск по названию и описанию"
+// @Param        sort    query     string  false  "Сортировка: updated_at, created_at, progress, deadline" default(updated_at)
+// @Param        order   query     string  false  "Направление: asc, desc. По умолчанию desc, для deadline — asc"
+// @Param        date_field  query  string  false  "Поле для диапазона дат: updated_at, created_at, deadline" default(updated_at)
+// @Param        from    query     string  false  "Начало диапазона YYYY-MM-DD (включительно)"
+// @Param        to      query     string  false  "Конец диапазона YYYY-MM-DD (включительно)"
+// @Param        limit   query     int     false  "Максимальное число элементов (до 100)" default(10)
 // @Param        offset  query     int     false  "Смещение для пагинации" default(0)
 // @Success      200     {object}  get.ListGoalsResponse
+// @Failure      400     {object}  response.ErrorResponse  "Invalid query"
 // @Failure      401     {object}  response.ErrorResponse  "Unauthorized"
 // @Failure      500     {object}  response.ErrorResponse  "Internal Server Error"
 // @Router       /api/goals [get]
@@ -119,17 +230,70 @@ func (h *Handler) ListGoals(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	q := r.URL.Query()
 	limit := 10
 	offset := 0
-	status := r.URL.Query().Get("status")
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+	status := q.Get("status")
+	archived := q.Get("archived") == "true"
+	kind := q.Get("kind")
+	if kind != "" && kind != GoalKindProject && kind != GoalKindHabit {
+		http.Error(w, ErrInvalidGoalKind.Error(), http.StatusBadRequest)
+		return
+	}
+	tagIDs, err := tag.ParseIDs(q.Get("tags"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	reqStruct := get.ListGoalsRequest{
-		Limit:  limit,
-		Offset: offset,
-		Status: status,
+		Limit:     limit,
+		Offset:    offset,
+		Status:    status,
+		Archived:  archived,
+		TagIDs:    tagIDs,
+		Kind:      kind,
+		Query:     q.Get("q"),
+		Sort:      q.Get("sort"),
+		Order:     q.Get("order"),
+		DateField: q.Get("date_field"),
+	}
+	if v := q.Get("from"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid from format (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		reqStruct.From = &d
+	}
+	if v := q.Get("to"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid to format (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		// The range is inclusive of the whole last day.
+		d = d.AddDate(0, 0, 1)
+		reqStruct.To = &d
 	}
 
 	resp, err := h.service.ListGoals(r.Context(), claims.UserID, reqStruct)
+	if errors.Is(err, ErrInvalidGoalListQuery) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		log.Printf("[GOAL] failed to list goals: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -140,6 +304,114 @@ func (h *Handler) ListGoals(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// @Summary      Дублировать цель
+// @Description  Создаёт копию цели с фазами, задачами и чек-листами в одной транзакции. Прогресс и статусы сбрасываются, выполненные задачи по умолчанию не копируются
+// @Tags         Goal
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id    path      string                       true  "UUID цели"
+// @Param        body  body      create.DuplicateGoalRequest  false  "Параметры копирования"
+// @Success      201   {object}  create.CreateGoalResponse
+// @Failure      400   {object}  response.ErrorResponse  "Invalid request"
+// @Failure      401   {object}  response.ErrorResponse  "Unauthorized"
+// @Failure      404   {object}  response.ErrorResponse  "Goal not found"
+// @Failure      500   {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/{id}/duplicate [post]
+func (h *Handler) DuplicateGoal(w http.ResponseWriter, r *http.Request) {
+	log.Println("[GOAL] DuplicateGoal request")
+
+	goalID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req create.DuplicateGoalRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := h.service.DuplicateGoal(r.Context(), claims.UserID, goalID, req)
+	if errors.Is(err, ErrGoalNotFound) {
+		http.Error(w, "Goal not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[GOAL] failed to duplicate goal: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// @Summary      История изменений цели
+// @Description  Возвращает журнал изменений цели, её фаз, задач, чек-листов и интервалов, новые записи первыми
+// @Tags         Goal
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id      path      string  true   "UUID цели"
+// @Param        limit   query     int     false  "Максимальное число записей (до 100)" default(20)
+// @Param        offset  query     int     false  "Смещение для пагинации" default(0)
+// @Success      200     {object}  get.ListActivityResponse
+// @Failure      400     {object}  response.ErrorResponse  "Invalid request"
+// @Failure      401     {object}  response.ErrorResponse  "Unauthorized"
+// @Failure      404     {object}  response.ErrorResponse  "Goal not found"
+// @Failure      500     {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/{id}/activity [get]
+func (h *Handler) ListActivity(w http.ResponseWriter, r *http.Request) {
+	goalID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var limit, offset int
+	q := r.URL.Query()
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+
+	resp, err := h.service.ListActivity(r.Context(), claims.UserID, goalID, limit, offset)
+	if errors.Is(err, ErrGoalNotFound) {
+		http.Error(w, "Goal not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[GOAL] failed to list activity: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 // @Summary      Получить цель по ID
 // @Description  Возвращает подробную информацию о цели, включая фазы и задачи
 // @Tags         Goal
@@ -148,13 +420,21 @@ func (h *Handler) ListGoals(w http.ResponseWriter, r *http.Request) {
 // @Security     ApiKeyAuth
 // @Param        id   path      string  true  "UUID цели"
 // @Success      200  {object}  dto.GoalResponse  "Подробная информация о цели"
+// @Header       200  {string}  ETag  "Версия цели для If-Match"
 // @Failure      400  {object}  response.ErrorResponse  "Invalid goal ID"
+// @Failure      401  {object}  response.ErrorResponse  "Unauthorized"
 // @Failure      404  {object}  response.ErrorResponse  "Goal not found"
 // @Failure      500  {object}  response.ErrorResponse  "Internal Server Error"
 // @Router       /api/goals/{id} [get]
 func (h *Handler) GetGoal(w http.ResponseWriter, r *http.Request) {
 	log.Println("[GOAL] GetGoal request")
 
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	goalIDStr := chi.URLParam(r, "id")
 	goalID, err := uuid.Parse(goalIDStr)
 	if err != nil {
@@ -162,7 +442,11 @@ func (h *Handler) GetGoal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	goalResp, err := h.service.GetGoalByID(r.Context(), goalID)
+	goalResp, err := h.service.GetGoalByID(r.Context(), claims.UserID, goalID)
+	if errors.Is(err, ErrGoalNotFound) {
+		http.Error(w, "Goal not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		log.Printf("[GOAL] failed to get goal: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -175,40 +459,1461 @@ func (h *Handler) GetGoal(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(goalResp.Version))
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"goal": goalResp,
 	})
 }
 
 // @Summary      Удалить цель
-// @Description  Удаляет цель по ID
+// @Description  Помечает цель удалённой; её можно восстановить, пока не истёк срок хранения
 // @Tags         Goal
 // @Accept       json
 // @Produce      json
 // @Security     ApiKeyAuth
 // @Param        id   path      string  true  "UUID цели"
+// @Param        If-Match header    string  true  "ETag, полученный при чтении"
 // @Success      204  {string}  string  "No Content"
 // @Failure      400  {object}  response.ErrorResponse  "Invalid goal ID"
 // @Failure      401  {object}  response.ErrorResponse  "Unauthorized"
+// @Failure      404  {object}  response.ErrorResponse  "Goal not found"
+// @Failure      412  {object}  response.ErrorResponse  "Changed by another request"
+// @Failure      428  {object}  response.ErrorResponse  "If-Match header is required"
 // @Failure      500  {object}  response.ErrorResponse  "Internal Server Error"
 // @Router       /api/goals/{id} [delete]
 func (h *Handler) DeleteGoal(w http.ResponseWriter, r *http.Request) {
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	h.changeGoalState(w, r, "delete", func(ctx context.Context, userID int64, goalID uuid.UUID) error {
+		return h.service.DeleteGoal(ctx, userID, goalID, version)
+	})
+}
+
+// @Summary      Восстановить удалённую цель
+// @Description  Снимает пометку об удалении, если срок хранения ещё не истёк
+// @Tags         Goal
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "UUID цели"
+// @Success      204  {string}  string  "No Content"
+// @Failure      400  {object}  response.ErrorResponse  "Invalid goal ID"
+// @Failure      401  {object}  response.ErrorResponse  "Unauthorized"
+// @Failure      404  {object}  response.ErrorResponse  "Goal not found or restore window has expired"
+// @Failure      500  {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/{id}/restore [post]
+func (h *Handler) RestoreGoal(w http.ResponseWriter, r *http.Request) {
+	h.changeGoalState(w, r, "restore", h.service.RestoreGoal)
+}
+
+// @Summary      Архивировать цель
+// @Description  Скрывает цель из списков, сохраняя её историю для статистики
+// @Tags         Goal
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "UUID цели"
+// @Success      204  {string}  string  "No Content"
+// @Failure      400  {object}  response.ErrorResponse  "Invalid goal ID"
+// @Failure      401  {object}  response.ErrorResponse  "Unauthorized"
+// @Failure      404  {object}  response.ErrorResponse  "Goal not found"
+// @Failure      500  {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/{id}/archive [post]
+func (h *Handler) ArchiveGoal(w http.ResponseWriter, r *http.Request) {
+	h.changeGoalState(w, r, "archive", h.service.ArchiveGoal)
+}
+
+// @Summary      Вернуть цель из архива
+// @Description  Возвращает архивную цель в основной список
+// @Tags         Goal
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "UUID цели"
+// @Success      204  {string}  string  "No Content"
+// @Failure      400  {object}  response.ErrorResponse  "Invalid goal ID"
+// @Failure      401  {object}  response.ErrorResponse  "Unauthorized"
+// @Failure      404  {object}  response.ErrorResponse  "Goal not found"
+// @Failure      500  {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/{id}/unarchive [post]
+func (h *Handler) UnarchiveGoal(w http.ResponseWriter, r *http.Request) {
+	h.changeGoalState(w, r, "unarchive", h.service.UnarchiveGoal)
+}
+
+func (h *Handler) changeGoalState(
+	w http.ResponseWriter,
+	r *http.Request,
+	action string,
+	apply func(ctx context.Context, userID int64, goalID uuid.UUID) error,
+) {
 	goalID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
 		return
 	}
 
-	if _, err := auth.GetUserFromContext(r.Context()); err != nil {
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	if err := h.service.DeleteGoal(r.Context(), goalID); err != nil {
-		log.Printf("[GOAL] delete failed: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := apply(r.Context(), claims.UserID, goalID); err != nil {
+		if errors.Is(err, ErrGoalNotFound) || errors.Is(err, ErrGoalNotRestorable) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, ErrVersionConflict) {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+		log.Printf("[GOAL] %s failed: %v", action, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Режим прогресса задачи
+// @Description  Переключает расчёт прогресса задачи между затраченным временем и чек-листом
+// @Tags         Task
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        task_id  path      string                            true  "UUID задачи"
+// @Param        body     body      update.UpdateProgressModeRequest  true  "Режим прогресса (time, checklist)"
+// @Param        If-Match header    string                            true  "ETag, полученный при чтении"
+// @Success      200      {object}  dto.TaskResponse
+// @Failure      400      {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404      {object}  response.ErrorResponse  "Task not found"
+// @Failure      412      {object}  response.ErrorResponse  "Changed by another request"
+// @Failure      428      {object}  response.ErrorResponse  "If-Match header is required"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tasks/{task_id}/progress_mode [patch]
+func (h *Handler) UpdateTaskProgressMode(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := taskRequestParams(w, r)
+	if !ok {
+		return
+	}
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var req update.UpdateProgressModeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.SetTaskProgressMode(r.Context(), userID, taskID, version, req)
+	if err != nil {
+		writeTaskError(w, "set progress mode", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(resp.Version))
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Статус задачи
+// @Description  Явно переводит задачу в статус todo, in_progress, blocked (нужна причина) или completed.
+// @Description  Статусы blocked и completed сохраняются независимо от затраченного времени
+// @Tags         Task
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        task_id  path      string                          true  "UUID задачи"
+// @Param        body     body      update.UpdateTaskStatusRequest  true  "Новый статус и причина блокировки"
+// @Param        If-Match header    string                          true  "ETag, полученный при чтении"
+// @Success      200      {object}  dto.TaskResponse
+// @Failure      400      {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404      {object}  response.ErrorResponse  "Task not found"
+// @Failure      409      {object}  response.ErrorResponse  "Invalid status transition"
+// @Failure      412      {object}  response.ErrorResponse  "Changed by another request"
+// @Failure      428      {object}  response.ErrorResponse  "If-Match header is required"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tasks/{task_id}/status [patch]
+func (h *Handler) UpdateTaskStatus(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := taskRequestParams(w, r)
+	if !ok {
+		return
+	}
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var req update.UpdateTaskStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.SetTaskStatus(r.Context(), userID, taskID, version, req)
+	if err != nil {
+		writeTaskError(w, "set task status", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(resp.Version))
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Приоритет задачи
+// @Description  Задаёт приоритет задачи (low, normal, high). Учитывается авторасписанием и выбором следующей задачи
+// @Tags         Task
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        task_id  path      string                            true  "UUID задачи"
+// @Param        body     body      update.UpdateTaskPriorityRequest  true  "Приоритет"
+// @Param        If-Match header    string                            true  "ETag, полученный при чтении"
+// @Success      200      {object}  dto.TaskResponse
+// @Failure      400      {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404      {object}  response.ErrorResponse  "Task not found"
+// @Failure      412      {object}  response.ErrorResponse  "Changed by another request"
+// @Failure      428      {object}  response.ErrorResponse  "If-Match header is required"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tasks/{task_id}/priority [patch]
+func (h *Handler) UpdateTaskPriority(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := taskRequestParams(w, r)
+	if !ok {
+		return
+	}
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var req update.UpdateTaskPriorityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.SetTaskPriority(r.Context(), userID, taskID, version, req)
+	if err != nil {
+		writeTaskError(w, "set task priority", err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(resp.Version))
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Массовые операции над задачами
+// @Description  Меняет статус, переносит в другую фазу (той же цели) или удаляет несколько задач за раз. Пакет применяется целиком: если хотя бы одна задача не прошла, ничего не меняется и возвращается 422 с результатом по каждой задаче
+// @Tags         Task
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body  body      update.BatchTasksRequest  true  "Действие (status, move, delete) и UUID задач"
+// @Success      200   {object}  dto.BatchResponse
+// @Failure      400   {object}  response.ErrorResponse  "Invalid request"
+// @Failure      401   {object}  response.ErrorResponse  "Unauthorized"
+// @Failure      404   {object}  response.ErrorResponse  "Phase not found"
+// @Failure      422   {object}  dto.BatchResponse       "Batch was not applied"
+// @Failure      500   {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tasks/batch [post]
+func (h *Handler) BatchTasks(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req update.BatchTasksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.BatchTasks(r.Context(), claims.UserID, req)
+	if errors.Is(err, ErrBatchFailed) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if err != nil {
+		writeTaskError(w, "batch tasks", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Чек-лист задачи
+// @Description  Возвращает пункты чек-листа задачи в заданном порядке
+// @Tags         Task
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        task_id  path      string  true  "UUID задачи"
+// @Success      200      {array}   dto.ChecklistItemResponse
+// @Failure      400      {object}  response.ErrorResponse  "Invalid task ID"
+// @Failure      404      {object}  response.ErrorResponse  "Task not found"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tasks/{task_id}/checklist [get]
+func (h *Handler) ListChecklist(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := taskRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.ListChecklist(r.Context(), userID, taskID)
+	if err != nil {
+		writeTaskError(w, "list checklist", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Добавить пункт чек-листа
+// @Description  Добавляет пункт в конец чек-листа задачи
+// @Tags         Task
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        task_id  path      string                             true  "UUID задачи"
+// @Param        body     body      create.CreateChecklistItemRequest  true  "Пункт чек-листа"
+// @Success      201      {object}  dto.ChecklistItemResponse
+// @Failure      400      {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404      {object}  response.ErrorResponse  "Task not found"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tasks/{task_id}/checklist [post]
+func (h *Handler) AddChecklistItem(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := taskRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req create.CreateChecklistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.AddChecklistItem(r.Context(), userID, taskID, req)
+	if err != nil {
+		writeTaskError(w, "add checklist item", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Изменить пункт чек-листа
+// @Description  Переименовывает пункт или отмечает его выполненным
+// @Tags         Task
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        task_id  path      string                             true  "UUID задачи"
+// @Param        item_id  path      string                             true  "UUID пункта"
+// @Param        body     body      update.UpdateChecklistItemRequest  true  "Изменения"
+// @Success      200      {object}  dto.ChecklistItemResponse
+// @Failure      400      {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404      {object}  response.ErrorResponse  "Task or checklist item not found"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tasks/{task_id}/checklist/{item_id} [patch]
+func (h *Handler) UpdateChecklistItem(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := taskRequestParams(w, r)
+	if !ok {
+		return
+	}
+	itemID, err := uuid.Parse(chi.URLParam(r, "item_id"))
+	if err != nil {
+		http.Error(w, "Invalid item ID", http.StatusBadRequest)
+		return
+	}
+
+	var req update.UpdateChecklistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.UpdateChecklistItem(r.Context(), userID, taskID, itemID, req)
+	if err != nil {
+		writeTaskError(w, "update checklist item", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Удалить пункт чек-листа
+// @Tags         Task
+// @Security     ApiKeyAuth
+// @Param        task_id  path      string  true  "UUID задачи"
+// @Param        item_id  path      string  true  "UUID пункта"
+// @Success      204      {string}  string  "No Content"
+// @Failure      400      {object}  response.ErrorResponse  "Invalid ID"
+// @Failure      404      {object}  response.ErrorResponse  "Task or checklist item not found"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tasks/{task_id}/checklist/{item_id} [delete]
+func (h *Handler) DeleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := taskRequestParams(w, r)
+	if !ok {
+		return
+	}
+	itemID, err := uuid.Parse(chi.URLParam(r, "item_id"))
+	if err != nil {
+		http.Error(w, "Invalid item ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteChecklistItem(r.Context(), userID, taskID, itemID); err != nil {
+		writeTaskError(w, "delete checklist item", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Порядок пунктов чек-листа
+// @Description  Задаёт новый порядок всех пунктов чек-листа задачи
+// @Tags         Task
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        task_id  path      string                          true  "UUID задачи"
+// @Param        body     body      update.ReorderChecklistRequest  true  "UUID пунктов в новом порядке"
+// @Success      200      {array}   dto.ChecklistItemResponse
+// @Failure      400      {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404      {object}  response.ErrorResponse  "Task not found"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tasks/{task_id}/checklist/order [put]
+func (h *Handler) ReorderChecklist(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := taskRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req update.ReorderChecklistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.ReorderChecklist(r.Context(), userID, taskID, req)
+	if err != nil {
+		writeTaskError(w, "reorder checklist", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// taskRequestParams extracts the caller and the {task_id} path parameter,
+// writing the error response itself when either is missing.
+func taskRequestParams(w http.ResponseWriter, r *http.Request) (int64, uuid.UUID, bool) {
+	taskID, err := uuid.Parse(chi.URLParam(r, "task_id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return 0, uuid.Nil, false
+	}
+
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, uuid.Nil, false
+	}
+	return claims.UserID, taskID, true
+}
+
+// etag renders an entity version as a strong entity tag.
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatchVersion reads the entity version the client last saw from the
+// If-Match header, which every change to a goal or task must carry.
+func ifMatchVersion(w http.ResponseWriter, r *http.Request) (int, bool) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" {
+		http.Error(w, "If-Match header is required", http.StatusPreconditionRequired)
+		return 0, false
+	}
+	if v == "*" {
+		return AnyVersion, true
+	}
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(v, "W/"), `"`))
+	if err != nil || version < 1 {
+		http.Error(w, "Invalid If-Match header", http.StatusBadRequest)
+		return 0, false
+	}
+	return version, true
+}
+
+func writeTaskError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, ErrTaskNotFound), errors.Is(err, ErrChecklistItemNotFound), errors.Is(err, ErrPhaseNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvalidStatusTransition), errors.Is(err, ErrTaskNotRecurring):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrVersionConflict):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, ErrInvalidProgressMode),
+		errors.Is(err, ErrInvalidChecklistItem),
+		errors.Is(err, ErrInvalidChecklistOrder),
+		errors.Is(err, ErrBlockedReasonRequired),
+		errors.Is(err, ErrInvalidPriority),
+		errors.Is(err, ErrInvalidRecurrence),
+		errors.Is(err, ErrInvalidOccurrence),
+		errors.Is(err, ErrInvalidOccurrenceStatus),
+		errors.Is(err, ErrInvalidBatch):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("[TASK] %s failed: %v", action, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// @Summary      Теги цели
+// @Description  Полностью заменяет набор тегов цели
+// @Tags         Goal
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id    path      string                 true  "UUID цели"
+// @Param        body  body      tagdto.SetTagsRequest  true  "UUID тегов"
+// @Success      200   {array}   tagdto.TagResponse
+// @Failure      400   {object}  response.ErrorResponse  "Invalid request or unknown tags"
+// @Failure      404   {object}  response.ErrorResponse  "Goal not found"
+// @Failure      500   {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/{id}/tags [put]
+func (h *Handler) SetGoalTags(w http.ResponseWriter, r *http.Request) {
+	goalID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req tagdto.SetTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.SetGoalTags(r.Context(), claims.UserID, goalID, req)
+	if errors.Is(err, ErrGoalNotFound) {
+		http.Error(w, "Goal not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		tag.WriteError(w, "set goal tags", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Теги задачи
+// @Description  Полностью заменяет набор тегов задачи
+// @Tags         Task
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        task_id  path      string                 true  "UUID задачи"
+// @Param        body     body      tagdto.SetTagsRequest  true  "UUID тегов"
+// @Success      200      {array}   tagdto.TagResponse
+// @Failure      400      {object}  response.ErrorResponse  "Invalid request or unknown tags"
+// @Failure      404      {object}  response.ErrorResponse  "Task not found"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tasks/{task_id}/tags [put]
+func (h *Handler) SetTaskTags(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := taskRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req tagdto.SetTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.SetTaskTags(r.Context(), userID, taskID, req)
+	if errors.Is(err, ErrTaskNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		tag.WriteError(w, "set task tags", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Стратегия прогресса цели
+// @Description  Задаёт способ расчёта прогресса цели и её фаз: time (затраченное время), count (доля выполненных задач),
+// @Description  weighted (прогресс задач, взвешенный по оценке) или manual (значение manual_progress, задаваемое вручную)
+// @Tags         Goal
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id    path      string                                true  "UUID цели"
+// @Param        body  body      update.UpdateProgressStrategyRequest  true  "Стратегия и ручное значение"
+// @Param        If-Match header    string                                true  "ETag, полученный при чтении"
+// @Success      200   {object}  dto.GoalResponse
+// @Failure      400   {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404   {object}  response.ErrorResponse  "Goal not found"
+// @Failure      412   {object}  response.ErrorResponse  "Changed by another request"
+// @Failure      428   {object}  response.ErrorResponse  "If-Match header is required"
+// @Failure      500   {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/{id}/progress_strategy [patch]
+func (h *Handler) UpdateGoalProgressStrategy(w http.ResponseWriter, r *http.Request) {
+	goalID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var req update.UpdateProgressStrategyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.SetGoalProgressStrategy(r.Context(), claims.UserID, goalID, version, req)
+	switch {
+	case errors.Is(err, ErrGoalNotFound):
+		http.Error(w, "Goal not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrVersionConflict):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		return
+	case errors.Is(err, ErrInvalidProgressStrategy), errors.Is(err, ErrInvalidManualProgress):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("[GOAL] failed to set progress strategy: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(resp.Version))
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Повторение задачи
+// @Description  Задаёт правило повторения в стиле RRULE (FREQ=DAILY|WEEKLY, INTERVAL, BYDAY, COUNT, UNTIL). Пустое правило отключает повторение
+// @Tags         Task
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        task_id  path      string                          true  "UUID задачи"
+// @Param        body     body      update.UpdateRecurrenceRequest  true  "Правило повторения"
+// @Success      200      {object}  dto.TaskResponse
+// @Failure      400      {object}  response.ErrorResponse  "Invalid rule"
+// @Failure      404      {object}  response.ErrorResponse  "Task not found"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tasks/{task_id}/recurrence [put]
+func (h *Handler) UpdateTaskRecurrence(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := taskRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req update.UpdateRecurrenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.SetTaskRecurrence(r.Context(), userID, taskID, req)
+	if err != nil {
+		writeTaskError(w, "set task recurrence", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Экземпляры повторяющейся задачи
+// @Description  Возвращает все ожидаемые даты задачи в диапазоне вместе с отметками о выполнении. По умолчанию — последние 30 дней
+// @Tags         Task
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        task_id     path      string  true   "UUID задачи"
+// @Param        start_date  query     string  false  "Начало диапазона YYYY-MM-DD"
+// @Param        end_date    query     string  false  "Конец диапазона YYYY-MM-DD"
+// @Success      200         {array}   dto.OccurrenceResponse
+// @Failure      400         {object}  response.ErrorResponse  "Invalid date"
+// @Failure      404         {object}  response.ErrorResponse  "Task not found"
+// @Failure      409         {object}  response.ErrorResponse  "Task is not recurring"
+// @Failure      500         {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tasks/{task_id}/occurrences [get]
+func (h *Handler) ListOccurrences(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := taskRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	end := time.Now()
+	start := end.AddDate(0, 0, -30)
+	if v := r.URL.Query().Get("start_date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid start_date format (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		start = d
+	}
+	if v := r.URL.Query().Get("end_date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid end_date format (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		end = d
+	}
+
+	resp, err := h.service.ListOccurrences(r.Context(), userID, taskID, start, end)
+	if err != nil {
+		writeTaskError(w, "list occurrences", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Отметить экземпляр задачи
+// @Description  Меняет статус экземпляра повторяющейся задачи на дату (pending, completed, skipped)
+// @Tags         Task
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        task_id  path      string                          true  "UUID задачи"
+// @Param        date     path      string                          true  "Дата экземпляра YYYY-MM-DD"
+// @Param        body     body      update.UpdateOccurrenceRequest  true  "Статус"
+// @Success      200      {object}  dto.OccurrenceResponse
+// @Failure      400      {object}  response.ErrorResponse  "Invalid date or status"
+// @Failure      404      {object}  response.ErrorResponse  "Task not found"
+// @Failure      409      {object}  response.ErrorResponse  "Task is not recurring"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tasks/{task_id}/occurrences/{date} [patch]
+func (h *Handler) UpdateOccurrence(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := taskRequestParams(w, r)
+	if !ok {
+		return
+	}
+	date, err := time.Parse("2006-01-02", chi.URLParam(r, "date"))
+	if err != nil {
+		http.Error(w, "Invalid date format (YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	var req update.UpdateOccurrenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.SetOccurrenceStatus(r.Context(), userID, taskID, date, req)
+	if err != nil {
+		writeTaskError(w, "update occurrence", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Серия выполнения
+// @Description  Текущая и лучшая серия выполнения повторяющейся задачи. Пропущенные (skipped) экземпляры серию не прерывают
+// @Tags         Task
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        task_id  path      string  true  "UUID задачи"
+// @Success      200      {object}  dto.StreakResponse
+// @Failure      404      {object}  response.ErrorResponse  "Task not found"
+// @Failure      409      {object}  response.ErrorResponse  "Task is not recurring"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tasks/{task_id}/streak [get]
+func (h *Handler) GetStreak(w http.ResponseWriter, r *http.Request) {
+	userID, taskID, ok := taskRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.GetStreak(r.Context(), userID, taskID)
+	if err != nil {
+		writeTaskError(w, "get streak", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Сохранить цель как шаблон
+// @Description  Сохраняет структуру цели (фазы, задачи, оценки, чек-листы) без прогресса и затраченного времени
+// @Tags         Template
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id    path      string                      true  "UUID цели"
+// @Param        body  body      tpldto.SaveTemplateRequest  true  "Параметры шаблона"
+// @Success      201   {object}  tpldto.TemplateResponse
+// @Failure      400   {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404   {object}  response.ErrorResponse  "Goal not found"
+// @Failure      500   {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/{id}/template [post]
+func (h *Handler) SaveGoalAsTemplate(w http.ResponseWriter, r *http.Request) {
+	goalID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req tpldto.SaveTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.SaveGoalAsTemplate(r.Context(), claims.UserID, goalID, req)
+	if err != nil {
+		writeTemplateError(w, "save template", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Библиотека шаблонов
+// @Description  Возвращает свои шаблоны и шаблоны, которыми поделились другие пользователи
+// @Tags         Template
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        scope  query     string  false  "mine — только свои, public — только общие; по умолчанию все доступные"
+// @Success      200    {object}  tpldto.ListTemplatesResponse
+// @Failure      400    {object}  response.ErrorResponse  "Invalid scope"
+// @Failure      500    {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/templates [get]
+func (h *Handler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := h.service.ListTemplates(r.Context(), claims.UserID, r.URL.Query().Get("scope"))
+	if err != nil {
+		writeTemplateError(w, "list templates", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Получить шаблон
+// @Description  Возвращает шаблон вместе с фазами и задачами
+// @Tags         Template
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        template_id  path      string  true  "UUID шаблона"
+// @Success      200          {object}  tpldto.TemplateResponse
+// @Failure      404          {object}  response.ErrorResponse  "Template not found"
+// @Failure      500          {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/templates/{template_id} [get]
+func (h *Handler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, templateID, ok := templateRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.GetTemplate(r.Context(), userID, templateID)
+	if err != nil {
+		writeTemplateError(w, "get template", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Изменить шаблон
+// @Description  Меняет название, описание или доступность шаблона для других пользователей. Доступно только автору
+// @Tags         Template
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        template_id  path      string                        true  "UUID шаблона"
+// @Param        body         body      tpldto.UpdateTemplateRequest  true  "Изменения"
+// @Success      200          {object}  tpldto.TemplateResponse
+// @Failure      400          {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404          {object}  response.ErrorResponse  "Template not found"
+// @Failure      500          {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/templates/{template_id} [patch]
+func (h *Handler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, templateID, ok := templateRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req tpldto.UpdateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.UpdateTemplate(r.Context(), userID, templateID, req)
+	if err != nil {
+		writeTemplateError(w, "update template", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Удалить шаблон
+// @Tags         Template
+// @Security     ApiKeyAuth
+// @Param        template_id  path      string  true  "UUID шаблона"
+// @Success      204          {string}  string  "No Content"
+// @Failure      404          {object}  response.ErrorResponse  "Template not found"
+// @Failure      500          {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/templates/{template_id} [delete]
+func (h *Handler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, templateID, ok := templateRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTemplate(r.Context(), userID, templateID); err != nil {
+		writeTemplateError(w, "delete template", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Создать цель из шаблона
+// @Description  Создаёт новую цель по шаблону тем же путём, что и POST /api/goals. Часы в неделю можно переопределить
+// @Tags         Template
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        template_id  path      string                             true  "UUID шаблона"
+// @Param        body         body      tpldto.InstantiateTemplateRequest  true  "Параметры новой цели"
+// @Success      201          {object}  create.CreateGoalResponse
+// @Failure      400          {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404          {object}  response.ErrorResponse  "Template not found"
+// @Failure      500          {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/templates/{template_id}/instantiate [post]
+func (h *Handler) InstantiateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, templateID, ok := templateRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req tpldto.InstantiateTemplateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.HoursPerWeek < 0 {
+		http.Error(w, "hours_per_week must be positive", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.InstantiateTemplate(r.Context(), userID, templateID, req)
+	if err != nil {
+		writeTemplateError(w, "instantiate template", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func templateRequestParams(w http.ResponseWriter, r *http.Request) (int64, uuid.UUID, bool) {
+	templateID, err := uuid.Parse(chi.URLParam(r, "template_id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return 0, uuid.Nil, false
+	}
+
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, uuid.Nil, false
+	}
+	return claims.UserID, templateID, true
+}
+
+func writeTemplateError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, ErrTemplateNotFound), errors.Is(err, ErrGoalNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvalidTemplateScope),
+		errors.Is(err, ErrInvalidPriority),
+		errors.Is(err, ErrInvalidProgressStrategy),
+		errors.Is(err, ErrInvalidGoalKind),
+		errors.Is(err, ErrInvalidRecurrence):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("[TEMPLATE] %s failed: %v", action, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func phaseRequestParams(w http.ResponseWriter, r *http.Request) (int64, uuid.UUID, bool) {
+	phaseID, err := uuid.Parse(chi.URLParam(r, "phase_id"))
+	if err != nil {
+		http.Error(w, "Invalid phase ID", http.StatusBadRequest)
+		return 0, uuid.Nil, false
+	}
+
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, uuid.Nil, false
+	}
+	return claims.UserID, phaseID, true
+}
+
+func writeMilestoneError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, ErrPhaseNotFound), errors.Is(err, ErrMilestoneNotFound), errors.Is(err, ErrCriterionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvalidMilestone):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("[GOAL] %s failed: %v", action, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// @Summary      Веха фазы
+// @Description  Возвращает веху фазы с критериями приёмки
+// @Tags         Milestone
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        phase_id  path      string  true  "UUID фазы"
+// @Success      200       {object}  dto.MilestoneResponse
+// @Failure      400       {object}  response.ErrorResponse  "Invalid phase ID"
+// @Failure      404       {object}  response.ErrorResponse  "Phase or milestone not found"
+// @Failure      500       {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/phases/{phase_id}/milestone [get]
+func (h *Handler) GetMilestone(w http.ResponseWriter, r *http.Request) {
+	userID, phaseID, ok := phaseRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.GetMilestone(r.Context(), userID, phaseID)
+	if err != nil {
+		writeMilestoneError(w, "get milestone", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Задать веху фазы
+// @Description  Создаёт или заменяет веху фазы. Фаза не завершится, а следующие фазы останутся закрытыми, пока пользователь не подтвердит все критерии приёмки. Критерии с прежним текстом сохраняют подтверждение
+// @Tags         Milestone
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        phase_id  path      string                      true  "UUID фазы"
+// @Param        body      body      update.SetMilestoneRequest  true  "Название, описание и критерии приёмки"
+// @Success      200       {object}  dto.MilestoneResponse
+// @Failure      400       {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404       {object}  response.ErrorResponse  "Phase not found"
+// @Failure      500       {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/phases/{phase_id}/milestone [put]
+func (h *Handler) SetMilestone(w http.ResponseWriter, r *http.Request) {
+	userID, phaseID, ok := phaseRequestParams(w, r)
+	if !ok {
+		return
+	}
+	var req update.SetMilestoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.SetMilestone(r.Context(), userID, phaseID, req)
+	if err != nil {
+		writeMilestoneError(w, "set milestone", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Удалить веху фазы
+// @Tags         Milestone
+// @Security     ApiKeyAuth
+// @Param        phase_id  path      string  true  "UUID фазы"
+// @Success      204       {string}  string  "No Content"
+// @Failure      400       {object}  response.ErrorResponse  "Invalid phase ID"
+// @Failure      404       {object}  response.ErrorResponse  "Phase or milestone not found"
+// @Failure      500       {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/phases/{phase_id}/milestone [delete]
+func (h *Handler) DeleteMilestone(w http.ResponseWriter, r *http.Request) {
+	userID, phaseID, ok := phaseRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteMilestone(r.Context(), userID, phaseID); err != nil {
+		writeMilestoneError(w, "delete milestone", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Подтвердить критерий приёмки
+// @Description  Подтверждает критерий вехи или снимает подтверждение. Когда подтверждён последний критерий, веха достигнута и следующие фазы открываются
+// @Tags         Milestone
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        phase_id      path      string                          true  "UUID фазы"
+// @Param        criterion_id  path      string                          true  "UUID критерия"
+// @Param        body          body      update.ConfirmCriterionRequest  true  "Выполнен ли критерий"
+// @Success      200           {object}  dto.MilestoneResponse
+// @Failure      400           {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404           {object}  response.ErrorResponse  "Phase, milestone or criterion not found"
+// @Failure      500           {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/phases/{phase_id}/milestone/criteria/{criterion_id} [patch]
+func (h *Handler) ConfirmCriterion(w http.ResponseWriter, r *http.Request) {
+	userID, phaseID, ok := phaseRequestParams(w, r)
+	if !ok {
+		return
+	}
+	criterionID, err := uuid.Parse(chi.URLParam(r, "criterion_id"))
+	if err != nil {
+		http.Error(w, "Invalid criterion ID", http.StatusBadRequest)
+		return
+	}
+	var req update.ConfirmCriterionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.ConfirmCriterion(r.Context(), userID, phaseID, criterionID, req)
+	if err != nil {
+		writeMilestoneError(w, "confirm criterion", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func writeEstimateError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidEstimateQuery), errors.Is(err, ErrInvalidCalibration):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrNotEnoughEstimates):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("[GOAL] %s failed: %v", action, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// @Summary      Точность оценок
+// @Description  Сравнивает оценку времени с фактически затраченным временем по завершённым задачам пользователя: в целом, по целям и по тегам. Отношение больше 1 означает, что задачи заняли больше времени, чем оценено. Когда задач достаточно, предлагает коэффициент калибровки
+// @Tags         Estimate
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        days  query     int  false  "За сколько последних дней (по умолчанию 90, не больше 365)"
+// @Success      200   {object}  dto.EstimateAccuracyResponse
+// @Failure      400   {object}  response.ErrorResponse  "Invalid days"
+// @Failure      500   {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/estimates/accuracy [get]
+func (h *Handler) GetEstimateAccuracy(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var days int
+	if v := r.URL.Query().Get("days"); v != "" {
+		if days, err = strconv.Atoi(v); err != nil || days <= 0 {
+			http.Error(w, ErrInvalidEstimateQuery.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	resp, err := h.service.GetEstimateAccuracy(r.Context(), claims.UserID, days)
+	if err != nil {
+		writeEstimateError(w, "get estimate accuracy", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Калибровка оценок
+// @Description  Задаёт коэффициент, на который планировщик и AI-декомпозиция умножают новые оценки. factor: null отключает калибровку, use_suggested берёт предложенный коэффициент
+// @Tags         Estimate
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body  body      update.SetCalibrationRequest  true  "Коэффициент от 0.25 до 5"
+// @Success      200   {object}  dto.CalibrationResponse
+// @Failure      400   {object}  response.ErrorResponse  "Invalid factor"
+// @Failure      409   {object}  response.ErrorResponse  "Not enough completed tasks to suggest a factor"
+// @Failure      500   {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/estimates/calibration [put]
+func (h *Handler) SetCalibration(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req update.SetCalibrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.SetCalibration(r.Context(), claims.UserID, req)
+	if err != nil {
+		writeEstimateError(w, "set calibration", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func draftRequestParams(w http.ResponseWriter, r *http.Request) (int64, uuid.UUID, bool) {
+	draftID, err := uuid.Parse(chi.URLParam(r, "draft_id"))
+	if err != nil {
+		http.Error(w, "Invalid draft ID", http.StatusBadRequest)
+		return 0, uuid.Nil, false
+	}
+
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, uuid.Nil, false
+	}
+	return claims.UserID, draftID, true
+}
+
+func writeDraftError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, ErrDraftNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvalidInstruction), errors.Is(err, ErrInvalidPriority),
+		errors.Is(err, ErrInvalidProgressStrategy), errors.Is(err, ErrInvalidGoalKind),
+		errors.Is(err, ErrInvalidRecurrence), errors.Is(err, ErrInvalidDeadline),
+		errors.Is(err, ErrInvalidPromptVersion):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrDraftTurnLimit), errors.Is(err, ErrVersionConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, usage.ErrQuotaExceeded):
+		usage.WriteQuotaError(w, err)
+	case errors.Is(err, llm.ErrInvalidOutput):
+		log.Printf("[GOAL] %s failed: %v", action, err)
+		http.Error(w, "The model did not return a usable plan, please try again", http.StatusBadGateway)
+	default:
+		log.Printf("[GOAL] %s failed: %v", action, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// @Summary      Начать доработку плана
+// @Description  Генерирует разбивку цели, как /api/goals/generate, и сохраняет её как черновик вместе с диалогом. Черновик можно дорабатывать инструкциями и затем сохранить как цель. Неизменённый 7 дней черновик удаляется
+// @Tags         Draft
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body  body      generate.GenerateGoalRequest  true  "Данные для генерации цели"
+// @Success      201   {object}  generate.DraftResponse
+// @Failure      400   {object}  response.ErrorResponse  "Invalid request body"
+// @Failure      429   {object}  response.ErrorResponse  "LLM usage quota exceeded"
+// @Failure      502   {object}  response.ErrorResponse  "The model did not return a usable plan"
+// @Failure      500   {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/drafts [post]
+func (h *Handler) CreateDraft(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req generate.GenerateGoalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.CreateDraft(r.Context(), claims.UserID, req)
+	if err != nil {
+		writeDraftError(w, "create draft", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Черновик плана
+// @Tags         Draft
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        draft_id  path      string  true  "UUID черновика"
+// @Success      200       {object}  generate.DraftResponse
+// @Failure      404       {object}  response.ErrorResponse  "Draft not found"
+// @Failure      500       {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/drafts/{draft_id} [get]
+func (h *Handler) GetDraft(w http.ResponseWriter, r *http.Request) {
+	userID, draftID, ok := draftRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.GetDraft(r.Context(), userID, draftID)
+	if err != nil {
+		writeDraftError(w, "get draft", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Доработать черновик
+// @Description  Передаёт модели инструкцию («сделай фазу 2 короче», «добавь фазу тестирования») вместе со всем предыдущим диалогом. Возвращает обновлённый план и список изменений. Фазы и задачи сопоставляются по названию
+// @Tags         Draft
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        draft_id  path      string                       true  "UUID черновика"
+// @Param        body      body      generate.RefineDraftRequest  true  "Инструкция"
+// @Success      200       {object}  generate.RefineDraftResponse
+// @Failure      400       {object}  response.ErrorResponse  "Invalid instruction"
+// @Failure      404       {object}  response.ErrorResponse  "Draft not found"
+// @Failure      409       {object}  response.ErrorResponse  "Draft changed concurrently or refined too often"
+// @Failure      429       {object}  response.ErrorResponse  "LLM usage quota exceeded"
+// @Failure      502       {object}  response.ErrorResponse  "The model did not return a usable plan"
+// @Failure      500       {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/drafts/{draft_id}/refine [post]
+func (h *Handler) RefineDraft(w http.ResponseWriter, r *http.Request) {
+	userID, draftID, ok := draftRequestParams(w, r)
+	if !ok {
+		return
+	}
+	var req generate.RefineDraftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.RefineDraft(r.Context(), userID, draftID, req)
+	if err != nil {
+		writeDraftError(w, "refine draft", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Сохранить черновик как цель
+// @Description  Создаёт цель из черновика тем же путём, что и POST /api/goals, и удаляет черновик
+// @Tags         Draft
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        draft_id  path      string  true  "UUID черновика"
+// @Success      201       {object}  create.CreateGoalResponse
+// @Failure      400       {object}  response.ErrorResponse  "Draft is not a valid goal"
+// @Failure      404       {object}  response.ErrorResponse  "Draft not found"
+// @Failure      500       {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/drafts/{draft_id}/commit [post]
+func (h *Handler) CommitDraft(w http.ResponseWriter, r *http.Request) {
+	userID, draftID, ok := draftRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.CommitDraft(r.Context(), userID, draftID)
+	if err != nil {
+		writeDraftError(w, "commit draft", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Удалить черновик
+// @Tags         Draft
+// @Security     ApiKeyAuth
+// @Param        draft_id  path      string  true  "UUID черновика"
+// @Success      204       {string}  string  "No Content"
+// @Failure      404       {object}  response.ErrorResponse  "Draft not found"
+// @Failure      500       {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/drafts/{draft_id} [delete]
+func (h *Handler) DeleteDraft(w http.ResponseWriter, r *http.Request) {
+	userID, draftID, ok := draftRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteDraft(r.Context(), userID, draftID); err != nil {
+		writeDraftError(w, "delete draft", err)
+		return
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,183 @@
+package goal
+
+import (
+	"fmt"
+	"task-planner/internal/goal/dto/generate"
+	"task-planner/internal/llm"
+)
+
+// llmDecomposition is a goal breakdown as the model returns it. All
+// estimates are in hours.
+type llmDecomposition struct {
+	Goal struct {
+		Title         string     `json:"title"`
+		Description   string     `json:"description"`
+		HoursPerWeek  int        `json:"hours_per_week"`
+		EstimatedTime int        `json:"estimated_time"`
+		Phases        []llmPhase `json:"phases"`
+	} `json:"goal"`
+}
+
+type llmPhase struct {
+	Title         string         `json:"title"`
+	Description   string         `json:"description"`
+	EstimatedTime int            `json:"estimated_time"`
+	Tasks         []llmDraftTask `json:"tasks"`
+}
+
+type llmDraftTask struct {
+	Title         string `json:"title"`
+	Description   string `json:"description"`
+	EstimatedTime int    `json:"estimated_time"`
+}
+
+// llmTask is a task as the model returns it; unlike Task, its estimate is
+// in minutes.
+type llmTask struct {
+	Title            string `json:"title"`
+	Description      string `json:"description,omitempty"`
+	EstimatedMinutes int    `json:"estimated_minutes"`
+}
+
+type llmSummary struct {
+	Summary string `json:"summary"`
+}
+
+var (
+	draftTaskSchema = &llm.Schema{
+		Type:     llm.TypeObject,
+		Required: []string{"title", "estimated_time"},
+		Properties: map[string]*llm.Schema{
+			"title":          {Type: llm.TypeString, MinLength: 1},
+			"description":    {Type: llm.TypeString},
+			"estimated_time": {Type: llm.TypeInteger, Minimum: llm.Min(1)},
+		},
+	}
+
+	decompositionSchema = &llm.Schema{
+		Type:     llm.TypeObject,
+		Required: []string{"goal"},
+		Properties: map[string]*llm.Schema{
+			"goal": {
+				Type:     llm.TypeObject,
+				Required: []string{"title", "estimated_time", "phases"},
+				Properties: map[string]*llm.Schema{
+					"title":          {Type: llm.TypeString, MinLength: 1},
+					"description":    {Type: llm.TypeString},
+					"hours_per_week": {Type: llm.TypeInteger, Minimum: llm.Min(0)},
+					"estimated_time": {Type: llm.TypeInteger, Minimum: llm.Min(1)},
+					"phases": {
+						Type:     llm.TypeArray,
+						MinItems: 1,
+						Items: &llm.Schema{
+							Type:     llm.TypeObject,
+							Required: []string{"title", "estimated_time", "tasks"},
+							Properties: map[string]*llm.Schema{
+								"title":          {Type: llm.TypeString, MinLength: 1},
+								"description":    {Type: llm.TypeString},
+								"estimated_time": {Type: llm.TypeInteger, Minimum: llm.Min(1)},
+								"tasks":          {Type: llm.TypeArray, Items: draftTaskSchema},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	extraTasksSchema = &llm.Schema{
+		Type: llm.TypeArray,
+		Items: &llm.Schema{
+			Type:     llm.TypeObject,
+			Required: []string{"title", "estimated_minutes"},
+			Properties: map[string]*llm.Schema{
+				"title":             {Type: llm.TypeString, MinLength: 1},
+				"description":       {Type: llm.TypeString},
+				"estimated_minutes": {Type: llm.TypeInteger, Minimum: llm.Min(1)},
+			},
+		},
+	}
+
+	summarySchema = &llm.Schema{
+		Type:       llm.TypeObject,
+		Required:   []string{"summary"},
+		Properties: map[string]*llm.Schema{"summary": {Type: llm.TypeString, MinLength: 1}},
+	}
+)
+
+// check reports breakdowns the schema lets through but the planner cannot
+// use: a first phase without tasks, or tasks that do not fit their phase.
+func (d *llmDecomposition) check() []string {
+	var problems []string
+	phases := d.Goal.Phases
+	if len(phases) > 0 && len(phases[0].Tasks) == 0 {
+		problems = append(problems, "$.goal.phases[0].tasks: the first phase must have tasks")
+	}
+	for i, ph := range phases {
+		sum := 0
+		for _, t := range ph.Tasks {
+			sum += t.EstimatedTime
+		}
+		if sum > ph.EstimatedTime {
+			problems = append(problems, fmt.Sprintf(
+				"$.goal.phases[%d]: tasks add up to %d hours, more than the phase estimate of %d hours",
+				i, sum, ph.EstimatedTime))
+		}
+	}
+	return problems
+}
+
+func (d *llmDecomposition) preview() *generate.GeneratedGoalPreview {
+	p := &generate.GeneratedGoalPreview{
+		Title:         d.Goal.Title,
+		Description:   d.Goal.Description,
+		HoursPerWeek:  d.Goal.HoursPerWeek,
+		EstimatedTime: d.Goal.EstimatedTime,
+		Phases:        make([]generate.GeneratedPhaseDraft, 0, len(d.Goal.Phases)),
+	}
+	for i, ph := range d.Goal.Phases {
+		p.Phases = append(p.Phases, ph.draft(i+1))
+	}
+	return p
+}
+
+func (ph llmPhase) draft(order int) generate.GeneratedPhaseDraft {
+	d := generate.GeneratedPhaseDraft{
+		Title:         ph.Title,
+		Description:   ph.Description,
+		EstimatedTime: ph.EstimatedTime,
+		Order:         order,
+		Tasks:         make([]generate.GeneratedTaskDraft, 0, len(ph.Tasks)),
+	}
+	for _, t := range ph.Tasks {
+		d.Tasks = append(d.Tasks, t.draft())
+	}
+	return d
+}
+
+func (t llmDraftTask) draft() generate.GeneratedTaskDraft {
+	return generate.GeneratedTaskDraft{
+		Title:         t.Title,
+		Description:   t.Description,
+		EstimatedTime: t.EstimatedTime,
+		Generated:     true,
+	}
+}
+
+// checkExtraTasks reports new tasks that together overrun the hours left
+// in the phase. Nothing is checked once the phase is already over budget.
+func checkExtraTasks(tasks []llmTask, remainingHours int) []string {
+	if remainingHours <= 0 {
+		return nil
+	}
+	sum := 0
+	for _, t := range tasks {
+		sum += t.EstimatedMinutes
+	}
+	if sum > remainingHours*60 {
+		return []string{fmt.Sprintf(
+			"$: tasks add up to %d minutes, more than the %d minutes left in the phase",
+			sum, remainingHours*60)}
+	}
+	return nil
+}
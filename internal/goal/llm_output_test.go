@@ -0,0 +1,141 @@
+package goal
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"task-planner/internal/goal/dto/generate"
+	"task-planner/internal/llm"
+	"task-planner/pkg/config"
+	"testing"
+)
+
+const overBudgetPlan = `{"goal": {"title": "Learn Go", "estimated_time": 20, "phases": [
+	{"title": "Basics", "estimated_time": 4, "tasks": [
+		{"title": "Tour of Go", "estimated_time": 3},
+		{"title": "Write a CLI", "estimated_time": 3}
+	]}
+]}}`
+
+const validPlan = "```json\n" + `{"goal": {"title": "Learn Go", "hours_per_week": 5, "estimated_time": 20, "phases": [
+	{"title": "Basics", "estimated_time": 6, "tasks": [
+		{"title": "Tour of Go", "estimated_time": 3},
+		{"title": "Write a CLI", "estimated_time": 3},
+	]},
+	{"title": "Concurrency", "estimated_time": 14, "tasks": []}
+]}}` + "\n```"
+
+func TestDecomposeRetriesOverBudgetPhase(t *testing.T) {
+	fake := llm.NewFake(overBudgetPlan, validPlan)
+	s := &service{repo: calibrationRepo{}, ai: fake, models: config.LLMConfig{MaxAttempts: 2}}
+
+	p, err := s.decompose(context.Background(), 1, generate.GenerateGoalRequest{Title: "Learn Go", HoursPerWeek: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Phases) != 2 || p.Phases[0].EstimatedTime != 6 || p.Phases[1].Order != 2 || len(p.Phases[0].Tasks) != 2 {
+		t.Errorf("preview = %+v", p)
+	}
+	if p.PromptVersion != "decompose.v1.ru" {
+		t.Errorf("prompt version = %q", p.PromptVersion)
+	}
+	feedback := fake.Requests()[1].Messages[2].Content
+	if !strings.Contains(feedback, "tasks add up to 6 hours, more than the phase estimate of 4 hours") {
+		t.Errorf("feedback = %q", feedback)
+	}
+}
+
+func TestDecomposeFailsWithTypedError(t *testing.T) {
+	s := &service{repo: calibrationRepo{}, ai: llm.NewFake(`not json`, `{"goal": {}}`), models: config.LLMConfig{MaxAttempts: 2}}
+
+	_, err := s.decompose(context.Background(), 1, generate.GenerateGoalRequest{Title: "Learn Go", HoursPerWeek: 5})
+	if !errors.Is(err, llm.ErrInvalidOutput) {
+		t.Fatalf("err = %v, want llm.ErrInvalidOutput", err)
+	}
+}
+
+func TestCheckExtraTasks(t *testing.T) {
+	tasks := []llmTask{{Title: "a", EstimatedMinutes: 90}, {Title: "b", EstimatedMinutes: 60}}
+	if p := checkExtraTasks(tasks, 3); p != nil {
+		t.Errorf("150 of 180 minutes: problems = %q", p)
+	}
+	if p := checkExtraTasks(tasks, 2); len(p) != 1 {
+		t.Errorf("150 of 120 minutes: problems = %q", p)
+	}
+	if p := checkExtraTasks(tasks, 0); p != nil {
+		t.Errorf("phase over budget: problems = %q", p)
+	}
+}
+
+// calibrationRepo answers only GetCalibration and GetUserLocale; other
+// calls panic.
+type calibrationRepo struct {
+	RepositoryAggregator
+	factor *float64
+	locale string
+}
+
+func (r calibrationRepo) GetCalibration(context.Context, int64) (*float64, error) {
+	return r.factor, nil
+}
+
+func (r calibrationRepo) GetUserLocale(context.Context, int64) (string, error) {
+	return r.locale, nil
+}
+
+func TestStreamGoalDecomposition(t *testing.T) {
+	factor := 1.5
+	fake := llm.NewFake(overBudgetPlan, validPlan)
+	s := &service{repo: calibrationRepo{factor: &factor, locale: "en"}, ai: fake, models: config.LLMConfig{MaxAttempts: 2}}
+
+	var events []string
+	var done generate.GenerateGoalResponse
+	emit := func(event string, data interface{}) error {
+		events = append(events, event)
+		if d, ok := data.(generate.GenerateGoalResponse); ok {
+			done = d
+		}
+		if e, ok := data.(generate.TaskEvent); ok && e.Index == 0 && e.Task.EstimatedTime != 5 {
+			t.Errorf("task event estimate = %d, want 3h calibrated to 5h", e.Task.EstimatedTime)
+		}
+		return nil
+	}
+	if err := s.StreamGoalDecomposition(context.Background(), 1, generate.GenerateGoalRequest{Title: "Learn Go"}, emit); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "task task phase retry task task phase phase done"
+	if strings.Join(events, " ") != want {
+		t.Errorf("events = %q, want %q", strings.Join(events, " "), want)
+	}
+	if len(done.GeneratedGoal.Phases) != 2 || done.GeneratedGoal.Phases[0].EstimatedTime != 9 {
+		t.Errorf("done = %+v", done.GeneratedGoal)
+	}
+	if done.GeneratedGoal.PromptVersion != "decompose.v1.en" {
+		t.Errorf("prompt version = %q", done.GeneratedGoal.PromptVersion)
+	}
+	if p := fake.Requests()[0].Messages[0].Content; !strings.Contains(p, "Goal: Learn Go") {
+		t.Errorf("prompt is not in English:\n%s", p)
+	}
+}
+
+func TestStreamGoalDecompositionStopsWhenClientLeaves(t *testing.T) {
+	fake := llm.NewFake(validPlan)
+	s := &service{repo: calibrationRepo{}, ai: fake, models: config.LLMConfig{MaxAttempts: 2}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var events []string
+	emit := func(event string, data interface{}) error {
+		events = append(events, event)
+		cancel()
+		return nil
+	}
+	err := s.StreamGoalDecomposition(ctx, 1, generate.GenerateGoalRequest{Title: "Learn Go"}, emit)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if len(events) != 1 || len(fake.Requests()) != 1 {
+		t.Errorf("events = %q, requests = %d", events, len(fake.Requests()))
+	}
+}
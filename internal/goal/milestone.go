@@ -0,0 +1,140 @@
+package goal
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Milestone is the gate at the end of a phase: acceptance criteria the user
+// confirms by hand once the real outcome is there. Until then the phase does
+// not complete, however much time was tracked, and later phases stay locked.
+type Milestone struct {
+	ID          uuid.UUID   `json:"id"`
+	PhaseID     uuid.UUID   `json:"phase_id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	AchievedAt  *time.Time  `json:"achieved_at,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	Criteria    []Criterion `json:"criteria,omitempty"`
+}
+
+type Criterion struct {
+	ID          uuid.UUID  `json:"id"`
+	MilestoneID uuid.UUID  `json:"milestone_id"`
+	Title       string     `json:"title"`
+	IsMet       bool       `json:"is_met"`
+	MetAt       *time.Time `json:"met_at,omitempty"`
+	Order       int        `json:"order"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Met reports whether every acceptance criterion has been confirmed.
+func (m *Milestone) Met() bool {
+	if len(m.Criteria) == 0 {
+		return false
+	}
+	for _, c := range m.Criteria {
+		if !c.IsMet {
+			return false
+		}
+	}
+	return true
+}
+
+// SyncAchieved sets or clears AchievedAt to match the criteria and reports
+// whether it changed.
+func (m *Milestone) SyncAchieved() bool {
+	met := m.Met()
+	switch {
+	case met && m.AchievedAt == nil:
+		now := time.Now()
+		m.AchievedAt = &now
+		return true
+	case !met && m.AchievedAt != nil:
+		m.AchievedAt = nil
+		return true
+	}
+	return false
+}
+
+// SetMet confirms or withdraws a criterion.
+func (c *Criterion) SetMet(met bool) {
+	if met == c.IsMet {
+		return
+	}
+	c.IsMet = met
+	c.MetAt = nil
+	if met {
+		now := time.Now()
+		c.MetAt = &now
+	}
+}
+
+// Gates maps phase IDs to their milestones.
+type Gates map[uuid.UUID]*Milestone
+
+// Open reports whether nothing holds the phase back from completing.
+func (gs Gates) Open(phaseID uuid.UUID) bool {
+	m, ok := gs[phaseID]
+	return !ok || m.AchievedAt != nil
+}
+
+// AllOpen reports whether every milestone of the goal has been achieved.
+func (gs Gates) AllOpen() bool {
+	for _, m := range gs {
+		if m.AchievedAt == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Locked returns the phases that wait behind an unachieved milestone of an
+// earlier phase. phases must be in their goal order.
+func (gs Gates) Locked(phases []Phase) map[uuid.UUID]bool {
+	locked := make(map[uuid.UUID]bool)
+	closed := false
+	for _, ph := range phases {
+		if closed {
+			locked[ph.ID] = true
+		}
+		if !gs.Open(ph.ID) {
+			closed = true
+		}
+	}
+	return locked
+}
+
+// LoadGates reads the milestones of every phase of the goal.
+func LoadGates(ctx context.Context, repo MilestoneRepository, goalID uuid.UUID) (Gates, error) {
+	milestones, err := repo.ListMilestonesByGoalID(ctx, goalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list milestones: %w", err)
+	}
+	gates := make(Gates, len(milestones))
+	for i := range milestones {
+		gates[milestones[i].PhaseID] = &milestones[i]
+	}
+	return gates, nil
+}
+
+// LockedPhaseIDs returns the phases of the goal that may not be worked on
+// yet because an earlier milestone is still open.
+func LockedPhaseIDs(ctx context.Context, repo RepositoryAggregator, goalID uuid.UUID) (map[uuid.UUID]bool, error) {
+	gates, err := LoadGates(ctx, repo, goalID)
+	if err != nil {
+		return nil, err
+	}
+	if len(gates) == 0 {
+		return map[uuid.UUID]bool{}, nil
+	}
+	phases, err := repo.ListPhasesByGoalID(ctx, goalID)
+	if err != nil {
+		return nil, err
+	}
+	return gates.Locked(phases), nil
+}
@@ -1,24 +1,40 @@
 package goal
 
 import (
+	"fmt"
 	"github.com/google/uuid"
-	"log"
+	"strings"
 	"time"
 )
 
 type Goal struct {
-	ID            uuid.UUID `json:"id"`
-	UserId        int64     `json:"user_id"`
-	Title         string    `json:"title"`
-	Description   string    `json:"description"`
-	Status        string    `json:"status"` // "planning", "active", "completed", "paused"
-	EstimatedTime int       `json:"estimated_time"`
-	Progress      int       `json:"progress"`
-	HoursPerWeek  int       `json:"hoursPerWeek"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            uuid.UUID  `json:"id"`
+	UserId        int64      `json:"user_id"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	Status        string     `json:"status"` // "planning", "active", "completed", "paused"
+	EstimatedTime int        `json:"estimated_time"`
+	Progress      int        `json:"progress"`
+	HoursPerWeek  int        `json:"hoursPerWeek"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	ArchivedAt    *time.Time `json:"archived_at,omitempty"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+	Deadline      *time.Time `json:"deadline,omitempty"`
+	Version       int        `json:"version"`
+
+	ProgressStrategy string `json:"progress_strategy"`
+	ManualProgress   *int   `json:"manual_progress,omitempty"`
+	Kind             string `json:"kind"` // "project", "habit"
+	// PromptVersion names the prompt that generated the goal, such as
+	// "decompose.v1.ru"; empty for goals written by hand.
+	PromptVersion string `json:"prompt_version"`
 }
 
+// AnyVersion stands for a client that does not care which version of an
+// entity it changes (If-Match: *). Stored versions start at 1.
+const AnyVersion = 0
+
 type Phase struct {
 	ID            uuid.UUID  `json:"id"`
 	GoalId        uuid.UUID  `json:"goalId"`
@@ -32,6 +48,60 @@ type Phase struct {
 	UpdatedAt     time.Time  `json:"updated_at"`
 	StartedAt     *time.Time `json:"started_at,omitempty"`
 	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	Version       int        `json:"version"`
+}
+
+// Task progress modes: by tracked time against the estimate, or by the
+// share of ticked checklist items.
+const (
+	ProgressModeTime      = "time"
+	ProgressModeChecklist = "checklist"
+)
+
+// Task statuses. Todo, in_progress and completed are derived from progress;
+// blocked is only ever set by the user.
+const (
+	TaskStatusTodo       = "todo"
+	TaskStatusInProgress = "in_progress"
+	TaskStatusBlocked    = "blocked"
+	TaskStatusCompleted  = "completed"
+)
+
+// taskTransitions lists the statuses a task may be moved to by hand.
+var taskTransitions = map[string][]string{
+	TaskStatusTodo:       {TaskStatusInProgress, TaskStatusBlocked, TaskStatusCompleted},
+	TaskStatusInProgress: {TaskStatusTodo, TaskStatusBlocked, TaskStatusCompleted},
+	TaskStatusBlocked:    {TaskStatusTodo, TaskStatusInProgress, TaskStatusCompleted},
+	TaskStatusCompleted:  {TaskStatusTodo, TaskStatusInProgress},
+}
+
+// Task priorities, stored as small integers so they sort naturally.
+const (
+	PriorityLow    = 1
+	PriorityNormal = 2
+	PriorityHigh   = 3
+)
+
+var priorityNames = map[int]string{
+	PriorityLow:    "low",
+	PriorityNormal: "normal",
+	PriorityHigh:   "high",
+}
+
+func PriorityName(p int) string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+	return priorityNames[PriorityNormal]
+}
+
+func ParsePriority(name string) (int, bool) {
+	for p, n := range priorityNames {
+		if n == name {
+			return p, true
+		}
+	}
+	return 0, false
 }
 
 type Task struct {
@@ -40,67 +110,206 @@ type Task struct {
 	PhaseId       *uuid.UUID `json:"phase_id,omitempty"`
 	Title         string     `json:"title"`
 	Description   string     `json:"description"`
-	Status        string     `json:"status"` // "todo", "in_progress", "completed"
-	EstimatedTime int        `json:"estimated_time"`
-	TimeSpent     int        `json:"time_spent"`
-	CompletedAt   *time.Time `json:"completed_at,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	Status        string     `json:"status"` // "todo", "in_progress", "blocked", "completed"
+	StatusManual  bool       `json:"status_manual"`
+	BlockedReason *string    `json:"blocked_reason,omitempty"`
+	Priority      int        `json:"priority"`
+	EstimatedTime int        `json:"estimated_time"` // hours
+	TimeSpent     int        `json:"time_spent"`     // minutes
+	ProgressMode  string     `json:"progress_mode"`
+	// EstimateCalibrated marks estimates that were already scaled by the
+	// user's calibration factor when the task was created.
+	EstimateCalibrated bool `json:"estimate_calibrated"`
+	// PromptVersion names the prompt that generated the task; empty for
+	// tasks written by hand.
+	PromptVersion string `json:"prompt_version"`
+	// RecurrenceRule makes the task a template for dated occurrences; see
+	// Recurrence for the supported RRULE subset.
+	RecurrenceRule    *string         `json:"recurrence_rule,omitempty"`
+	OccurrenceMinutes *int            `json:"occurrence_minutes,omitempty"`
+	CompletedAt       *time.Time      `json:"completed_at,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+	Checklist         []ChecklistItem `json:"checklist,omitempty"`
+	Version           int             `json:"version"`
+}
+
+type ChecklistItem struct {
+	ID          uuid.UUID  `json:"id"`
+	TaskID      uuid.UUID  `json:"task_id"`
+	Title       string     `json:"title"`
+	IsDone      bool       `json:"is_done"`
+	Order       int        `json:"order"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
-func (p *Phase) CalculateProgress(tasks []Task) int {
-	spent := 0
+// CalculateProgress rolls the phase's tasks up with the goal's strategy.
+// A manual goal value says nothing about individual phases, so phases of
+// manual goals are measured by completed-task count.
+func (p *Phase) CalculateProgress(tasks []Task, strategy ProgressStrategy) int {
+	if _, ok := strategy.(manualStrategy); ok {
+		strategy = countStrategy{}
+	}
+	own := make([]Task, 0, len(tasks))
 	for _, t := range tasks {
 		if t.PhaseId != nil && *t.PhaseId == p.ID {
-			log.Printf("[PHASE PROGRESS] Task with id: %s, Time Spent: %d, PhaseID: %s", t.ID, t.TimeSpent, t.PhaseId)
-			spent += t.TimeSpent
+			own = append(own, t)
 		}
 	}
-	log.Printf("Phase with id: %d Estimated time of phase: %d", p.ID, p.EstimatedTime)
-	if p.EstimatedTime == 0 {
-		return 0
-	}
-	log.Printf("[PHASE PROGRESS] Spent: %d, EstimatedTime * 60: %d", spent, p.EstimatedTime*60)
-	perc := spent * 100 / (p.EstimatedTime * 60)
-	if perc > 100 {
-		perc = 100
+	return strategy.Progress(own, p.EstimatedTime)
+}
+
+// ApplyProgress stores progress and moves the phase status along with it.
+// A phase whose milestone is not achieved yet (gateOpen false) stays in
+// progress at 100% until the user confirms the outcome.
+func (p *Phase) ApplyProgress(progress int, gateOpen bool) {
+	p.Progress = progress
+	switch {
+	case progress == 0:
+		p.Status = "not_started"
+	case progress == 100 && gateOpen:
+		p.Status = "completed"
+		p.MarkCompleted()
+	default:
+		p.Status = "in_progress"
+		p.MarkStarted()
 	}
-	log.Printf("[PHASE PROGRESS] Perc of phase %s : %d", p.ID, perc)
-	return perc
+}
+
+func (g *Goal) Strategy() ProgressStrategy {
+	return StrategyFor(g.ProgressStrategy, g.ManualProgress)
 }
 
 func (g *Goal) CalculateProgress(tasks []Task) int {
-	spent := 0
+	own := make([]Task, 0, len(tasks))
 	for _, t := range tasks {
 		if t.GoalId == g.ID {
-			log.Printf("[PHASE PROGRESS] Task with id: %s, Time Spent: %d, PhaseID: %s", t.ID, t.TimeSpent, t.PhaseId)
-			spent += t.TimeSpent
-			log.Printf("[GOAL PROGRESS] Spent Total: %d", spent)
+			own = append(own, t)
 		}
 	}
-	if g.EstimatedTime == 0 {
-		return 0
+	return g.Strategy().Progress(own, g.EstimatedTime)
+}
+
+// CalculateProgress reports a single task's progress. A completed task is
+// always at 100%, however much time was tracked against it.
+func (t *Task) CalculateProgress() int {
+	if t.Status == TaskStatusCompleted {
+		return 100
+	}
+	return t.trackedProgress()
+}
+
+// DeriveStatus sets Status (and CompletedAt) from the task's current progress.
+// A status the user set by hand (blocked, or completed ahead of the estimate)
+// is left alone.
+func (t *Task) DeriveStatus() {
+	if t.StatusManual {
+		return
 	}
-	log.Printf("[GOAL PROGRESS] Spent: %d, EstimatedTime * 60: %d", spent, g.EstimatedTime*60)
-	perc := spent * 100 / (g.EstimatedTime * 60)
-	if perc > 100 {
-		perc = 100
+	switch t.trackedProgress() {
+	case 0:
+		t.Status = "todo"
+		t.CompletedAt = nil
+	case 100:
+		t.Status = "completed"
+		if t.CompletedAt == nil {
+			now := time.Now()
+			t.CompletedAt = &now
+		}
+	default:
+		t.Status = "in_progress"
+		t.CompletedAt = nil
 	}
-	log.Printf("[GOAL PROGRESS] Perc: %d", perc)
-	return perc
 }
 
-func (t *Task) CalculateProgress() int {
-	if t.EstimatedTime == 0 {
+// TransitionTo moves the task to status on the user's request. Blocked needs
+// a reason; blocked and completed stick until the user changes them again,
+// while todo and in_progress hand the task back to progress derivation.
+func (t *Task) TransitionTo(status, reason string) error {
+	if _, known := taskTransitions[status]; !known {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidStatusTransition, status)
+	}
+	if status == t.Status {
+		// Re-blocking only updates the reason.
+		if status != TaskStatusBlocked {
+			return nil
+		}
+	} else if !containsStatus(taskTransitions[t.Status], status) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, t.Status, status)
+	}
+
+	reason = strings.TrimSpace(reason)
+	if status == TaskStatusBlocked && reason == "" {
+		return ErrBlockedReasonRequired
+	}
+
+	t.BlockedReason = nil
+	switch status {
+	case TaskStatusBlocked:
+		t.BlockedReason = &reason
+		t.CompletedAt = nil
+		t.StatusManual = true
+	case TaskStatusCompleted:
+		if t.CompletedAt == nil {
+			now := time.Now()
+			t.CompletedAt = &now
+		}
+		t.StatusManual = true
+	default:
+		t.CompletedAt = nil
+		t.StatusManual = false
+	}
+	t.Status = status
+	return nil
+}
+
+// NextTask picks the todo task to work on next: the highest priority one,
+// and among equals the earliest in the given order.
+func NextTask(tasks []Task) *Task {
+	var next *Task
+	for i := range tasks {
+		t := &tasks[i]
+		if t.Status != TaskStatusTodo {
+			continue
+		}
+		if next == nil || t.Priority > next.Priority {
+			next = t
+		}
+	}
+	return next
+}
+
+func containsStatus(list []string, status string) bool {
+	for _, s := range list {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// trackedProgress is the progress implied by tracked time or ticked
+// checklist items alone, ignoring the current status.
+func (t *Task) trackedProgress() int {
+	if t.ProgressMode == ProgressModeChecklist && len(t.Checklist) > 0 {
+		return t.checklistProgress()
+	}
+	if t.EstimatedTime <= 0 {
 		return 0
 	}
-	log.Printf("[TASK PROGRESS] Spent: %d, EstimatedTime: %d", t.TimeSpent, t.EstimatedTime*60)
-	p := t.TimeSpent * 100 / (t.EstimatedTime * 60)
-	if p > 100 {
-		p = 100
+	return percent(t.TimeSpent, t.EstimatedTime*60)
+}
+
+func (t *Task) checklistProgress() int {
+	done := 0
+	for _, it := range t.Checklist {
+		if it.IsDone {
+			done++
+		}
 	}
-	log.Printf("[TASK PROGRESS] Perc: %d", p)
-	return p
+	return done * 100 / len(t.Checklist)
 }
 
 func (p *Phase) MarkStarted() {
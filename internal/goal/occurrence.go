@@ -0,0 +1,124 @@
+package goal
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Goal kinds. Habit goals are made of recurring tasks tracked by streaks
+// rather than by a finishing line.
+const (
+	GoalKindProject = "project"
+	GoalKindHabit   = "habit"
+)
+
+// Occurrence statuses.
+const (
+	OccurrencePending   = "pending"
+	OccurrenceCompleted = "completed"
+	OccurrenceSkipped   = "skipped"
+)
+
+// DefaultOccurrenceMinutes is used for recurring tasks that set neither a
+// per-instance duration nor an estimate.
+const DefaultOccurrenceMinutes = 30
+
+// Occurrence is one dated instance of a recurring task. Time and completion
+// are tracked here instead of on the task row, so every day stands alone.
+type Occurrence struct {
+	ID          uuid.UUID  `json:"id"`
+	TaskID      uuid.UUID  `json:"task_id"`
+	Date        time.Time  `json:"date"`
+	Status      string     `json:"status"`
+	TimeSpent   int        `json:"time_spent"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (t *Task) IsRecurring() bool {
+	return t.RecurrenceRule != nil && *t.RecurrenceRule != ""
+}
+
+// OccurrenceDuration is how many minutes one instance of the task takes.
+func (t *Task) OccurrenceDuration() int {
+	if t.OccurrenceMinutes != nil && *t.OccurrenceMinutes > 0 {
+		return *t.OccurrenceMinutes
+	}
+	if t.EstimatedTime > 0 {
+		return t.EstimatedTime * 60
+	}
+	return DefaultOccurrenceMinutes
+}
+
+// ApplyTimeSpent records tracked minutes and completes the instance once
+// they cover its duration. A skipped instance stays skipped.
+func (o *Occurrence) ApplyTimeSpent(spent, needed int) {
+	o.TimeSpent = spent
+	if o.Status == OccurrenceSkipped {
+		return
+	}
+	o.SetStatus(OccurrencePending)
+	if spent >= needed {
+		o.SetStatus(OccurrenceCompleted)
+	}
+}
+
+func (o *Occurrence) SetStatus(status string) {
+	o.Status = status
+	if status != OccurrenceCompleted {
+		o.CompletedAt = nil
+		return
+	}
+	if o.CompletedAt == nil {
+		now := time.Now()
+		o.CompletedAt = &now
+	}
+}
+
+func ValidOccurrenceStatus(s string) bool {
+	return s == OccurrencePending || s == OccurrenceCompleted || s == OccurrenceSkipped
+}
+
+// Streak summarises how consistently a recurring task was done.
+type Streak struct {
+	Current       int
+	Longest       int
+	Completed     int
+	Missed        int
+	LastCompleted *time.Time
+}
+
+// ComputeStreak walks every expected date of the rule up to today. Completed
+// instances extend the streak, skipped ones neither extend nor break it, and
+// anything else breaks it — except today, which is still in progress.
+func ComputeStreak(rule *Recurrence, anchor, today time.Time, occurrences []Occurrence) Streak {
+	byDate := make(map[time.Time]Occurrence, len(occurrences))
+	for _, o := range occurrences {
+		byDate[civilDate(o.Date)] = o
+	}
+	today = civilDate(today)
+
+	var s Streak
+	run := 0
+	for _, d := range rule.Between(anchor, anchor, today) {
+		o, ok := byDate[d]
+		switch {
+		case ok && o.Status == OccurrenceCompleted:
+			run++
+			s.Completed++
+			day := d
+			s.LastCompleted = &day
+		case ok && o.Status == OccurrenceSkipped:
+		case d.Equal(today):
+		default:
+			run = 0
+			s.Missed++
+		}
+		if run > s.Longest {
+			s.Longest = run
+		}
+	}
+	s.Current = run
+	return s
+}
@@ -0,0 +1,125 @@
+package goal
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"task-planner/internal/llm"
+	"task-planner/internal/prompt"
+	"task-planner/internal/usage"
+)
+
+// maxPhaseContextTasks caps how many completed tasks per earlier phase are
+// quoted to the model when generating the tasks of the next phase.
+const maxPhaseContextTasks = 15
+
+// GeneratePhaseTasks fills a phase that has become current without any
+// tasks, as every phase after the first of an AI decomposition does. The
+// model is given what was done in the completed phases. It returns the
+// number of tasks created; zero when the phase was filled in the meantime.
+func (s *service) GeneratePhaseTasks(ctx context.Context, phaseID uuid.UUID) (int, error) {
+	ph, err := s.repo.GetPhaseByID(ctx, phaseID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get phase: %w", err)
+	}
+	g, err := s.repo.GetGoalByID(ctx, ph.GoalId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get goal: %w", err)
+	}
+	if g == nil {
+		return 0, ErrGoalNotFound
+	}
+	ctx = usage.WithCaller(ctx, g.UserId, usage.FeaturePhaseTasks)
+	phases, err := s.repo.ListPhasesByGoalID(ctx, g.ID)
+	if err != nil {
+		return 0, err
+	}
+	tasks, err := s.repo.ListTasksByGoalID(ctx, g.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	var out []llmTask
+	spec := llm.JSONSpec{
+		Schema: extraTasksSchema,
+		Check: func() []string {
+			if len(out) == 0 {
+				return []string{"$: the phase needs at least one task"}
+			}
+			return checkExtraTasks(out, ph.EstimatedTime)
+		},
+		Attempts: s.models.MaxAttempts,
+	}
+	p, err := s.renderPrompt(ctx, g.UserId, prompt.PhaseTasks, phaseTasksData(g, ph, phases, tasks))
+	if err != nil {
+		return 0, err
+	}
+	if _, err := llm.AskJSON(ctx, s.ai, s.models.Refill, p.Text, &out, spec); err != nil {
+		return 0, fmt.Errorf("failed to generate phase tasks: %w", err)
+	}
+
+	factor, err := CalibrationFactor(ctx, s.repo, g.UserId)
+	if err != nil {
+		return 0, err
+	}
+
+	var created int
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		claimed, err := s.repo.ClaimPhaseTasks(ctx, ph.ID)
+		if err != nil || !claimed {
+			return err
+		}
+		titles, err := insertNewTasks(ctx, s.repo, g.ID, ph.ID, out, factor, p.Version)
+		if err != nil {
+			return err
+		}
+		created = len(titles)
+		details := map[string]interface{}{"count": len(titles), "titles": titles}
+		if err := RecordActivity(ctx, s.repo, g.ID, EntityPhase, ph.ID, ActionAIPhaseTasks, details); err != nil {
+			return err
+		}
+		// The new tasks pull goal progress back below 100%.
+		return RecalcGoalPhases(ctx, s.repo, g.ID, ph.ID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+// phaseTasksData fills the prompt for the tasks of ph with the completed
+// tasks of the phases before it.
+func phaseTasksData(g *Goal, ph *Phase, phases []Phase, tasks []Task) prompt.PhaseTasksData {
+	data := prompt.PhaseTasksData{
+		Goal:             g.Title,
+		GoalDescription:  g.Description,
+		HoursPerWeek:     g.HoursPerWeek,
+		Order:            ph.Order,
+		Phase:            ph.Title,
+		PhaseDescription: ph.Description,
+		PhaseHours:       ph.EstimatedTime,
+		MaxMinutes:       ph.EstimatedTime * 60,
+	}
+	for _, p := range phases {
+		if p.Order >= ph.Order {
+			continue
+		}
+		done := prompt.PhaseData{Order: p.Order, Title: p.Title, Description: p.Description}
+		for _, t := range tasks {
+			if t.PhaseId == nil || *t.PhaseId != p.ID || t.Status != TaskStatusCompleted {
+				continue
+			}
+			if len(done.Tasks) == maxPhaseContextTasks {
+				done.More = true
+				break
+			}
+			done.Tasks = append(done.Tasks, prompt.TaskData{
+				Title:          t.Title,
+				EstimatedHours: t.EstimatedTime,
+				SpentMinutes:   t.TimeSpent,
+			})
+		}
+		data.Completed = append(data.Completed, done)
+	}
+	return data
+}
@@ -0,0 +1,46 @@
+package goal
+
+import (
+	"github.com/google/uuid"
+	"testing"
+)
+
+func TestPhaseTasksData(t *testing.T) {
+	g := &Goal{ID: uuid.New(), Title: "Learn Go", HoursPerWeek: 5}
+	phases := []Phase{
+		{ID: uuid.New(), Title: "Basics", Order: 1, Status: "completed"},
+		{ID: uuid.New(), Title: "Concurrency", Order: 2, EstimatedTime: 10},
+		{ID: uuid.New(), Title: "Web", Order: 3},
+	}
+	tasks := []Task{
+		{Title: "Tour of Go", PhaseId: &phases[0].ID, Status: TaskStatusCompleted, EstimatedTime: 3, TimeSpent: 200},
+		{Title: "Skipped draft", PhaseId: &phases[0].ID, Status: "todo"},
+		{Title: "Later work", PhaseId: &phases[2].ID, Status: TaskStatusCompleted},
+	}
+
+	data := phaseTasksData(g, &phases[1], phases, tasks)
+	if data.Order != 2 || data.Phase != "Concurrency" || data.MaxMinutes != 600 {
+		t.Errorf("data = %+v", data)
+	}
+	if len(data.Completed) != 1 || data.Completed[0].Title != "Basics" || data.Completed[0].More {
+		t.Fatalf("completed = %+v", data.Completed)
+	}
+	done := data.Completed[0].Tasks
+	if len(done) != 1 || done[0].Title != "Tour of Go" || done[0].EstimatedHours != 3 || done[0].SpentMinutes != 200 {
+		t.Errorf("tasks = %+v", done)
+	}
+}
+
+func TestPhaseTasksDataCapsTasks(t *testing.T) {
+	g := &Goal{ID: uuid.New()}
+	phases := []Phase{{ID: uuid.New(), Order: 1}, {ID: uuid.New(), Order: 2}}
+	var tasks []Task
+	for i := 0; i <= maxPhaseContextTasks; i++ {
+		tasks = append(tasks, Task{PhaseId: &phases[0].ID, Status: TaskStatusCompleted})
+	}
+
+	data := phaseTasksData(g, &phases[1], phases, tasks)
+	if got := data.Completed[0]; len(got.Tasks) != maxPhaseContextTasks || !got.More {
+		t.Errorf("%d tasks, more = %v", len(got.Tasks), got.More)
+	}
+}
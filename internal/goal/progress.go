@@ -0,0 +1,119 @@
+package goal
+
+// Progress strategies a goal can use to roll its tasks up into phase and
+// goal progress.
+const (
+	// ProgressStrategyTime compares tracked minutes with the estimate in hours.
+	ProgressStrategyTime = "time"
+	// ProgressStrategyCount is the share of completed tasks.
+	ProgressStrategyCount = "count"
+	// ProgressStrategyWeighted averages task progress weighted by estimate.
+	ProgressStrategyWeighted = "weighted"
+	// ProgressStrategyManual reports a value the user sets on the goal.
+	ProgressStrategyManual = "manual"
+)
+
+// ProgressStrategy computes a 0–100 progress value for a set of tasks.
+// estimatedHours is the estimate of the phase or goal the tasks belong to.
+type ProgressStrategy interface {
+	Progress(tasks []Task, estimatedHours int) int
+}
+
+// StrategyFor returns the strategy registered under name, falling back to
+// time-based progress for unknown or empty names.
+func StrategyFor(name string, manual *int) ProgressStrategy {
+	switch name {
+	case ProgressStrategyCount:
+		return countStrategy{}
+	case ProgressStrategyWeighted:
+		return weightedStrategy{}
+	case ProgressStrategyManual:
+		return manualStrategy{value: manual}
+	default:
+		return timeStrategy{}
+	}
+}
+
+func ValidProgressStrategy(name string) bool {
+	switch name {
+	case ProgressStrategyTime, ProgressStrategyCount, ProgressStrategyWeighted, ProgressStrategyManual:
+		return true
+	}
+	return false
+}
+
+type timeStrategy struct{}
+
+func (timeStrategy) Progress(tasks []Task, estimatedHours int) int {
+	if estimatedHours <= 0 {
+		return 0
+	}
+	spent := 0
+	for _, t := range tasks {
+		spent += t.TimeSpent
+	}
+	return percent(spent, estimatedHours*60)
+}
+
+type countStrategy struct{}
+
+func (countStrategy) Progress(tasks []Task, _ int) int {
+	if len(tasks) == 0 {
+		return 0
+	}
+	done := 0
+	for _, t := range tasks {
+		if t.Status == TaskStatusCompleted {
+			done++
+		}
+	}
+	return percent(done, len(tasks))
+}
+
+type weightedStrategy struct{}
+
+// Progress weights each task's own progress by its estimate, so finishing a
+// three-hour task moves the needle three times as far as a one-hour one.
+// Without any estimates it degrades to a plain completed-task count.
+func (weightedStrategy) Progress(tasks []Task, _ int) int {
+	total, done := 0, 0
+	for i := range tasks {
+		w := tasks[i].EstimatedTime
+		total += w
+		done += w * tasks[i].CalculateProgress()
+	}
+	if total == 0 {
+		return countStrategy{}.Progress(tasks, 0)
+	}
+	return percent(done, total*100)
+}
+
+type manualStrategy struct {
+	value *int
+}
+
+// Progress returns the user's value. Phases have no value of their own, so
+// callers roll them up by task count instead (see Phase.CalculateProgress).
+func (m manualStrategy) Progress(_ []Task, _ int) int {
+	if m.value == nil {
+		return 0
+	}
+	return clampPercent(*m.value)
+}
+
+func percent(part, whole int) int {
+	if whole <= 0 {
+		return 0
+	}
+	return clampPercent(part * 100 / whole)
+}
+
+func clampPercent(p int) int {
+	if p < 0 {
+		return 0
+	}
+	if p > 100 {
+		return 100
+	}
+	return p
+}
@@ -0,0 +1,332 @@
+package goal
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestStrategyProgress(t *testing.T) {
+	tests := []struct {
+		name      string
+		strategy  string
+		manual    *int
+		tasks     []Task
+		estimated int
+		want      int
+	}{
+		{
+			name:      "time: spent minutes against estimated hours",
+			strategy:  ProgressStrategyTime,
+			tasks:     []Task{{TimeSpent: 60}, {TimeSpent: 30}},
+			estimated: 3,
+			want:      50,
+		},
+		{
+			name:      "time: capped at 100",
+			strategy:  ProgressStrategyTime,
+			tasks:     []Task{{TimeSpent: 600}},
+			estimated: 2,
+			want:      100,
+		},
+		{
+			name:      "time: no estimate",
+			strategy:  ProgressStrategyTime,
+			tasks:     []Task{{TimeSpent: 60}},
+			estimated: 0,
+			want:      0,
+		},
+		{
+			name:      "unknown strategy falls back to time",
+			strategy:  "bogus",
+			tasks:     []Task{{TimeSpent: 30}},
+			estimated: 1,
+			want:      50,
+		},
+		{
+			name:     "count: share of completed tasks",
+			strategy: ProgressStrategyCount,
+			tasks: []Task{
+				{Status: TaskStatusCompleted},
+				{Status: TaskStatusInProgress, TimeSpent: 500},
+				{Status: TaskStatusBlocked},
+				{Status: TaskStatusTodo},
+			},
+			want: 25,
+		},
+		{
+			name:     "count: no tasks",
+			strategy: ProgressStrategyCount,
+			want:     0,
+		},
+		{
+			name:     "weighted: completed tasks weigh by estimate",
+			strategy: ProgressStrategyWeighted,
+			tasks: []Task{
+				{Status: TaskStatusCompleted, EstimatedTime: 3},
+				{Status: TaskStatusTodo, EstimatedTime: 1},
+			},
+			want: 75,
+		},
+		{
+			name:     "weighted: partial task progress counts",
+			strategy: ProgressStrategyWeighted,
+			tasks: []Task{
+				{Status: TaskStatusInProgress, EstimatedTime: 2, TimeSpent: 60},
+				{Status: TaskStatusTodo, EstimatedTime: 2},
+			},
+			want: 25,
+		},
+		{
+			name:     "weighted: finished early still counts fully",
+			strategy: ProgressStrategyWeighted,
+			tasks: []Task{
+				{Status: TaskStatusCompleted, EstimatedTime: 4, TimeSpent: 10},
+				{Status: TaskStatusTodo, EstimatedTime: 4},
+			},
+			want: 50,
+		},
+		{
+			name:     "weighted: without estimates degrades to count",
+			strategy: ProgressStrategyWeighted,
+			tasks: []Task{
+				{Status: TaskStatusCompleted},
+				{Status: TaskStatusTodo},
+			},
+			want: 50,
+		},
+		{
+			name:     "weighted: checklist tasks use their checklist",
+			strategy: ProgressStrategyWeighted,
+			tasks: []Task{
+				{
+					Status:        TaskStatusInProgress,
+					EstimatedTime: 2,
+					ProgressMode:  ProgressModeChecklist,
+					Checklist:     []ChecklistItem{{IsDone: true}, {IsDone: false}},
+				},
+			},
+			want: 50,
+		},
+		{
+			name:     "manual: reports the stored value",
+			strategy: ProgressStrategyManual,
+			manual:   intPtr(42),
+			tasks:    []Task{{Status: TaskStatusCompleted}},
+			want:     42,
+		},
+		{
+			name:     "manual: unset value is zero",
+			strategy: ProgressStrategyManual,
+			want:     0,
+		},
+		{
+			name:     "manual: out of range value is clamped",
+			strategy: ProgressStrategyManual,
+			manual:   intPtr(150),
+			want:     100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := StrategyFor(tt.strategy, tt.manual).Progress(tt.tasks, tt.estimated)
+			if got != tt.want {
+				t.Errorf("Progress() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskCalculateProgress(t *testing.T) {
+	tests := []struct {
+		name string
+		task Task
+		want int
+	}{
+		{
+			name: "time based",
+			task: Task{Status: TaskStatusInProgress, EstimatedTime: 2, TimeSpent: 30},
+			want: 25,
+		},
+		{
+			name: "no estimate",
+			task: Task{Status: TaskStatusTodo, TimeSpent: 30},
+			want: 0,
+		},
+		{
+			name: "overrun is capped",
+			task: Task{Status: TaskStatusInProgress, EstimatedTime: 1, TimeSpent: 120},
+			want: 100,
+		},
+		{
+			name: "completed ahead of estimate",
+			task: Task{Status: TaskStatusCompleted, EstimatedTime: 3, TimeSpent: 15},
+			want: 100,
+		},
+		{
+			name: "checklist mode",
+			task: Task{
+				Status:       TaskStatusInProgress,
+				ProgressMode: ProgressModeChecklist,
+				Checklist:    []ChecklistItem{{IsDone: true}, {IsDone: true}, {IsDone: false}, {IsDone: false}},
+			},
+			want: 50,
+		},
+		{
+			name: "checklist mode without items falls back to time",
+			task: Task{Status: TaskStatusInProgress, ProgressMode: ProgressModeChecklist, EstimatedTime: 1, TimeSpent: 15},
+			want: 25,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.task.CalculateProgress(); got != tt.want {
+				t.Errorf("CalculateProgress() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPhaseAndGoalCalculateProgress(t *testing.T) {
+	goalID := uuid.New()
+	phaseA := uuid.New()
+	phaseB := uuid.New()
+	tasks := []Task{
+		{GoalId: goalID, PhaseId: &phaseA, Status: TaskStatusCompleted, EstimatedTime: 1, TimeSpent: 60},
+		{GoalId: goalID, PhaseId: &phaseA, Status: TaskStatusTodo, EstimatedTime: 1},
+		{GoalId: goalID, PhaseId: &phaseB, Status: TaskStatusTodo, EstimatedTime: 2},
+		{GoalId: uuid.New(), PhaseId: &phaseB, Status: TaskStatusCompleted, EstimatedTime: 5},
+	}
+
+	tests := []struct {
+		name      string
+		goal      Goal
+		wantGoal  int
+		wantPhase int
+	}{
+		{
+			name:      "time",
+			goal:      Goal{ID: goalID, ProgressStrategy: ProgressStrategyTime, EstimatedTime: 4},
+			wantGoal:  25,
+			wantPhase: 50,
+		},
+		{
+			name:      "count",
+			goal:      Goal{ID: goalID, ProgressStrategy: ProgressStrategyCount},
+			wantGoal:  33,
+			wantPhase: 50,
+		},
+		{
+			name:      "weighted",
+			goal:      Goal{ID: goalID, ProgressStrategy: ProgressStrategyWeighted},
+			wantGoal:  25,
+			wantPhase: 50,
+		},
+		{
+			name:      "manual goal, phases by count",
+			goal:      Goal{ID: goalID, ProgressStrategy: ProgressStrategyManual, ManualProgress: intPtr(80)},
+			wantGoal:  80,
+			wantPhase: 50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.goal.CalculateProgress(tasks); got != tt.wantGoal {
+				t.Errorf("Goal.CalculateProgress() = %d, want %d", got, tt.wantGoal)
+			}
+			ph := Phase{ID: phaseA, GoalId: goalID, EstimatedTime: 2}
+			if got := ph.CalculateProgress(tasks, tt.goal.Strategy()); got != tt.wantPhase {
+				t.Errorf("Phase.CalculateProgress() = %d, want %d", got, tt.wantPhase)
+			}
+		})
+	}
+}
+
+func TestDeriveStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		task       Task
+		wantStatus string
+		wantDone   bool
+	}{
+		{
+			name:       "no progress",
+			task:       Task{Status: TaskStatusInProgress, EstimatedTime: 1},
+			wantStatus: TaskStatusTodo,
+		},
+		{
+			name:       "partial progress",
+			task:       Task{Status: TaskStatusTodo, EstimatedTime: 1, TimeSpent: 20},
+			wantStatus: TaskStatusInProgress,
+		},
+		{
+			name:       "estimate reached",
+			task:       Task{Status: TaskStatusInProgress, EstimatedTime: 1, TimeSpent: 60},
+			wantStatus: TaskStatusCompleted,
+			wantDone:   true,
+		},
+		{
+			name:       "auto-completed task reopens when time is untracked",
+			task:       Task{Status: TaskStatusCompleted, EstimatedTime: 1, TimeSpent: 30},
+			wantStatus: TaskStatusInProgress,
+		},
+		{
+			name:       "manual completion sticks",
+			task:       Task{Status: TaskStatusCompleted, StatusManual: true, EstimatedTime: 4, TimeSpent: 10},
+			wantStatus: TaskStatusCompleted,
+		},
+		{
+			name:       "blocked sticks",
+			task:       Task{Status: TaskStatusBlocked, StatusManual: true, EstimatedTime: 1, TimeSpent: 60},
+			wantStatus: TaskStatusBlocked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := tt.task
+			task.DeriveStatus()
+			if task.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", task.Status, tt.wantStatus)
+			}
+			if tt.wantDone && task.CompletedAt == nil {
+				t.Errorf("CompletedAt not set")
+			}
+		})
+	}
+}
+
+func TestPhaseGates(t *testing.T) {
+	p1, p2, p3 := uuid.New(), uuid.New(), uuid.New()
+	phases := []Phase{{ID: p1}, {ID: p2}, {ID: p3}}
+	pending := &Milestone{PhaseID: p1, Criteria: []Criterion{{IsMet: true}, {IsMet: false}}}
+	gates := Gates{p1: pending}
+
+	locked := gates.Locked(phases)
+	if locked[p1] || !locked[p2] || !locked[p3] {
+		t.Errorf("Locked() = %v, want phases after the open milestone locked", locked)
+	}
+
+	ph := Phase{ID: p1}
+	ph.ApplyProgress(100, gates.Open(p1))
+	if ph.Status != "in_progress" || ph.CompletedAt != nil {
+		t.Errorf("gated phase at 100%%: status %q, completed %v", ph.Status, ph.CompletedAt)
+	}
+
+	pending.Criteria[1].SetMet(true)
+	if !pending.SyncAchieved() || pending.AchievedAt == nil {
+		t.Fatalf("milestone not achieved after confirming every criterion")
+	}
+	if locked := gates.Locked(phases); len(locked) != 0 {
+		t.Errorf("Locked() = %v after the milestone was achieved, want none", locked)
+	}
+	ph.ApplyProgress(100, gates.Open(p1))
+	if ph.Status != "completed" {
+		t.Errorf("status = %q, want completed once the gate is open", ph.Status)
+	}
+}
@@ -0,0 +1,210 @@
+package goal
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported RRULE frequencies.
+const (
+	FreqDaily  = "DAILY"
+	FreqWeekly = "WEEKLY"
+)
+
+var rruleDays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Recurrence is the subset of RFC 5545 RRULE we support: FREQ=DAILY|WEEKLY
+// with optional INTERVAL, BYDAY, COUNT and UNTIL, e.g.
+//
+//	FREQ=DAILY
+//	FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR
+//	FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10
+//
+// Occurrences are counted from an anchor date, normally the day the task
+// was created; it plays the role of DTSTART.
+type Recurrence struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday
+	Count    int
+	Until    *time.Time
+}
+
+// ParseRecurrence parses an RRULE string, with or without the "RRULE:" prefix.
+func ParseRecurrence(s string) (*Recurrence, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty rule", ErrInvalidRecurrence)
+	}
+
+	r := &Recurrence{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%w: malformed part %q", ErrInvalidRecurrence, part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: bad INTERVAL %q", ErrInvalidRecurrence, value)
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, d := range strings.Split(strings.ToUpper(value), ",") {
+				wd, ok := rruleDays[d]
+				if !ok {
+					return nil, fmt.Errorf("%w: bad BYDAY %q", ErrInvalidRecurrence, d)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: bad COUNT %q", ErrInvalidRecurrence, value)
+			}
+			r.Count = n
+		case "UNTIL":
+			until, err := time.Parse("20060102", value[:min(len(value), 8)])
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad UNTIL %q", ErrInvalidRecurrence, value)
+			}
+			r.Until = &until
+		default:
+			return nil, fmt.Errorf("%w: unsupported part %q", ErrInvalidRecurrence, key)
+		}
+	}
+
+	if r.Freq != FreqDaily && r.Freq != FreqWeekly {
+		return nil, fmt.Errorf("%w: FREQ must be DAILY or WEEKLY", ErrInvalidRecurrence)
+	}
+	if r.Count > 0 && r.Until != nil {
+		return nil, fmt.Errorf("%w: COUNT and UNTIL are mutually exclusive", ErrInvalidRecurrence)
+	}
+	sort.Slice(r.ByDay, func(i, j int) bool { return isoWeekday(r.ByDay[i]) < isoWeekday(r.ByDay[j]) })
+	return r, nil
+}
+
+// String renders the rule in canonical form, which is what gets stored.
+func (r *Recurrence) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, wd := range r.ByDay {
+			for code, d := range rruleDays {
+				if d == wd {
+					days = append(days, code)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Between lists the occurrence dates in [from, to], counting from anchor.
+func (r *Recurrence) Between(anchor, from, to time.Time) []time.Time {
+	anchor, from, to = civilDate(anchor), civilDate(from), civilDate(to)
+	start := from
+	if r.Count > 0 || start.Before(anchor) {
+		// COUNT is relative to the first occurrence, so walk from the anchor.
+		start = anchor
+	}
+
+	var dates []time.Time
+	seen := 0
+	for d := start; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if r.Until != nil && d.After(*r.Until) {
+			break
+		}
+		if !r.matches(anchor, d) {
+			continue
+		}
+		seen++
+		if r.Count > 0 && seen > r.Count {
+			break
+		}
+		if !d.Before(from) {
+			dates = append(dates, d)
+		}
+	}
+	return dates
+}
+
+// Occurs reports whether day is an occurrence of the rule.
+func (r *Recurrence) Occurs(anchor, day time.Time) bool {
+	return len(r.Between(anchor, day, day)) == 1
+}
+
+// matches checks FREQ, INTERVAL and BYDAY, ignoring COUNT and UNTIL.
+func (r *Recurrence) matches(anchor, d time.Time) bool {
+	if d.Before(anchor) {
+		return false
+	}
+	switch r.Freq {
+	case FreqDaily:
+		if daysBetween(anchor, d)%r.Interval != 0 {
+			return false
+		}
+		return len(r.ByDay) == 0 || r.hasDay(d.Weekday())
+	case FreqWeekly:
+		weeks := daysBetween(weekStart(anchor), weekStart(d)) / 7
+		if weeks%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return d.Weekday() == anchor.Weekday()
+		}
+		return r.hasDay(d.Weekday())
+	}
+	return false
+}
+
+func (r *Recurrence) hasDay(wd time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// civilDate strips the clock so date arithmetic is not skewed by DST or
+// time zones.
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(a, b time.Time) int {
+	return int(b.Sub(a).Hours() / 24)
+}
+
+func isoWeekday(wd time.Weekday) int {
+	return (int(wd) + 6) % 7
+}
+
+func weekStart(d time.Time) time.Time {
+	return d.AddDate(0, 0, -isoWeekday(d.Weekday()))
+}
@@ -0,0 +1,312 @@
+package goal
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// day parses a YYYY-MM-DD date as UTC midnight, the form Between returns.
+func day(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func formatDates(dates []time.Time) []string {
+	out := make([]string, 0, len(dates))
+	for _, d := range dates {
+		out = append(out, d.Format("2006-01-02"))
+	}
+	return out
+}
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{name: "daily", rule: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{name: "prefix and lower case", rule: " RRULE:freq=daily;interval=1 ", want: "FREQ=DAILY"},
+		{name: "weekly days are sorted from monday", rule: "FREQ=WEEKLY;BYDAY=SU,FR,MO", want: "FREQ=WEEKLY;BYDAY=MO,FR,SU"},
+		{name: "interval and count", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10", want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10"},
+		{name: "until keeps the date only", rule: "FREQ=DAILY;UNTIL=20240131T235959Z", want: "FREQ=DAILY;UNTIL=20240131"},
+		{name: "empty", rule: "", wantErr: true},
+		{name: "blank", rule: "   ", wantErr: true},
+		{name: "prefix only", rule: "RRULE:", wantErr: true},
+		{name: "missing freq", rule: "BYDAY=MO", wantErr: true},
+		{name: "unsupported freq", rule: "FREQ=MONTHLY", wantErr: true},
+		{name: "part without value", rule: "FREQ=", wantErr: true},
+		{name: "part without equals", rule: "FREQ", wantErr: true},
+		{name: "trailing separator", rule: "FREQ=DAILY;", wantErr: true},
+		{name: "zero interval", rule: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{name: "non-numeric interval", rule: "FREQ=DAILY;INTERVAL=x", wantErr: true},
+		{name: "unknown day", rule: "FREQ=WEEKLY;BYDAY=MO,XX", wantErr: true},
+		{name: "zero count", rule: "FREQ=DAILY;COUNT=0", wantErr: true},
+		{name: "short until", rule: "FREQ=DAILY;UNTIL=2024", wantErr: true},
+		{name: "count with until", rule: "FREQ=DAILY;COUNT=3;UNTIL=20240101", wantErr: true},
+		{name: "unsupported part", rule: "FREQ=DAILY;BYMONTH=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.rule)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRecurrence) {
+					t.Fatalf("ParseRecurrence(%q) error = %v, want ErrInvalidRecurrence", tt.rule, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRecurrence(%q) error = %v", tt.rule, err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceBetween(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		rule             string
+		anchor, from, to time.Time
+		want             []string
+	}{
+		{
+			name:   "daily",
+			rule:   "FREQ=DAILY",
+			anchor: day("2024-01-01"), from: day("2024-01-01"), to: day("2024-01-03"),
+			want: []string{"2024-01-01", "2024-01-02", "2024-01-03"},
+		},
+		{
+			name:   "daily interval counts from the anchor",
+			rule:   "FREQ=DAILY;INTERVAL=2",
+			anchor: day("2024-01-01"), from: day("2024-01-04"), to: day("2024-01-09"),
+			want: []string{"2024-01-05", "2024-01-07", "2024-01-09"},
+		},
+		{
+			name:   "daily restricted to weekend",
+			rule:   "FREQ=DAILY;BYDAY=SA,SU",
+			anchor: day("2024-01-01"), from: day("2024-01-01"), to: day("2024-01-08"),
+			want: []string{"2024-01-06", "2024-01-07"},
+		},
+		{
+			name:   "weekly defaults to the anchor weekday",
+			rule:   "FREQ=WEEKLY",
+			anchor: day("2024-01-03"), from: day("2024-01-01"), to: day("2024-01-24"),
+			want: []string{"2024-01-03", "2024-01-10", "2024-01-17", "2024-01-24"},
+		},
+		{
+			name:   "biweekly skips days before the anchor in its week",
+			rule:   "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH",
+			anchor: day("2024-01-03"), from: day("2024-01-01"), to: day("2024-01-21"),
+			want: []string{"2024-01-04", "2024-01-15", "2024-01-18"},
+		},
+		{
+			name:   "biweekly across the year boundary uses monday weeks",
+			rule:   "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU",
+			anchor: day("2023-12-31"), from: day("2023-12-31"), to: day("2024-01-15"),
+			want: []string{"2023-12-31", "2024-01-08", "2024-01-14"},
+		},
+		{
+			name:   "count includes occurrences before from",
+			rule:   "FREQ=DAILY;COUNT=3",
+			anchor: day("2024-01-01"), from: day("2024-01-02"), to: day("2024-01-10"),
+			want: []string{"2024-01-02", "2024-01-03"},
+		},
+		{
+			name:   "until is inclusive",
+			rule:   "FREQ=DAILY;UNTIL=20240103",
+			anchor: day("2024-01-01"), from: day("2024-01-01"), to: day("2024-01-10"),
+			want: []string{"2024-01-01", "2024-01-02", "2024-01-03"},
+		},
+		{
+			name:   "nothing before the anchor",
+			rule:   "FREQ=DAILY",
+			anchor: day("2024-01-05"), from: day("2024-01-01"), to: day("2024-01-06"),
+			want: []string{"2024-01-05", "2024-01-06"},
+		},
+		{
+			name:   "empty range",
+			rule:   "FREQ=DAILY",
+			anchor: day("2024-01-01"), from: day("2024-01-05"), to: day("2024-01-04"),
+			want: []string{},
+		},
+		{
+			name:   "local dates across the spring DST switch",
+			rule:   "FREQ=DAILY;INTERVAL=2",
+			anchor: time.Date(2024, 3, 8, 23, 30, 0, 0, ny),
+			from:   time.Date(2024, 3, 8, 0, 0, 0, 0, ny),
+			to:     time.Date(2024, 3, 12, 23, 59, 0, 0, ny),
+			want:   []string{"2024-03-08", "2024-03-10", "2024-03-12"},
+		},
+		{
+			name:   "weekly across the autumn DST switch",
+			rule:   "FREQ=WEEKLY",
+			anchor: time.Date(2024, 10, 27, 1, 30, 0, 0, ny),
+			from:   time.Date(2024, 10, 27, 0, 0, 0, 0, ny),
+			to:     time.Date(2024, 11, 10, 0, 30, 0, 0, ny),
+			want:   []string{"2024-10-27", "2024-11-03", "2024-11-10"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := formatDates(r.Between(tt.anchor, tt.from, tt.to))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Between() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceOccurs(t *testing.T) {
+	r, err := ParseRecurrence("FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	anchor := day("2024-01-01")
+	for date, want := range map[string]bool{
+		"2024-01-01": true,
+		"2024-01-02": false,
+		"2024-01-03": true,
+		"2024-01-08": true,
+		"2024-01-10": false, // past COUNT
+		"2023-12-27": false, // before the anchor
+	} {
+		if got := r.Occurs(anchor, day(date)); got != want {
+			t.Errorf("Occurs(%s) = %v, want %v", date, got, want)
+		}
+	}
+}
+
+func TestComputeStreak(t *testing.T) {
+	done := func(date string) Occurrence {
+		return Occurrence{Date: day(date), Status: OccurrenceCompleted}
+	}
+	withStatus := func(date, status string) Occurrence {
+		return Occurrence{Date: day(date), Status: status}
+	}
+	lastDay := func(s string) *time.Time {
+		d := day(s)
+		return &d
+	}
+
+	tests := []struct {
+		name        string
+		rule        string
+		anchor      time.Time
+		today       time.Time
+		occurrences []Occurrence
+		want        Streak
+	}{
+		{
+			name:        "unbroken",
+			rule:        "FREQ=DAILY",
+			anchor:      day("2024-01-01"),
+			today:       day("2024-01-05"),
+			occurrences: []Occurrence{done("2024-01-01"), done("2024-01-02"), done("2024-01-03"), done("2024-01-04"), done("2024-01-05")},
+			want:        Streak{Current: 5, Longest: 5, Completed: 5, LastCompleted: lastDay("2024-01-05")},
+		},
+		{
+			name:        "a missing day breaks the run",
+			rule:        "FREQ=DAILY",
+			anchor:      day("2024-01-01"),
+			today:       day("2024-01-05"),
+			occurrences: []Occurrence{done("2024-01-01"), done("2024-01-02"), done("2024-01-04"), done("2024-01-05")},
+			want:        Streak{Current: 2, Longest: 2, Completed: 4, Missed: 1, LastCompleted: lastDay("2024-01-05")},
+		},
+		{
+			name:        "a pending past day breaks the run",
+			rule:        "FREQ=DAILY",
+			anchor:      day("2024-01-01"),
+			today:       day("2024-01-04"),
+			occurrences: []Occurrence{done("2024-01-01"), done("2024-01-02"), withStatus("2024-01-03", OccurrencePending), done("2024-01-04")},
+			want:        Streak{Current: 1, Longest: 2, Completed: 3, Missed: 1, LastCompleted: lastDay("2024-01-04")},
+		},
+		{
+			name:        "a skipped day neither extends nor breaks",
+			rule:        "FREQ=DAILY",
+			anchor:      day("2024-01-01"),
+			today:       day("2024-01-05"),
+			occurrences: []Occurrence{done("2024-01-01"), done("2024-01-02"), withStatus("2024-01-03", OccurrenceSkipped), done("2024-01-04"), done("2024-01-05")},
+			want:        Streak{Current: 4, Longest: 4, Completed: 4, LastCompleted: lastDay("2024-01-05")},
+		},
+		{
+			name:        "today is still in progress",
+			rule:        "FREQ=DAILY",
+			anchor:      day("2024-01-01"),
+			today:       time.Date(2024, 1, 3, 18, 0, 0, 0, time.UTC),
+			occurrences: []Occurrence{done("2024-01-01"), done("2024-01-02"), withStatus("2024-01-03", OccurrencePending)},
+			want:        Streak{Current: 2, Longest: 2, Completed: 2, LastCompleted: lastDay("2024-01-02")},
+		},
+		{
+			name:   "days off between weekly occurrences do not break",
+			rule:   "FREQ=WEEKLY;BYDAY=MO,FR",
+			anchor: day("2024-01-01"),
+			today:  day("2024-01-15"),
+			occurrences: []Occurrence{
+				done("2024-01-01"), done("2024-01-05"), done("2024-01-12"),
+			},
+			want: Streak{Current: 1, Longest: 2, Completed: 3, Missed: 1, LastCompleted: lastDay("2024-01-12")},
+		},
+		{
+			name:   "a missed week breaks a weekly streak",
+			rule:   "FREQ=WEEKLY",
+			anchor: day("2024-01-01"),
+			today:  day("2024-01-29"),
+			occurrences: []Occurrence{
+				done("2024-01-01"), done("2024-01-08"), done("2024-01-22"), done("2024-01-29"),
+			},
+			want: Streak{Current: 2, Longest: 2, Completed: 4, Missed: 1, LastCompleted: lastDay("2024-01-29")},
+		},
+		{
+			name:   "occurrence dates with a clock still match their day",
+			rule:   "FREQ=DAILY",
+			anchor: day("2024-01-01"),
+			today:  day("2024-01-02"),
+			occurrences: []Occurrence{
+				{Date: time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC), Status: OccurrenceCompleted},
+				{Date: time.Date(2024, 1, 2, 6, 0, 0, 0, time.UTC), Status: OccurrenceCompleted},
+			},
+			want: Streak{Current: 2, Longest: 2, Completed: 2, LastCompleted: lastDay("2024-01-02")},
+		},
+		{
+			name:   "nothing due yet",
+			rule:   "FREQ=WEEKLY;BYDAY=FR",
+			anchor: day("2024-01-01"),
+			today:  day("2024-01-03"),
+			want:   Streak{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ComputeStreak(r, tt.anchor, tt.today, tt.occurrences)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ComputeStreak() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,10 +3,14 @@ package goal
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"strconv"
 	"strings"
+	"task-planner/internal/db"
 	"time"
 )
 
@@ -14,30 +18,77 @@ type RepositoryAggregator interface {
 	GoalRepository
 	PhaseRepository
 	TaskRepository
+	ChecklistRepository
+	OccurrenceRepository
+	TemplateRepository
+	ActivityRepository
+	MilestoneRepository
+	EstimateRepository
+	DraftRepository
+
+	// InTx runs fn in a single transaction and commits when fn succeeds.
+	// Every repository call made with the context passed to fn joins the
+	// transaction, including calls on other repositories.
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type GoalRepository interface {
 	CreateGoal(ctx context.Context, g *Goal) error
 	GetGoalByID(ctx context.Context, id uuid.UUID) (*Goal, error)
 	UpdateGoal(ctx context.Context, g *Goal) error
-	ListGoals(ctx context.Context, userID int64, limit, offset int, status string) ([]Goal, int, error)
+	ListGoals(ctx context.Context, userID int64, f GoalFilter) ([]Goal, int, error)
+	SetGoalArchived(ctx context.Context, userID int64, id uuid.UUID, archivedAt *time.Time) (bool, error)
+	// SoftDeleteGoal only matches the goal at the given version.
+	SoftDeleteGoal(ctx context.Context, userID int64, id uuid.UUID, version int) (bool, error)
+	RestoreGoal(ctx context.Context, userID int64, id uuid.UUID, deletedAfter time.Time) (bool, error)
+	PurgeDeletedGoals(ctx context.Context, deletedBefore time.Time) (int64, error)
+	// PurgeableAttachmentKeys returns the storage keys of files attached to
+	// the goals PurgeDeletedGoals would remove, or to their phases and tasks.
+	PurgeableAttachmentKeys(ctx context.Context, deletedBefore time.Time) ([]string, error)
+	// CopyAvailability copies the weekly availability and time slots of one
+	// goal onto another.
+	CopyAvailability(ctx context.Context, fromGoalID, toGoalID uuid.UUID) error
 
 	GetPhaseByID(ctx context.Context, id uuid.UUID) (*Phase, error)
 	UpdatePhase(ctx context.Context, p *Phase) error
 	GetTaskByID(ctx context.Context, id uuid.UUID) (*Task, error)
 	UpdateTask(ctx context.Context, t *Task) error
 	UpdateTaskTimeSpent(ctx context.Context, id uuid.UUID, spent int) error
-	DeleteGoal(ctx context.Context, id uuid.UUID) error
+	MoveTask(ctx context.Context, id, phaseID uuid.UUID) error
+	// NextTasksByGoalIDs returns, per goal, the todo task NextTask would
+	// pick from its task list, in one query.
+	NextTasksByGoalIDs(ctx context.Context, goalIDs []uuid.UUID) (map[uuid.UUID]Task, error)
+	DeleteTask(ctx context.Context, id uuid.UUID) error
+	// TaskAttachmentKeys returns the storage keys of files attached to the
+	// task, which DeleteTask removes with it.
+	TaskAttachmentKeys(ctx context.Context, id uuid.UUID) ([]string, error)
 
 	CountPendingTasks(ctx context.Context, phaseID uuid.UUID) (int, error)
 	ListActiveGoals(ctx context.Context) ([]Goal, error)
+	// GetUserLocale returns the locale the user's prompts are rendered in,
+	// or "" for an unknown user.
+	GetUserLocale(ctx context.Context, userID int64) (string, error)
 }
 type PhaseRepository interface {
 	CreatePhase(ctx context.Context, p *Phase) error
 	ListPhasesByGoalID(ctx context.Context, goalID uuid.UUID) ([]Phase, error)
+	// SumTimeSpentPhase returns the minutes tracked on the phase's tasks.
 	SumTimeSpentPhase(
 		ctx context.Context, phaseID uuid.UUID,
 	) (int, error)
+	// ListPhasesAwaitingTasks returns phases of AI-generated goals opted in
+	// to refill that have become current, with every earlier phase of their
+	// goal completed, but have no tasks and have never had tasks generated.
+	// Phases that failed maxAttempts times, or whose next attempt is not due
+	// yet, are left out.
+	ListPhasesAwaitingTasks(ctx context.Context, maxAttempts int) ([]Phase, error)
+	// RecordPhaseTasksFailure counts a failed attempt at generating the
+	// phase's tasks and puts the next one off by backoff, doubled for every
+	// earlier failure.
+	RecordPhaseTasksFailure(ctx context.Context, phaseID uuid.UUID, backoff time.Duration) error
+	// ClaimPhaseTasks marks the phase's tasks as generated. It reports false
+	// when the phase has tasks already or was claimed before.
+	ClaimPhaseTasks(ctx context.Context, phaseID uuid.UUID) (bool, error)
 }
 type TaskRepository interface {
 	CreateTask(ctx context.Context, t *Task) error
@@ -51,22 +102,218 @@ type TaskRepository interface {
 	) ([]Task, error)
 }
 
+type ChecklistRepository interface {
+	CreateChecklistItem(ctx context.Context, it *ChecklistItem) error
+	GetChecklistItemByID(ctx context.Context, id uuid.UUID) (*ChecklistItem, error)
+	UpdateChecklistItem(ctx context.Context, it *ChecklistItem) error
+	DeleteChecklistItem(ctx context.Context, id uuid.UUID) error
+	ListChecklistItemsByTaskIDs(ctx context.Context, taskIDs []uuid.UUID) ([]ChecklistItem, error)
+	NextChecklistOrder(ctx context.Context, taskID uuid.UUID) (int, error)
+	ReorderChecklistItems(ctx context.Context, taskID uuid.UUID, itemIDs []uuid.UUID) error
+}
+
+type TemplateRepository interface {
+	CreateTemplate(ctx context.Context, t *Template) error
+	GetTemplateByID(ctx context.Context, id uuid.UUID) (*Template, error)
+	UpdateTemplate(ctx context.Context, t *Template) error
+	// DeleteTemplate removes a template owned by the user and reports
+	// whether there was one.
+	DeleteTemplate(ctx context.Context, userID int64, id uuid.UUID) (bool, error)
+	ListTemplates(ctx context.Context, userID int64, scope string) ([]Template, error)
+}
+
+type ActivityRepository interface {
+	AppendActivity(ctx context.Context, a *Activity) error
+	// ListActivity returns a page of the goal's log, newest first, and the
+	// total number of entries.
+	ListActivity(ctx context.Context, goalID uuid.UUID, limit, offset int) ([]Activity, int, error)
+}
+
+type MilestoneRepository interface {
+	// CreateMilestone inserts the milestone together with its criteria.
+	CreateMilestone(ctx context.Context, m *Milestone) error
+	// GetMilestoneByPhaseID returns the phase's milestone with its criteria,
+	// or nil when the phase has none.
+	GetMilestoneByPhaseID(ctx context.Context, phaseID uuid.UUID) (*Milestone, error)
+	UpdateMilestone(ctx context.Context, m *Milestone) error
+	DeleteMilestone(ctx context.Context, id uuid.UUID) error
+	ListMilestonesByGoalID(ctx context.Context, goalID uuid.UUID) ([]Milestone, error)
+
+	CreateCriterion(ctx context.Context, c *Criterion) error
+	UpdateCriterion(ctx context.Context, c *Criterion) error
+	DeleteCriterion(ctx context.Context, id uuid.UUID) error
+}
+
+type EstimateRepository interface {
+	// GetCalibration returns the user's calibration factor, or nil when the
+	// user has none.
+	GetCalibration(ctx context.Context, userID int64) (*float64, error)
+	// SetCalibration stores the user's factor; nil removes it.
+	SetCalibration(ctx context.Context, userID int64, factor *float64) error
+	// EstimateStats compares estimated and tracked time of the user's
+	// non-recurring tasks completed since the given time.
+	EstimateStats(ctx context.Context, userID int64, since time.Time) (*EstimateStats, error)
+}
+
+type DraftRepository interface {
+	CreateDraft(ctx context.Context, d *PlanDraft) error
+	// GetDraft returns the draft, or nil when there is none.
+	GetDraft(ctx context.Context, id uuid.UUID) (*PlanDraft, error)
+	// UpdateDraft only matches the draft at d.Version and bumps it.
+	UpdateDraft(ctx context.Context, d *PlanDraft) (bool, error)
+	// DeleteDraft reports whether there was a draft to delete.
+	DeleteDraft(ctx context.Context, id uuid.UUID) (bool, error)
+	PurgeDrafts(ctx context.Context, updatedBefore time.Time) (int64, error)
+}
+
+type OccurrenceRepository interface {
+	// EnsureOccurrence returns the occurrence of the task on date, creating
+	// it when it does not exist yet.
+	EnsureOccurrence(ctx context.Context, taskID uuid.UUID, date time.Time) (*Occurrence, error)
+	GetOccurrenceByID(ctx context.Context, id uuid.UUID) (*Occurrence, error)
+	UpdateOccurrence(ctx context.Context, o *Occurrence) error
+	ListOccurrences(ctx context.Context, taskID uuid.UUID, from, to time.Time) ([]Occurrence, error)
+}
+
+// GoalFilter narrows ListGoals. Soft-deleted goals are never listed;
+// archived ones are listed only when Archived is set.
+type GoalFilter struct {
+	Limit    int
+	Offset   int
+	Status   string
+	Archived bool
+	// TagIDs keeps goals carrying any of the tags, directly or on one of
+	// their tasks.
+	TagIDs []uuid.UUID
+	Kind   string
+	// Query is matched against the goal's title and description using the
+	// full-text index.
+	Query string
+	// DateField names the column From and To apply to; one of the
+	// GoalSort values. Either bound may be nil.
+	DateField string
+	From      *time.Time
+	To        *time.Time
+	// Sort is one of the GoalSort values; goals without a deadline come
+	// last whatever the direction.
+	Sort string
+	Desc bool
+}
+
+// Columns goals can be sorted and ranged by.
+const (
+	GoalSortUpdatedAt = "updated_at"
+	GoalSortCreatedAt = "created_at"
+	GoalSortProgress  = "progress"
+	GoalSortDeadline  = "deadline"
+)
+
+var goalSortColumns = map[string]bool{
+	GoalSortUpdatedAt: true,
+	GoalSortCreatedAt: true,
+	GoalSortProgress:  true,
+	GoalSortDeadline:  true,
+}
+
+// ValidGoalSort reports whether field is a column goals can be sorted by.
+func ValidGoalSort(field string) bool {
+	return goalSortColumns[field]
+}
+
 type repositoryImpl struct {
 	db *sql.DB
 }
 
+const goalColumns = `id, user_id, title, description, status, estimated_time, hours_per_week,
+	progress, created_at, updated_at, archived_at, deleted_at, progress_strategy, manual_progress, kind, deadline, version,
+	prompt_version`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanGoal(row rowScanner, g *Goal) error {
+	return row.Scan(
+		&g.ID,
+		&g.UserId,
+		&g.Title,
+		&g.Description,
+		&g.Status,
+		&g.EstimatedTime,
+		&g.HoursPerWeek,
+		&g.Progress,
+		&g.CreatedAt,
+		&g.UpdatedAt,
+		&g.ArchivedAt,
+		&g.DeletedAt,
+		&g.ProgressStrategy,
+		&g.ManualProgress,
+		&g.Kind,
+		&g.Deadline,
+		&g.Version,
+		&g.PromptVersion,
+	)
+}
+
+const taskColumns = `t.id, t.goal_id, t.phase_id, t.title, t.description, t.status,
+	t.status_manual, t.blocked_reason, t.priority, t.recurrence_rule, t.occurrence_minutes,
+	t.estimated_time, t.time_spent, t.progress_mode, t.completed_at, t.created_at, t.updated_at, t.version,
+	t.estimate_calibrated, t.prompt_version`
+
+func scanTask(row rowScanner, t *Task) error {
+	return row.Scan(
+		&t.ID,
+		&t.GoalId,
+		&t.PhaseId,
+		&t.Title,
+		&t.Description,
+		&t.Status,
+		&t.StatusManual,
+		&t.BlockedReason,
+		&t.Priority,
+		&t.RecurrenceRule,
+		&t.OccurrenceMinutes,
+		&t.EstimatedTime,
+		&t.TimeSpent,
+		&t.ProgressMode,
+		&t.CompletedAt,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+		&t.Version,
+		&t.EstimateCalibrated,
+		&t.PromptVersion,
+	)
+}
+
 func NewRepository(db *sql.DB) *repositoryImpl {
 	return &repositoryImpl{db: db}
 }
 
+// conn returns the transaction carried by ctx, if any.
+func (r *repositoryImpl) conn(ctx context.Context) db.Executor {
+	return db.Conn(ctx, r.db)
+}
+
+func (r *repositoryImpl) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return db.WithTx(ctx, r.db, fn)
+}
+
 func (r *repositoryImpl) CreateGoal(ctx context.Context, g *Goal) error {
 	query := `
 	INSERT INTO goals (id, user_id, title, description, status, estimated_time, hours_per_week, 
-    	progress, created_at, updated_at
+    	progress, created_at, updated_at, progress_strategy, manual_progress, kind, deadline,
+    	prompt_version
 	)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
+
+	if g.ProgressStrategy == "" {
+		g.ProgressStrategy = ProgressStrategyTime
+	}
+	if g.Kind == "" {
+		g.Kind = GoalKindProject
+	}
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := r.conn(ctx).ExecContext(ctx, query,
 		g.ID,
 		g.UserId,
 		g.Title,
@@ -77,32 +324,25 @@ func (r *repositoryImpl) CreateGoal(ctx context.Context, g *Goal) error {
 		g.Progress,
 		g.CreatedAt,
 		g.UpdatedAt,
+		g.ProgressStrategy,
+		g.ManualProgress,
+		g.Kind,
+		g.Deadline,
+		g.PromptVersion,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert goal: %w", err)
 	}
+	g.Version = 1
 	return nil
 }
 
 func (r *repositoryImpl) GetGoalByID(ctx context.Context, id uuid.UUID) (*Goal, error) {
-	query := ` SELECT id, user_id, title, description, status, estimated_time, hours_per_week,
-        progress, created_at, updated_at FROM goals
-		WHERE id = $1
+	query := `SELECT ` + goalColumns + ` FROM goals
+		WHERE id = $1 AND deleted_at IS NULL
 `
 	var g Goal
-	err := r.db.QueryRowContext(ctx, query, id).
-		Scan(
-			&g.ID,
-			&g.UserId,
-			&g.Title,
-			&g.Description,
-			&g.Status,
-			&g.EstimatedTime,
-			&g.HoursPerWeek,
-			&g.Progress,
-			&g.CreatedAt,
-			&g.UpdatedAt,
-		)
+	err := scanGoal(r.conn(ctx).QueryRowContext(ctx, query, id), &g)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -116,10 +356,11 @@ func (r *repositoryImpl) UpdateGoal(ctx context.Context, g *Goal) error {
 	g.UpdatedAt = time.Now()
 	query := `UPDATE goals
 			SET title = $2, description = $3, status = $4, estimated_time = $5, 
-				hours_per_week = $6, progress = $7, updated_at = $8
-			WHERE id = $1
+				hours_per_week = $6, progress = $7, updated_at = $8,
+				progress_strategy = $9, manual_progress = $10, version = version + 1
+			WHERE id = $1 AND version = $11
 `
-	_, err := r.db.ExecContext(ctx, query,
+	res, err := r.conn(ctx).ExecContext(ctx, query,
 		g.ID,
 		g.Title,
 		g.Description,
@@ -128,48 +369,117 @@ func (r *repositoryImpl) UpdateGoal(ctx context.Context, g *Goal) error {
 		g.HoursPerWeek,
 		g.Progress,
 		g.UpdatedAt,
+		g.ProgressStrategy,
+		g.ManualProgress,
+		g.Version,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update goal: %w", err)
 	}
+	if err := checkVersioned(res); err != nil {
+		return err
+	}
+	g.Version++
+	return nil
+}
+
+// checkVersioned turns an update that matched no row into
+// ErrVersionConflict: the row changed since the caller read it.
+func checkVersioned(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrVersionConflict
+	}
 	return nil
 }
 
 func (r *repositoryImpl) ListGoals(
 	ctx context.Context,
 	userID int64,
-	limit, offset int,
-	status string,
+	f GoalFilter,
 ) ([]Goal, int, error) {
 	args := make([]interface{}, 0, 4)
 	idx := 1
 
-	where := "WHERE user_id = $" + strconv.Itoa(idx)
+	where := "WHERE user_id = $" + strconv.Itoa(idx) + " AND deleted_at IS NULL"
 	args = append(args, userID)
 	idx++
 
-	if status != "" {
+	if f.Archived {
+		where += " AND archived_at IS NOT NULL"
+	} else {
+		where += " AND archived_at IS NULL"
+	}
+
+	if f.Status != "" {
 		where += " AND status = $" + strconv.Itoa(idx)
-		args = append(args, status)
+		args = append(args, f.Status)
+		idx++
+	}
+
+	if f.Kind != "" {
+		where += " AND kind = $" + strconv.Itoa(idx)
+		args = append(args, f.Kind)
+		idx++
+	}
+
+	if len(f.TagIDs) > 0 {
+		p := "$" + strconv.Itoa(idx)
+		where += " AND (EXISTS (SELECT 1 FROM goal_tags gt WHERE gt.goal_id = goals.id AND gt.tag_id = ANY(" + p + "))" +
+			" OR EXISTS (SELECT 1 FROM tasks tk JOIN task_tags tt ON tt.task_id = tk.id" +
+			" WHERE tk.goal_id = goals.id AND tt.tag_id = ANY(" + p + ")))"
+		args = append(args, pq.Array(f.TagIDs))
+		idx++
+	}
+
+	if f.Query != "" {
+		where += " AND search_vector @@ websearch_to_tsquery('simple', $" + strconv.Itoa(idx) + ")"
+		args = append(args, f.Query)
+		idx++
+	}
+
+	dateField := GoalSortUpdatedAt
+	if goalSortColumns[f.DateField] && f.DateField != GoalSortProgress {
+		dateField = f.DateField
+	}
+	if f.From != nil {
+		where += " AND " + dateField + " >= $" + strconv.Itoa(idx)
+		args = append(args, *f.From)
+		idx++
+	}
+	if f.To != nil {
+		where += " AND " + dateField + " < $" + strconv.Itoa(idx)
+		args = append(args, *f.To)
 		idx++
 	}
 
 	countQuery := "SELECT COUNT(*) FROM goals " + where
 	var total int
-	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+	if err := r.conn(ctx).QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
 		return nil, 0, fmt.Errorf("failed to count goals: %w", err)
 	}
 
+	sortField := GoalSortUpdatedAt
+	if goalSortColumns[f.Sort] {
+		sortField = f.Sort
+	}
+	dir := " ASC"
+	if f.Desc {
+		dir = " DESC"
+	}
+
 	selectQuery := "" +
-		"SELECT id, user_id, title, description, status, estimated_time, hours_per_week, progress, " +
-		"       created_at, updated_at " +
+		"SELECT " + goalColumns + " " +
 		"FROM goals " + where +
-		" ORDER BY updated_at DESC " +
+		" ORDER BY " + sortField + dir + " NULLS LAST, id" + dir +
 		" LIMIT $" + strconv.Itoa(idx) +
 		" OFFSET $" + strconv.Itoa(idx+1)
-	args = append(args, limit, offset)
+	args = append(args, f.Limit, f.Offset)
 
-	rows, err := r.db.QueryContext(ctx, selectQuery, args...)
+	rows, err := r.conn(ctx).QueryContext(ctx, selectQuery, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to query goals: %w", err)
 	}
@@ -178,18 +488,7 @@ func (r *repositoryImpl) ListGoals(
 	var goals []Goal
 	for rows.Next() {
 		var g Goal
-		if err := rows.Scan(
-			&g.ID,
-			&g.UserId,
-			&g.Title,
-			&g.Description,
-			&g.Status,
-			&g.EstimatedTime,
-			&g.HoursPerWeek,
-			&g.Progress,
-			&g.CreatedAt,
-			&g.UpdatedAt,
-		); err != nil {
+		if err := scanGoal(rows, &g); err != nil {
 			return nil, 0, fmt.Errorf("failed to scan goal: %w", err)
 		}
 		goals = append(goals, g)
@@ -201,12 +500,95 @@ func (r *repositoryImpl) ListGoals(
 	return goals, total, nil
 }
 
+func (r *repositoryImpl) SetGoalArchived(
+	ctx context.Context, userID int64, id uuid.UUID, archivedAt *time.Time,
+) (bool, error) {
+	res, err := r.conn(ctx).ExecContext(ctx, `
+		UPDATE goals SET archived_at = $3, updated_at = NOW(), version = version + 1
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`,
+		id, userID, archivedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to archive goal: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *repositoryImpl) SoftDeleteGoal(ctx context.Context, userID int64, id uuid.UUID, version int) (bool, error) {
+	res, err := r.conn(ctx).ExecContext(ctx, `
+		UPDATE goals SET deleted_at = NOW(), updated_at = NOW(), version = version + 1
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND version = $3`,
+		id, userID, version)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete goal: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *repositoryImpl) RestoreGoal(
+	ctx context.Context, userID int64, id uuid.UUID, deletedAfter time.Time,
+) (bool, error) {
+	res, err := r.conn(ctx).ExecContext(ctx, `
+		UPDATE goals SET deleted_at = NULL, updated_at = NOW(), version = version + 1
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL AND deleted_at >= $3`,
+		id, userID, deletedAfter)
+	if err != nil {
+		return false, fmt.Errorf("failed to restore goal: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// PurgeDeletedGoals hard-deletes goals soft-deleted before the given moment.
+// Phases, tasks and scheduled intervals go with them via ON DELETE CASCADE.
+func (r *repositoryImpl) PurgeDeletedGoals(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	res, err := r.conn(ctx).ExecContext(ctx,
+		`DELETE FROM goals WHERE deleted_at IS NOT NULL AND deleted_at < $1`, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge goals: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (r *repositoryImpl) PurgeableAttachmentKeys(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	return r.storageKeys(ctx, `
+		SELECT a.storage_key
+		FROM attachments a
+		LEFT JOIN phases p ON p.id = a.phase_id
+		LEFT JOIN tasks t ON t.id = a.task_id
+		JOIN goals g ON g.id = COALESCE(a.goal_id, p.goal_id, t.goal_id)
+		WHERE g.deleted_at IS NOT NULL AND g.deleted_at < $1`, deletedBefore)
+}
+
+func (r *repositoryImpl) TaskAttachmentKeys(ctx context.Context, id uuid.UUID) ([]string, error) {
+	return r.storageKeys(ctx, `SELECT storage_key FROM attachments WHERE task_id = $1`, id)
+}
+
+func (r *repositoryImpl) storageKeys(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachment keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
 func (r *repositoryImpl) CreatePhase(ctx context.Context, p *Phase) error {
 	query := `
 INSERT INTO phases (id, goal_id, title, description, status, progress, estimated_time, "order",created_at, updated_at)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 `
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := r.conn(ctx).ExecContext(ctx, query,
 		p.ID,
 		p.GoalId,
 		p.Title,
@@ -221,15 +603,16 @@ INSERT INTO phases (id, goal_id, title, description, status, progress, estimated
 	if err != nil {
 		return fmt.Errorf("failed to insert phase: %w", err)
 	}
+	p.Version = 1
 	return nil
 }
 
 func (r *repositoryImpl) ListPhasesByGoalID(ctx context.Context, goalID uuid.UUID) ([]Phase, error) {
 	query := `SELECT id, goal_id, title, description, status, progress, estimated_time, "order",
-    			created_at, updated_at FROM phases WHERE goal_id = $1
+    			created_at, updated_at, version FROM phases WHERE goal_id = $1
 				ORDER BY "order" ASC
 `
-	rows, err := r.db.QueryContext(ctx, query, goalID)
+	rows, err := r.conn(ctx).QueryContext(ctx, query, goalID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list phases: %w", err)
 	}
@@ -249,6 +632,7 @@ func (r *repositoryImpl) ListPhasesByGoalID(ctx context.Context, goalID uuid.UUI
 			&p.Order,
 			&p.CreatedAt,
 			&p.UpdatedAt,
+			&p.Version,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan phase: %w", err)
 		}
@@ -259,11 +643,18 @@ func (r *repositoryImpl) ListPhasesByGoalID(ctx context.Context, goalID uuid.UUI
 
 func (r *repositoryImpl) CreateTask(ctx context.Context, t *Task) error {
 	query := `
-INSERT INTO tasks (id, goal_id, phase_id, title, description, status, estimated_time, completed_at, 
-    			created_at, updated_at
-) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+INSERT INTO tasks (id, goal_id, phase_id, title, description, status, estimated_time, progress_mode,
+    			completed_at, created_at, updated_at, priority, recurrence_rule, occurrence_minutes,
+    			estimate_calibrated, prompt_version
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 `
-	_, err := r.db.ExecContext(ctx, query,
+	if t.ProgressMode == "" {
+		t.ProgressMode = ProgressModeTime
+	}
+	if t.Priority == 0 {
+		t.Priority = PriorityNormal
+	}
+	_, err := r.conn(ctx).ExecContext(ctx, query,
 		t.ID,
 		t.GoalId,
 		t.PhaseId,
@@ -271,22 +662,28 @@ INSERT INTO tasks (id, goal_id, phase_id, title, description, status, estimated_
 		t.Description,
 		t.Status,
 		t.EstimatedTime,
+		t.ProgressMode,
 		t.CompletedAt,
 		t.CreatedAt,
 		t.UpdatedAt,
+		t.Priority,
+		t.RecurrenceRule,
+		t.OccurrenceMinutes,
+		t.EstimateCalibrated,
+		t.PromptVersion,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert task: %w", err)
 	}
+	t.Version = 1
 	return nil
 }
 
 func (r *repositoryImpl) ListTasksByGoalID(ctx context.Context, goalID uuid.UUID) ([]Task, error) {
-	query := `SELECT id, goal_id, phase_id, title, description, status, estimated_time, time_spent, 
-    		completed_at, created_at, updated_at FROM tasks WHERE goal_id = $1
-			ORDER BY created_at ASC
+	query := `SELECT ` + taskColumns + ` FROM tasks t WHERE t.goal_id = $1
+			ORDER BY t.created_at ASC
 `
-	rows, err := r.db.QueryContext(ctx, query, goalID)
+	rows, err := r.conn(ctx).QueryContext(ctx, query, goalID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tasks: %w", err)
 	}
@@ -295,19 +692,7 @@ func (r *repositoryImpl) ListTasksByGoalID(ctx context.Context, goalID uuid.UUID
 	var tasks []Task
 	for rows.Next() {
 		var t Task
-		if err := rows.Scan(
-			&t.ID,
-			&t.GoalId,
-			&t.PhaseId,
-			&t.Title,
-			&t.Description,
-			&t.Status,
-			&t.EstimatedTime,
-			&t.TimeSpent,
-			&t.CompletedAt,
-			&t.CreatedAt,
-			&t.UpdatedAt,
-		); err != nil {
+		if err := scanTask(rows, &t); err != nil {
 			return nil, fmt.Errorf("failed to scan task: %w", err)
 		}
 		tasks = append(tasks, t)
@@ -327,12 +712,12 @@ func (r *repositoryImpl) GetTasksByIDs(ctx context.Context, ids []uuid.UUID) ([]
 		args[i] = id
 	}
 	query := fmt.Sprintf(`
-        SELECT id, goal_id, phase_id, title, description, status, estimated_time, time_spent, created_at, updated_at
-        FROM tasks
-        WHERE id IN (%s)
-    `, strings.Join(placeholders, ", "))
+        SELECT %s
+        FROM tasks t
+        WHERE t.id IN (%s)
+    `, taskColumns, strings.Join(placeholders, ", "))
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query tasks by ids: %w", err)
 	}
@@ -341,11 +726,7 @@ func (r *repositoryImpl) GetTasksByIDs(ctx context.Context, ids []uuid.UUID) ([]
 	var result []Task
 	for rows.Next() {
 		var t Task
-		err := rows.Scan(
-			&t.ID, &t.GoalId, &t.PhaseId, &t.Title, &t.Description,
-			&t.Status, &t.EstimatedTime, &t.TimeSpent, &t.CreatedAt, &t.UpdatedAt,
-		)
-		if err != nil {
+		if err := scanTask(rows, &t); err != nil {
 			return nil, fmt.Errorf("scan task: %w", err)
 		}
 		result = append(result, t)
@@ -366,12 +747,12 @@ func (r *repositoryImpl) GetGoalsByIDs(ctx context.Context, ids []uuid.UUID) ([]
 		args[i] = id
 	}
 	query := fmt.Sprintf(`
-        SELECT id, user_id, title, description, status, estimated_time, created_at, updated_at
+        SELECT %s
         FROM goals
         WHERE id IN (%s)
-    `, strings.Join(placeholders, ", "))
+    `, goalColumns, strings.Join(placeholders, ", "))
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query goals by ids: %w", err)
 	}
@@ -380,11 +761,7 @@ func (r *repositoryImpl) GetGoalsByIDs(ctx context.Context, ids []uuid.UUID) ([]
 	var result []Goal
 	for rows.Next() {
 		var g Goal
-		err := rows.Scan(
-			&g.ID, &g.UserId, &g.Title, &g.Description, &g.Status,
-			&g.EstimatedTime, &g.CreatedAt, &g.UpdatedAt,
-		)
-		if err != nil {
+		if err := scanGoal(rows, &g); err != nil {
 			return nil, fmt.Errorf("scan goal: %w", err)
 		}
 		result = append(result, g)
@@ -394,45 +771,83 @@ func (r *repositoryImpl) GetGoalsByIDs(ctx context.Context, ids []uuid.UUID) ([]
 }
 
 func (r *repositoryImpl) GetTaskByID(ctx context.Context, id uuid.UUID) (*Task, error) {
-	q := `SELECT id, goal_id, phase_id, title, description, status,
-	             estimated_time, time_spent, completed_at, created_at, updated_at
-	      FROM tasks WHERE id = $1`
+	q := `SELECT ` + taskColumns + ` FROM tasks t WHERE t.id = $1`
 	var t Task
-	var phaseID *uuid.UUID
-	if err := r.db.QueryRowContext(ctx, q, id).Scan(
-		&t.ID, &t.GoalId, &phaseID, &t.Title, &t.Description,
-		&t.Status, &t.EstimatedTime, &t.TimeSpent,
-		&t.CompletedAt, &t.CreatedAt, &t.UpdatedAt); err != nil {
+	if err := scanTask(r.conn(ctx).QueryRowContext(ctx, q, id), &t); err != nil {
 		return nil, err
 	}
-	t.PhaseId = phaseID
 	return &t, nil
 }
 
 func (r *repositoryImpl) UpdateTask(ctx context.Context, t *Task) error {
 	t.UpdatedAt = time.Now()
-	_, err := r.db.ExecContext(ctx, `
+	res, err := r.conn(ctx).ExecContext(ctx, `
 	    UPDATE tasks
-	    SET status = $2, time_spent = $3, completed_at = $4, updated_at = $5
-	    WHERE id = $1`,
-		t.ID, t.Status, t.TimeSpent, t.CompletedAt, t.UpdatedAt)
-	return err
+	    SET status = $2, time_spent = $3, completed_at = $4, updated_at = $5, progress_mode = $6,
+	        status_manual = $7, blocked_reason = $8, priority = $9,
+	        recurrence_rule = $10, occurrence_minutes = $11, version = version + 1
+	    WHERE id = $1 AND version = $12`,
+		t.ID, t.Status, t.TimeSpent, t.CompletedAt, t.UpdatedAt, t.ProgressMode,
+		t.StatusManual, t.BlockedReason, t.Priority, t.RecurrenceRule, t.OccurrenceMinutes, t.Version)
+	if err != nil {
+		return err
+	}
+	if err := checkVersioned(res); err != nil {
+		return err
+	}
+	t.Version++
+	return nil
 }
 
 func (r *repositoryImpl) UpdateTaskTimeSpent(ctx context.Context, id uuid.UUID, spent int) error {
-	_, err := r.db.ExecContext(ctx,
-		`UPDATE tasks SET time_spent = $2, updated_at = NOW() WHERE id = $1`, id, spent)
+	_, err := r.conn(ctx).ExecContext(ctx,
+		`UPDATE tasks SET time_spent = $2, updated_at = NOW(), version = version + 1 WHERE id = $1`, id, spent)
+	return err
+}
+
+func (r *repositoryImpl) NextTasksByGoalIDs(ctx context.Context, goalIDs []uuid.UUID) (map[uuid.UUID]Task, error) {
+	next := make(map[uuid.UUID]Task, len(goalIDs))
+	if len(goalIDs) == 0 {
+		return next, nil
+	}
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT DISTINCT ON (t.goal_id) `+taskColumns+`
+		  FROM tasks t
+		 WHERE t.goal_id = ANY($1) AND t.status = 'todo'
+		 ORDER BY t.goal_id, t.priority DESC, t.created_at ASC`, pq.Array(goalIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query next tasks: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t Task
+		if err := scanTask(rows, &t); err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		next[t.GoalId] = t
+	}
+	return next, rows.Err()
+}
+
+func (r *repositoryImpl) MoveTask(ctx context.Context, id, phaseID uuid.UUID) error {
+	_, err := r.conn(ctx).ExecContext(ctx,
+		`UPDATE tasks SET phase_id = $2, updated_at = NOW(), version = version + 1 WHERE id = $1`, id, phaseID)
+	return err
+}
+
+func (r *repositoryImpl) DeleteTask(ctx context.Context, id uuid.UUID) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM tasks WHERE id = $1`, id)
 	return err
 }
 
 func (r *repositoryImpl) GetPhaseByID(ctx context.Context, id uuid.UUID) (*Phase, error) {
 	q := `SELECT id, goal_id, title, description, status,
-	             estimated_time, progress, "order", created_at, updated_at
+	             estimated_time, progress, "order", created_at, updated_at, version
 	      FROM phases WHERE id = $1`
 	var p Phase
-	if err := r.db.QueryRowContext(ctx, q, id).Scan(
+	if err := r.conn(ctx).QueryRowContext(ctx, q, id).Scan(
 		&p.ID, &p.GoalId, &p.Title, &p.Description, &p.Status,
-		&p.EstimatedTime, &p.Progress, &p.Order, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		&p.EstimatedTime, &p.Progress, &p.Order, &p.CreatedAt, &p.UpdatedAt, &p.Version); err != nil {
 		return nil, err
 	}
 	return &p, nil
@@ -440,17 +855,20 @@ func (r *repositoryImpl) GetPhaseByID(ctx context.Context, id uuid.UUID) (*Phase
 
 func (r *repositoryImpl) UpdatePhase(ctx context.Context, p *Phase) error {
 	p.UpdatedAt = time.Now()
-	_, err := r.db.ExecContext(ctx, `
+	res, err := r.conn(ctx).ExecContext(ctx, `
 	    UPDATE phases
-	    SET status = $2, progress = $3, updated_at = $4, started_at = $5, completed_at = $6
-	    WHERE id = $1`,
-		p.ID, p.Status, p.Progress, p.UpdatedAt, p.StartedAt, p.CompletedAt)
-	return err
-}
-
-func (r *repositoryImpl) DeleteGoal(ctx context.Context, id uuid.UUID) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM goals WHERE id = $1`, id)
-	return err
+	    SET status = $2, progress = $3, updated_at = $4, started_at = $5, completed_at = $6,
+	        version = version + 1
+	    WHERE id = $1 AND version = $7`,
+		p.ID, p.Status, p.Progress, p.UpdatedAt, p.StartedAt, p.CompletedAt, p.Version)
+	if err != nil {
+		return err
+	}
+	if err := checkVersioned(res); err != nil {
+		return err
+	}
+	p.Version++
+	return nil
 }
 
 func (r *repositoryImpl) ListTasksByUserAndDate(
@@ -460,16 +878,17 @@ func (r *repositoryImpl) ListTasksByUserAndDate(
 ) ([]Task, error) {
 
 	query := `
-SELECT t.id, t.goal_id, t.phase_id, t.title, t.description, 
-       t.status, t.estimated_time, t.time_spent, t.created_at, t.updated_at
+SELECT ` + taskColumns + `
 FROM tasks t
 JOIN goals g       ON g.id = t.goal_id  
 JOIN scheduled_task st ON st.task_id = t.id
 WHERE g.user_id = $1
   AND st.scheduled_date = $2
+  AND g.deleted_at IS NULL
+  AND g.archived_at IS NULL
 ORDER BY st.start_time
 `
-	rows, err := r.db.QueryContext(
+	rows, err := r.conn(ctx).QueryContext(
 		ctx,
 		query,
 		userID,
@@ -483,10 +902,7 @@ ORDER BY st.start_time
 	var result []Task
 	for rows.Next() {
 		var t Task
-		if err := rows.Scan(
-			&t.ID, &t.GoalId, &t.PhaseId, &t.Title, &t.Description,
-			&t.Status, &t.EstimatedTime, &t.TimeSpent, &t.CreatedAt, &t.UpdatedAt,
-		); err != nil {
+		if err := scanTask(rows, &t); err != nil {
 			return nil, fmt.Errorf("scan task: %w", err)
 		}
 		result = append(result, t)
@@ -505,8 +921,10 @@ FROM scheduled_task  st
 JOIN tasks           t ON t.id = st.task_id
 JOIN goals           g ON g.id = t.goal_id
 WHERE st.scheduled_date = $1
+  AND g.deleted_at IS NULL
+  AND g.archived_at IS NULL
 `
-	rows, err := r.db.QueryContext(ctx, query, date.Format("2006-01-02"))
+	rows, err := r.conn(ctx).QueryContext(ctx, query, date.Format("2006-01-02"))
 	if err != nil {
 		return nil, fmt.Errorf("query users with tasks: %w", err)
 	}
@@ -523,11 +941,24 @@ WHERE st.scheduled_date = $1
 	return users, nil
 }
 
+func (r *repositoryImpl) GetUserLocale(ctx context.Context, userID int64) (string, error) {
+	var locale string
+	err := r.conn(ctx).QueryRowContext(ctx,
+		`SELECT locale FROM users WHERE id = $1`, userID).Scan(&locale)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get user locale: %w", err)
+	}
+	return locale, nil
+}
+
 func (r *repositoryImpl) ListDoneTasksSince(
 	ctx context.Context, phaseID uuid.UUID, since time.Time,
 ) ([]Task, error) {
 
-	rows, err := r.db.QueryContext(ctx, `
+	rows, err := r.conn(ctx).QueryContext(ctx, `
         SELECT id, goal_id, phase_id, title, description,
                estimated_time, time_spent, completed_at
         FROM tasks
@@ -551,18 +982,82 @@ func (r *repositoryImpl) ListDoneTasksSince(
 func (r *repositoryImpl) SumTimeSpentPhase(
 	ctx context.Context, phaseID uuid.UUID,
 ) (int, error) {
-	var hours int
-	err := r.db.QueryRowContext(ctx,
+	var minutes int
+	err := r.conn(ctx).QueryRowContext(ctx,
 		`SELECT COALESCE(SUM(time_spent),0) FROM tasks WHERE phase_id=$1`, phaseID).
-		Scan(&hours)
-	return hours, err
+		Scan(&minutes)
+	return minutes, err
+}
+
+func (r *repositoryImpl) ListPhasesAwaitingTasks(ctx context.Context, maxAttempts int) ([]Phase, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT p.id, p.goal_id, p.title, p.description, p.status, p.progress,
+		       p.estimated_time, p."order", p.created_at, p.updated_at, p.version
+		  FROM phases p
+		  JOIN goals g ON g.id = p.goal_id
+		 WHERE p.tasks_generated_at IS NULL
+		   AND p.status <> 'completed'
+		   AND p.task_generation_attempts < $1
+		   AND (p.task_generation_retry_at IS NULL OR p.task_generation_retry_at <= NOW())
+		   AND g.auto_refill
+		   AND g.prompt_version <> ''
+		   AND g.deleted_at IS NULL
+		   AND g.archived_at IS NULL
+		   AND NOT EXISTS (SELECT 1 FROM tasks t WHERE t.phase_id = p.id)
+		   AND EXISTS (SELECT 1 FROM phases e WHERE e.goal_id = p.goal_id AND e."order" < p."order")
+		   AND NOT EXISTS (SELECT 1 FROM phases e
+		                    WHERE e.goal_id = p.goal_id AND e."order" < p."order"
+		                      AND e.status <> 'completed')
+		 ORDER BY p.updated_at`, maxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list phases awaiting tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var phases []Phase
+	for rows.Next() {
+		var p Phase
+		if err := rows.Scan(
+			&p.ID, &p.GoalId, &p.Title, &p.Description, &p.Status, &p.Progress,
+			&p.EstimatedTime, &p.Order, &p.CreatedAt, &p.UpdatedAt, &p.Version,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan phase: %w", err)
+		}
+		phases = append(phases, p)
+	}
+	return phases, rows.Err()
+}
+
+func (r *repositoryImpl) RecordPhaseTasksFailure(ctx context.Context, phaseID uuid.UUID, backoff time.Duration) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `
+		UPDATE phases
+		   SET task_generation_attempts = task_generation_attempts + 1,
+		       task_generation_retry_at = NOW() + $2 * POWER(2, task_generation_attempts) * INTERVAL '1 second'
+		 WHERE id = $1`, phaseID, backoff.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to record phase task failure: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) ClaimPhaseTasks(ctx context.Context, phaseID uuid.UUID) (bool, error) {
+	res, err := r.conn(ctx).ExecContext(ctx, `
+		UPDATE phases SET tasks_generated_at = NOW()
+		 WHERE id = $1
+		   AND tasks_generated_at IS NULL
+		   AND NOT EXISTS (SELECT 1 FROM tasks WHERE phase_id = $1)`, phaseID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
 }
 
 func (r *repositoryImpl) CountPendingTasks(
 	ctx context.Context, phaseID uuid.UUID,
 ) (int, error) {
 	var cnt int
-	err := r.db.QueryRowContext(
+	err := r.conn(ctx).QueryRowContext(
 		ctx,
 		`SELECT COUNT(*) FROM tasks WHERE phase_id = $1 AND status = 'todo'`,
 		phaseID,
@@ -573,12 +1068,12 @@ func (r *repositoryImpl) CountPendingTasks(
 func (r *repositoryImpl) ListActiveGoals(
 	ctx context.Context,
 ) ([]Goal, error) {
-	rows, err := r.db.QueryContext(ctx,
-		`SELECT id, user_id, title, description, status,
-		        estimated_time, hours_per_week, progress,
-		        created_at, updated_at
+	rows, err := r.conn(ctx).QueryContext(ctx,
+		`SELECT `+goalColumns+`
 		   FROM goals
-		  WHERE status = 'active'`)
+		  WHERE status = 'active'
+		    AND archived_at IS NULL
+		    AND deleted_at IS NULL`)
 	if err != nil {
 		return nil, err
 	}
@@ -587,12 +1082,741 @@ func (r *repositoryImpl) ListActiveGoals(
 	var res []Goal
 	for rows.Next() {
 		var g Goal
-		_ = rows.Scan(
-			&g.ID, &g.UserId, &g.Title, &g.Description, &g.Status,
-			&g.EstimatedTime, &g.HoursPerWeek, &g.Progress,
-			&g.CreatedAt, &g.UpdatedAt,
-		)
+		_ = scanGoal(rows, &g)
 		res = append(res, g)
 	}
 	return res, nil
 }
+
+func (r *repositoryImpl) CreateChecklistItem(ctx context.Context, it *ChecklistItem) error {
+	query := `
+INSERT INTO task_checklist_items (id, task_id, title, is_done, "order", completed_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+`
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		it.ID,
+		it.TaskID,
+		it.Title,
+		it.IsDone,
+		it.Order,
+		it.CompletedAt,
+		it.CreatedAt,
+		it.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert checklist item: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetChecklistItemByID(ctx context.Context, id uuid.UUID) (*ChecklistItem, error) {
+	q := `SELECT id, task_id, title, is_done, "order", completed_at, created_at, updated_at
+	      FROM task_checklist_items WHERE id = $1`
+	var it ChecklistItem
+	err := r.conn(ctx).QueryRowContext(ctx, q, id).Scan(
+		&it.ID, &it.TaskID, &it.Title, &it.IsDone, &it.Order,
+		&it.CompletedAt, &it.CreatedAt, &it.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checklist item: %w", err)
+	}
+	return &it, nil
+}
+
+func (r *repositoryImpl) UpdateChecklistItem(ctx context.Context, it *ChecklistItem) error {
+	it.UpdatedAt = time.Now()
+	_, err := r.conn(ctx).ExecContext(ctx, `
+	    UPDATE task_checklist_items
+	    SET title = $2, is_done = $3, "order" = $4, completed_at = $5, updated_at = $6
+	    WHERE id = $1`,
+		it.ID, it.Title, it.IsDone, it.Order, it.CompletedAt, it.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update checklist item: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) DeleteChecklistItem(ctx context.Context, id uuid.UUID) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM task_checklist_items WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete checklist item: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) ListChecklistItemsByTaskIDs(
+	ctx context.Context, taskIDs []uuid.UUID,
+) ([]ChecklistItem, error) {
+	if len(taskIDs) == 0 {
+		return []ChecklistItem{}, nil
+	}
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT id, task_id, title, is_done, "order", completed_at, created_at, updated_at
+		FROM task_checklist_items
+		WHERE task_id = ANY($1)
+		ORDER BY task_id, "order", created_at`,
+		pq.Array(taskIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checklist items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []ChecklistItem
+	for rows.Next() {
+		var it ChecklistItem
+		if err := rows.Scan(
+			&it.ID, &it.TaskID, &it.Title, &it.IsDone, &it.Order,
+			&it.CompletedAt, &it.CreatedAt, &it.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan checklist item: %w", err)
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+func (r *repositoryImpl) NextChecklistOrder(ctx context.Context, taskID uuid.UUID) (int, error) {
+	var next int
+	err := r.conn(ctx).QueryRowContext(ctx,
+		`SELECT COALESCE(MAX("order"), 0) + 1 FROM task_checklist_items WHERE task_id = $1`, taskID).
+		Scan(&next)
+	return next, err
+}
+
+// ReorderChecklistItems sets each item's order to its 1-based position in itemIDs.
+func (r *repositoryImpl) ReorderChecklistItems(ctx context.Context, taskID uuid.UUID, itemIDs []uuid.UUID) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `
+		UPDATE task_checklist_items
+		SET "order" = array_position($2::uuid[], id), updated_at = NOW()
+		WHERE task_id = $1 AND id = ANY($2::uuid[])`,
+		taskID, pq.Array(itemIDs))
+	if err != nil {
+		return fmt.Errorf("failed to reorder checklist: %w", err)
+	}
+	return nil
+}
+
+const occurrenceColumns = `id, task_id, occurrence_date, status, time_spent, completed_at, created_at, updated_at`
+
+func scanOccurrence(row rowScanner, o *Occurrence) error {
+	return row.Scan(&o.ID, &o.TaskID, &o.Date, &o.Status, &o.TimeSpent, &o.CompletedAt, &o.CreatedAt, &o.UpdatedAt)
+}
+
+func (r *repositoryImpl) EnsureOccurrence(ctx context.Context, taskID uuid.UUID, date time.Time) (*Occurrence, error) {
+	// DO UPDATE (rather than DO NOTHING) so RETURNING yields the existing row.
+	row := r.conn(ctx).QueryRowContext(ctx, `
+		INSERT INTO task_occurrences (id, task_id, occurrence_date, status)
+		VALUES ($1, $2, $3, 'pending')
+		ON CONFLICT (task_id, occurrence_date) DO UPDATE SET task_id = EXCLUDED.task_id
+		RETURNING `+occurrenceColumns,
+		uuid.New(), taskID, date.Format("2006-01-02"))
+	var o Occurrence
+	if err := scanOccurrence(row, &o); err != nil {
+		return nil, fmt.Errorf("failed to ensure occurrence: %w", err)
+	}
+	return &o, nil
+}
+
+func (r *repositoryImpl) GetOccurrenceByID(ctx context.Context, id uuid.UUID) (*Occurrence, error) {
+	row := r.conn(ctx).QueryRowContext(ctx,
+		`SELECT `+occurrenceColumns+` FROM task_occurrences WHERE id = $1`, id)
+	var o Occurrence
+	if err := scanOccurrence(row, &o); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get occurrence: %w", err)
+	}
+	return &o, nil
+}
+
+func (r *repositoryImpl) UpdateOccurrence(ctx context.Context, o *Occurrence) error {
+	o.UpdatedAt = time.Now()
+	_, err := r.conn(ctx).ExecContext(ctx, `
+		UPDATE task_occurrences
+		SET status = $2, time_spent = $3, completed_at = $4, updated_at = $5
+		WHERE id = $1`,
+		o.ID, o.Status, o.TimeSpent, o.CompletedAt, o.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update occurrence: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) ListOccurrences(
+	ctx context.Context, taskID uuid.UUID, from, to time.Time,
+) ([]Occurrence, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT `+occurrenceColumns+`
+		FROM task_occurrences
+		WHERE task_id = $1 AND occurrence_date BETWEEN $2 AND $3
+		ORDER BY occurrence_date`,
+		taskID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list occurrences: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Occurrence
+	for rows.Next() {
+		var o Occurrence
+		if err := scanOccurrence(rows, &o); err != nil {
+			return nil, fmt.Errorf("failed to scan occurrence: %w", err)
+		}
+		result = append(result, o)
+	}
+	return result, rows.Err()
+}
+
+const templateColumns = `id, user_id, source_goal_id, title, COALESCE(description, ''), hours_per_week,
+	estimated_time, kind, progress_strategy, is_public, structure, created_at, updated_at`
+
+func scanTemplate(row rowScanner, t *Template) error {
+	var structure []byte
+	if err := row.Scan(
+		&t.ID, &t.UserID, &t.SourceGoalID, &t.Title, &t.Description, &t.HoursPerWeek,
+		&t.EstimatedTime, &t.Kind, &t.ProgressStrategy, &t.IsPublic, &structure,
+		&t.CreatedAt, &t.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(structure, &t.Phases); err != nil {
+		return fmt.Errorf("failed to decode template structure: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) CreateTemplate(ctx context.Context, t *Template) error {
+	structure, err := json.Marshal(t.Phases)
+	if err != nil {
+		return fmt.Errorf("failed to encode template structure: %w", err)
+	}
+	_, err = r.conn(ctx).ExecContext(ctx, `
+		INSERT INTO goal_templates (
+			id, user_id, source_goal_id, title, description, hours_per_week, estimated_time,
+			kind, progress_strategy, is_public, structure, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		t.ID, t.UserID, t.SourceGoalID, t.Title, t.Description, t.HoursPerWeek, t.EstimatedTime,
+		t.Kind, t.ProgressStrategy, t.IsPublic, structure, t.CreatedAt, t.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert template: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetTemplateByID(ctx context.Context, id uuid.UUID) (*Template, error) {
+	row := r.conn(ctx).QueryRowContext(ctx,
+		`SELECT `+templateColumns+` FROM goal_templates WHERE id = $1`, id)
+	var t Template
+	if err := scanTemplate(row, &t); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return &t, nil
+}
+
+func (r *repositoryImpl) UpdateTemplate(ctx context.Context, t *Template) error {
+	t.UpdatedAt = time.Now()
+	_, err := r.conn(ctx).ExecContext(ctx, `
+		UPDATE goal_templates
+		SET title = $2, description = $3, is_public = $4, updated_at = $5
+		WHERE id = $1`,
+		t.ID, t.Title, t.Description, t.IsPublic, t.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update template: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) DeleteTemplate(ctx context.Context, userID int64, id uuid.UUID) (bool, error) {
+	res, err := r.conn(ctx).ExecContext(ctx,
+		`DELETE FROM goal_templates WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete template: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *repositoryImpl) ListTemplates(ctx context.Context, userID int64, scope string) ([]Template, error) {
+	where := "(user_id = $1 OR is_public)"
+	args := []interface{}{userID}
+	switch scope {
+	case TemplateScopeMine:
+		where = "user_id = $1"
+	case TemplateScopePublic:
+		where = "is_public"
+		args = nil
+	}
+	rows, err := r.conn(ctx).QueryContext(ctx,
+		`SELECT `+templateColumns+` FROM goal_templates WHERE `+where+` ORDER BY updated_at DESC`,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Template
+	for rows.Next() {
+		var t Template
+		if err := scanTemplate(rows, &t); err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+func (r *repositoryImpl) CopyAvailability(ctx context.Context, fromGoalID, toGoalID uuid.UUID) error {
+	rows, err := r.conn(ctx).QueryContext(ctx,
+		`SELECT id, day_of_week FROM availability WHERE goal_id = $1`, fromGoalID)
+	if err != nil {
+		return fmt.Errorf("failed to list availability: %w", err)
+	}
+	type day struct {
+		id  uuid.UUID
+		dow int
+	}
+	var days []day
+	for rows.Next() {
+		var d day
+		if err := rows.Scan(&d.id, &d.dow); err != nil {
+			rows.Close()
+			return err
+		}
+		days = append(days, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, d := range days {
+		newID := uuid.New()
+		if _, err := r.conn(ctx).ExecContext(ctx, `
+			INSERT INTO availability (id, goal_id, day_of_week, created_at, updated_at)
+			VALUES ($1, $2, $3, NOW(), NOW())`,
+			newID, toGoalID, d.dow); err != nil {
+			return fmt.Errorf("failed to copy availability: %w", err)
+		}
+		if _, err := r.conn(ctx).ExecContext(ctx, `
+			INSERT INTO time_slot (id, availability_id, start_time, end_time, created_at, updated_at)
+			SELECT uuid_generate_v4(), $2, start_time, end_time, NOW(), NOW()
+			FROM time_slot WHERE availability_id = $1`,
+			d.id, newID); err != nil {
+			return fmt.Errorf("failed to copy time slots: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *repositoryImpl) AppendActivity(ctx context.Context, a *Activity) error {
+	details, err := json.Marshal(a.Details)
+	if err != nil {
+		return fmt.Errorf("failed to encode activity details: %w", err)
+	}
+	if a.Details == nil {
+		details = []byte("{}")
+	}
+	_, err = r.conn(ctx).ExecContext(ctx, `
+		INSERT INTO goal_activity (id, goal_id, actor_id, entity_type, entity_id, action, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		a.ID, a.GoalID, a.ActorID, a.EntityType, a.EntityID, a.Action, details, a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to append activity: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) ListActivity(ctx context.Context, goalID uuid.UUID, limit, offset int) ([]Activity, int, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT id, goal_id, actor_id, entity_type, entity_id, action, details, created_at,
+		       COUNT(*) OVER ()
+		FROM goal_activity
+		WHERE goal_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3`,
+		goalID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list activity: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		result []Activity
+		total  int
+	)
+	for rows.Next() {
+		var (
+			a       Activity
+			details []byte
+		)
+		if err := rows.Scan(&a.ID, &a.GoalID, &a.ActorID, &a.EntityType, &a.EntityID,
+			&a.Action, &details, &a.CreatedAt, &total); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		if err := json.Unmarshal(details, &a.Details); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode activity details: %w", err)
+		}
+		result = append(result, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(result) == 0 && offset > 0 {
+		// Past the last page the window count is not available.
+		if err := r.conn(ctx).QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM goal_activity WHERE goal_id = $1`, goalID).Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("failed to count activity: %w", err)
+		}
+	}
+	return result, total, nil
+}
+
+func (r *repositoryImpl) CreateMilestone(ctx context.Context, m *Milestone) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `
+INSERT INTO milestones (id, phase_id, title, description, achieved_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		m.ID, m.PhaseID, m.Title, m.Description, m.AchievedAt, m.CreatedAt, m.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert milestone: %w", err)
+	}
+	for i := range m.Criteria {
+		if err := r.CreateCriterion(ctx, &m.Criteria[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetMilestoneByPhaseID(ctx context.Context, phaseID uuid.UUID) (*Milestone, error) {
+	var m Milestone
+	err := r.conn(ctx).QueryRowContext(ctx, `
+SELECT id, phase_id, title, description, achieved_at, created_at, updated_at
+FROM milestones WHERE phase_id = $1`, phaseID).Scan(
+		&m.ID, &m.PhaseID, &m.Title, &m.Description, &m.AchievedAt, &m.CreatedAt, &m.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get milestone: %w", err)
+	}
+	criteria, err := r.listCriteria(ctx, []uuid.UUID{m.ID})
+	if err != nil {
+		return nil, err
+	}
+	m.Criteria = criteria[m.ID]
+	return &m, nil
+}
+
+func (r *repositoryImpl) UpdateMilestone(ctx context.Context, m *Milestone) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `
+UPDATE milestones SET title = $2, description = $3, achieved_at = $4, updated_at = $5
+WHERE id = $1`,
+		m.ID, m.Title, m.Description, m.AchievedAt, m.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update milestone: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) DeleteMilestone(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM milestones WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete milestone: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) ListMilestonesByGoalID(ctx context.Context, goalID uuid.UUID) ([]Milestone, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+SELECT m.id, m.phase_id, m.title, m.description, m.achieved_at, m.created_at, m.updated_at
+FROM milestones m
+JOIN phases p ON p.id = m.phase_id
+WHERE p.goal_id = $1
+ORDER BY p."order"`, goalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list milestones: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Milestone
+	for rows.Next() {
+		var m Milestone
+		if err := rows.Scan(
+			&m.ID, &m.PhaseID, &m.Title, &m.Description, &m.AchievedAt, &m.CreatedAt, &m.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan milestone: %w", err)
+		}
+		result = append(result, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(result))
+	for _, m := range result {
+		ids = append(ids, m.ID)
+	}
+	criteria, err := r.listCriteria(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result {
+		result[i].Criteria = criteria[result[i].ID]
+	}
+	return result, nil
+}
+
+// listCriteria loads the criteria of the milestones, grouped by milestone
+// and in their order.
+func (r *repositoryImpl) listCriteria(ctx context.Context, milestoneIDs []uuid.UUID) (map[uuid.UUID][]Criterion, error) {
+	result := make(map[uuid.UUID][]Criterion)
+	if len(milestoneIDs) == 0 {
+		return result, nil
+	}
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+SELECT id, milestone_id, title, is_met, met_at, "order", created_at, updated_at
+FROM milestone_criteria
+WHERE milestone_id = ANY($1)
+ORDER BY milestone_id, "order", created_at`, pq.Array(milestoneIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list milestone criteria: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c Criterion
+		if err := rows.Scan(
+			&c.ID, &c.MilestoneID, &c.Title, &c.IsMet, &c.MetAt, &c.Order, &c.CreatedAt, &c.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan milestone criterion: %w", err)
+		}
+		result[c.MilestoneID] = append(result[c.MilestoneID], c)
+	}
+	return result, rows.Err()
+}
+
+func (r *repositoryImpl) CreateCriterion(ctx context.Context, c *Criterion) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `
+INSERT INTO milestone_criteria (id, milestone_id, title, is_met, met_at, "order", created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		c.ID, c.MilestoneID, c.Title, c.IsMet, c.MetAt, c.Order, c.CreatedAt, c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert milestone criterion: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) UpdateCriterion(ctx context.Context, c *Criterion) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `
+UPDATE milestone_criteria SET title = $2, is_met = $3, met_at = $4, "order" = $5, updated_at = $6
+WHERE id = $1`,
+		c.ID, c.Title, c.IsMet, c.MetAt, c.Order, c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update milestone criterion: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) DeleteCriterion(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM milestone_criteria WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete milestone criterion: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetCalibration(ctx context.Context, userID int64) (*float64, error) {
+	var f float64
+	err := r.conn(ctx).QueryRowContext(ctx,
+		`SELECT factor FROM estimate_calibration WHERE user_id = $1`, userID).Scan(&f)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *repositoryImpl) SetCalibration(ctx context.Context, userID int64, factor *float64) error {
+	if factor == nil {
+		_, err := r.conn(ctx).ExecContext(ctx,
+			`DELETE FROM estimate_calibration WHERE user_id = $1`, userID)
+		return err
+	}
+	_, err := r.conn(ctx).ExecContext(ctx, `
+		INSERT INTO estimate_calibration (user_id, factor, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET factor = EXCLUDED.factor, updated_at = NOW()`,
+		userID, *factor)
+	return err
+}
+
+// estimatedTasks selects the tasks estimation accuracy is measured on:
+// completed, with an estimate and tracked time. Recurring tasks are left
+// out since their tracked time belongs to the occurrences.
+const estimatedTasks = `
+WITH done AS (
+    SELECT t.id, t.goal_id, t.estimated_time * 60 AS estimated, t.time_spent AS spent,
+           t.estimate_calibrated
+      FROM tasks t
+      JOIN goals g ON g.id = t.goal_id
+     WHERE g.user_id = $1 AND g.deleted_at IS NULL
+       AND t.status = 'completed' AND t.completed_at >= $2
+       AND t.recurrence_rule IS NULL
+       AND t.estimated_time > 0 AND t.time_spent > 0
+)`
+
+func (r *repositoryImpl) EstimateStats(ctx context.Context, userID int64, since time.Time) (*EstimateStats, error) {
+	st := &EstimateStats{}
+	err := r.conn(ctx).QueryRowContext(ctx, estimatedTasks+`
+		SELECT COUNT(*), COALESCE(SUM(estimated), 0), COALESCE(SUM(spent), 0),
+		       COUNT(*) FILTER (WHERE NOT estimate_calibrated),
+		       COALESCE(SUM(estimated) FILTER (WHERE NOT estimate_calibrated), 0),
+		       COALESCE(SUM(spent) FILTER (WHERE NOT estimate_calibrated), 0)
+		  FROM done`, userID, since).Scan(
+		&st.Overall.Tasks, &st.Overall.EstimatedMinutes, &st.Overall.ActualMinutes,
+		&st.Raw.Tasks, &st.Raw.EstimatedMinutes, &st.Raw.ActualMinutes,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum estimates: %w", err)
+	}
+
+	st.ByGoal, err = r.groupedAccuracy(ctx, estimatedTasks+`
+		SELECT g.id, g.title, COUNT(*), SUM(d.estimated), SUM(d.spent)
+		  FROM done d
+		  JOIN goals g ON g.id = d.goal_id
+		 GROUP BY g.id, g.title
+		 ORDER BY SUM(d.spent) DESC, g.title`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to group estimates by goal: %w", err)
+	}
+
+	// A task counts towards its own tags and those of its goal, once each.
+	st.ByTag, err = r.groupedAccuracy(ctx, estimatedTasks+`
+		SELECT tg.id, tg.name, COUNT(*), SUM(d.estimated), SUM(d.spent)
+		  FROM done d
+		  JOIN LATERAL (
+		        SELECT tt.tag_id FROM task_tags tt WHERE tt.task_id = d.id
+		        UNION
+		        SELECT gt.tag_id FROM goal_tags gt WHERE gt.goal_id = d.goal_id
+		       ) x ON TRUE
+		  JOIN tags tg ON tg.id = x.tag_id
+		 GROUP BY tg.id, tg.name
+		 ORDER BY SUM(d.spent) DESC, tg.name`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to group estimates by tag: %w", err)
+	}
+	return st, nil
+}
+
+func (r *repositoryImpl) groupedAccuracy(ctx context.Context, query string, args ...interface{}) ([]Accuracy, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Accuracy
+	for rows.Next() {
+		var a Accuracy
+		if err := rows.Scan(&a.ID, &a.Name, &a.Tasks, &a.EstimatedMinutes, &a.ActualMinutes); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (r *repositoryImpl) CreateDraft(ctx context.Context, d *PlanDraft) error {
+	draft, messages, err := encodeDraft(d)
+	if err != nil {
+		return err
+	}
+	_, err = r.conn(ctx).ExecContext(ctx, `
+		INSERT INTO plan_drafts (id, user_id, draft, messages, turns, version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		d.ID, d.UserID, draft, messages, d.Turns, d.Version, d.CreatedAt, d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert draft: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetDraft(ctx context.Context, id uuid.UUID) (*PlanDraft, error) {
+	var d PlanDraft
+	var draft, messages []byte
+	err := r.conn(ctx).QueryRowContext(ctx, `
+		SELECT id, user_id, draft, messages, turns, version, created_at, updated_at
+		  FROM plan_drafts WHERE id = $1`, id).
+		Scan(&d.ID, &d.UserID, &draft, &messages, &d.Turns, &d.Version, &d.CreatedAt, &d.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get draft: %w", err)
+	}
+	if err := json.Unmarshal(draft, &d.Draft); err != nil {
+		return nil, fmt.Errorf("failed to decode draft: %w", err)
+	}
+	if err := json.Unmarshal(messages, &d.Messages); err != nil {
+		return nil, fmt.Errorf("failed to decode draft messages: %w", err)
+	}
+	return &d, nil
+}
+
+func (r *repositoryImpl) UpdateDraft(ctx context.Context, d *PlanDraft) (bool, error) {
+	draft, messages, err := encodeDraft(d)
+	if err != nil {
+		return false, err
+	}
+	d.UpdatedAt = time.Now()
+	res, err := r.conn(ctx).ExecContext(ctx, `
+		UPDATE plan_drafts
+		   SET draft = $3, messages = $4, turns = $5, version = version + 1, updated_at = $6
+		 WHERE id = $1 AND version = $2`,
+		d.ID, d.Version, draft, messages, d.Turns, d.UpdatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to update draft: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+	d.Version++
+	return true, nil
+}
+
+func (r *repositoryImpl) DeleteDraft(ctx context.Context, id uuid.UUID) (bool, error) {
+	res, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM plan_drafts WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete draft: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *repositoryImpl) PurgeDrafts(ctx context.Context, updatedBefore time.Time) (int64, error) {
+	res, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM plan_drafts WHERE updated_at < $1`, updatedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func encodeDraft(d *PlanDraft) (draft, messages []byte, err error) {
+	if draft, err = json.Marshal(d.Draft); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode draft: %w", err)
+	}
+	if messages, err = json.Marshal(d.Messages); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode draft messages: %w", err)
+	}
+	return draft, messages, nil
+}
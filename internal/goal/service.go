@@ -3,54 +3,126 @@ package goal
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
-	"github.com/sashabaranov/go-openai"
+	"log"
 	"strings"
 	"task-planner/internal/goal/dto"
 	"task-planner/internal/goal/dto/create"
 	"task-planner/internal/goal/dto/generate"
 	"task-planner/internal/goal/dto/get"
+	tpldto "task-planner/internal/goal/dto/template"
+	"task-planner/internal/goal/dto/update"
+	"task-planner/internal/llm"
+	"task-planner/internal/prompt"
+	"task-planner/internal/tag"
+	tagdto "task-planner/internal/tag/dto"
+	"task-planner/internal/usage"
+	"task-planner/pkg/config"
+	"task-planner/pkg/storage"
 	"time"
 )
 
 type Service interface {
 	CreateGoal(ctx context.Context, userID int64, req create.CreateGoalRequest) (*create.CreateGoalResponse, error)
-	GetGoalByID(ctx context.Context, goalID uuid.UUID) (*dto.GoalResponse, error)
+	DuplicateGoal(ctx context.Context, userID int64, goalID uuid.UUID, req create.DuplicateGoalRequest) (*create.CreateGoalResponse, error)
+	GetGoalByID(ctx context.Context, userID int64, goalID uuid.UUID) (*dto.GoalResponse, error)
 	ListGoals(ctx context.Context, userID int64, req get.ListGoalsRequest) (*get.ListGoalsResponse, error)
 	GenerateGoalDecomposition(ctx context.Context, userID int64, req generate.GenerateGoalRequest) (*generate.GenerateGoalResponse, error)
-	DeleteGoal(ctx context.Context, goalID uuid.UUID) error
+	StreamGoalDecomposition(ctx context.Context, userID int64, req generate.GenerateGoalRequest, emit EmitFunc) error
+	CreateDraft(ctx context.Context, userID int64, req generate.GenerateGoalRequest) (*generate.DraftResponse, error)
+	GetDraft(ctx context.Context, userID int64, draftID uuid.UUID) (*generate.DraftResponse, error)
+	RefineDraft(ctx context.Context, userID int64, draftID uuid.UUID, req generate.RefineDraftRequest) (*generate.RefineDraftResponse, error)
+	CommitDraft(ctx context.Context, userID int64, draftID uuid.UUID) (*create.CreateGoalResponse, error)
+	DeleteDraft(ctx context.Context, userID int64, draftID uuid.UUID) error
+	PurgeExpiredDrafts(ctx context.Context) (int64, error)
+	DeleteGoal(ctx context.Context, userID int64, goalID uuid.UUID, version int) error
+	RestoreGoal(ctx context.Context, userID int64, goalID uuid.UUID) error
+	ArchiveGoal(ctx context.Context, userID int64, goalID uuid.UUID) error
+	UnarchiveGoal(ctx context.Context, userID int64, goalID uuid.UUID) error
+	PurgeDeletedGoals(ctx context.Context) (int64, error)
 	AutoRefillTasks(ctx context.Context, goalID uuid.UUID) (int, error)
+	GeneratePhaseTasks(ctx context.Context, phaseID uuid.UUID) (int, error)
+	ListActivity(ctx context.Context, userID int64, goalID uuid.UUID, limit, offset int) (*get.ListActivityResponse, error)
+
+	SetTaskProgressMode(ctx context.Context, userID int64, taskID uuid.UUID, version int, req update.UpdateProgressModeRequest) (*dto.TaskResponse, error)
+	SetTaskStatus(ctx context.Context, userID int64, taskID uuid.UUID, version int, req update.UpdateTaskStatusRequest) (*dto.TaskResponse, error)
+	SetTaskPriority(ctx context.Context, userID int64, taskID uuid.UUID, version int, req update.UpdateTaskPriorityRequest) (*dto.TaskResponse, error)
+	BatchTasks(ctx context.Context, userID int64, req update.BatchTasksRequest) (*dto.BatchResponse, error)
+	ListChecklist(ctx context.Context, userID int64, taskID uuid.UUID) ([]dto.ChecklistItemResponse, error)
+	AddChecklistItem(ctx context.Context, userID int64, taskID uuid.UUID, req create.CreateChecklistItemRequest) (*dto.ChecklistItemResponse, error)
+	UpdateChecklistItem(ctx context.Context, userID int64, taskID, itemID uuid.UUID, req update.UpdateChecklistItemRequest) (*dto.ChecklistItemResponse, error)
+	DeleteChecklistItem(ctx context.Context, userID int64, taskID, itemID uuid.UUID) error
+	ReorderChecklist(ctx context.Context, userID int64, taskID uuid.UUID, req update.ReorderChecklistRequest) ([]dto.ChecklistItemResponse, error)
+
+	SetGoalProgressStrategy(ctx context.Context, userID int64, goalID uuid.UUID, version int, req update.UpdateProgressStrategyRequest) (*dto.GoalResponse, error)
+
+	GetMilestone(ctx context.Context, userID int64, phaseID uuid.UUID) (*dto.MilestoneResponse, error)
+	SetMilestone(ctx context.Context, userID int64, phaseID uuid.UUID, req update.SetMilestoneRequest) (*dto.MilestoneResponse, error)
+	DeleteMilestone(ctx context.Context, userID int64, phaseID uuid.UUID) error
+	ConfirmCriterion(ctx context.Context, userID int64, phaseID, criterionID uuid.UUID, req update.ConfirmCriterionRequest) (*dto.MilestoneResponse, error)
+
+	SetTaskRecurrence(ctx context.Context, userID int64, taskID uuid.UUID, req update.UpdateRecurrenceRequest) (*dto.TaskResponse, error)
+	ListOccurrences(ctx context.Context, userID int64, taskID uuid.UUID, from, to time.Time) ([]dto.OccurrenceResponse, error)
+	SetOccurrenceStatus(ctx context.Context, userID int64, taskID uuid.UUID, date time.Time, req update.UpdateOccurrenceRequest) (*dto.OccurrenceResponse, error)
+	GetStreak(ctx context.Context, userID int64, taskID uuid.UUID) (*dto.StreakResponse, error)
+
+	GetEstimateAccuracy(ctx context.Context, userID int64, days int) (*dto.EstimateAccuracyResponse, error)
+	SetCalibration(ctx context.Context, userID int64, req update.SetCalibrationRequest) (*dto.CalibrationResponse, error)
+
+	SaveGoalAsTemplate(ctx context.Context, userID int64, goalID uuid.UUID, req tpldto.SaveTemplateRequest) (*tpldto.TemplateResponse, error)
+	ListTemplates(ctx context.Context, userID int64, scope string) (*tpldto.ListTemplatesResponse, error)
+	GetTemplate(ctx context.Context, userID int64, templateID uuid.UUID) (*tpldto.TemplateResponse, error)
+	UpdateTemplate(ctx context.Context, userID int64, templateID uuid.UUID, req tpldto.UpdateTemplateRequest) (*tpldto.TemplateResponse, error)
+	DeleteTemplate(ctx context.Context, userID int64, templateID uuid.UUID) error
+	InstantiateTemplate(ctx context.Context, userID int64, templateID uuid.UUID, req tpldto.InstantiateTemplateRequest) (*create.CreateGoalResponse, error)
+
+	SetGoalTags(ctx context.Context, userID int64, goalID uuid.UUID, req tagdto.SetTagsRequest) ([]tagdto.TagResponse, error)
+	SetTaskTags(ctx context.Context, userID int64, taskID uuid.UUID, req tagdto.SetTagsRequest) ([]tagdto.TagResponse, error)
 }
 
 type service struct {
-	repo  RepositoryAggregator
-	db    *sql.DB
-	aiKey string
+	repo    RepositoryAggregator
+	tags    tag.Service
+	db      *sql.DB
+	ai      llm.Client
+	storage storage.Storage
+	cfg     config.GoalConfig
+	models  config.LLMConfig
 }
 
-func NewService(repo RepositoryAggregator, db *sql.DB, openAIKey string) Service {
+func NewService(
+	repo RepositoryAggregator, tags tag.Service, db *sql.DB, ai llm.Client,
+	store storage.Storage, cfg config.GoalConfig, models config.LLMConfig,
+) Service {
 	return &service{
-		repo:  repo,
-		db:    db,
-		aiKey: openAIKey,
+		repo:    repo,
+		tags:    tags,
+		db:      db,
+		ai:      ai,
+		storage: store,
+		cfg:     cfg,
+		models:  models,
 	}
 }
 
 func (s *service) CreateGoal(ctx context.Context, userID int64, req create.CreateGoalRequest) (*create.CreateGoalResponse, error) {
-	tx, err := s.db.BeginTx(ctx, nil)
+	var resp *create.CreateGoalResponse
+	err := s.repo.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = s.createGoal(ctx, userID, req)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, err
 	}
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		} else {
-			_ = tx.Commit()
-		}
-	}()
+	return resp, nil
+}
 
+// createGoal writes a goal with its phases, tasks and checklists; callers
+// run it inside a transaction.
+func (s *service) createGoal(ctx context.Context, userID int64, req create.CreateGoalRequest) (*create.CreateGoalResponse, error) {
 	now := time.Now()
 	goalID := uuid.New()
 
@@ -65,10 +137,33 @@ func (s *service) CreateGoal(ctx context.Context, userID int64, req create.Creat
 		Progress:      0,
 		CreatedAt:     now,
 		UpdatedAt:     now,
+
+		ProgressStrategy: req.ProgressStrategy,
+		Kind:             req.Kind,
+		PromptVersion:    req.PromptVersion,
+	}
+	if goal.ProgressStrategy != "" && !ValidProgressStrategy(goal.ProgressStrategy) {
+		return nil, ErrInvalidProgressStrategy
 	}
-	if err = s.repo.CreateGoal(ctx, goal); err != nil {
+	if goal.Kind != "" && goal.Kind != GoalKindProject && goal.Kind != GoalKindHabit {
+		return nil, ErrInvalidGoalKind
+	}
+	if goal.PromptVersion != "" && !prompt.Known(goal.PromptVersion) {
+		return nil, ErrInvalidPromptVersion
+	}
+	if req.Deadline != "" {
+		d, err := time.Parse("2006-01-02", req.Deadline)
+		if err != nil {
+			return nil, ErrInvalidDeadline
+		}
+		goal.Deadline = &d
+	}
+	if err := s.repo.CreateGoal(ctx, goal); err != nil {
 		return nil, fmt.Errorf("failed to create goal: %w", err)
 	}
+	if err := RecordActivity(ctx, s.repo, goalID, EntityGoal, goalID, ActionCreated, nil); err != nil {
+		return nil, err
+	}
 
 	var phaseResponses []dto.PhaseResponse
 	for i, phaseReq := range req.Phases {
@@ -88,12 +183,28 @@ func (s *service) CreateGoal(ctx context.Context, userID int64, req create.Creat
 		if phase.Order == 0 {
 			phase.Order = i + 1
 		}
-		if err = s.repo.CreatePhase(ctx, phase); err != nil {
+		if err := s.repo.CreatePhase(ctx, phase); err != nil {
 			return nil, fmt.Errorf("failed to create phase: %w", err)
 		}
 
 		var taskResponses []dto.TaskResponse
 		for _, taskReq := range phaseReq.Tasks {
+			priority := PriorityNormal
+			if taskReq.Priority != "" {
+				var ok bool
+				if priority, ok = ParsePriority(taskReq.Priority); !ok {
+					return nil, ErrInvalidPriority
+				}
+			}
+			var rule *string
+			if taskReq.Recurrence != "" {
+				rec, err := ParseRecurrence(taskReq.Recurrence)
+				if err != nil {
+					return nil, err
+				}
+				canonical := rec.String()
+				rule = &canonical
+			}
 			taskID := uuid.New()
 			t := &Task{
 				ID:            taskID,
@@ -103,12 +214,35 @@ func (s *service) CreateGoal(ctx context.Context, userID int64, req create.Creat
 				Description:   taskReq.Description,
 				Status:        "todo",
 				EstimatedTime: taskReq.EstimatedTime,
+				ProgressMode:  taskReq.ProgressMode,
+				Priority:      priority,
 				CreatedAt:     now,
 				UpdatedAt:     now,
+
+				RecurrenceRule:     rule,
+				OccurrenceMinutes:  taskReq.OccurrenceMin,
+				EstimateCalibrated: taskReq.EstimateCalibrated,
 			}
-			if err = s.repo.CreateTask(ctx, t); err != nil {
+			if taskReq.Generated {
+				t.PromptVersion = req.PromptVersion
+			}
+			if err := s.repo.CreateTask(ctx, t); err != nil {
 				return nil, fmt.Errorf("failed to create task: %w", err)
 			}
+			for j, itemReq := range taskReq.Checklist {
+				it := &ChecklistItem{
+					ID:        uuid.New(),
+					TaskID:    taskID,
+					Title:     itemReq.Title,
+					Order:     j + 1,
+					CreatedAt: now,
+					UpdatedAt: now,
+				}
+				if err := s.repo.CreateChecklistItem(ctx, it); err != nil {
+					return nil, fmt.Errorf("failed to create checklist item: %w", err)
+				}
+				t.Checklist = append(t.Checklist, *it)
+			}
 			taskResponses = append(taskResponses, *s.toTaskResponse(t))
 		}
 
@@ -126,13 +260,193 @@ func (s *service) CreateGoal(ctx context.Context, userID int64, req create.Creat
 	}, nil
 }
 
-func (s *service) GetGoalByID(ctx context.Context, goalID uuid.UUID) (*dto.GoalResponse, error) {
+// DuplicateGoal deep-copies a goal with its phases, milestones, tasks and
+// checklists in one transaction. The copy starts from scratch: planning
+// status, no progress, no tracked time, no ticked checklist items and no
+// confirmed acceptance criteria.
+func (s *service) DuplicateGoal(
+	ctx context.Context, userID int64, goalID uuid.UUID, req create.DuplicateGoalRequest,
+) (*create.CreateGoalResponse, error) {
+	var resp *create.CreateGoalResponse
+	err := s.repo.InTx(ctx, func(ctx context.Context) error {
+		src, err := s.repo.GetGoalByID(ctx, goalID)
+		if err != nil {
+			return fmt.Errorf("failed to get goal: %w", err)
+		}
+		if src == nil || src.UserId != userID {
+			return ErrGoalNotFound
+		}
+		phases, err := s.repo.ListPhasesByGoalID(ctx, src.ID)
+		if err != nil {
+			return err
+		}
+		tasks, err := s.repo.ListTasksByGoalID(ctx, src.ID)
+		if err != nil {
+			return err
+		}
+		if err := loadChecklists(ctx, s.repo, tasks); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		g := &Goal{
+			ID:               uuid.New(),
+			UserId:           userID,
+			Title:            src.Title,
+			Description:      src.Description,
+			Status:           "planning",
+			EstimatedTime:    src.EstimatedTime,
+			HoursPerWeek:     src.HoursPerWeek,
+			CreatedAt:        now,
+			UpdatedAt:        now,
+			ProgressStrategy: src.ProgressStrategy,
+			Kind:             src.Kind,
+			Deadline:         src.Deadline,
+			PromptVersion:    src.PromptVersion,
+		}
+		if req.Title != "" {
+			g.Title = req.Title
+		}
+		if err := s.repo.CreateGoal(ctx, g); err != nil {
+			return fmt.Errorf("failed to create goal: %w", err)
+		}
+		details := map[string]interface{}{"source_goal_id": src.ID}
+		if err := RecordActivity(ctx, s.repo, g.ID, EntityGoal, g.ID, ActionDuplicated, details); err != nil {
+			return err
+		}
+
+		phaseIDs := make(map[uuid.UUID]uuid.UUID, len(phases))
+		phaseResponses := make([]dto.PhaseResponse, 0, len(phases))
+		for _, p := range phases {
+			ph := &Phase{
+				ID:            uuid.New(),
+				GoalId:        g.ID,
+				Title:         p.Title,
+				Description:   p.Description,
+				Status:        "not_started",
+				EstimatedTime: p.EstimatedTime,
+				Order:         p.Order,
+				CreatedAt:     now,
+				UpdatedAt:     now,
+			}
+			if err := s.repo.CreatePhase(ctx, ph); err != nil {
+				return fmt.Errorf("failed to create phase: %w", err)
+			}
+			phaseIDs[p.ID] = ph.ID
+			phaseResponses = append(phaseResponses, *s.toPhaseResponse(ph))
+		}
+
+		gates, err := LoadGates(ctx, s.repo, src.ID)
+		if err != nil {
+			return err
+		}
+		closed := false
+		for i, p := range phases {
+			phaseResponses[i].Locked = closed
+			old, ok := gates[p.ID]
+			if !ok {
+				continue
+			}
+			m := &Milestone{
+				ID:          uuid.New(),
+				PhaseID:     phaseIDs[p.ID],
+				Title:       old.Title,
+				Description: old.Description,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+			for _, c := range old.Criteria {
+				m.Criteria = append(m.Criteria, Criterion{
+					ID:          uuid.New(),
+					MilestoneID: m.ID,
+					Title:       c.Title,
+					Order:       c.Order,
+					CreatedAt:   now,
+					UpdatedAt:   now,
+				})
+			}
+			if err := s.repo.CreateMilestone(ctx, m); err != nil {
+				return err
+			}
+			phaseResponses[i].Milestone = toMilestoneResponse(m)
+			closed = true
+		}
+
+		for _, old := range tasks {
+			if old.Status == TaskStatusCompleted && !req.KeepCompletedTasks {
+				continue
+			}
+			t := &Task{
+				ID:                uuid.New(),
+				GoalId:            g.ID,
+				Title:             old.Title,
+				Description:       old.Description,
+				Status:            TaskStatusTodo,
+				Priority:          old.Priority,
+				EstimatedTime:     old.EstimatedTime,
+				ProgressMode:      old.ProgressMode,
+				RecurrenceRule:    old.RecurrenceRule,
+				OccurrenceMinutes: old.OccurrenceMinutes,
+				CreatedAt:         now,
+				UpdatedAt:         now,
+
+				EstimateCalibrated: old.EstimateCalibrated,
+				PromptVersion:      old.PromptVersion,
+			}
+			if old.PhaseId != nil {
+				id := phaseIDs[*old.PhaseId]
+				t.PhaseId = &id
+			}
+			if err := s.repo.CreateTask(ctx, t); err != nil {
+				return fmt.Errorf("failed to create task: %w", err)
+			}
+			for _, item := range old.Checklist {
+				it := &ChecklistItem{
+					ID:        uuid.New(),
+					TaskID:    t.ID,
+					Title:     item.Title,
+					Order:     item.Order,
+					CreatedAt: now,
+					UpdatedAt: now,
+				}
+				if err := s.repo.CreateChecklistItem(ctx, it); err != nil {
+					return fmt.Errorf("failed to create checklist item: %w", err)
+				}
+				t.Checklist = append(t.Checklist, *it)
+			}
+
+			taskResp := *s.toTaskResponse(t)
+			for i := range phaseResponses {
+				if t.PhaseId != nil && phaseResponses[i].ID == *t.PhaseId {
+					phaseResponses[i].Tasks = append(phaseResponses[i].Tasks, taskResp)
+				}
+			}
+		}
+
+		if req.IncludeAvailability {
+			if err := s.repo.CopyAvailability(ctx, src.ID, g.ID); err != nil {
+				return err
+			}
+		}
+
+		goalResp := s.toGoalResponse(g)
+		goalResp.Phases = phaseResponses
+		resp = &create.CreateGoalResponse{Goal: *goalResp}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *service) GetGoalByID(ctx context.Context, userID int64, goalID uuid.UUID) (*dto.GoalResponse, error) {
 	g, err := s.repo.GetGoalByID(ctx, goalID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get goal: %w", err)
 	}
-	if g == nil {
-		return nil, fmt.Errorf("goal not found")
+	if g == nil || g.UserId != userID {
+		return nil, ErrGoalNotFound
 	}
 
 	phases, err := s.repo.ListPhasesByGoalID(ctx, g.ID)
@@ -143,25 +457,51 @@ func (s *service) GetGoalByID(ctx context.Context, goalID uuid.UUID) (*dto.GoalR
 	if err != nil {
 		return nil, err
 	}
+	if err := loadChecklists(ctx, s.repo, tasks); err != nil {
+		return nil, err
+	}
+	goalTags, err := s.tags.TagsForGoals(ctx, []uuid.UUID{g.ID})
+	if err != nil {
+		return nil, err
+	}
+	taskIDs := make([]uuid.UUID, 0, len(tasks))
+	for _, t := range tasks {
+		taskIDs = append(taskIDs, t.ID)
+	}
+	taskTags, err := s.tags.TagsForTasks(ctx, taskIDs)
+	if err != nil {
+		return nil, err
+	}
 
+	gates, err := LoadGates(ctx, s.repo, g.ID)
+	if err != nil {
+		return nil, err
+	}
+	locked := gates.Locked(phases)
 	for i := range phases {
 		ph := &phases[i]
-		ph.Progress = ph.CalculateProgress(tasks)
-		ph.Status = calculatePhaseStatus(ph)
+		ph.ApplyProgress(ph.CalculateProgress(tasks, g.Strategy()), gates.Open(ph.ID))
 	}
 
-	_ = s.repo.UpdateGoal(ctx, g)
+	g.Progress = g.CalculateProgress(tasks)
 
 	goalResp := s.toGoalResponse(g)
+	goalResp.Tags = goalTags[g.ID]
 
 	var phaseResponses []dto.PhaseResponse
 	for _, p := range phases {
 		phResp := s.toPhaseResponse(&p)
+		phResp.Locked = locked[p.ID]
+		if m, ok := gates[p.ID]; ok {
+			phResp.Milestone = toMilestoneResponse(m)
+		}
 
 		var taskResps []dto.TaskResponse
 		for _, t := range tasks {
 			if t.PhaseId != nil && *t.PhaseId == p.ID {
-				taskResps = append(taskResps, *s.toTaskResponse(&t))
+				taskResp := s.toTaskResponse(&t)
+				taskResp.Tags = taskTags[t.ID]
+				taskResps = append(taskResps, *taskResp)
 			}
 		}
 		phResp.Tasks = taskResps
@@ -172,68 +512,91 @@ func (s *service) GetGoalByID(ctx context.Context, goalID uuid.UUID) (*dto.GoalR
 	return goalResp, nil
 }
 
+// ListGoals returns a page of the user's goals. Progress is the value the
+// change cascade keeps stored on each goal and the next tasks come from one
+// batched query, so the cost does not grow with the page size.
 func (s *service) ListGoals(ctx context.Context, userID int64, req get.ListGoalsRequest) (*get.ListGoalsResponse, error) {
 	if req.Limit <= 0 {
 		req.Limit = 10
 	}
+	if req.Limit > 100 {
+		req.Limit = 100
+	}
+	if req.Offset < 0 {
+		req.Offset = 0
+	}
+	if req.Sort == "" {
+		req.Sort = GoalSortUpdatedAt
+	}
+	if req.Order == "" {
+		// Nearest deadline first, otherwise newest or furthest along first.
+		req.Order = "desc"
+		if req.Sort == GoalSortDeadline {
+			req.Order = "asc"
+		}
+	}
+	if !ValidGoalSort(req.Sort) || (req.Order != "asc" && req.Order != "desc") {
+		return nil, ErrInvalidGoalListQuery
+	}
+	if req.DateField != "" && (!ValidGoalSort(req.DateField) || req.DateField == GoalSortProgress) {
+		return nil, ErrInvalidGoalListQuery
+	}
+
+	goals, total, err := s.repo.ListGoals(ctx, userID, GoalFilter{
+		Limit:     req.Limit,
+		Offset:    req.Offset,
+		Status:    req.Status,
+		Archived:  req.Archived,
+		TagIDs:    req.TagIDs,
+		Kind:      req.Kind,
+		Query:     strings.TrimSpace(req.Query),
+		DateField: req.DateField,
+		From:      req.From,
+		To:        req.To,
+		Sort:      req.Sort,
+		Desc:      req.Order == "desc",
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	goals, total, err := s.repo.ListGoals(ctx, userID, req.Limit, req.Offset, req.Status)
+	goalIDs := make([]uuid.UUID, 0, len(goals))
+	for _, g := range goals {
+		goalIDs = append(goalIDs, g.ID)
+	}
+	goalTags, err := s.tags.TagsForGoals(ctx, goalIDs)
+	if err != nil {
+		return nil, err
+	}
+	nextTasks, err := s.repo.NextTasksByGoalIDs(ctx, goalIDs)
 	if err != nil {
 		return nil, err
 	}
 
 	listItems := make([]get.ListGoalItem, 0, len(goals))
-
 	for _, g := range goals {
-		phases, err := s.repo.ListPhasesByGoalID(ctx, g.ID)
-		if err != nil {
-			return nil, err
-		}
-		tasks, err := s.repo.ListTasksByGoalID(ctx, g.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		for i := range phases {
-			p := &phases[i]
-			p.Progress = p.CalculateProgress(tasks)
-		}
-
-		var nextTask *struct {
-			ID      uuid.UUID  `json:"id"`
-			Title   string     `json:"title"`
-			DueDate *time.Time `json:"due_date,omitempty"`
-		}
-		for _, t := range tasks {
-			if t.Status == "todo" {
-				nextTask = (*struct {
-					ID      uuid.UUID  `json:"id"`
-					Title   string     `json:"title"`
-					DueDate *time.Time `json:"due_date,omitempty"`
-				})(&struct {
-					ID      uuid.UUID
-					Title   string
-					DueDate *time.Time
-				}{
-					ID:    t.ID,
-					Title: t.Title,
-				})
-				break
-			}
-		}
-
-		//_ = s.repo.UpdateGoal(ctx, &g)
-
-		listItems = append(listItems, get.ListGoalItem{
+		item := get.ListGoalItem{
 			ID:           g.ID,
 			Title:        g.Title,
 			Description:  g.Description,
 			Status:       g.Status,
+			Kind:         g.Kind,
 			Progress:     g.Progress,
 			HoursPerWeek: g.HoursPerWeek,
 			UpdatedAt:    g.UpdatedAt,
-			NextTask:     nextTask,
-		})
+			ArchivedAt:   g.ArchivedAt,
+			Deadline:     formatDate(g.Deadline),
+			Version:      g.Version,
+			Tags:         goalTags[g.ID],
+		}
+		if t, ok := nextTasks[g.ID]; ok {
+			item.NextTask = &struct {
+				ID      uuid.UUID  `json:"id"`
+				Title   string     `json:"title"`
+				DueDate *time.Time `json:"due_date,omitempty"`
+			}{ID: t.ID, Title: t.Title}
+		}
+		listItems = append(listItems, item)
 	}
 
 	resp := &get.ListGoalsResponse{
@@ -246,16 +609,56 @@ func (s *service) ListGoals(ctx context.Context, userID int64, req get.ListGoals
 	return resp, nil
 }
 
+// formatDate renders an optional calendar date as YYYY-MM-DD.
+func formatDate(d *time.Time) *string {
+	if d == nil {
+		return nil
+	}
+	v := d.Format("2006-01-02")
+	return &v
+}
+
 func (s *service) GenerateGoalDecomposition(ctx context.Context, userID int64, req generate.GenerateGoalRequest) (*generate.GenerateGoalResponse, error) {
-	preview, err := s.callOpenAIForDecomposition(req.Title, req.Description, req.HoursPerWeek)
+	ctx = usage.WithCaller(ctx, userID, usage.FeatureDecompose)
+	preview, err := s.decompose(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	factor, err := CalibrationFactor(ctx, s.repo, userID)
 	if err != nil {
 		return nil, err
 	}
+	calibratePreview(preview, factor)
 	return &generate.GenerateGoalResponse{
 		GeneratedGoal: *preview,
 	}, nil
 }
 
+// calibratePreview scales the generated estimates by the user's factor and
+// marks the tasks so the scheduler does not scale them again.
+func calibratePreview(p *generate.GeneratedGoalPreview, factor float64) {
+	p.EstimatedTime = calibrateHours(p.EstimatedTime, factor)
+	for i := range p.Phases {
+		calibratePhaseDraft(&p.Phases[i], factor)
+	}
+}
+
+func calibratePhaseDraft(ph *generate.GeneratedPhaseDraft, factor float64) {
+	ph.EstimatedTime = calibrateHours(ph.EstimatedTime, factor)
+	for j := range ph.Tasks {
+		calibrateTaskDraft(&ph.Tasks[j], factor)
+	}
+}
+
+func calibrateTaskDraft(t *generate.GeneratedTaskDraft, factor float64) {
+	t.EstimatedTime = calibrateHours(t.EstimatedTime, factor)
+	t.EstimateCalibrated = true
+}
+
+func calibrateHours(hours int, factor float64) int {
+	return MinutesToHours(CalibrateMinutes(hours*60, factor))
+}
+
 func (s *service) toGoalResponse(g *Goal) *dto.GoalResponse {
 	return &dto.GoalResponse{
 		ID:            g.ID,
@@ -268,6 +671,14 @@ func (s *service) toGoalResponse(g *Goal) *dto.GoalResponse {
 		Progress:      g.Progress,
 		CreatedAt:     g.CreatedAt,
 		UpdatedAt:     g.UpdatedAt,
+		ArchivedAt:    g.ArchivedAt,
+		Deadline:      formatDate(g.Deadline),
+		Version:       g.Version,
+
+		ProgressStrategy: g.ProgressStrategy,
+		ManualProgress:   g.ManualProgress,
+		Kind:             g.Kind,
+		PromptVersion:    g.PromptVersion,
 	}
 }
 
@@ -282,124 +693,253 @@ func (s *service) toPhaseResponse(p *Phase) *dto.PhaseResponse {
 		Order:       p.Order,
 		CreatedAt:   p.CreatedAt,
 		UpdatedAt:   p.UpdatedAt,
+		Version:     p.Version,
 	}
 }
 
 func (s *service) toTaskResponse(t *Task) *dto.TaskResponse {
-	return &dto.TaskResponse{
+	resp := &dto.TaskResponse{
 		ID:            t.ID,
 		GoalID:        t.GoalId,
 		PhaseID:       t.PhaseId,
 		Title:         t.Title,
 		Description:   t.Description,
 		Status:        t.Status,
+		BlockedReason: t.BlockedReason,
+		Priority:      PriorityName(t.Priority),
 		EstimatedTime: t.EstimatedTime,
+		ProgressMode:  t.ProgressMode,
+		Progress:      t.CalculateProgress(),
+		Recurrence:    t.RecurrenceRule,
+		OccurrenceMin: t.OccurrenceMinutes,
 		CompletedAt:   t.CompletedAt,
 		CreatedAt:     t.CreatedAt,
 		UpdatedAt:     t.UpdatedAt,
+		Version:       t.Version,
+		PromptVersion: t.PromptVersion,
+
+		EstimateCalibrated: t.EstimateCalibrated,
+	}
+	for i := range t.Checklist {
+		resp.Checklist = append(resp.Checklist, *s.toChecklistItemResponse(&t.Checklist[i]))
 	}
+	return resp
 }
 
-func (s *service) callOpenAIForDecomposition(title, description string, hoursPerWeek int) (*generate.GeneratedGoalPreview, error) {
-	prompt := fmt.Sprintf(`
-Ты ассистент, помогаешь декомпозировать большие цели на фазы и задачи.
-1. Учитывай, что "фаза" – это крупный этап, состоящий из нескольких задач.
-2. Задачи – это конкретные, короткие, понятные действия, которые пользователь может выполнить в ближайшее время.
-3. В ответе верни JSON со структурой:
-{
-  "goal": {
-    "title": "string",
-    "description": "string",
-	"hours_per_week": %d,
-	"estimated_time": "number",
-    "phases": [
-      {
-        "title": "string",
-        "description": "string",
-        "estimated_time": "number",
-        "tasks": [
-          {
-            "title": "string",
-            "description": "string",
-            "estimated_time": "number"
-          }
-        ]
-      },
-      ...
-    ]
-  }
-}
-
-ВАЖНЫЕ ПРАВИЛА ДЛЯ ДЕКОМПОЗИЦИИ:
-1. Каждая задача (task) должна быть конкретным действием, которое можно выполнить за 1-3 часа
-2. Задачи (task) должны быть измеримыми и проверяемыми
-3. Сумма времени всех задач в фазе НЕ ДОЛЖНА превышать estimated_time фазы
-4. Для первой фазы создавай задачи на первую неделю работы
-5. Задачи должны быть последовательными и логически связанными
-6. Избегай слишком общих формулировок, используй конкретные действия
-7. Каждая задача должна иметь четкий результат
-
-Пример хорошей задачи:
-"Создать макеты экранов" - ПЛОХО
-"Нарисовать макет главного экрана в Figma" - ХОРОШО
-
-Пример плохой задачи:
-"Определить технологии" - ПЛОХО
-"Составить список необходимых библиотек для работы с базой данных" - ХОРОШО
-
-Задачи нужны только для первой фазы, для других оставь tasks пустым.
-
-Цель: %s
-Описание: %s
-Пользователь готов выделять на цель %d часов в неделю`, hoursPerWeek, title, description, hoursPerWeek)
-
-	client := openai.NewClient(s.aiKey)
-	resp, err := client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT4,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
-			},
-			Temperature: 0.7,
-		},
-	)
+func (s *service) toChecklistItemResponse(it *ChecklistItem) *dto.ChecklistItemResponse {
+	return &dto.ChecklistItemResponse{
+		ID:          it.ID,
+		TaskID:      it.TaskID,
+		Title:       it.Title,
+		Done:        it.IsDone,
+		Order:       it.Order,
+		CompletedAt: it.CompletedAt,
+		CreatedAt:   it.CreatedAt,
+		UpdatedAt:   it.UpdatedAt,
+	}
+}
+
+func (s *service) decompose(ctx context.Context, userID int64, req generate.GenerateGoalRequest) (*generate.GeneratedGoalPreview, error) {
+	p, err := s.decompositionPrompt(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	result, _, err := s.askPlan(ctx, []llm.Message{{Role: llm.RoleUser, Content: p.Text}})
+	if err != nil {
+		return nil, err
+	}
+	preview := result.preview()
+	preview.PromptVersion = p.Version
+	return preview, nil
+}
+
+// askPlan sends a conversation that ends in a request for a plan and
+// returns the validated plan together with the model's reply.
+func (s *service) askPlan(ctx context.Context, msgs []llm.Message) (*llmDecomposition, string, error) {
+	var result llmDecomposition
+	spec := llm.JSONSpec{Schema: decompositionSchema, Check: result.check, Attempts: s.models.MaxAttempts}
+	resp, err := llm.AskJSONMessages(ctx, s.ai, s.models.Decompose, msgs, &result, spec)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create chat completion: %w", err)
+		return nil, "", fmt.Errorf("failed to decompose goal: %w", err)
 	}
+	return &result, resp.Content, nil
+}
 
-	type decomposedResult struct {
-		Goal generate.GeneratedGoalPreview `json:"goal"`
+func (s *service) decompositionPrompt(ctx context.Context, userID int64, req generate.GenerateGoalRequest) (prompt.Rendered, error) {
+	return s.renderPrompt(ctx, userID, prompt.Decompose, prompt.DecomposeData{
+		Title:        req.Title,
+		Description:  req.Description,
+		HoursPerWeek: req.HoursPerWeek,
+	})
+}
+
+// renderPrompt renders the named prompt in the user's locale.
+func (s *service) renderPrompt(ctx context.Context, userID int64, name string, data any) (prompt.Rendered, error) {
+	locale, err := s.repo.GetUserLocale(ctx, userID)
+	if err != nil {
+		return prompt.Rendered{}, err
 	}
+	return prompt.Render(name, locale, data)
+}
+
+// DeleteGoal only marks the goal as deleted; it can be restored until
+// PurgeDeletedGoals removes it after the configured retention.
+func (s *service) DeleteGoal(ctx context.Context, userID int64, goalID uuid.UUID, version int) error {
+	return s.repo.InTx(ctx, func(ctx context.Context) error {
+		g, err := s.repo.GetGoalByID(ctx, goalID)
+		if err != nil {
+			return fmt.Errorf("failed to get goal: %w", err)
+		}
+		if g == nil || g.UserId != userID || g.DeletedAt != nil {
+			return ErrGoalNotFound
+		}
+		if err := expectVersion(g.Version, version); err != nil {
+			return err
+		}
+		ok, err := s.repo.SoftDeleteGoal(ctx, userID, goalID, g.Version)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrVersionConflict
+		}
+		return RecordActivity(ctx, s.repo, goalID, EntityGoal, goalID, ActionDeleted, nil)
+	})
+}
+
+func (s *service) RestoreGoal(ctx context.Context, userID int64, goalID uuid.UUID) error {
+	return s.repo.InTx(ctx, func(ctx context.Context) error {
+		ok, err := s.repo.RestoreGoal(ctx, userID, goalID, time.Now().Add(-s.retention()))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrGoalNotRestorable
+		}
+		return RecordActivity(ctx, s.repo, goalID, EntityGoal, goalID, ActionRestored, nil)
+	})
+}
+
+func (s *service) ArchiveGoal(ctx context.Context, userID int64, goalID uuid.UUID) error {
+	now := time.Now()
+	return s.setArchived(ctx, userID, goalID, &now)
+}
+
+func (s *service) UnarchiveGoal(ctx context.Context, userID int64, goalID uuid.UUID) error {
+	return s.setArchived(ctx, userID, goalID, nil)
+}
+
+func (s *service) setArchived(ctx context.Context, userID int64, goalID uuid.UUID, at *time.Time) error {
+	return s.repo.InTx(ctx, func(ctx context.Context) error {
+		ok, err := s.repo.SetGoalArchived(ctx, userID, goalID, at)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrGoalNotFound
+		}
+		action := ActionArchived
+		if at == nil {
+			action = ActionUnarchived
+		}
+		return RecordActivity(ctx, s.repo, goalID, EntityGoal, goalID, action, nil)
+	})
+}
 
-	var result decomposedResult
-	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+// PurgeDeletedGoals removes the goals and, once that is committed, the
+// files attached to them; the attachment rows go with the goals via
+// ON DELETE CASCADE.
+func (s *service) PurgeDeletedGoals(ctx context.Context) (int64, error) {
+	cutoff := time.Now().Add(-s.retention())
+	var keys []string
+	var purged int64
+	err := s.repo.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if keys, err = s.repo.PurgeableAttachmentKeys(ctx, cutoff); err != nil {
+			return err
+		}
+		purged, err = s.repo.PurgeDeletedGoals(ctx, cutoff)
+		return err
+	})
+	if err != nil {
+		return 0, err
 	}
+	s.discardFiles(ctx, keys)
+	return purged, nil
+}
 
-	return &result.Goal, nil
+// discardFiles deletes stored attachments whose rows are already gone. A
+// failure only leaves an orphaned file behind, so it is logged.
+func (s *service) discardFiles(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.storage.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("[ATTACHMENT] failed to delete %s: %v", key, err)
+		}
+	}
 }
 
-func (s *service) DeleteGoal(ctx context.Context, goalID uuid.UUID) error {
-	return s.repo.DeleteGoal(ctx, goalID)
+func (s *service) retention() time.Duration {
+	return time.Duration(s.cfg.PurgeAfterDays) * 24 * time.Hour
 }
 
-func calculatePhaseStatus(p *Phase) string {
-	progress := p.Progress
+const (
+	defaultActivityLimit = 20
+	maxActivityLimit     = 100
+)
+
+// ListActivity returns a page of the goal's change log, newest first.
+func (s *service) ListActivity(
+	ctx context.Context, userID int64, goalID uuid.UUID, limit, offset int,
+) (*get.ListActivityResponse, error) {
+	g, err := s.repo.GetGoalByID(ctx, goalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get goal: %w", err)
+	}
+	if g == nil || g.UserId != userID {
+		return nil, ErrGoalNotFound
+	}
+	if limit <= 0 {
+		limit = defaultActivityLimit
+	}
+	if limit > maxActivityLimit {
+		limit = maxActivityLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
 
-	if progress == 0 {
-		return "not_started"
+	entries, total, err := s.repo.ListActivity(ctx, goalID, limit, offset)
+	if err != nil {
+		return nil, err
 	}
-	if progress == 100 {
-		return "completed"
+	resp := &get.ListActivityResponse{Items: make([]get.ActivityItem, 0, len(entries))}
+	for _, a := range entries {
+		resp.Items = append(resp.Items, get.ActivityItem{
+			ID:         a.ID,
+			ActorID:    a.ActorID,
+			EntityType: a.EntityType,
+			EntityID:   a.EntityID,
+			Action:     a.Action,
+			Details:    a.Details,
+			CreatedAt:  a.CreatedAt,
+		})
 	}
-	return "in_progress"
+	resp.Meta.Total = total
+	resp.Meta.Limit = limit
+	resp.Meta.Offset = offset
+	return resp, nil
 }
 
 func (s *service) AutoRefillTasks(ctx context.Context, goalID uuid.UUID) (int, error) {
+	g, err := s.repo.GetGoalByID(ctx, goalID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get goal: %w", err)
+	}
+	if g == nil {
+		return 0, ErrGoalNotFound
+	}
+	ctx = usage.WithCaller(ctx, g.UserId, usage.FeatureRefill)
+
 	phase, err := s.currentPhase(ctx, goalID)
 	if err != nil || phase == nil {
 		return 0, err
@@ -410,20 +950,32 @@ func (s *service) AutoRefillTasks(ctx context.Context, goalID uuid.UUID) (int, e
 		return 0, nil
 	}
 
-	summary, err := s.generatePhaseSummary(ctx, goalID, *phase)
+	summary, err := s.generatePhaseSummary(ctx, g, *phase)
 	if err != nil {
 		return 0, err
 	}
 
-	newTasks, err := s.generateExtraTasks(ctx, goalID, phase, summary, 3-pending)
+	newTasks, version, err := s.generateExtraTasks(ctx, g, phase, summary, 3-pending)
 	if err != nil {
 		return 0, err
 	}
 	if len(newTasks) == 0 {
 		return 0, nil
 	}
+	factor, err := CalibrationFactor(ctx, s.repo, g.UserId)
+	if err != nil {
+		return 0, err
+	}
 
-	if err := insertNewTasks(ctx, s.repo, goalID, phase.ID, newTasks); err != nil {
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		titles, err := insertNewTasks(ctx, s.repo, goalID, phase.ID, newTasks, factor, version)
+		if err != nil {
+			return err
+		}
+		details := map[string]interface{}{"count": len(titles), "titles": titles}
+		return RecordActivity(ctx, s.repo, goalID, EntityPhase, phase.ID, ActionAIRefill, details)
+	})
+	if err != nil {
 		return 0, err
 	}
 	return len(newTasks), nil
@@ -445,121 +997,73 @@ func (s *service) currentPhase(ctx context.Context, goalID uuid.UUID) (*Phase, e
 }
 
 func (s *service) generatePhaseSummary(
-	ctx context.Context, goalID uuid.UUID, ph Phase,
+	ctx context.Context, g *Goal, ph Phase,
 ) (string, error) {
 
 	since := time.Now().AddDate(0, 0, -14)
 	done, _ := s.repo.ListDoneTasksSince(ctx, ph.ID, since)
 
-	var bullets []string
+	data := prompt.PhaseSummaryData{Goal: g.Title, Phase: ph.Title}
 	for _, t := range done {
-		bullets = append(bullets, fmt.Sprintf("- %s (%d ч)", t.Title, t.EstimatedTime))
+		data.Done = append(data.Done, prompt.TaskData{Title: t.Title, EstimatedHours: t.EstimatedTime})
 	}
-
-	goalObj, _ := s.repo.GetGoalByID(ctx, goalID)
-	prompt := fmt.Sprintf(`
-Ты ведёшь дневник выполнения фазы "%s" цели "%s".
-За последние 14 дней выполнено:
-%s
-
-Опиши краткое саммари (5-7 предложений, какие навыки/темы закрыты, на что делать упор далее).
-Ответ JSON:
-{ "summary": "..." }`, ph.Title, goalObj.Title, strings.Join(bullets, "\n"))
-
-	resp, err := s.askLLM(ctx, prompt)
+	p, err := s.renderPrompt(ctx, g.UserId, prompt.PhaseSummary, data)
 	if err != nil {
 		return "", err
 	}
-	var out struct {
-		Summary string `json:"summary"`
-	}
-	if err = json.Unmarshal([]byte(resp), &out); err != nil {
-		return "", err
-	}
-	return out.Summary, nil
-}
 
-func (s *service) askLLM(ctx context.Context, prompt string) (string, error) {
-	client := openai.NewClient(s.aiKey)
-	resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model:       openai.GPT4o,
-		Temperature: 0.7,
-		Messages: []openai.ChatCompletionMessage{
-			{Role: openai.ChatMessageRoleUser, Content: prompt},
-		},
-	})
-	if err != nil {
-		return "", err
+	var out llmSummary
+	spec := llm.JSONSpec{Schema: summarySchema, Attempts: s.models.MaxAttempts}
+	if _, err := llm.AskJSON(ctx, s.ai, s.models.Refill, p.Text, &out, spec); err != nil {
+		return "", fmt.Errorf("failed to summarize phase: %w", err)
 	}
-	return resp.Choices[0].Message.Content, nil
-}
-
-type llmTask struct {
-	Title         string `json:"title"`
-	Description   string `json:"description,omitempty"`
-	EstimatedTime int    `json:"estimated_time"`
+	return out.Summary, nil
 }
 
+// generateExtraTasks asks for at most count tasks that move the phase
+// forward and returns them with the version of the prompt used.
 func (s *service) generateExtraTasks(
 	ctx context.Context,
-	goalID uuid.UUID,
+	g *Goal,
 	ph *Phase,
 	summary string,
 	count int,
-) ([]llmTask, error) {
+) ([]llmTask, string, error) {
 
 	spent, _ := s.repo.SumTimeSpentPhase(ctx, ph.ID)
-	remaining := ph.EstimatedTime - spent
+	remaining := ph.EstimatedTime - spent/60
 	if remaining < 0 {
 		remaining = 0
 	}
-	goal, _ := s.repo.GetGoalByID(ctx, goalID)
-
-	prompt := fmt.Sprintf(`
-Ты – ассистент-планировщик. Пользователь работает над фазой "%s" цели "%s".
-У него осталось %d из %d запланированных часов фазы.
-Он готов уделять цели %d ч/нед.
-Последние 2 недели он сделал:
-%s
-
-Нужно создать новые конкретные задачи длиной 1-3 ч каждая, 
-чтобы продвинуть фазу дальше. Ответ строго в JSON-массиве, 
-каждый элемент:
-{ "title": "...", "description": "...", "estimated_time": 120 }
-
-Только ASCII кавычки, без комментариев.
-ВАЖНЫЕ ПРАВИЛА:
-1. Каждая задача должна быть конкретным действием, которое можно выполнить за 1-3 часа
-2. Задачи должны быть измеримыми и проверяемыми
-3. Сумма времени всех задач в фазе НЕ ДОЛЖНА превышать времени всей фазы
-4. Задачи должны быть последовательными и логически связанными
-5. Избегай слишком общих формулировок, используй конкретные действия
-6. Каждая задача должна иметь четкий результат
 
-Пример хорошей задачи:
-"Создать макеты экранов" - ПЛОХО
-"Нарисовать макет главного экрана в Figma" - ХОРОШО
-
-Пример плохой задачи:
-"Определить технологии" - ПЛОХО
-"Составить список необходимых библиотек для работы с базой данных" - ХОРОШО
-`,
-
-		ph.Title, goal.Title, remaining, ph.EstimatedTime,
-		goal.HoursPerWeek, summary)
-
-	raw, err := s.askLLM(ctx, prompt)
+	p, err := s.renderPrompt(ctx, g.UserId, prompt.ExtraTasks, prompt.ExtraTasksData{
+		Goal:           g.Title,
+		Phase:          ph.Title,
+		RemainingHours: remaining,
+		PhaseHours:     ph.EstimatedTime,
+		HoursPerWeek:   g.HoursPerWeek,
+		Summary:        summary,
+		Count:          count,
+	})
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+
 	var out []llmTask
-	if err := json.Unmarshal([]byte(raw), &out); err != nil {
-		return nil, fmt.Errorf("bad LLM json: %w, raw: %s", err, raw)
+	spec := llm.JSONSpec{
+		Schema: extraTasksSchema,
+		Check: func() []string {
+			if len(out) > count {
+				out = out[:count]
+			}
+			return checkExtraTasks(out, remaining)
+		},
+		Attempts: s.models.MaxAttempts,
 	}
-	if len(out) > count {
-		out = out[:count]
+	if _, err := llm.AskJSON(ctx, s.ai, s.models.Refill, p.Text, &out, spec); err != nil {
+		return nil, "", fmt.Errorf("failed to generate tasks: %w", err)
 	}
-	return out, nil
+	return out, p.Version, nil
 }
 
 func insertNewTasks(
@@ -567,10 +1071,13 @@ func insertNewTasks(
 	repo RepositoryAggregator,
 	goalID, phaseID uuid.UUID,
 	tasks []llmTask,
-) error {
+	factor float64,
+	promptVersion string,
+) ([]string, error) {
 	now := time.Now()
+	var titles []string
 	for _, it := range tasks {
-		if it.Title == "" || it.EstimatedTime <= 0 {
+		if it.Title == "" || it.EstimatedMinutes <= 0 {
 			continue
 		}
 		t := &Task{
@@ -580,13 +1087,1432 @@ func insertNewTasks(
 			Title:         it.Title,
 			Description:   it.Description,
 			Status:        "todo",
-			EstimatedTime: it.EstimatedTime,
+			EstimatedTime: MinutesToHours(CalibrateMinutes(it.EstimatedMinutes, factor)),
 			CreatedAt:     now,
 			UpdatedAt:     now,
+
+			EstimateCalibrated: true,
+			PromptVersion:      promptVersion,
 		}
 		if err := repo.CreateTask(ctx, t); err != nil {
-			return err
+			return nil, err
 		}
+		titles = append(titles, t.Title)
+	}
+	return titles, nil
+}
+
+// ownedTask loads a task and checks that its goal belongs to the user.
+func (s *service) ownedTask(ctx context.Context, userID int64, taskID uuid.UUID) (*Task, error) {
+	t, err := s.repo.GetTaskByID(ctx, taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	g, err := s.repo.GetGoalByID(ctx, t.GoalId)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil || g.UserId != userID {
+		return nil, ErrTaskNotFound
+	}
+	return t, nil
+}
+
+// expectVersion fails with ErrVersionConflict when the client's copy of an
+// entity is older than the stored one. The repository re-checks the
+// version in the UPDATE itself, so a write racing between the two still
+// conflicts.
+func expectVersion(current, expected int) error {
+	if expected != AnyVersion && current != expected {
+		return ErrVersionConflict
 	}
 	return nil
 }
+
+// syncChecklistProgress re-derives a checklist-driven task after its items
+// change and rolls the result up. Time-driven tasks are left to the schedule
+// toggle cascade.
+func (s *service) syncChecklistProgress(ctx context.Context, t *Task) error {
+	if t.ProgressMode != ProgressModeChecklist {
+		return nil
+	}
+	return RecalcTaskCascade(ctx, s.repo, t.ID)
+}
+
+func (s *service) SetTaskProgressMode(
+	ctx context.Context, userID int64, taskID uuid.UUID, version int, req update.UpdateProgressModeRequest,
+) (*dto.TaskResponse, error) {
+	if req.Mode != ProgressModeTime && req.Mode != ProgressModeChecklist {
+		return nil, ErrInvalidProgressMode
+	}
+	t, err := s.ownedTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectVersion(t.Version, version); err != nil {
+		return nil, err
+	}
+	items, err := s.repo.ListChecklistItemsByTaskIDs(ctx, []uuid.UUID{t.ID})
+	if err != nil {
+		return nil, err
+	}
+	t.Checklist = items
+	prevMode, prevStatus := t.ProgressMode, t.Status
+	t.ProgressMode = req.Mode
+	t.DeriveStatus()
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateTask(ctx, t); err != nil {
+			return fmt.Errorf("failed to update task: %w", err)
+		}
+		if err := RecordActivity(ctx, s.repo, t.GoalId, EntityTask, t.ID, ActionUpdated, change("progress_mode", prevMode, t.ProgressMode)); err != nil {
+			return err
+		}
+		if t.Status != prevStatus {
+			if err := RecordActivity(ctx, s.repo, t.GoalId, EntityTask, t.ID, ActionStatusChanged, statusChange(prevStatus, t.Status)); err != nil {
+				return err
+			}
+		}
+		return RecalcGoalProgress(ctx, s.repo, t.GoalId, t.PhaseId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.toTaskResponse(t), nil
+}
+
+func (s *service) ListChecklist(ctx context.Context, userID int64, taskID uuid.UUID) ([]dto.ChecklistItemResponse, error) {
+	t, err := s.ownedTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repo.ListChecklistItemsByTaskIDs(ctx, []uuid.UUID{t.ID})
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]dto.ChecklistItemResponse, 0, len(items))
+	for i := range items {
+		resp = append(resp, *s.toChecklistItemResponse(&items[i]))
+	}
+	return resp, nil
+}
+
+func (s *service) AddChecklistItem(
+	ctx context.Context, userID int64, taskID uuid.UUID, req create.CreateChecklistItemRequest,
+) (*dto.ChecklistItemResponse, error) {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return nil, ErrInvalidChecklistItem
+	}
+	t, err := s.ownedTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.repo.NextChecklistOrder(ctx, t.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	it := &ChecklistItem{
+		ID:        uuid.New(),
+		TaskID:    t.ID,
+		Title:     title,
+		Order:     order,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.CreateChecklistItem(ctx, it); err != nil {
+			return err
+		}
+		details := map[string]interface{}{"task_id": t.ID, "title": it.Title}
+		if err := RecordActivity(ctx, s.repo, t.GoalId, EntityChecklistItem, it.ID, ActionCreated, details); err != nil {
+			return err
+		}
+		return s.syncChecklistProgress(ctx, t)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.toChecklistItemResponse(it), nil
+}
+
+func (s *service) UpdateChecklistItem(
+	ctx context.Context, userID int64, taskID, itemID uuid.UUID, req update.UpdateChecklistItemRequest,
+) (*dto.ChecklistItemResponse, error) {
+	t, it, err := s.ownedChecklistItem(ctx, userID, taskID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []map[string]interface{}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return nil, ErrInvalidChecklistItem
+		}
+		if title != it.Title {
+			changes = append(changes, change("title", it.Title, title))
+		}
+		it.Title = title
+	}
+	if req.Done != nil && *req.Done != it.IsDone {
+		changes = append(changes, change("is_done", it.IsDone, *req.Done))
+		it.IsDone = *req.Done
+		it.CompletedAt = nil
+		if it.IsDone {
+			now := time.Now()
+			it.CompletedAt = &now
+		}
+	}
+
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateChecklistItem(ctx, it); err != nil {
+			return err
+		}
+		for _, c := range changes {
+			c["task_id"] = t.ID
+			if err := RecordActivity(ctx, s.repo, t.GoalId, EntityChecklistItem, it.ID, ActionUpdated, c); err != nil {
+				return err
+			}
+		}
+		return s.syncChecklistProgress(ctx, t)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.toChecklistItemResponse(it), nil
+}
+
+func (s *service) DeleteChecklistItem(ctx context.Context, userID int64, taskID, itemID uuid.UUID) error {
+	t, it, err := s.ownedChecklistItem(ctx, userID, taskID, itemID)
+	if err != nil {
+		return err
+	}
+	return s.repo.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.DeleteChecklistItem(ctx, it.ID); err != nil {
+			return err
+		}
+		details := map[string]interface{}{"task_id": t.ID, "title": it.Title}
+		if err := RecordActivity(ctx, s.repo, t.GoalId, EntityChecklistItem, it.ID, ActionDeleted, details); err != nil {
+			return err
+		}
+		return s.syncChecklistProgress(ctx, t)
+	})
+}
+
+func (s *service) ReorderChecklist(
+	ctx context.Context, userID int64, taskID uuid.UUID, req update.ReorderChecklistRequest,
+) ([]dto.ChecklistItemResponse, error) {
+	t, err := s.ownedTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repo.ListChecklistItemsByTaskIDs(ctx, []uuid.UUID{t.ID})
+	if err != nil {
+		return nil, err
+	}
+	if len(req.ItemIDs) != len(items) {
+		return nil, ErrInvalidChecklistOrder
+	}
+	known := make(map[uuid.UUID]bool, len(items))
+	prevOrder := make([]uuid.UUID, 0, len(items))
+	for _, it := range items {
+		known[it.ID] = true
+		prevOrder = append(prevOrder, it.ID)
+	}
+	for _, id := range req.ItemIDs {
+		if !known[id] {
+			return nil, ErrInvalidChecklistOrder
+		}
+		delete(known, id)
+	}
+
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.ReorderChecklistItems(ctx, t.ID, req.ItemIDs); err != nil {
+			return err
+		}
+		return RecordActivity(ctx, s.repo, t.GoalId, EntityTask, t.ID, ActionUpdated, change("checklist_order", prevOrder, req.ItemIDs))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.ListChecklist(ctx, userID, taskID)
+}
+
+func (s *service) ownedChecklistItem(
+	ctx context.Context, userID int64, taskID, itemID uuid.UUID,
+) (*Task, *ChecklistItem, error) {
+	t, err := s.ownedTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	it, err := s.repo.GetChecklistItemByID(ctx, itemID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if it == nil || it.TaskID != t.ID {
+		return nil, nil, ErrChecklistItemNotFound
+	}
+	return t, it, nil
+}
+
+func (s *service) SetGoalTags(
+	ctx context.Context, userID int64, goalID uuid.UUID, req tagdto.SetTagsRequest,
+) ([]tagdto.TagResponse, error) {
+	g, err := s.repo.GetGoalByID(ctx, goalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get goal: %w", err)
+	}
+	if g == nil || g.UserId != userID {
+		return nil, ErrGoalNotFound
+	}
+	return s.tags.AssignToGoal(ctx, userID, goalID, req.TagIDs)
+}
+
+func (s *service) SetTaskTags(
+	ctx context.Context, userID int64, taskID uuid.UUID, req tagdto.SetTagsRequest,
+) ([]tagdto.TagResponse, error) {
+	if _, err := s.ownedTask(ctx, userID, taskID); err != nil {
+		return nil, err
+	}
+	return s.tags.AssignToTask(ctx, userID, taskID, req.TagIDs)
+}
+
+func (s *service) SetTaskStatus(
+	ctx context.Context, userID int64, taskID uuid.UUID, version int, req update.UpdateTaskStatusRequest,
+) (*dto.TaskResponse, error) {
+	t, err := s.ownedTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectVersion(t.Version, version); err != nil {
+		return nil, err
+	}
+	prev := t.Status
+	if err := t.TransitionTo(req.Status, req.Reason); err != nil {
+		return nil, err
+	}
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateTask(ctx, t); err != nil {
+			return fmt.Errorf("failed to update task: %w", err)
+		}
+		details := statusChange(prev, t.Status)
+		if req.Reason != "" {
+			details["reason"] = req.Reason
+		}
+		if err := RecordActivity(ctx, s.repo, t.GoalId, EntityTask, t.ID, ActionStatusChanged, details); err != nil {
+			return err
+		}
+		return RecalcGoalProgress(ctx, s.repo, t.GoalId, t.PhaseId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.taskResponseWithChecklist(ctx, t)
+}
+
+// taskResponseWithChecklist loads the checklist of a task read without it
+// and builds the response.
+func (s *service) taskResponseWithChecklist(ctx context.Context, t *Task) (*dto.TaskResponse, error) {
+	items, err := s.repo.ListChecklistItemsByTaskIDs(ctx, []uuid.UUID{t.ID})
+	if err != nil {
+		return nil, err
+	}
+	t.Checklist = items
+	return s.toTaskResponse(t), nil
+}
+
+func (s *service) SetTaskPriority(
+	ctx context.Context, userID int64, taskID uuid.UUID, version int, req update.UpdateTaskPriorityRequest,
+) (*dto.TaskResponse, error) {
+	priority, ok := ParsePriority(req.Priority)
+	if !ok {
+		return nil, ErrInvalidPriority
+	}
+	t, err := s.ownedTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectVersion(t.Version, version); err != nil {
+		return nil, err
+	}
+	prev := t.Priority
+	t.Priority = priority
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateTask(ctx, t); err != nil {
+			return fmt.Errorf("failed to update task: %w", err)
+		}
+		return RecordActivity(ctx, s.repo, t.GoalId, EntityTask, t.ID, ActionUpdated, change("priority", prev, t.Priority))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.taskResponseWithChecklist(ctx, t)
+}
+
+// BatchTasks applies one action to many tasks in a single transaction and
+// recomputes progress once per affected goal. When any task fails nothing
+// is changed and the per-item results come back with ErrBatchFailed.
+func (s *service) BatchTasks(ctx context.Context, userID int64, req update.BatchTasksRequest) (*dto.BatchResponse, error) {
+	if len(req.TaskIDs) == 0 || len(req.TaskIDs) > MaxBatchSize {
+		return nil, ErrInvalidBatch
+	}
+
+	var apply func(ctx context.Context, t *Task, touched ProgressBatch) error
+	// orphaned collects the files of deleted tasks; they are removed only
+	// after the transaction commits.
+	var orphaned []string
+	switch req.Action {
+	case "status":
+		if req.Status == "" {
+			return nil, ErrInvalidBatch
+		}
+		apply = func(ctx context.Context, t *Task, touched ProgressBatch) error {
+			prev := t.Status
+			if err := t.TransitionTo(req.Status, req.Reason); err != nil {
+				return err
+			}
+			if err := s.repo.UpdateTask(ctx, t); err != nil {
+				return fmt.Errorf("failed to update task: %w", err)
+			}
+			touched.Touch(t.GoalId, t.PhaseId)
+			return RecordActivity(ctx, s.repo, t.GoalId, EntityTask, t.ID, ActionStatusChanged, statusChange(prev, t.Status))
+		}
+	case "move":
+		if req.PhaseID == nil {
+			return nil, ErrInvalidBatch
+		}
+		ph, err := s.repo.GetPhaseByID(ctx, *req.PhaseID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPhaseNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get phase: %w", err)
+		}
+		apply = func(ctx context.Context, t *Task, touched ProgressBatch) error {
+			if t.GoalId != ph.GoalId {
+				return ErrPhaseOutsideGoal
+			}
+			if t.PhaseId != nil && *t.PhaseId == ph.ID {
+				return nil
+			}
+			if err := s.repo.MoveTask(ctx, t.ID, ph.ID); err != nil {
+				return fmt.Errorf("failed to move task: %w", err)
+			}
+			touched.Touch(t.GoalId, t.PhaseId)
+			touched.Touch(t.GoalId, &ph.ID)
+			return RecordActivity(ctx, s.repo, t.GoalId, EntityTask, t.ID, ActionUpdated, change("phase_id", t.PhaseId, ph.ID))
+		}
+	case "delete":
+		apply = func(ctx context.Context, t *Task, touched ProgressBatch) error {
+			keys, err := s.repo.TaskAttachmentKeys(ctx, t.ID)
+			if err != nil {
+				return err
+			}
+			orphaned = append(orphaned, keys...)
+			if err := s.repo.DeleteTask(ctx, t.ID); err != nil {
+				return fmt.Errorf("failed to delete task: %w", err)
+			}
+			touched.Touch(t.GoalId, t.PhaseId)
+			details := map[string]interface{}{"title": t.Title}
+			return RecordActivity(ctx, s.repo, t.GoalId, EntityTask, t.ID, ActionDeleted, details)
+		}
+	default:
+		return nil, ErrInvalidBatch
+	}
+
+	var resp *dto.BatchResponse
+	err := s.repo.InTx(ctx, func(ctx context.Context) error {
+		touched := ProgressBatch{}
+		var err error
+		resp, err = RunBatch(req.TaskIDs, func(id uuid.UUID) error {
+			t, err := s.ownedTask(ctx, userID, id)
+			if err != nil {
+				return err
+			}
+			return apply(ctx, t, touched)
+		}, isTaskItemErr)
+		if err != nil {
+			return err
+		}
+		return touched.Recalc(ctx, s.repo)
+	})
+	if errors.Is(err, ErrBatchFailed) {
+		return resp, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.discardFiles(ctx, orphaned)
+	return resp, nil
+}
+
+// isTaskItemErr reports whether err concerns a single task of a batch
+// rather than the batch as a whole.
+func isTaskItemErr(err error) bool {
+	return errors.Is(err, ErrTaskNotFound) ||
+		errors.Is(err, ErrInvalidStatusTransition) ||
+		errors.Is(err, ErrBlockedReasonRequired) ||
+		errors.Is(err, ErrPhaseOutsideGoal)
+}
+
+func (s *service) SetGoalProgressStrategy(
+	ctx context.Context, userID int64, goalID uuid.UUID, version int, req update.UpdateProgressStrategyRequest,
+) (*dto.GoalResponse, error) {
+	if !ValidProgressStrategy(req.Strategy) {
+		return nil, ErrInvalidProgressStrategy
+	}
+	if req.ManualProgress != nil {
+		if req.Strategy != ProgressStrategyManual || *req.ManualProgress < 0 || *req.ManualProgress > 100 {
+			return nil, ErrInvalidManualProgress
+		}
+	}
+
+	g, err := s.repo.GetGoalByID(ctx, goalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get goal: %w", err)
+	}
+	if g == nil || g.UserId != userID {
+		return nil, ErrGoalNotFound
+	}
+	if err := expectVersion(g.Version, version); err != nil {
+		return nil, err
+	}
+
+	prev := g.ProgressStrategy
+	g.ProgressStrategy = req.Strategy
+	switch {
+	case req.Strategy != ProgressStrategyManual:
+		g.ManualProgress = nil
+	case req.ManualProgress != nil:
+		g.ManualProgress = req.ManualProgress
+	case g.ManualProgress == nil:
+		// Switching to manual without a value keeps what the user saw.
+		current := g.Progress
+		g.ManualProgress = &current
+	}
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateGoal(ctx, g); err != nil {
+			return err
+		}
+		details := change("progress_strategy", prev, g.ProgressStrategy)
+		if g.ManualProgress != nil {
+			details["manual_progress"] = *g.ManualProgress
+		}
+		if err := RecordActivity(ctx, s.repo, g.ID, EntityGoal, g.ID, ActionUpdated, details); err != nil {
+			return err
+		}
+
+		// Re-roll every phase so the new strategy shows up everywhere at once.
+		phases, err := s.repo.ListPhasesByGoalID(ctx, g.ID)
+		if err != nil {
+			return err
+		}
+		for i := range phases {
+			if err := RecalcGoalProgress(ctx, s.repo, g.ID, &phases[i].ID); err != nil {
+				return err
+			}
+		}
+		if len(phases) == 0 {
+			return RecalcGoalProgress(ctx, s.repo, g.ID, nil)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetGoalByID(ctx, userID, g.ID)
+}
+
+func (s *service) SetTaskRecurrence(
+	ctx context.Context, userID int64, taskID uuid.UUID, req update.UpdateRecurrenceRequest,
+) (*dto.TaskResponse, error) {
+	t, err := s.ownedTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if req.OccurrenceMinutes != nil && *req.OccurrenceMinutes <= 0 {
+		return nil, ErrInvalidRecurrence
+	}
+
+	var prev string
+	if t.RecurrenceRule != nil {
+		prev = *t.RecurrenceRule
+	}
+	if strings.TrimSpace(req.Rule) == "" {
+		t.RecurrenceRule = nil
+		t.OccurrenceMinutes = nil
+	} else {
+		rec, err := ParseRecurrence(req.Rule)
+		if err != nil {
+			return nil, err
+		}
+		canonical := rec.String()
+		t.RecurrenceRule = &canonical
+		t.OccurrenceMinutes = req.OccurrenceMinutes
+	}
+	var next string
+	if t.RecurrenceRule != nil {
+		next = *t.RecurrenceRule
+	}
+	t.UpdatedAt = time.Now()
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateTask(ctx, t); err != nil {
+			return fmt.Errorf("failed to update task: %w", err)
+		}
+		return RecordActivity(ctx, s.repo, t.GoalId, EntityTask, t.ID, ActionUpdated, change("recurrence", prev, next))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.taskResponseWithChecklist(ctx, t)
+}
+
+// recurringTask loads an owned task together with its parsed rule.
+func (s *service) recurringTask(ctx context.Context, userID int64, taskID uuid.UUID) (*Task, *Recurrence, error) {
+	t, err := s.ownedTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !t.IsRecurring() {
+		return nil, nil, ErrTaskNotRecurring
+	}
+	rec, err := ParseRecurrence(*t.RecurrenceRule)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, rec, nil
+}
+
+func (s *service) ListOccurrences(
+	ctx context.Context, userID int64, taskID uuid.UUID, from, to time.Time,
+) ([]dto.OccurrenceResponse, error) {
+	t, rec, err := s.recurringTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.repo.ListOccurrences(ctx, t.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	byDate := make(map[string]*Occurrence, len(stored))
+	for i := range stored {
+		byDate[stored[i].Date.Format("2006-01-02")] = &stored[i]
+	}
+
+	dates := rec.Between(t.CreatedAt, from, to)
+	result := make([]dto.OccurrenceResponse, 0, len(dates))
+	for _, d := range dates {
+		day := d.Format("2006-01-02")
+		if o, ok := byDate[day]; ok {
+			result = append(result, toOccurrenceResponse(o))
+			continue
+		}
+		result = append(result, dto.OccurrenceResponse{
+			TaskID: t.ID,
+			Date:   day,
+			Status: OccurrencePending,
+		})
+	}
+	return result, nil
+}
+
+func (s *service) SetOccurrenceStatus(
+	ctx context.Context, userID int64, taskID uuid.UUID, date time.Time, req update.UpdateOccurrenceRequest,
+) (*dto.OccurrenceResponse, error) {
+	if !ValidOccurrenceStatus(req.Status) {
+		return nil, ErrInvalidOccurrenceStatus
+	}
+	t, rec, err := s.recurringTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !rec.Occurs(t.CreatedAt, date) {
+		return nil, ErrInvalidOccurrence
+	}
+
+	var o *Occurrence
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if o, err = s.repo.EnsureOccurrence(ctx, t.ID, date); err != nil {
+			return err
+		}
+		prev := o.Status
+		o.SetStatus(req.Status)
+		if err := s.repo.UpdateOccurrence(ctx, o); err != nil {
+			return err
+		}
+		details := statusChange(prev, o.Status)
+		details["task_id"] = t.ID
+		details["date"] = o.Date.Format("2006-01-02")
+		return RecordActivity(ctx, s.repo, t.GoalId, EntityOccurrence, o.ID, ActionStatusChanged, details)
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := toOccurrenceResponse(o)
+	return &resp, nil
+}
+
+func (s *service) GetStreak(ctx context.Context, userID int64, taskID uuid.UUID) (*dto.StreakResponse, error) {
+	t, rec, err := s.recurringTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	stored, err := s.repo.ListOccurrences(ctx, t.ID, t.CreatedAt, now)
+	if err != nil {
+		return nil, err
+	}
+
+	streak := ComputeStreak(rec, t.CreatedAt, now, stored)
+	resp := &dto.StreakResponse{
+		TaskID:    t.ID,
+		Rule:      *t.RecurrenceRule,
+		Current:   streak.Current,
+		Longest:   streak.Longest,
+		Completed: streak.Completed,
+		Missed:    streak.Missed,
+	}
+	if streak.LastCompleted != nil {
+		day := streak.LastCompleted.Format("2006-01-02")
+		resp.LastCompleted = &day
+	}
+	return resp, nil
+}
+
+func toOccurrenceResponse(o *Occurrence) dto.OccurrenceResponse {
+	id := o.ID
+	return dto.OccurrenceResponse{
+		ID:          &id,
+		TaskID:      o.TaskID,
+		Date:        o.Date.Format("2006-01-02"),
+		Status:      o.Status,
+		TimeSpent:   o.TimeSpent,
+		CompletedAt: o.CompletedAt,
+	}
+}
+
+func (s *service) SaveGoalAsTemplate(
+	ctx context.Context, userID int64, goalID uuid.UUID, req tpldto.SaveTemplateRequest,
+) (*tpldto.TemplateResponse, error) {
+	g, err := s.repo.GetGoalByID(ctx, goalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get goal: %w", err)
+	}
+	if g == nil || g.UserId != userID {
+		return nil, ErrGoalNotFound
+	}
+	phases, err := s.repo.ListPhasesByGoalID(ctx, g.ID)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.repo.ListTasksByGoalID(ctx, g.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadChecklists(ctx, s.repo, tasks); err != nil {
+		return nil, err
+	}
+
+	tpl := NewTemplate(g, phases, tasks)
+	tpl.ID = uuid.New()
+	tpl.IsPublic = req.IsPublic
+	if req.Title != "" {
+		tpl.Title = req.Title
+	}
+	if req.Description != "" {
+		tpl.Description = req.Description
+	}
+	tpl.CreatedAt = time.Now()
+	tpl.UpdatedAt = tpl.CreatedAt
+	if err := s.repo.CreateTemplate(ctx, tpl); err != nil {
+		return nil, err
+	}
+	return toTemplateResponse(tpl, userID, true), nil
+}
+
+func (s *service) ListTemplates(ctx context.Context, userID int64, scope string) (*tpldto.ListTemplatesResponse, error) {
+	if scope != TemplateScopeAll && scope != TemplateScopeMine && scope != TemplateScopePublic {
+		return nil, ErrInvalidTemplateScope
+	}
+	templates, err := s.repo.ListTemplates(ctx, userID, scope)
+	if err != nil {
+		return nil, err
+	}
+	resp := &tpldto.ListTemplatesResponse{
+		Templates: make([]tpldto.TemplateResponse, 0, len(templates)),
+	}
+	for i := range templates {
+		resp.Templates = append(resp.Templates, *toTemplateResponse(&templates[i], userID, false))
+	}
+	return resp, nil
+}
+
+func (s *service) GetTemplate(ctx context.Context, userID int64, templateID uuid.UUID) (*tpldto.TemplateResponse, error) {
+	tpl, err := s.visibleTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	return toTemplateResponse(tpl, userID, true), nil
+}
+
+func (s *service) UpdateTemplate(
+	ctx context.Context, userID int64, templateID uuid.UUID, req tpldto.UpdateTemplateRequest,
+) (*tpldto.TemplateResponse, error) {
+	tpl, err := s.visibleTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	// Shared templates are read-only for everyone but the author.
+	if tpl.UserID != userID {
+		return nil, ErrTemplateNotFound
+	}
+	if req.Title != nil && strings.TrimSpace(*req.Title) != "" {
+		tpl.Title = *req.Title
+	}
+	if req.Description != nil {
+		tpl.Description = *req.Description
+	}
+	if req.IsPublic != nil {
+		tpl.IsPublic = *req.IsPublic
+	}
+	if err := s.repo.UpdateTemplate(ctx, tpl); err != nil {
+		return nil, err
+	}
+	return toTemplateResponse(tpl, userID, true), nil
+}
+
+func (s *service) DeleteTemplate(ctx context.Context, userID int64, templateID uuid.UUID) error {
+	ok, err := s.repo.DeleteTemplate(ctx, userID, templateID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// InstantiateTemplate builds a regular create request from the template and
+// hands it to CreateGoal, so a goal from a template is indistinguishable
+// from one created by hand.
+func (s *service) InstantiateTemplate(
+	ctx context.Context, userID int64, templateID uuid.UUID, req tpldto.InstantiateTemplateRequest,
+) (*create.CreateGoalResponse, error) {
+	tpl, err := s.visibleTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	createReq := create.CreateGoalRequest{
+		Title:            tpl.Title,
+		Description:      tpl.Description,
+		HoursPerWeek:     tpl.HoursPerWeek,
+		EstimatedTime:    tpl.EstimatedTime,
+		ProgressStrategy: tpl.ProgressStrategy,
+		Kind:             tpl.Kind,
+		Phases:           templatePhaseRequests(tpl.Phases),
+	}
+	if req.Title != "" {
+		createReq.Title = req.Title
+	}
+	if req.Description != "" {
+		createReq.Description = req.Description
+	}
+	if req.HoursPerWeek > 0 {
+		createReq.HoursPerWeek = req.HoursPerWeek
+	}
+	return s.CreateGoal(ctx, userID, createReq)
+}
+
+func (s *service) visibleTemplate(ctx context.Context, userID int64, templateID uuid.UUID) (*Template, error) {
+	tpl, err := s.repo.GetTemplateByID(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if tpl == nil || !tpl.VisibleTo(userID) {
+		return nil, ErrTemplateNotFound
+	}
+	return tpl, nil
+}
+
+func templatePhaseRequests(phases []TemplatePhase) []create.CreatePhaseRequest {
+	result := make([]create.CreatePhaseRequest, 0, len(phases))
+	for i, p := range phases {
+		pr := create.CreatePhaseRequest{
+			Title:         p.Title,
+			Description:   p.Description,
+			Order:         i + 1,
+			EstimatedTime: p.EstimatedTime,
+		}
+		for _, t := range p.Tasks {
+			tr := create.CreateTaskRequest{
+				Title:         t.Title,
+				Description:   t.Description,
+				EstimatedTime: t.EstimatedTime,
+				ProgressMode:  t.ProgressMode,
+				Priority:      t.Priority,
+				Recurrence:    t.RecurrenceRule,
+				OccurrenceMin: t.OccurrenceMinutes,
+			}
+			for _, item := range t.Checklist {
+				tr.Checklist = append(tr.Checklist, create.CreateChecklistItemRequest{Title: item})
+			}
+			pr.Tasks = append(pr.Tasks, tr)
+		}
+		result = append(result, pr)
+	}
+	return result
+}
+
+func toTemplateResponse(t *Template, userID int64, withPhases bool) *tpldto.TemplateResponse {
+	resp := &tpldto.TemplateResponse{
+		ID:               t.ID,
+		Title:            t.Title,
+		Description:      t.Description,
+		HoursPerWeek:     t.HoursPerWeek,
+		EstimatedTime:    t.EstimatedTime,
+		Kind:             t.Kind,
+		ProgressStrategy: t.ProgressStrategy,
+		IsPublic:         t.IsPublic,
+		IsOwner:          t.UserID == userID,
+		PhaseCount:       len(t.Phases),
+		TaskCount:        t.TaskCount(),
+		CreatedAt:        t.CreatedAt,
+		UpdatedAt:        t.UpdatedAt,
+	}
+	if withPhases {
+		resp.Phases = templatePhaseRequests(t.Phases)
+	}
+	return resp
+}
+
+// ownedPhase loads a phase and checks that its goal belongs to the user.
+func (s *service) ownedPhase(ctx context.Context, userID int64, phaseID uuid.UUID) (*Phase, error) {
+	ph, err := s.repo.GetPhaseByID(ctx, phaseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPhaseNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get phase: %w", err)
+	}
+	g, err := s.repo.GetGoalByID(ctx, ph.GoalId)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil || g.UserId != userID {
+		return nil, ErrPhaseNotFound
+	}
+	return ph, nil
+}
+
+func (s *service) GetMilestone(ctx context.Context, userID int64, phaseID uuid.UUID) (*dto.MilestoneResponse, error) {
+	if _, err := s.ownedPhase(ctx, userID, phaseID); err != nil {
+		return nil, err
+	}
+	m, err := s.repo.GetMilestoneByPhaseID(ctx, phaseID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, ErrMilestoneNotFound
+	}
+	return toMilestoneResponse(m), nil
+}
+
+// SetMilestone creates or replaces the milestone of a phase. Criteria are
+// matched by title, so rewording one resets its confirmation. The phase is
+// re-evaluated at once: gating an already completed phase reopens it.
+func (s *service) SetMilestone(
+	ctx context.Context, userID int64, phaseID uuid.UUID, req update.SetMilestoneRequest,
+) (*dto.MilestoneResponse, error) {
+	title := strings.TrimSpace(req.Title)
+	if title == "" || len([]rune(title)) > 255 || len(req.Criteria) == 0 {
+		return nil, ErrInvalidMilestone
+	}
+	criteria := make([]string, 0, len(req.Criteria))
+	for _, c := range req.Criteria {
+		c = strings.TrimSpace(c)
+		if c == "" || len([]rune(c)) > 255 {
+			return nil, ErrInvalidMilestone
+		}
+		criteria = append(criteria, c)
+	}
+
+	var m *Milestone
+	err := s.repo.InTx(ctx, func(ctx context.Context) error {
+		ph, err := s.ownedPhase(ctx, userID, phaseID)
+		if err != nil {
+			return err
+		}
+		if m, err = s.repo.GetMilestoneByPhaseID(ctx, ph.ID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if m == nil {
+			m = &Milestone{
+				ID:          uuid.New(),
+				PhaseID:     ph.ID,
+				Title:       title,
+				Description: strings.TrimSpace(req.Description),
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+			for i, c := range criteria {
+				m.Criteria = append(m.Criteria, Criterion{
+					ID:          uuid.New(),
+					MilestoneID: m.ID,
+					Title:       c,
+					Order:       i,
+					CreatedAt:   now,
+					UpdatedAt:   now,
+				})
+			}
+			if err := s.repo.CreateMilestone(ctx, m); err != nil {
+				return err
+			}
+			details := map[string]interface{}{"phase_id": ph.ID, "title": m.Title, "criteria": len(m.Criteria)}
+			if err := RecordActivity(ctx, s.repo, ph.GoalId, EntityMilestone, m.ID, ActionCreated, details); err != nil {
+				return err
+			}
+			return RecalcGoalPhases(ctx, s.repo, ph.GoalId, ph.ID)
+		}
+
+		m.Title = title
+		m.Description = strings.TrimSpace(req.Description)
+		m.UpdatedAt = now
+		if err := s.replaceCriteria(ctx, m, criteria, now); err != nil {
+			return err
+		}
+		m.SyncAchieved()
+		if err := s.repo.UpdateMilestone(ctx, m); err != nil {
+			return err
+		}
+		details := map[string]interface{}{"title": m.Title, "criteria": len(m.Criteria)}
+		if err := RecordActivity(ctx, s.repo, ph.GoalId, EntityMilestone, m.ID, ActionUpdated, details); err != nil {
+			return err
+		}
+		return RecalcGoalPhases(ctx, s.repo, ph.GoalId, ph.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toMilestoneResponse(m), nil
+}
+
+// replaceCriteria makes the milestone's criteria match titles, in that
+// order, keeping the rows (and confirmations) of titles it already had.
+func (s *service) replaceCriteria(ctx context.Context, m *Milestone, titles []string, now time.Time) error {
+	existing := make(map[string][]Criterion)
+	for _, c := range m.Criteria {
+		existing[c.Title] = append(existing[c.Title], c)
+	}
+
+	next := make([]Criterion, 0, len(titles))
+	for i, title := range titles {
+		if same := existing[title]; len(same) > 0 {
+			c := same[0]
+			existing[title] = same[1:]
+			if c.Order != i {
+				c.Order = i
+				c.UpdatedAt = now
+				if err := s.repo.UpdateCriterion(ctx, &c); err != nil {
+					return err
+				}
+			}
+			next = append(next, c)
+			continue
+		}
+		c := Criterion{
+			ID:          uuid.New(),
+			MilestoneID: m.ID,
+			Title:       title,
+			Order:       i,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if err := s.repo.CreateCriterion(ctx, &c); err != nil {
+			return err
+		}
+		next = append(next, c)
+	}
+	for _, rest := range existing {
+		for _, c := range rest {
+			if err := s.repo.DeleteCriterion(ctx, c.ID); err != nil {
+				return err
+			}
+		}
+	}
+	m.Criteria = next
+	return nil
+}
+
+func (s *service) DeleteMilestone(ctx context.Context, userID int64, phaseID uuid.UUID) error {
+	return s.repo.InTx(ctx, func(ctx context.Context) error {
+		ph, err := s.ownedPhase(ctx, userID, phaseID)
+		if err != nil {
+			return err
+		}
+		m, err := s.repo.GetMilestoneByPhaseID(ctx, ph.ID)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			return ErrMilestoneNotFound
+		}
+		if err := s.repo.DeleteMilestone(ctx, m.ID); err != nil {
+			return err
+		}
+		details := map[string]interface{}{"phase_id": ph.ID, "title": m.Title}
+		if err := RecordActivity(ctx, s.repo, ph.GoalId, EntityMilestone, m.ID, ActionDeleted, details); err != nil {
+			return err
+		}
+		return RecalcGoalPhases(ctx, s.repo, ph.GoalId, ph.ID)
+	})
+}
+
+// ConfirmCriterion confirms or withdraws one acceptance criterion. The
+// milestone is achieved, and the next phases unlock, when the last one is
+// confirmed.
+func (s *service) ConfirmCriterion(
+	ctx context.Context, userID int64, phaseID, criterionID uuid.UUID, req update.ConfirmCriterionRequest,
+) (*dto.MilestoneResponse, error) {
+	var m *Milestone
+	err := s.repo.InTx(ctx, func(ctx context.Context) error {
+		ph, err := s.ownedPhase(ctx, userID, phaseID)
+		if err != nil {
+			return err
+		}
+		if m, err = s.repo.GetMilestoneByPhaseID(ctx, ph.ID); err != nil {
+			return err
+		}
+		if m == nil {
+			return ErrMilestoneNotFound
+		}
+
+		var c *Criterion
+		for i := range m.Criteria {
+			if m.Criteria[i].ID == criterionID {
+				c = &m.Criteria[i]
+				break
+			}
+		}
+		if c == nil {
+			return ErrCriterionNotFound
+		}
+		if c.IsMet == req.Met {
+			return nil
+		}
+
+		now := time.Now()
+		c.SetMet(req.Met)
+		c.UpdatedAt = now
+		if err := s.repo.UpdateCriterion(ctx, c); err != nil {
+			return err
+		}
+		details := map[string]interface{}{"criterion_id": c.ID, "criterion": c.Title, "met": c.IsMet}
+		if err := RecordActivity(ctx, s.repo, ph.GoalId, EntityMilestone, m.ID, ActionUpdated, details); err != nil {
+			return err
+		}
+
+		wasAchieved := m.AchievedAt != nil
+		if !m.SyncAchieved() {
+			return nil
+		}
+		m.UpdatedAt = now
+		if err := s.repo.UpdateMilestone(ctx, m); err != nil {
+			return err
+		}
+		from, to := "pending", "achieved"
+		if wasAchieved {
+			from, to = to, from
+		}
+		if err := RecordActivity(ctx, s.repo, ph.GoalId, EntityMilestone, m.ID, ActionStatusChanged, statusChange(from, to)); err != nil {
+			return err
+		}
+		return RecalcGoalPhases(ctx, s.repo, ph.GoalId, ph.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toMilestoneResponse(m), nil
+}
+
+func toMilestoneResponse(m *Milestone) *dto.MilestoneResponse {
+	resp := &dto.MilestoneResponse{
+		ID:          m.ID,
+		PhaseID:     m.PhaseID,
+		Title:       m.Title,
+		Description: m.Description,
+		Achieved:    m.AchievedAt != nil,
+		AchievedAt:  m.AchievedAt,
+		Criteria:    make([]dto.CriterionResponse, 0, len(m.Criteria)),
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}
+	for _, c := range m.Criteria {
+		resp.Criteria = append(resp.Criteria, dto.CriterionResponse{
+			ID:    c.ID,
+			Title: c.Title,
+			Met:   c.IsMet,
+			MetAt: c.MetAt,
+			Order: c.Order,
+		})
+	}
+	return resp
+}
+
+// GetEstimateAccuracy compares estimated and tracked time of the tasks the
+// user completed in the last days, overall and per goal and tag, and
+// suggests a calibration factor once there are enough of them.
+func (s *service) GetEstimateAccuracy(ctx context.Context, userID int64, days int) (*dto.EstimateAccuracyResponse, error) {
+	if days < 0 {
+		return nil, ErrInvalidEstimateQuery
+	}
+	days, since := estimateWindow(days)
+	st, err := s.repo.EstimateStats(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	factor, err := s.repo.GetCalibration(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.EstimateAccuracyResponse{
+		Days:              days,
+		Overall:           toAccuracyItem(st.Overall, false),
+		ByGoal:            make([]dto.EstimateAccuracyItem, 0, len(st.ByGoal)),
+		ByTag:             make([]dto.EstimateAccuracyItem, 0, len(st.ByTag)),
+		CalibrationFactor: factor,
+	}
+	for _, a := range st.ByGoal {
+		resp.ByGoal = append(resp.ByGoal, toAccuracyItem(a, true))
+	}
+	for _, a := range st.ByTag {
+		resp.ByTag = append(resp.ByTag, toAccuracyItem(a, true))
+	}
+	if f, ok := st.Raw.SuggestedFactor(); ok {
+		resp.SuggestedFactor = &f
+	}
+	return resp, nil
+}
+
+// SetCalibration sets or clears the factor the scheduler and the AI
+// decomposition apply to new estimates.
+func (s *service) SetCalibration(ctx context.Context, userID int64, req update.SetCalibrationRequest) (*dto.CalibrationResponse, error) {
+	factor := req.Factor
+	if req.UseSuggested {
+		_, since := estimateWindow(0)
+		st, err := s.repo.EstimateStats(ctx, userID, since)
+		if err != nil {
+			return nil, err
+		}
+		f, ok := st.Raw.SuggestedFactor()
+		if !ok {
+			return nil, ErrNotEnoughEstimates
+		}
+		factor = &f
+	}
+	if factor != nil {
+		f := roundFactor(*factor)
+		if !ValidCalibrationFactor(f) {
+			return nil, ErrInvalidCalibration
+		}
+		factor = &f
+	}
+	if err := s.repo.SetCalibration(ctx, userID, factor); err != nil {
+		return nil, err
+	}
+	return &dto.CalibrationResponse{Factor: factor}, nil
+}
+
+func toAccuracyItem(a Accuracy, grouped bool) dto.EstimateAccuracyItem {
+	item := dto.EstimateAccuracyItem{
+		Name:             a.Name,
+		Tasks:            a.Tasks,
+		EstimatedMinutes: a.EstimatedMinutes,
+		ActualMinutes:    a.ActualMinutes,
+		Ratio:            a.Ratio(),
+	}
+	if grouped {
+		id := a.ID
+		item.ID = &id
+	}
+	return item
+}
+
+// CreateDraft generates a plan like GenerateGoalDecomposition and keeps it
+// with the conversation so it can be refined before it is saved.
+func (s *service) CreateDraft(ctx context.Context, userID int64, req generate.GenerateGoalRequest) (*generate.DraftResponse, error) {
+	ctx = usage.WithCaller(ctx, userID, usage.FeatureDecompose)
+	p, err := s.decompositionPrompt(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	msgs := []llm.Message{{Role: llm.RoleUser, Content: p.Text}}
+	result, reply, err := s.askPlan(ctx, msgs)
+	if err != nil {
+		return nil, err
+	}
+	factor, err := CalibrationFactor(ctx, s.repo, userID)
+	if err != nil {
+		return nil, err
+	}
+	preview := result.preview()
+	preview.PromptVersion = p.Version
+	calibratePreview(preview, factor)
+
+	now := time.Now()
+	d := &PlanDraft{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Draft:     *preview,
+		Messages:  append(msgs, llm.Message{Role: llm.RoleAssistant, Content: reply}),
+		Version:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.CreateDraft(ctx, d); err != nil {
+		return nil, err
+	}
+	return toDraftResponse(d), nil
+}
+
+func (s *service) GetDraft(ctx context.Context, userID int64, draftID uuid.UUID) (*generate.DraftResponse, error) {
+	d, err := s.ownedDraft(ctx, userID, draftID)
+	if err != nil {
+		return nil, err
+	}
+	return toDraftResponse(d), nil
+}
+
+// RefineDraft asks the model to change the draft as instructed and returns
+// the new plan with what changed. A draft refined concurrently by another
+// request fails with ErrVersionConflict.
+func (s *service) RefineDraft(
+	ctx context.Context, userID int64, draftID uuid.UUID, req generate.RefineDraftRequest,
+) (*generate.RefineDraftResponse, error) {
+	instruction := strings.TrimSpace(req.Instruction)
+	if instruction == "" || len([]rune(instruction)) > maxInstructionLength {
+		return nil, ErrInvalidInstruction
+	}
+	d, err := s.ownedDraft(ctx, userID, draftID)
+	if err != nil {
+		return nil, err
+	}
+	if d.Turns >= maxDraftTurns {
+		return nil, ErrDraftTurnLimit
+	}
+
+	locale, err := s.repo.GetUserLocale(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	msgs, version, err := d.conversation(locale, instruction)
+	if err != nil {
+		return nil, err
+	}
+	result, reply, err := s.askPlan(usage.WithCaller(ctx, userID, usage.FeatureRefine), msgs)
+	if err != nil {
+		return nil, err
+	}
+	factor, err := CalibrationFactor(ctx, s.repo, userID)
+	if err != nil {
+		return nil, err
+	}
+	preview := result.preview()
+	preview.PromptVersion = version
+	calibratePreview(preview, factor)
+	diff := DiffPlans(&d.Draft, preview)
+
+	d.Draft = *preview
+	d.Messages = append(d.Messages,
+		llm.Message{Role: llm.RoleUser, Content: instruction},
+		llm.Message{Role: llm.RoleAssistant, Content: reply},
+	)
+	d.Turns++
+	ok, err := s.repo.UpdateDraft(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrVersionConflict
+	}
+	return &generate.RefineDraftResponse{Draft: *toDraftResponse(d), Diff: diff}, nil
+}
+
+// CommitDraft saves the draft as a new goal through the same path as
+// CreateGoal and ends the session. The draft is deleted first: of two
+// concurrent commits, the second waits on the row and then finds it gone,
+// so only one goal is created.
+func (s *service) CommitDraft(ctx context.Context, userID int64, draftID uuid.UUID) (*create.CreateGoalResponse, error) {
+	var resp *create.CreateGoalResponse
+	err := s.repo.InTx(ctx, func(ctx context.Context) error {
+		d, err := s.ownedDraft(ctx, userID, draftID)
+		if err != nil {
+			return err
+		}
+		if err := s.deleteDraft(ctx, d.ID); err != nil {
+			return err
+		}
+		resp, err = s.createGoal(ctx, userID, d.createRequest())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *service) DeleteDraft(ctx context.Context, userID int64, draftID uuid.UUID) error {
+	d, err := s.ownedDraft(ctx, userID, draftID)
+	if err != nil {
+		return err
+	}
+	return s.deleteDraft(ctx, d.ID)
+}
+
+func (s *service) deleteDraft(ctx context.Context, draftID uuid.UUID) error {
+	ok, err := s.repo.DeleteDraft(ctx, draftID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDraftNotFound
+	}
+	return nil
+}
+
+func (s *service) PurgeExpiredDrafts(ctx context.Context) (int64, error) {
+	return s.repo.PurgeDrafts(ctx, time.Now().Add(-draftTTL))
+}
+
+func (s *service) ownedDraft(ctx context.Context, userID int64, draftID uuid.UUID) (*PlanDraft, error) {
+	d, err := s.repo.GetDraft(ctx, draftID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil || d.UserID != userID || d.Expired(time.Now()) {
+		return nil, ErrDraftNotFound
+	}
+	return d, nil
+}
+
+func toDraftResponse(d *PlanDraft) *generate.DraftResponse {
+	return &generate.DraftResponse{
+		ID:           d.ID,
+		Draft:        d.Draft,
+		Instructions: d.Instructions(),
+		Version:      d.Version,
+		CreatedAt:    d.CreatedAt,
+		UpdatedAt:    d.UpdatedAt,
+	}
+}
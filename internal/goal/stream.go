@@ -0,0 +1,95 @@
+package goal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"task-planner/internal/goal/dto/generate"
+	"task-planner/internal/llm"
+	"task-planner/internal/usage"
+)
+
+// EmitFunc sends one event of a streamed response to the client.
+type EmitFunc func(event string, data interface{}) error
+
+// StreamGoalDecomposition is GenerateGoalDecomposition with every phase and
+// task passed to emit as soon as the model has written it. When a reply is
+// rejected a retry event is sent and the corrected reply streams from the
+// start. The last event is done with the validated, calibrated plan.
+func (s *service) StreamGoalDecomposition(ctx context.Context, userID int64, req generate.GenerateGoalRequest, emit EmitFunc) error {
+	ctx = usage.WithCaller(ctx, userID, usage.FeatureDecompose)
+	factor, err := CalibrationFactor(ctx, s.repo, userID)
+	if err != nil {
+		return err
+	}
+
+	var result llmDecomposition
+	spec := llm.JSONSpec{Schema: decompositionSchema, Check: result.check, Attempts: s.models.MaxAttempts}
+	p, err := s.decompositionPrompt(ctx, userID, req)
+	if err != nil {
+		return err
+	}
+
+	var scanner *llm.ObjectScanner
+	current := 0
+	onDelta := func(attempt int, delta string) error {
+		if attempt != current {
+			if current > 0 {
+				if err := emit(generate.EventRetry, generate.RetryEvent{Attempt: attempt}); err != nil {
+					return err
+				}
+			}
+			current = attempt
+			scanner = &llm.ObjectScanner{OnObject: func(path, raw string) error {
+				return emitDraft(path, raw, factor, emit)
+			}}
+		}
+		return scanner.Write(delta)
+	}
+	if _, err := llm.StreamJSON(ctx, s.ai, s.models.Decompose, p.Text, &result, spec, onDelta); err != nil {
+		return fmt.Errorf("failed to decompose goal: %w", err)
+	}
+
+	preview := result.preview()
+	preview.PromptVersion = p.Version
+	calibratePreview(preview, factor)
+	return emit(generate.EventDone, generate.GenerateGoalResponse{GeneratedGoal: *preview})
+}
+
+// emitDraft sends a phase or task the scanner found complete. Objects that
+// do not decode yet are skipped; the final plan is validated as a whole.
+func emitDraft(path, raw string, factor float64, emit EmitFunc) error {
+	parts := strings.Split(path, ".")
+	if len(parts) < 3 || parts[0] != "goal" || parts[1] != "phases" {
+		return nil
+	}
+	phaseIdx, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil
+	}
+	switch {
+	case len(parts) == 3:
+		var ph llmPhase
+		if json.Unmarshal(llm.CleanJSON(raw), &ph) != nil || ph.Title == "" {
+			return nil
+		}
+		d := ph.draft(phaseIdx + 1)
+		calibratePhaseDraft(&d, factor)
+		return emit(generate.EventPhase, generate.PhaseEvent{Index: phaseIdx, Phase: d})
+	case len(parts) == 5 && parts[3] == "tasks":
+		taskIdx, err := strconv.Atoi(parts[4])
+		if err != nil {
+			return nil
+		}
+		var t llmDraftTask
+		if json.Unmarshal(llm.CleanJSON(raw), &t) != nil || t.Title == "" {
+			return nil
+		}
+		d := t.draft()
+		calibrateTaskDraft(&d, factor)
+		return emit(generate.EventTask, generate.TaskEvent{PhaseIndex: phaseIdx, Index: taskIdx, Task: d})
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package goal
+
+import (
+	"github.com/google/uuid"
+	"sort"
+	"time"
+)
+
+// Template scopes for the template library.
+const (
+	TemplateScopeAll    = "" // own templates and everything shared
+	TemplateScopeMine   = "mine"
+	TemplateScopePublic = "public"
+)
+
+// Template is the reusable structure of a goal: phases, tasks and their
+// estimates, without any progress, status or tracked time.
+type Template struct {
+	ID               uuid.UUID       `json:"id"`
+	UserID           int64           `json:"user_id"`
+	SourceGoalID     *uuid.UUID      `json:"source_goal_id,omitempty"`
+	Title            string          `json:"title"`
+	Description      string          `json:"description"`
+	HoursPerWeek     int             `json:"hours_per_week"`
+	EstimatedTime    int             `json:"estimated_time"`
+	Kind             string          `json:"kind"`
+	ProgressStrategy string          `json:"progress_strategy"`
+	IsPublic         bool            `json:"is_public"`
+	Phases           []TemplatePhase `json:"phases"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+type TemplatePhase struct {
+	Title         string         `json:"title"`
+	Description   string         `json:"description,omitempty"`
+	EstimatedTime int            `json:"estimated_time"`
+	Tasks         []TemplateTask `json:"tasks,omitempty"`
+}
+
+type TemplateTask struct {
+	Title             string   `json:"title"`
+	Description       string   `json:"description,omitempty"`
+	EstimatedTime     int      `json:"estimated_time"`
+	Priority          string   `json:"priority,omitempty"`
+	ProgressMode      string   `json:"progress_mode,omitempty"`
+	RecurrenceRule    string   `json:"recurrence_rule,omitempty"`
+	OccurrenceMinutes *int     `json:"occurrence_minutes,omitempty"`
+	Checklist         []string `json:"checklist,omitempty"`
+}
+
+// unphasedTitle names the extra phase that collects tasks without a phase.
+const unphasedTitle = "Other tasks"
+
+// NewTemplate captures the structure of a goal. Tasks are expected to carry
+// their checklists; tasks outside any phase end up in a trailing phase.
+func NewTemplate(g *Goal, phases []Phase, tasks []Task) *Template {
+	sorted := make([]Phase, len(phases))
+	copy(sorted, phases)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Order < sorted[j].Order })
+
+	byPhase := make(map[uuid.UUID][]TemplateTask)
+	var unphased []TemplateTask
+	for i := range tasks {
+		tt := templateTask(&tasks[i])
+		if tasks[i].PhaseId == nil {
+			unphased = append(unphased, tt)
+			continue
+		}
+		byPhase[*tasks[i].PhaseId] = append(byPhase[*tasks[i].PhaseId], tt)
+	}
+
+	tpl := &Template{
+		UserID:           g.UserId,
+		Title:            g.Title,
+		Description:      g.Description,
+		HoursPerWeek:     g.HoursPerWeek,
+		EstimatedTime:    g.EstimatedTime,
+		Kind:             g.Kind,
+		ProgressStrategy: g.ProgressStrategy,
+		Phases:           make([]TemplatePhase, 0, len(sorted)+1),
+	}
+	id := g.ID
+	tpl.SourceGoalID = &id
+	for _, p := range sorted {
+		tpl.Phases = append(tpl.Phases, TemplatePhase{
+			Title:         p.Title,
+			Description:   p.Description,
+			EstimatedTime: p.EstimatedTime,
+			Tasks:         byPhase[p.ID],
+		})
+	}
+	if len(unphased) > 0 {
+		tp := TemplatePhase{Title: unphasedTitle, Tasks: unphased}
+		for _, t := range unphased {
+			tp.EstimatedTime += t.EstimatedTime
+		}
+		tpl.Phases = append(tpl.Phases, tp)
+	}
+	return tpl
+}
+
+func templateTask(t *Task) TemplateTask {
+	tt := TemplateTask{
+		Title:             t.Title,
+		Description:       t.Description,
+		EstimatedTime:     t.EstimatedTime,
+		Priority:          PriorityName(t.Priority),
+		ProgressMode:      t.ProgressMode,
+		OccurrenceMinutes: t.OccurrenceMinutes,
+	}
+	if t.RecurrenceRule != nil {
+		tt.RecurrenceRule = *t.RecurrenceRule
+	}
+	for _, it := range t.Checklist {
+		tt.Checklist = append(tt.Checklist, it.Title)
+	}
+	return tt
+}
+
+// VisibleTo reports whether the user may read and instantiate the template.
+func (t *Template) VisibleTo(userID int64) bool {
+	return t.IsPublic || t.UserID == userID
+}
+
+// TaskCount is the number of tasks across all phases.
+func (t *Template) TaskCount() int {
+	n := 0
+	for _, p := range t.Phases {
+		n += len(p.Tasks)
+	}
+	return n
+}
@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type CreateItemRequest struct {
+	Title            string `json:"title" validate:"required"`
+	Description      string `json:"description"`
+	EstimatedMinutes int    `json:"estimated_minutes"`
+	// Priority is low, normal or high; normal when empty.
+	Priority string `json:"priority"`
+}
+
+// UpdateItemRequest changes only the fields that are set. Changing the
+// estimate drops the item's booking in the schedule.
+type UpdateItemRequest struct {
+	Title            *string `json:"title"`
+	Description      *string `json:"description"`
+	EstimatedMinutes *int    `json:"estimated_minutes"`
+	Priority         *string `json:"priority"`
+}
+
+type ItemResponse struct {
+	ID               uuid.UUID `json:"id"`
+	Title            string    `json:"title"`
+	Description      string    `json:"description"`
+	EstimatedMinutes int       `json:"estimated_minutes"`
+	Priority         string    `json:"priority"`
+	// ScheduledDate, StartTime and EndTime are set when the scheduler booked
+	// the item into free time.
+	ScheduledDate *string   `json:"scheduled_date,omitempty"`
+	StartTime     *string   `json:"start_time,omitempty"`
+	EndTime       *string   `json:"end_time,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TriageRequest moves an item into a goal. Without a phase the task goes
+// to the goal's first unfinished phase.
+type TriageRequest struct {
+	GoalID  uuid.UUID  `json:"goal_id" validate:"required"`
+	PhaseID *uuid.UUID `json:"phase_id"`
+}
+
+type TriageResponse struct {
+	TaskID  uuid.UUID `json:"task_id"`
+	GoalID  uuid.UUID `json:"goal_id"`
+	PhaseID uuid.UUID `json:"phase_id"`
+}
@@ -0,0 +1,12 @@
+package inbox
+
+import "errors"
+
+var (
+	ErrItemNotFound     = errors.New("inbox item not found")
+	ErrEmptyTitle       = errors.New("title is required")
+	ErrTitleTooLong     = errors.New("title must be at most 255 characters")
+	ErrInvalidEstimate  = errors.New("estimated_minutes must not be negative")
+	ErrGoalHasNoPhases  = errors.New("goal has no phase to put the task into")
+	ErrInvalidTriageDst = errors.New("goal_id is required")
+)
@@ -0,0 +1,220 @@
+package inbox
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"task-planner/internal/auth"
+	"task-planner/internal/goal"
+	"task-planner/internal/inbox/dto"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func userID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, false
+	}
+	return claims.UserID, true
+}
+
+func itemID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "item_id"))
+	if err != nil {
+		http.Error(w, "Invalid item ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, ErrItemNotFound),
+		errors.Is(err, goal.ErrGoalNotFound),
+		errors.Is(err, goal.ErrPhaseNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrGoalHasNoPhases),
+		errors.Is(err, goal.ErrPhaseOutsideGoal):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrEmptyTitle),
+		errors.Is(err, ErrTitleTooLong),
+		errors.Is(err, ErrInvalidEstimate),
+		errors.Is(err, ErrInvalidTriageDst),
+		errors.Is(err, goal.ErrInvalidPriority):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("[INBOX] %s failed: %v", action, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// @Summary      Входящие
+// @Description  Возвращает быстро записанные задачи без цели, старые сверху
+// @Tags         Inbox
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {array}   dto.ItemResponse
+// @Failure      500  {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/inbox [get]
+func (h *Handler) ListItems(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.ListItems(r.Context(), uid)
+	if err != nil {
+		writeError(w, "list inbox", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Записать задачу во входящие
+// @Tags         Inbox
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body  body      dto.CreateItemRequest  true  "Задача"
+// @Success      201   {object}  dto.ItemResponse
+// @Failure      400   {object}  response.ErrorResponse  "Invalid request"
+// @Failure      500   {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/inbox [post]
+func (h *Handler) CreateItem(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	var req dto.CreateItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.CreateItem(r.Context(), uid, req)
+	if err != nil {
+		writeError(w, "create inbox item", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Изменить задачу во входящих
+// @Description  Меняет только переданные поля. Новая оценка снимает задачу из расписания
+// @Tags         Inbox
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        item_id  path      string                 true  "UUID задачи во входящих"
+// @Param        body     body      dto.UpdateItemRequest  true  "Поля для изменения"
+// @Success      200      {object}  dto.ItemResponse
+// @Failure      400      {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404      {object}  response.ErrorResponse  "Item not found"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/inbox/{item_id} [patch]
+func (h *Handler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := itemID(w, r)
+	if !ok {
+		return
+	}
+	var req dto.UpdateItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.UpdateItem(r.Context(), uid, id, req)
+	if err != nil {
+		writeError(w, "update inbox item", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Удалить задачу из входящих
+// @Tags         Inbox
+// @Security     ApiKeyAuth
+// @Param        item_id  path      string  true  "UUID задачи во входящих"
+// @Success      204      {string}  string  "No Content"
+// @Failure      404      {object}  response.ErrorResponse  "Item not found"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/inbox/{item_id} [delete]
+func (h *Handler) DeleteItem(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := itemID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteItem(r.Context(), uid, id); err != nil {
+		writeError(w, "delete inbox item", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Разобрать задачу из входящих
+// @Description  Переносит задачу в цель (и фазу) и убирает её из входящих. Без фазы задача попадает в первую незавершённую фазу цели
+// @Tags         Inbox
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        item_id  path      string             true  "UUID задачи во входящих"
+// @Param        body     body      dto.TriageRequest  true  "Цель и необязательная фаза"
+// @Success      201      {object}  dto.TriageResponse
+// @Failure      400      {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404      {object}  response.ErrorResponse  "Item, goal or phase not found"
+// @Failure      409      {object}  response.ErrorResponse  "Phase belongs to another goal or goal has no phases"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/inbox/{item_id}/triage [post]
+func (h *Handler) TriageItem(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := itemID(w, r)
+	if !ok {
+		return
+	}
+	var req dto.TriageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.TriageItem(r.Context(), uid, id, req)
+	if err != nil {
+		writeError(w, "triage inbox item", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,31 @@
+package inbox
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Item is a quick-captured task that does not belong to a goal yet.
+type Item struct {
+	ID               uuid.UUID
+	UserID           int64
+	Title            string
+	Description      string
+	EstimatedMinutes int
+	Priority         int
+	// Placement is where the scheduler booked the item, if anywhere.
+	Placement *Placement
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Placement is a booking of an inbox item inside one of the user's time
+// slots. StartTime and EndTime carry Date as their day, in UTC, the same
+// way the schedule package combines dates and slot times.
+type Placement struct {
+	ItemID     uuid.UUID
+	TimeSlotID uuid.UUID
+	Date       time.Time
+	StartTime  time.Time
+	EndTime    time.Time
+}
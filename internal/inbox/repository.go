@@ -0,0 +1,200 @@
+package inbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"task-planner/internal/db"
+	"time"
+)
+
+type Repository interface {
+	CreateItem(ctx context.Context, it *Item) error
+	GetItem(ctx context.Context, id uuid.UUID) (*Item, error)
+	// UpdateItem stores every field of the item, its placement included.
+	UpdateItem(ctx context.Context, it *Item) error
+	DeleteItem(ctx context.Context, id uuid.UUID) error
+	// ListItems returns the user's inbox, oldest first.
+	ListItems(ctx context.Context, userID int64) ([]Item, error)
+	// ListPlacements returns the items booked into any of the slots on date.
+	ListPlacements(ctx context.Context, slotIDs []uuid.UUID, date time.Time) ([]Placement, error)
+}
+
+type repositoryImpl struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repositoryImpl{db: db}
+}
+
+// conn returns the transaction carried by ctx, if any.
+func (r *repositoryImpl) conn(ctx context.Context) db.Executor {
+	return db.Conn(ctx, r.db)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const itemColumns = `id, user_id, title, description, estimated_minutes, priority,
+	time_slot_id, scheduled_date, start_time, end_time, created_at, updated_at`
+
+func scanItem(row rowScanner, it *Item) error {
+	var slotID *uuid.UUID
+	var date sql.NullTime
+	var start, end sql.NullString
+	if err := row.Scan(
+		&it.ID, &it.UserID, &it.Title, &it.Description, &it.EstimatedMinutes, &it.Priority,
+		&slotID, &date, &start, &end, &it.CreatedAt, &it.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	it.Placement = nil
+	if slotID == nil {
+		return nil
+	}
+	p, err := placement(it.ID, *slotID, date.Time, start.String, end.String)
+	if err != nil {
+		return err
+	}
+	it.Placement = p
+	return nil
+}
+
+// placement assembles a placement from its columns; TIME values come back
+// from Postgres as HH:MM:SS text.
+func placement(itemID, slotID uuid.UUID, date time.Time, start, end string) (*Placement, error) {
+	st, err := time.Parse("15:04:05", start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse start_time %q: %w", start, err)
+	}
+	et, err := time.Parse("15:04:05", end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse end_time %q: %w", end, err)
+	}
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	return &Placement{
+		ItemID:     itemID,
+		TimeSlotID: slotID,
+		Date:       day,
+		StartTime:  day.Add(time.Duration(st.Hour())*time.Hour + time.Duration(st.Minute())*time.Minute),
+		EndTime:    day.Add(time.Duration(et.Hour())*time.Hour + time.Duration(et.Minute())*time.Minute),
+	}, nil
+}
+
+// placementColumns splits the item's placement into its nullable columns.
+func placementColumns(p *Placement) (slotID *uuid.UUID, date, start, end *string) {
+	if p == nil {
+		return nil, nil, nil, nil
+	}
+	id := p.TimeSlotID
+	d := p.Date.Format("2006-01-02")
+	st := p.StartTime.Format("15:04:05")
+	et := p.EndTime.Format("15:04:05")
+	return &id, &d, &st, &et
+}
+
+func (r *repositoryImpl) CreateItem(ctx context.Context, it *Item) error {
+	slotID, date, start, end := placementColumns(it.Placement)
+	_, err := r.conn(ctx).ExecContext(ctx, `
+		INSERT INTO inbox_items (
+			id, user_id, title, description, estimated_minutes, priority,
+			time_slot_id, scheduled_date, start_time, end_time, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		it.ID, it.UserID, it.Title, it.Description, it.EstimatedMinutes, it.Priority,
+		slotID, date, start, end, it.CreatedAt, it.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert inbox item: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetItem(ctx context.Context, id uuid.UUID) (*Item, error) {
+	var it Item
+	err := scanItem(r.conn(ctx).QueryRowContext(ctx,
+		`SELECT `+itemColumns+` FROM inbox_items WHERE id = $1`, id), &it)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inbox item: %w", err)
+	}
+	return &it, nil
+}
+
+func (r *repositoryImpl) UpdateItem(ctx context.Context, it *Item) error {
+	slotID, date, start, end := placementColumns(it.Placement)
+	_, err := r.conn(ctx).ExecContext(ctx, `
+		UPDATE inbox_items
+		SET title = $2, description = $3, estimated_minutes = $4, priority = $5,
+		    time_slot_id = $6, scheduled_date = $7, start_time = $8, end_time = $9, updated_at = $10
+		WHERE id = $1`,
+		it.ID, it.Title, it.Description, it.EstimatedMinutes, it.Priority,
+		slotID, date, start, end, it.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update inbox item: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) DeleteItem(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM inbox_items WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete inbox item: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) ListItems(ctx context.Context, userID int64) ([]Item, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx,
+		`SELECT `+itemColumns+` FROM inbox_items WHERE user_id = $1 ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inbox items: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Item
+	for rows.Next() {
+		var it Item
+		if err := scanItem(rows, &it); err != nil {
+			return nil, err
+		}
+		result = append(result, it)
+	}
+	return result, rows.Err()
+}
+
+func (r *repositoryImpl) ListPlacements(ctx context.Context, slotIDs []uuid.UUID, date time.Time) ([]Placement, error) {
+	if len(slotIDs) == 0 {
+		return nil, nil
+	}
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT id, time_slot_id, scheduled_date, start_time, end_time
+		FROM inbox_items
+		WHERE time_slot_id = ANY($1) AND scheduled_date = $2
+		ORDER BY start_time`,
+		pq.Array(slotIDs), date.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inbox placements: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Placement
+	for rows.Next() {
+		var itemID, slotID uuid.UUID
+		var day time.Time
+		var start, end string
+		if err := rows.Scan(&itemID, &slotID, &day, &start, &end); err != nil {
+			return nil, err
+		}
+		p, err := placement(itemID, slotID, day, start, end)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *p)
+	}
+	return result, rows.Err()
+}
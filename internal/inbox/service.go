@@ -0,0 +1,272 @@
+package inbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+	"task-planner/internal/goal"
+	"task-planner/internal/inbox/dto"
+	"time"
+)
+
+// maxTitleLength matches the title column of inbox_items and tasks.
+const maxTitleLength = 255
+
+type Service interface {
+	ListItems(ctx context.Context, userID int64) ([]dto.ItemResponse, error)
+	CreateItem(ctx context.Context, userID int64, req dto.CreateItemRequest) (*dto.ItemResponse, error)
+	UpdateItem(ctx context.Context, userID int64, itemID uuid.UUID, req dto.UpdateItemRequest) (*dto.ItemResponse, error)
+	DeleteItem(ctx context.Context, userID int64, itemID uuid.UUID) error
+	// TriageItem turns the item into a task of one of the user's goals and
+	// removes it from the inbox, in one transaction. A booking the item had
+	// is dropped; the task is planned with the rest of its goal.
+	TriageItem(ctx context.Context, userID int64, itemID uuid.UUID, req dto.TriageRequest) (*dto.TriageResponse, error)
+}
+
+type service struct {
+	repo     Repository
+	goalRepo goal.RepositoryAggregator
+}
+
+func NewService(repo Repository, goalRepo goal.RepositoryAggregator) Service {
+	return &service{repo: repo, goalRepo: goalRepo}
+}
+
+func validTitle(title string) (string, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return "", ErrEmptyTitle
+	}
+	if len([]rune(title)) > maxTitleLength {
+		return "", ErrTitleTooLong
+	}
+	return title, nil
+}
+
+// parsePriority maps a priority name to its stored value; an empty name
+// means normal.
+func parsePriority(name string) (int, error) {
+	if name == "" {
+		return goal.PriorityNormal, nil
+	}
+	p, ok := goal.ParsePriority(name)
+	if !ok {
+		return 0, goal.ErrInvalidPriority
+	}
+	return p, nil
+}
+
+func (s *service) ListItems(ctx context.Context, userID int64) ([]dto.ItemResponse, error) {
+	items, err := s.repo.ListItems(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.ItemResponse, 0, len(items))
+	for i := range items {
+		result = append(result, toItemResponse(&items[i]))
+	}
+	return result, nil
+}
+
+func (s *service) CreateItem(ctx context.Context, userID int64, req dto.CreateItemRequest) (*dto.ItemResponse, error) {
+	title, err := validTitle(req.Title)
+	if err != nil {
+		return nil, err
+	}
+	if req.EstimatedMinutes < 0 {
+		return nil, ErrInvalidEstimate
+	}
+	priority, err := parsePriority(req.Priority)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	it := &Item{
+		ID:               uuid.New(),
+		UserID:           userID,
+		Title:            title,
+		Description:      strings.TrimSpace(req.Description),
+		EstimatedMinutes: req.EstimatedMinutes,
+		Priority:         priority,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := s.repo.CreateItem(ctx, it); err != nil {
+		return nil, err
+	}
+	resp := toItemResponse(it)
+	return &resp, nil
+}
+
+func (s *service) UpdateItem(
+	ctx context.Context, userID int64, itemID uuid.UUID, req dto.UpdateItemRequest,
+) (*dto.ItemResponse, error) {
+	it, err := s.ownedItem(ctx, userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Title != nil {
+		if it.Title, err = validTitle(*req.Title); err != nil {
+			return nil, err
+		}
+	}
+	if req.Description != nil {
+		it.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.EstimatedMinutes != nil {
+		if *req.EstimatedMinutes < 0 {
+			return nil, ErrInvalidEstimate
+		}
+		if *req.EstimatedMinutes != it.EstimatedMinutes {
+			it.EstimatedMinutes = *req.EstimatedMinutes
+			it.Placement = nil
+		}
+	}
+	if req.Priority != nil {
+		if it.Priority, err = parsePriority(*req.Priority); err != nil {
+			return nil, err
+		}
+	}
+	it.UpdatedAt = time.Now()
+	if err := s.repo.UpdateItem(ctx, it); err != nil {
+		return nil, err
+	}
+	resp := toItemResponse(it)
+	return &resp, nil
+}
+
+func (s *service) DeleteItem(ctx context.Context, userID int64, itemID uuid.UUID) error {
+	if _, err := s.ownedItem(ctx, userID, itemID); err != nil {
+		return err
+	}
+	return s.repo.DeleteItem(ctx, itemID)
+}
+
+func (s *service) TriageItem(
+	ctx context.Context, userID int64, itemID uuid.UUID, req dto.TriageRequest,
+) (*dto.TriageResponse, error) {
+	if req.GoalID == uuid.Nil {
+		return nil, ErrInvalidTriageDst
+	}
+
+	var resp *dto.TriageResponse
+	err := s.goalRepo.InTx(ctx, func(ctx context.Context) error {
+		it, err := s.ownedItem(ctx, userID, itemID)
+		if err != nil {
+			return err
+		}
+		g, err := s.goalRepo.GetGoalByID(ctx, req.GoalID)
+		if err != nil {
+			return fmt.Errorf("failed to get goal: %w", err)
+		}
+		if g == nil || g.UserId != userID {
+			return goal.ErrGoalNotFound
+		}
+		phaseID, err := s.targetPhase(ctx, g.ID, req.PhaseID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		t := &goal.Task{
+			ID:          uuid.New(),
+			GoalId:      g.ID,
+			PhaseId:     &phaseID,
+			Title:       it.Title,
+			Description: it.Description,
+			Status:      goal.TaskStatusTodo,
+			Priority:    it.Priority,
+			// Tasks are estimated in whole hours.
+			EstimatedTime: goal.MinutesToHours(it.EstimatedMinutes),
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if err := s.goalRepo.CreateTask(ctx, t); err != nil {
+			return fmt.Errorf("failed to create task: %w", err)
+		}
+		if err := s.repo.DeleteItem(ctx, it.ID); err != nil {
+			return err
+		}
+		details := map[string]interface{}{"title": t.Title, "from": "inbox"}
+		if err := goal.RecordActivity(ctx, s.goalRepo, g.ID, goal.EntityTask, t.ID, goal.ActionCreated, details); err != nil {
+			return err
+		}
+		if err := goal.RecalcGoalProgress(ctx, s.goalRepo, g.ID, &phaseID); err != nil {
+			return err
+		}
+
+		resp = &dto.TriageResponse{TaskID: t.ID, GoalID: g.ID, PhaseID: phaseID}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// targetPhase checks the requested phase against the goal or, when none
+// was requested, picks the goal's first phase that is not completed yet,
+// falling back to its last one.
+func (s *service) targetPhase(ctx context.Context, goalID uuid.UUID, phaseID *uuid.UUID) (uuid.UUID, error) {
+	if phaseID != nil {
+		ph, err := s.goalRepo.GetPhaseByID(ctx, *phaseID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, goal.ErrPhaseNotFound
+		}
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to get phase: %w", err)
+		}
+		if ph.GoalId != goalID {
+			return uuid.Nil, goal.ErrPhaseOutsideGoal
+		}
+		return ph.ID, nil
+	}
+
+	phases, err := s.goalRepo.ListPhasesByGoalID(ctx, goalID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to list phases: %w", err)
+	}
+	if len(phases) == 0 {
+		return uuid.Nil, ErrGoalHasNoPhases
+	}
+	for _, ph := range phases {
+		if ph.Status != "completed" {
+			return ph.ID, nil
+		}
+	}
+	return phases[len(phases)-1].ID, nil
+}
+
+func (s *service) ownedItem(ctx context.Context, userID int64, itemID uuid.UUID) (*Item, error) {
+	it, err := s.repo.GetItem(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if it == nil || it.UserID != userID {
+		return nil, ErrItemNotFound
+	}
+	return it, nil
+}
+
+func toItemResponse(it *Item) dto.ItemResponse {
+	resp := dto.ItemResponse{
+		ID:               it.ID,
+		Title:            it.Title,
+		Description:      it.Description,
+		EstimatedMinutes: it.EstimatedMinutes,
+		Priority:         goal.PriorityName(it.Priority),
+		CreatedAt:        it.CreatedAt,
+		UpdatedAt:        it.UpdatedAt,
+	}
+	if p := it.Placement; p != nil {
+		date := p.Date.Format("2006-01-02")
+		start := p.StartTime.Format("15:04")
+		end := p.EndTime.Format("15:04")
+		resp.ScheduledDate, resp.StartTime, resp.EndTime = &date, &start, &end
+	}
+	return resp
+}
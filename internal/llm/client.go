@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"task-planner/pkg/config"
+)
+
+// Message roles.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+var (
+	ErrEmptyResponse   = errors.New("llm returned no choices")
+	ErrNoFakeReply     = errors.New("fake llm has no reply queued")
+	ErrUnknownProvider = errors.New("unknown llm provider")
+)
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type Request struct {
+	Model       string
+	Temperature float32
+	Messages    []Message
+}
+
+type Response struct {
+	Content string
+	Model   string
+	Usage   Usage
+}
+
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Client sends chat completions to a language model. Implementations must
+// be safe for concurrent use.
+type Client interface {
+	Complete(ctx context.Context, req Request) (*Response, error)
+	// Stream is Complete with the reply passed to onDelta piece by piece as
+	// the model produces it. An error from onDelta stops the stream and is
+	// returned. The response holds the whole reply; when the stream fails
+	// midway it holds what arrived so far, usually without usage.
+	Stream(ctx context.Context, req Request, onDelta func(string) error) (*Response, error)
+}
+
+// New builds the client for the configured provider. Fake is not a
+// provider: it only answers what a test queued.
+func New(cfg config.LLMConfig) (Client, error) {
+	switch cfg.Provider {
+	case "", config.LLMProviderOpenAI:
+		return NewOpenAI(cfg.APIKey, cfg.BaseURL), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, cfg.Provider)
+}
+
+// Ask sends a single user message with the model settings.
+func Ask(ctx context.Context, c Client, model config.LLMModel, prompt string) (*Response, error) {
+	return c.Complete(ctx, Request{
+		Model:       model.Name,
+		Temperature: model.Temperature,
+		Messages:    []Message{{Role: RoleUser, Content: prompt}},
+	})
+}
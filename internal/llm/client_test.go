@@ -0,0 +1,123 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"task-planner/pkg/config"
+	"testing"
+)
+
+func TestOpenAIUsesBaseURLAndModel(t *testing.T) {
+	var got struct {
+		Model       string  `json:"model"`
+		Temperature float32 `json:"temperature"`
+		Messages    []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("path = %s, want /v1/chat/completions", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model":"llama3","choices":[{"message":{"role":"assistant","content":"hi"}}],
+			"usage":{"prompt_tokens":3,"completion_tokens":1}}`))
+	}))
+	defer srv.Close()
+
+	c := NewOpenAI("", srv.URL+"/v1")
+	resp, err := Ask(context.Background(), c, config.LLMModel{Name: "llama3", Temperature: 0.2}, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Model != "llama3" || got.Temperature != 0.2 {
+		t.Errorf("request model=%q temperature=%v", got.Model, got.Temperature)
+	}
+	if len(got.Messages) != 1 || got.Messages[0].Role != RoleUser || got.Messages[0].Content != "hello" {
+		t.Errorf("request messages = %+v", got.Messages)
+	}
+	if resp.Content != "hi" || resp.Usage.PromptTokens != 3 || resp.Usage.CompletionTokens != 1 {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestOpenAINoChoices(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[]}`))
+	}))
+	defer srv.Close()
+
+	_, err := NewOpenAI("", srv.URL).Complete(context.Background(), Request{Model: "m"})
+	if !errors.Is(err, ErrEmptyResponse) {
+		t.Fatalf("err = %v, want ErrEmptyResponse", err)
+	}
+}
+
+func TestFakeRepliesInOrder(t *testing.T) {
+	f := NewFake("one", "two")
+	ctx := context.Background()
+	for _, want := range []string{"one", "two"} {
+		resp, err := Ask(ctx, f, config.LLMModel{Name: "m"}, "prompt "+want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Content != want {
+			t.Errorf("reply = %q, want %q", resp.Content, want)
+		}
+	}
+	if _, err := Ask(ctx, f, config.LLMModel{Name: "m"}, "again"); !errors.Is(err, ErrNoFakeReply) {
+		t.Errorf("err = %v, want ErrNoFakeReply", err)
+	}
+	if reqs := f.Requests(); len(reqs) != 3 || reqs[1].Messages[0].Content != "prompt two" {
+		t.Errorf("requests = %+v", reqs)
+	}
+}
+
+func TestOpenAISendsTemperature(t *testing.T) {
+	tests := []struct {
+		temperature float32
+		want        string
+	}{
+		{temperature: 0, want: "0"},
+		{temperature: 0.7, want: "0.7"},
+	}
+	for _, tt := range tests {
+		var body map[string]json.RawMessage
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+		}))
+
+		model := config.LLMModel{Name: "m", Temperature: tt.temperature}
+		_, err := Ask(context.Background(), NewOpenAI("", srv.URL), model, "hello")
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(body["temperature"]); got != tt.want {
+			t.Errorf("temperature %v sent as %q, want %q", tt.temperature, got, tt.want)
+		}
+		if string(body["model"]) != `"m"` || body["messages"] == nil {
+			t.Errorf("request body lost fields: %v", body)
+		}
+	}
+}
+
+func TestNewUnknownProvider(t *testing.T) {
+	for _, provider := range []string{"nope", "fake"} {
+		if _, err := New(config.LLMConfig{Provider: provider}); !errors.Is(err, ErrUnknownProvider) {
+			t.Errorf("New(%q) err = %v, want ErrUnknownProvider", provider, err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Fake is a deterministic Client for tests. It
+// answers with the queued replies in order and records every request.
+// Streamed replies arrive in chunks of FakeChunkSize bytes.
+type Fake struct {
+	mu       sync.Mutex
+	replies  []string
+	requests []Request
+}
+
+const FakeChunkSize = 16
+
+func NewFake(replies ...string) *Fake {
+	return &Fake{replies: replies}
+}
+
+// Queue appends replies to be returned by later calls.
+func (f *Fake) Queue(replies ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.replies = append(f.replies, replies...)
+}
+
+// Requests returns the requests received so far.
+func (f *Fake) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
+
+func (f *Fake) Complete(ctx context.Context, req Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+	if len(f.replies) == 0 {
+		return nil, ErrNoFakeReply
+	}
+	reply := f.replies[0]
+	f.replies = f.replies[1:]
+	return &Response{
+		Content: reply,
+		Model:   req.Model,
+		Usage: Usage{
+			PromptTokens:     countWords(req.Messages),
+			CompletionTokens: countWords([]Message{{Content: reply}}),
+		},
+	}, nil
+}
+
+// countWords stands in for a tokenizer so usage stays deterministic.
+func countWords(msgs []Message) int {
+	n := 0
+	for _, m := range msgs {
+		n += len(strings.Fields(m.Content))
+	}
+	return n
+}
+
+func (f *Fake) Stream(ctx context.Context, req Request, onDelta func(string) error) (*Response, error) {
+	resp, err := f.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	// Like a real provider, a stream cut short reports no usage.
+	partial := &Response{Model: resp.Model}
+	for rest := resp.Content; rest != ""; {
+		if err := ctx.Err(); err != nil {
+			return partial, err
+		}
+		n := FakeChunkSize
+		if n >= len(rest) {
+			n = len(rest)
+		} else {
+			for n > 1 && !utf8.RuneStart(rest[n]) {
+				n--
+			}
+		}
+		partial.Content += rest[:n]
+		if err := onDelta(rest[:n]); err != nil {
+			return partial, err
+		}
+		rest = rest[n:]
+	}
+	return resp, nil
+}
@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/sashabaranov/go-openai"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// openAIClient talks to any OpenAI-compatible chat completions endpoint,
+// such as the OpenAI API itself or a local Ollama at
+// http://localhost:11434/v1.
+type openAIClient struct {
+	client *openai.Client
+}
+
+// NewOpenAI returns a client for the endpoint at baseURL, or for the
+// OpenAI API when baseURL is empty.
+func NewOpenAI(apiKey, baseURL string) Client {
+	cfg := openai.DefaultConfig(apiKey)
+	if baseURL != "" {
+		cfg.BaseURL = baseURL
+	}
+	cfg.HTTPClient = zeroTemperature{next: cfg.HTTPClient}
+	return &openAIClient{client: openai.NewClientWithConfig(cfg)}
+}
+
+// zeroTemperature puts back the temperature the library leaves out of a
+// chat request when it is zero, as the provider would then fall back to its
+// own default instead.
+type zeroTemperature struct {
+	next openai.HTTPDoer
+}
+
+func (d zeroTemperature) Do(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return d.next.Do(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err == nil {
+		if _, ok := fields["temperature"]; !ok {
+			fields["temperature"] = json.RawMessage("0")
+			if b, err := json.Marshal(fields); err == nil {
+				body = b
+			}
+		}
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	return d.next.Do(req)
+}
+
+func chatRequest(req Request) openai.ChatCompletionRequest {
+	msgs := make([]openai.ChatCompletionMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		msgs = append(msgs, openai.ChatCompletionMessage{Role: m.Role, Content: m.Content})
+	}
+	// A zero temperature is left out here and put back by zeroTemperature.
+	return openai.ChatCompletionRequest{
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		Messages:    msgs,
+	}
+}
+
+func (c *openAIClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	resp, err := c.client.CreateChatCompletion(ctx, chatRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	return &Response{
+		Content: resp.Choices[0].Message.Content,
+		Model:   resp.Model,
+		Usage: Usage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+		},
+	}, nil
+}
+
+func (c *openAIClient) Stream(ctx context.Context, req Request, onDelta func(string) error) (*Response, error) {
+	creq := chatRequest(req)
+	creq.Stream = true
+	creq.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	stream, err := c.client.CreateChatCompletionStream(ctx, creq)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	out := &Response{Model: req.Model}
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			out.Content = content.String()
+			return out, err
+		}
+		if chunk.Model != "" {
+			out.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			out.Usage = Usage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+			}
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		delta := chunk.Choices[0].Delta.Content
+		content.WriteString(delta)
+		if err := onDelta(delta); err != nil {
+			out.Content = content.String()
+			return out, err
+		}
+	}
+	out.Content = content.String()
+	if content.Len() == 0 {
+		return out, ErrEmptyResponse
+	}
+	return out, nil
+}
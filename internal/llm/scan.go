@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ObjectScanner finds JSON objects in streamed text as soon as they close,
+// so parts of a long reply can be used before the rest arrives. Text
+// outside the top-level value, such as a code fence, is skipped.
+type ObjectScanner struct {
+	// OnObject gets every completed object with its path, e.g.
+	// "goal.phases.0.tasks.2", and its raw text.
+	OnObject func(path string, raw string) error
+
+	buf      strings.Builder
+	stack    []scanFrame
+	inString bool
+	escaped  bool
+	keyStart int
+	done     bool
+}
+
+type scanFrame struct {
+	array   bool
+	start   int
+	index   int
+	key     string
+	wantKey bool
+}
+
+// Write feeds the next piece of text to the scanner.
+func (s *ObjectScanner) Write(text string) error {
+	offset := s.buf.Len()
+	s.buf.WriteString(text)
+	for i := 0; i < len(text); i++ {
+		if err := s.step(offset+i, text[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ObjectScanner) step(pos int, ch byte) error {
+	if s.done {
+		return nil
+	}
+	if s.inString {
+		switch {
+		case s.escaped:
+			s.escaped = false
+		case ch == '\\':
+			s.escaped = true
+		case ch == '"':
+			s.inString = false
+			if top := s.top(); top != nil && !top.array && top.wantKey {
+				top.key = s.buf.String()[s.keyStart:pos]
+			}
+		}
+		return nil
+	}
+	top := s.top()
+	if top == nil && ch != '{' && ch != '[' {
+		return nil
+	}
+	switch ch {
+	case '"':
+		s.inString = true
+		s.keyStart = pos + 1
+	case '{', '[':
+		s.stack = append(s.stack, scanFrame{array: ch == '[', start: pos, wantKey: ch == '{'})
+	case ':':
+		if top != nil && !top.array {
+			top.wantKey = false
+		}
+	case ',':
+		if top.array {
+			top.index++
+		} else {
+			top.wantKey = true
+		}
+	case '}', ']':
+		if len(s.stack) == 0 {
+			return nil
+		}
+		closed := s.stack[len(s.stack)-1]
+		s.stack = s.stack[:len(s.stack)-1]
+		if len(s.stack) == 0 {
+			s.done = true
+		}
+		if ch == '}' && !closed.array && s.OnObject != nil {
+			return s.OnObject(s.path(), s.buf.String()[closed.start:pos+1])
+		}
+	}
+	return nil
+}
+
+func (s *ObjectScanner) top() *scanFrame {
+	if len(s.stack) == 0 {
+		return nil
+	}
+	return &s.stack[len(s.stack)-1]
+}
+
+// path names the position of the value that starts in the innermost open
+// container.
+func (s *ObjectScanner) path() string {
+	parts := make([]string, 0, len(s.stack))
+	for _, f := range s.stack {
+		if f.array {
+			parts = append(parts, strconv.Itoa(f.index))
+		} else {
+			parts = append(parts, f.key)
+		}
+	}
+	return strings.Join(parts, ".")
+}
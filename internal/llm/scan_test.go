@@ -0,0 +1,37 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestObjectScannerAcrossChunks(t *testing.T) {
+	text := "```json\n" + `{"goal": {"title": "a {b}", "phases": [
+		{"title": "p1", "tasks": [{"title": "t\"1"}, {"title": "t2"}]},
+		{"title": "p2", "tasks": []}
+	]}}` + "\n```"
+
+	var got []string
+	s := &ObjectScanner{OnObject: func(path, raw string) error {
+		got = append(got, path)
+		return nil
+	}}
+	for rest := text; rest != ""; {
+		n := 5
+		if n > len(rest) {
+			n = len(rest)
+		}
+		if err := s.Write(rest[:n]); err != nil {
+			t.Fatal(err)
+		}
+		rest = rest[n:]
+	}
+
+	want := []string{
+		"goal.phases.0.tasks.0", "goal.phases.0.tasks.1", "goal.phases.0",
+		"goal.phases.1", "goal", "",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("paths = %q, want %q", got, want)
+	}
+}
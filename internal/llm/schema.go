@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema types.
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+)
+
+// Schema is the subset of JSON Schema that model output is checked
+// against. Properties not listed are allowed and ignored.
+type Schema struct {
+	Type       string             `json:"type"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	MinItems   int                `json:"minItems,omitempty"`
+	MinLength  int                `json:"minLength,omitempty"`
+	Minimum    *float64           `json:"minimum,omitempty"`
+}
+
+// Min returns a pointer to v, for Schema.Minimum.
+func Min(v float64) *float64 {
+	return &v
+}
+
+// String renders the schema as JSON, for prompts.
+func (s *Schema) String() string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// Validate checks a value decoded with json.Decoder.UseNumber against the
+// schema and returns every violation found, each prefixed with its path.
+func (s *Schema) Validate(v interface{}) []string {
+	var errs []string
+	s.validate("$", v, &errs)
+	return errs
+}
+
+func (s *Schema) validate(path string, v interface{}, errs *[]string) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+	}
+	if v == nil {
+		fail("must be %s, got null", s.Type)
+		return
+	}
+	switch s.Type {
+	case TypeObject:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				fail("missing required field %q", name)
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if pv, ok := obj[name]; ok {
+				s.Properties[name].validate(path+"."+name, pv, errs)
+			}
+		}
+	case TypeArray:
+		arr, ok := v.([]interface{})
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		if len(arr) < s.MinItems {
+			fail("must have at least %d items", s.MinItems)
+		}
+		if s.Items != nil {
+			for i, it := range arr {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), it, errs)
+			}
+		}
+	case TypeString:
+		str, ok := v.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		if utf8.RuneCountInString(strings.TrimSpace(str)) < s.MinLength {
+			fail("must be at least %d characters", s.MinLength)
+		}
+	case TypeInteger, TypeNumber:
+		n, ok := v.(json.Number)
+		if !ok {
+			fail("must be a %s", s.Type)
+			return
+		}
+		if s.Type == TypeInteger {
+			if _, err := n.Int64(); err != nil {
+				fail("must be an integer")
+				return
+			}
+		}
+		f, err := n.Float64()
+		if err != nil {
+			fail("must be a number")
+			return
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("must be at least %v", *s.Minimum)
+		}
+	case TypeBoolean:
+		if _, ok := v.(bool); !ok {
+			fail("must be a boolean")
+		}
+	}
+}
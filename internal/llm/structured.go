@@ -0,0 +1,227 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"task-planner/pkg/config"
+)
+
+// DefaultAttempts is used when a JSONSpec does not set Attempts.
+const DefaultAttempts = 3
+
+// ErrInvalidOutput is matched by the OutputError returned once every
+// attempt produced unusable JSON.
+var ErrInvalidOutput = errors.New("llm output is invalid")
+
+// OutputError reports model output that still failed after all attempts.
+type OutputError struct {
+	Attempts int
+	// Problems are the violations found in the last reply.
+	Problems []string
+	// Raw is the last reply as the model sent it.
+	Raw string
+}
+
+func (e *OutputError) Error() string {
+	return fmt.Sprintf("llm output is invalid after %d attempts: %s", e.Attempts, strings.Join(e.Problems, "; "))
+}
+
+func (e *OutputError) Unwrap() error {
+	return ErrInvalidOutput
+}
+
+// JSONSpec describes the JSON a prompt must produce.
+type JSONSpec struct {
+	Schema *Schema
+	// Check validates invariants the schema cannot express. It runs after
+	// the reply was decoded into out; returned problems are fed back to
+	// the model like schema violations.
+	Check    func() []string
+	Attempts int
+}
+
+// AskJSON sends prompt and decodes the reply into out. Code fences,
+// comments and trailing commas are stripped first. A reply that does not
+// parse, match the schema or pass Check is sent back with the problems and
+// a request to correct them, up to spec.Attempts times in total. The usage
+// of all attempts is added up in the returned response.
+func AskJSON(ctx context.Context, c Client, model config.LLMModel, prompt string, out interface{}, spec JSONSpec) (*Response, error) {
+	return AskJSONMessages(ctx, c, model, []Message{{Role: RoleUser, Content: prompt}}, out, spec)
+}
+
+// AskJSONMessages is AskJSON continuing a conversation: msgs are sent as
+// they are and must end with the user's request.
+func AskJSONMessages(ctx context.Context, c Client, model config.LLMModel, msgs []Message, out interface{}, spec JSONSpec) (*Response, error) {
+	return askJSON(model, msgs, out, spec, func(_ int, req Request) (*Response, error) {
+		return c.Complete(ctx, req)
+	})
+}
+
+// StreamJSON is AskJSON with every reply streamed to onDelta together with
+// its attempt number, starting at 1. A new attempt number means the earlier
+// reply was rejected and the text starts over.
+func StreamJSON(
+	ctx context.Context, c Client, model config.LLMModel, prompt string, out interface{}, spec JSONSpec,
+	onDelta func(attempt int, delta string) error,
+) (*Response, error) {
+	return askJSON(model, []Message{{Role: RoleUser, Content: prompt}}, out, spec, func(attempt int, req Request) (*Response, error) {
+		return c.Stream(ctx, req, func(delta string) error {
+			return onDelta(attempt, delta)
+		})
+	})
+}
+
+func askJSON(
+	model config.LLMModel, msgs []Message, out interface{}, spec JSONSpec,
+	complete func(attempt int, req Request) (*Response, error),
+) (*Response, error) {
+	attempts := spec.Attempts
+	if attempts <= 0 {
+		attempts = DefaultAttempts
+	}
+	req := Request{
+		Model:       model.Name,
+		Temperature: model.Temperature,
+		Messages:    append([]Message(nil), msgs...),
+	}
+	total := &Response{}
+	var problems []string
+	for i := 1; i <= attempts; i++ {
+		resp, err := complete(i, req)
+		if err != nil {
+			return nil, err
+		}
+		total.Content = resp.Content
+		total.Model = resp.Model
+		total.Usage.PromptTokens += resp.Usage.PromptTokens
+		total.Usage.CompletionTokens += resp.Usage.CompletionTokens
+
+		problems = decodeJSON(resp.Content, out, spec)
+		if len(problems) == 0 {
+			return total, nil
+		}
+		req.Messages = append(req.Messages,
+			Message{Role: RoleAssistant, Content: resp.Content},
+			Message{Role: RoleUser, Content: feedbackPrompt(problems, spec.Schema)},
+		)
+	}
+	return nil, &OutputError{Attempts: attempts, Problems: problems, Raw: total.Content}
+}
+
+func decodeJSON(raw string, out interface{}, spec JSONSpec) []string {
+	clean := CleanJSON(raw)
+	dec := json.NewDecoder(bytes.NewReader(clean))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return []string{"not valid JSON: " + err.Error()}
+	}
+	if spec.Schema != nil {
+		if problems := spec.Schema.Validate(v); len(problems) > 0 {
+			return problems
+		}
+	}
+	// out may hold a rejected earlier reply; json.Unmarshal would keep its
+	// fields wherever this reply leaves them out.
+	if v := reflect.ValueOf(out); v.Kind() == reflect.Pointer && !v.IsNil() {
+		v.Elem().Set(reflect.Zero(v.Elem().Type()))
+	}
+	if err := json.Unmarshal(clean, out); err != nil {
+		return []string{err.Error()}
+	}
+	if spec.Check != nil {
+		return spec.Check()
+	}
+	return nil
+}
+
+func feedbackPrompt(problems []string, schema *Schema) string {
+	var b strings.Builder
+	b.WriteString("Your previous answer could not be used:\n")
+	for _, p := range problems {
+		b.WriteString("- " + p + "\n")
+	}
+	if schema != nil {
+		b.WriteString("It must match this JSON Schema: " + schema.String() + "\n")
+	}
+	b.WriteString("Reply again with the corrected JSON only, without code fences or comments.")
+	return b.String()
+}
+
+// CleanJSON strips what models commonly wrap around or put into JSON: code
+// fences, prose before and after the value, comments and trailing commas.
+func CleanJSON(raw string) []byte {
+	s := strings.TrimSpace(raw)
+	if strings.HasPrefix(s, "```") {
+		if nl := strings.IndexByte(s, '\n'); nl >= 0 {
+			s = s[nl+1:]
+		}
+		if end := strings.LastIndex(s, "```"); end >= 0 {
+			s = s[:end]
+		}
+	}
+	if start := strings.IndexAny(s, "{["); start > 0 {
+		s = s[start:]
+	}
+	if end := strings.LastIndexAny(s, "}]"); end >= 0 {
+		s = s[:end+1]
+	}
+	return stripCommentsAndCommas(s)
+}
+
+// stripCommentsAndCommas drops // and /* */ comments and commas directly
+// before a closing bracket, leaving string literals alone.
+func stripCommentsAndCommas(s string) []byte {
+	out := make([]byte, 0, len(s))
+	inString := false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if inString {
+			out = append(out, ch)
+			if ch == '\\' && i+1 < len(s) {
+				i++
+				out = append(out, s[i])
+			} else if ch == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case ch == '"':
+			inString = true
+		case ch == '/' && i+1 < len(s) && s[i+1] == '/':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+			continue
+		case ch == '/' && i+1 < len(s) && s[i+1] == '*':
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				i = len(s)
+			} else {
+				i += end + 3
+			}
+			continue
+		case ch == '}' || ch == ']':
+			out = trimTrailingComma(out)
+		}
+		out = append(out, ch)
+	}
+	return out
+}
+
+func trimTrailingComma(b []byte) []byte {
+	j := len(b) - 1
+	for j >= 0 && (b[j] == ' ' || b[j] == '\n' || b[j] == '\r' || b[j] == '\t') {
+		j--
+	}
+	if j >= 0 && b[j] == ',' {
+		return append(b[:j], b[j+1:]...)
+	}
+	return b
+}
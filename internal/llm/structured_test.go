@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"task-planner/pkg/config"
+	"testing"
+)
+
+func TestCleanJSON(t *testing.T) {
+	cases := map[string]string{
+		"```json\n{\"a\": 1}\n```":                    `{"a": 1}`,
+		"Here you go: [1, 2, 3,] hope it helps":       `[1, 2, 3]`,
+		"{\"a\": \"http://x\", // note\n\"b\": 2,\n}": "{\"a\": \"http://x\", \"b\": 2\n}",
+		`{"a": /* why */ "x\"y"}`:                     `{"a":  "x\"y"}`,
+	}
+	for in, want := range cases {
+		if got := string(CleanJSON(in)); got != want {
+			t.Errorf("CleanJSON(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+var pointSchema = &Schema{
+	Type:     TypeObject,
+	Required: []string{"x", "name"},
+	Properties: map[string]*Schema{
+		"x":    {Type: TypeInteger, Minimum: Min(1)},
+		"name": {Type: TypeString, MinLength: 1},
+	},
+}
+
+func TestSchemaValidate(t *testing.T) {
+	f := NewFake(`{"x": 1.5, "name": ""}`)
+	var out struct{}
+	_, err := AskJSON(context.Background(), f, config.LLMModel{}, "p", &out, JSONSpec{Schema: pointSchema, Attempts: 1})
+	var oe *OutputError
+	if !errors.As(err, &oe) {
+		t.Fatalf("err = %v, want *OutputError", err)
+	}
+	want := []string{"$.name: must be at least 1 characters", "$.x: must be an integer"}
+	if strings.Join(oe.Problems, "|") != strings.Join(want, "|") {
+		t.Errorf("problems = %q, want %q", oe.Problems, want)
+	}
+}
+
+func TestSchemaMinLengthCountsCharacters(t *testing.T) {
+	s := &Schema{Type: TypeString, MinLength: 4}
+	tests := []struct {
+		value string
+		ok    bool
+	}{
+		{value: "план", ok: true},
+		{value: "пла", ok: false},
+		{value: "ab", ok: false},
+		{value: "  abcd  ", ok: true},
+	}
+	for _, tt := range tests {
+		if errs := s.Validate(tt.value); (len(errs) == 0) != tt.ok {
+			t.Errorf("Validate(%q) = %v, want ok=%v", tt.value, errs, tt.ok)
+		}
+	}
+}
+
+func TestAskJSONRetriesWithFeedback(t *testing.T) {
+	f := NewFake(`{"x": 0}`, "```\n{\"x\": 2, \"name\": \"a\"}\n```")
+	var out struct {
+		X    int    `json:"x"`
+		Name string `json:"name"`
+	}
+	resp, err := AskJSON(context.Background(), f, config.LLMModel{Name: "m"}, "give a point", &out, JSONSpec{Schema: pointSchema})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.X != 2 || out.Name != "a" {
+		t.Errorf("out = %+v", out)
+	}
+	reqs := f.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("requests = %d, want 2", len(reqs))
+	}
+	retry := reqs[1].Messages
+	if len(retry) != 3 || retry[1].Role != RoleAssistant || !strings.Contains(retry[2].Content, `missing required field "name"`) {
+		t.Errorf("retry messages = %+v", retry)
+	}
+	if resp.Usage.PromptTokens == 0 || resp.Usage.CompletionTokens == 0 {
+		t.Errorf("usage = %+v, want both attempts counted", resp.Usage)
+	}
+}
+
+func TestAskJSONRetryDropsRejectedReply(t *testing.T) {
+	f := NewFake(
+		`{"note": "first", "tasks": [{"title": "a", "description": "from the rejected reply"}]}`,
+		`{"tasks": [{"title": "a"}, {"title": "b"}]}`,
+	)
+	var out struct {
+		Note  string `json:"note"`
+		Tasks []struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+		} `json:"tasks"`
+	}
+	spec := JSONSpec{Check: func() []string {
+		if len(out.Tasks) < 2 {
+			return []string{"$.tasks: want at least 2 tasks"}
+		}
+		return nil
+	}}
+	if _, err := AskJSON(context.Background(), f, config.LLMModel{Name: "m"}, "plan", &out, spec); err != nil {
+		t.Fatal(err)
+	}
+	if out.Note != "" || len(out.Tasks) != 2 || out.Tasks[0].Description != "" {
+		t.Errorf("out = %+v, want only the accepted reply", out)
+	}
+}
+
+func TestAskJSONGivesUpAfterAttempts(t *testing.T) {
+	f := NewFake(`{"x": 2, "name": "a"}`, `{"x": 3, "name": "b"}`)
+	var out struct {
+		X int `json:"x"`
+	}
+	check := func() []string { return []string{"x must be even and above 2"} }
+	_, err := AskJSON(context.Background(), f, config.LLMModel{}, "p", &out, JSONSpec{Schema: pointSchema, Check: check, Attempts: 2})
+	if !errors.Is(err, ErrInvalidOutput) {
+		t.Fatalf("err = %v, want ErrInvalidOutput", err)
+	}
+	var oe *OutputError
+	if !errors.As(err, &oe) || oe.Attempts != 2 || oe.Raw != `{"x": 3, "name": "b"}` {
+		t.Errorf("err = %+v", oe)
+	}
+}
@@ -11,4 +11,6 @@ type Motivation struct {
 	Date      time.Time
 	Text      string
 	CreatedAt time.Time
+	// PromptVersion names the prompt the message was generated from.
+	PromptVersion string
 }
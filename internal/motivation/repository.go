@@ -10,6 +10,9 @@ import (
 type Repository interface {
 	Create(ctx context.Context, m *Motivation) error
 	GetByUserAndDate(ctx context.Context, userID int64, date time.Time) (*Motivation, error)
+	// GetUserLocale returns the locale the user's prompts are rendered in,
+	// or "" for an unknown user.
+	GetUserLocale(ctx context.Context, userID int64) (string, error)
 }
 
 type repositoryImpl struct {
@@ -22,12 +25,12 @@ func NewRepository(db *sql.DB) Repository {
 
 func (r *repositoryImpl) Create(ctx context.Context, m *Motivation) error {
 	query := `
-INSERT INTO motivation (id, user_id, date, text, created_at)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO motivation (id, user_id, date, text, created_at, prompt_version)
+VALUES ($1, $2, $3, $4, $5, $6)
 ON CONFLICT (user_id, date) DO NOTHING
 `
 	_, err := r.db.ExecContext(ctx, query,
-		m.ID, m.UserID, m.Date.Format("2006-01-02"), m.Text, m.CreatedAt,
+		m.ID, m.UserID, m.Date.Format("2006-01-02"), m.Text, m.CreatedAt, m.PromptVersion,
 	)
 	if err != nil {
 		return fmt.Errorf("insert motivation: %w", err)
@@ -37,13 +40,13 @@ ON CONFLICT (user_id, date) DO NOTHING
 
 func (r *repositoryImpl) GetByUserAndDate(ctx context.Context, userID int64, date time.Time) (*Motivation, error) {
 	query := `
-SELECT id, user_id, date, text, created_at
+SELECT id, user_id, date, text, created_at, prompt_version
 FROM motivation
 WHERE user_id = $1 AND date = $2
 `
 	var m Motivation
 	row := r.db.QueryRowContext(ctx, query, userID, date.Format("2006-01-02"))
-	if err := row.Scan(&m.ID, &m.UserID, &m.Date, &m.Text, &m.CreatedAt); err != nil {
+	if err := row.Scan(&m.ID, &m.UserID, &m.Date, &m.Text, &m.CreatedAt, &m.PromptVersion); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -51,3 +54,15 @@ WHERE user_id = $1 AND date = $2
 	}
 	return &m, nil
 }
+
+func (r *repositoryImpl) GetUserLocale(ctx context.Context, userID int64) (string, error) {
+	var locale string
+	err := r.db.QueryRowContext(ctx, `SELECT locale FROM users WHERE id = $1`, userID).Scan(&locale)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("query user locale: %w", err)
+	}
+	return locale, nil
+}
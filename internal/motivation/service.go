@@ -2,11 +2,15 @@ package motivation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
-	"github.com/sashabaranov/go-openai"
-	"strings"
+	"log"
 	"task-planner/internal/goal"
+	"task-planner/internal/llm"
+	"task-planner/internal/prompt"
+	"task-planner/internal/usage"
+	"task-planner/pkg/config"
 	"time"
 )
 
@@ -18,11 +22,12 @@ type Service interface {
 type service struct {
 	repo           Repository
 	taskRepository goal.TaskRepository
-	aiKey          string
+	ai             llm.Client
+	model          config.LLMModel
 }
 
-func NewService(repo Repository, taskRepo goal.TaskRepository, openAIKey string) Service {
-	return &service{repo: repo, taskRepository: taskRepo, aiKey: openAIKey}
+func NewService(repo Repository, taskRepo goal.TaskRepository, ai llm.Client, model config.LLMModel) Service {
+	return &service{repo: repo, taskRepository: taskRepo, ai: ai, model: model}
 }
 
 func (s *service) GenerateDailyMotivations(ctx context.Context) error {
@@ -47,23 +52,25 @@ func (s *service) GenerateDailyMotivations(ctx context.Context) error {
 			return err
 		}
 
-		prompt := buildMotivationPrompt(tasks)
-
-		client := openai.NewClient(s.aiKey)
-		resp, err := client.CreateChatCompletion(
-			context.Background(),
-			openai.ChatCompletionRequest{
-				Model: openai.GPT4,
-				Messages: []openai.ChatCompletionMessage{
-					{Role: openai.ChatMessageRoleUser, Content: prompt},
-				},
-				Temperature: 0.8,
-			},
-		)
+		locale, err := s.repo.GetUserLocale(ctx, userID)
+		if err != nil {
+			return err
+		}
+		p, err := buildMotivationPrompt(locale, tasks)
+		if err != nil {
+			return err
+		}
+
+		resp, err := llm.Ask(usage.WithCaller(ctx, userID, usage.FeatureMotivation), s.ai, s.model, p.Text)
+		if errors.Is(err, usage.ErrQuotaExceeded) {
+			// The user gets the default message; the others still get theirs.
+			log.Printf("motivation for user %d: %v", userID, err)
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("motivation LLM: %w", err)
 		}
-		text := resp.Choices[0].Message.Content
+		text := resp.Content
 
 		m := &Motivation{
 			ID:        uuid.New(),
@@ -71,6 +78,8 @@ func (s *service) GenerateDailyMotivations(ctx context.Context) error {
 			Date:      today,
 			Text:      text,
 			CreatedAt: time.Now(),
+
+			PromptVersion: p.Version,
 		}
 		if err := s.repo.Create(ctx, m); err != nil {
 			return err
@@ -99,24 +108,28 @@ func (s *service) GetTodayMotivation(ctx context.Context, userID int64) (string,
 	if m != nil {
 		return m.Text, nil
 	}
-	return "Желаем отличного дня!", nil
+	locale, err := s.repo.GetUserLocale(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return fallbackMessages[prompt.Normalize(locale)], nil
+}
+
+// fallbackMessages are shown to users without a generated message, per
+// prompt locale.
+var fallbackMessages = map[string]string{
+	prompt.LocaleEN: "Have a great day!",
+	prompt.LocaleRU: "Желаем отличного дня!",
 }
 
 func dateOnly(t time.Time) time.Time {
 	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
 }
 
-func buildMotivationPrompt(tasks []goal.Task) string {
-	if len(tasks) == 0 {
-		return "Подбодри меня и скажи что-нибудь вдохновляющее для отдыха или похвалы."
-	}
-	var lines []string
+func buildMotivationPrompt(locale string, tasks []goal.Task) (prompt.Rendered, error) {
+	var data prompt.MotivationData
 	for _, t := range tasks {
-		lines = append(lines, fmt.Sprintf("- %s", t.Title))
+		data.Tasks = append(data.Tasks, t.Title)
 	}
-	return fmt.Sprintf(`
-У меня сегодня задачи:
-%s
-Напиши короткое вдохновляющее сообщение, чтобы я замотивировался выполнить именно эти дела.
-`, strings.Join(lines, "\n"))
+	return prompt.Render(prompt.Motivation, locale, data)
 }
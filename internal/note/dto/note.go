@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type CreateNoteRequest struct {
+	TargetType string    `json:"target_type" validate:"required,oneof=goal phase task"`
+	TargetID   uuid.UUID `json:"target_id" validate:"required"`
+	Body       string    `json:"body" validate:"required"`
+}
+
+type UpdateNoteRequest struct {
+	Body string `json:"body" validate:"required"`
+}
+
+type NoteResponse struct {
+	ID         uuid.UUID `json:"id"`
+	TargetType string    `json:"target_type"`
+	TargetID   uuid.UUID `json:"target_id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type AttachmentResponse struct {
+	ID          uuid.UUID `json:"id"`
+	TargetType  string    `json:"target_type"`
+	TargetID    uuid.UUID `json:"target_id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	DownloadURL string    `json:"download_url"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package note
+
+import "errors"
+
+var (
+	ErrInvalidTarget      = errors.New("target_type must be goal, phase or task")
+	ErrTargetNotFound     = errors.New("target not found")
+	ErrNoteNotFound       = errors.New("note not found")
+	ErrEmptyNote          = errors.New("note body is required")
+	ErrNoteTooLong        = errors.New("note body is too long")
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrFileTooLarge       = errors.New("file exceeds the size limit")
+	ErrUnsupportedType    = errors.New("file type is not allowed")
+	ErrInvalidFileName    = errors.New("file name is required")
+)
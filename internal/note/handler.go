@@ -0,0 +1,378 @@
+package note
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"task-planner/internal/auth"
+	"task-planner/internal/note/dto"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service Service
+	// maxUpload caps the request body of an upload.
+	maxUpload int64
+}
+
+func NewHandler(service Service, maxUploadBytes int64) *Handler {
+	return &Handler{service: service, maxUpload: maxUploadBytes}
+}
+
+// targetFromQuery reads ?target_type=&target_id=.
+func targetFromQuery(r *http.Request) (Target, error) {
+	id, err := uuid.Parse(r.URL.Query().Get("target_id"))
+	if err != nil {
+		return Target{}, ErrInvalidTarget
+	}
+	t := Target{Type: r.URL.Query().Get("target_type"), ID: id}
+	if !t.valid() {
+		return Target{}, ErrInvalidTarget
+	}
+	return t, nil
+}
+
+func userID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, false
+	}
+	return claims.UserID, true
+}
+
+func writeError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, ErrTargetNotFound),
+		errors.Is(err, ErrNoteNotFound),
+		errors.Is(err, ErrAttachmentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrFileTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, ErrUnsupportedType):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, ErrInvalidTarget),
+		errors.Is(err, ErrEmptyNote),
+		errors.Is(err, ErrNoteTooLong),
+		errors.Is(err, ErrInvalidFileName):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("[NOTE] %s failed: %v", action, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// @Summary      Заметки
+// @Description  Возвращает заметки цели, фазы или задачи, новые сверху
+// @Tags         Note
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        target_type  query     string  true  "goal, phase или task"
+// @Param        target_id    query     string  true  "UUID цели, фазы или задачи"
+// @Success      200          {array}   dto.NoteResponse
+// @Failure      400          {object}  response.ErrorResponse  "Invalid target"
+// @Failure      404          {object}  response.ErrorResponse  "Target not found"
+// @Failure      500          {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/notes [get]
+func (h *Handler) ListNotes(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	t, err := targetFromQuery(r)
+	if err != nil {
+		writeError(w, "list notes", err)
+		return
+	}
+
+	resp, err := h.service.ListNotes(r.Context(), uid, t)
+	if err != nil {
+		writeError(w, "list notes", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Создать заметку
+// @Description  Добавляет markdown-заметку к цели, фазе или задаче
+// @Tags         Note
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body  body      dto.CreateNoteRequest  true  "Заметка"
+// @Success      201   {object}  dto.NoteResponse
+// @Failure      400   {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404   {object}  response.ErrorResponse  "Target not found"
+// @Failure      500   {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/notes [post]
+func (h *Handler) CreateNote(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	var req dto.CreateNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.CreateNote(r.Context(), uid, req)
+	if err != nil {
+		writeError(w, "create note", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Изменить заметку
+// @Tags         Note
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        note_id  path      string                 true  "UUID заметки"
+// @Param        body     body      dto.UpdateNoteRequest  true  "Новый текст"
+// @Success      200      {object}  dto.NoteResponse
+// @Failure      400      {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404      {object}  response.ErrorResponse  "Note not found"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/notes/{note_id} [patch]
+func (h *Handler) UpdateNote(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	noteID, err := uuid.Parse(chi.URLParam(r, "note_id"))
+	if err != nil {
+		http.Error(w, "Invalid note ID", http.StatusBadRequest)
+		return
+	}
+	var req dto.UpdateNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.UpdateNote(r.Context(), uid, noteID, req)
+	if err != nil {
+		writeError(w, "update note", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Удалить заметку
+// @Tags         Note
+// @Security     ApiKeyAuth
+// @Param        note_id  path      string  true  "UUID заметки"
+// @Success      204      {string}  string  "No Content"
+// @Failure      404      {object}  response.ErrorResponse  "Note not found"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/notes/{note_id} [delete]
+func (h *Handler) DeleteNote(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	noteID, err := uuid.Parse(chi.URLParam(r, "note_id"))
+	if err != nil {
+		http.Error(w, "Invalid note ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteNote(r.Context(), uid, noteID); err != nil {
+		writeError(w, "delete note", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Вложения
+// @Description  Возвращает файлы, прикреплённые к цели, фазе или задаче
+// @Tags         Attachment
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        target_type  query     string  true  "goal, phase или task"
+// @Param        target_id    query     string  true  "UUID цели, фазы или задачи"
+// @Success      200          {array}   dto.AttachmentResponse
+// @Failure      400          {object}  response.ErrorResponse  "Invalid target"
+// @Failure      404          {object}  response.ErrorResponse  "Target not found"
+// @Failure      500          {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/attachments [get]
+func (h *Handler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	t, err := targetFromQuery(r)
+	if err != nil {
+		writeError(w, "list attachments", err)
+		return
+	}
+
+	resp, err := h.service.ListAttachments(r.Context(), uid, t)
+	if err != nil {
+		writeError(w, "list attachments", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Загрузить вложение
+// @Description  Прикрепляет файл к цели, фазе или задаче. Тип файла определяется по содержимому и проверяется по списку разрешённых
+// @Tags         Attachment
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        target_type  formData  string  true  "goal, phase или task"
+// @Param        target_id    formData  string  true  "UUID цели, фазы или задачи"
+// @Param        file         formData  file    true  "Файл"
+// @Success      201          {object}  dto.AttachmentResponse
+// @Failure      400          {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404          {object}  response.ErrorResponse  "Target not found"
+// @Failure      413          {object}  response.ErrorResponse  "File too large"
+// @Failure      415          {object}  response.ErrorResponse  "File type not allowed"
+// @Failure      500          {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/attachments [post]
+func (h *Handler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	// Leave room for the multipart envelope; the file itself is checked
+	// against the exact limit by the service.
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUpload+1<<20)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Expected multipart/form-data", http.StatusBadRequest)
+		return
+	}
+
+	var t Target
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			http.Error(w, "file is required", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				writeError(w, "upload attachment", ErrFileTooLarge)
+				return
+			}
+			http.Error(w, "Invalid multipart body", http.StatusBadRequest)
+			return
+		}
+
+		switch part.FormName() {
+		case "target_type", "target_id":
+			// Form fields must come before the file so the target can be
+			// checked without buffering the upload.
+			value, err := io.ReadAll(io.LimitReader(part, 64))
+			if err != nil {
+				http.Error(w, "Invalid multipart body", http.StatusBadRequest)
+				return
+			}
+			if part.FormName() == "target_type" {
+				t.Type = string(value)
+			} else if t.ID, err = uuid.Parse(string(value)); err != nil {
+				writeError(w, "upload attachment", ErrInvalidTarget)
+				return
+			}
+		case "file":
+			resp, err := h.service.UploadAttachment(r.Context(), uid, t, part.FileName(), part)
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				err = ErrFileTooLarge
+			}
+			if err != nil {
+				writeError(w, "upload attachment", err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+	}
+}
+
+// @Summary      Скачать вложение
+// @Description  Отдаёт файл вложения. Доступно только владельцу цели
+// @Tags         Attachment
+// @Produce      octet-stream
+// @Security     ApiKeyAuth
+// @Param        attachment_id  path  string  true  "UUID вложения"
+// @Success      200  {file}    file
+// @Failure      404  {object}  response.ErrorResponse  "Attachment not found"
+// @Failure      500  {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/attachments/{attachment_id}/download [get]
+func (h *Handler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	attachmentID, err := uuid.Parse(chi.URLParam(r, "attachment_id"))
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	a, content, err := h.service.OpenAttachment(r.Context(), uid, attachmentID)
+	if err != nil {
+		writeError(w, "download attachment", err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	w.Header().Set("Content-Disposition",
+		mime.FormatMediaType("attachment", map[string]string{"filename": a.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, content); err != nil {
+		log.Printf("[NOTE] download of %s interrupted: %v", a.ID, err)
+	}
+}
+
+// @Summary      Удалить вложение
+// @Tags         Attachment
+// @Security     ApiKeyAuth
+// @Param        attachment_id  path      string  true  "UUID вложения"
+// @Success      204            {string}  string  "No Content"
+// @Failure      404            {object}  response.ErrorResponse  "Attachment not found"
+// @Failure      500            {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/attachments/{attachment_id} [delete]
+func (h *Handler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	attachmentID, err := uuid.Parse(chi.URLParam(r, "attachment_id"))
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteAttachment(r.Context(), uid, attachmentID); err != nil {
+		writeError(w, "delete attachment", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,71 @@
+package note
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Target types notes and attachments can be attached to.
+const (
+	TargetGoal  = "goal"
+	TargetPhase = "phase"
+	TargetTask  = "task"
+)
+
+type Target struct {
+	Type string
+	ID   uuid.UUID
+}
+
+func (t Target) valid() bool {
+	return t.Type == TargetGoal || t.Type == TargetPhase || t.Type == TargetTask
+}
+
+// columns splits the target into the goal_id, phase_id and task_id columns
+// of which exactly one is set.
+func (t Target) columns() (goalID, phaseID, taskID *uuid.UUID) {
+	id := t.ID
+	switch t.Type {
+	case TargetGoal:
+		goalID = &id
+	case TargetPhase:
+		phaseID = &id
+	case TargetTask:
+		taskID = &id
+	}
+	return
+}
+
+func targetFromColumns(goalID, phaseID, taskID *uuid.UUID) Target {
+	switch {
+	case goalID != nil:
+		return Target{Type: TargetGoal, ID: *goalID}
+	case phaseID != nil:
+		return Target{Type: TargetPhase, ID: *phaseID}
+	case taskID != nil:
+		return Target{Type: TargetTask, ID: *taskID}
+	}
+	return Target{}
+}
+
+// Note is a markdown note. The body is stored and returned as is; rendering
+// is up to the client.
+type Note struct {
+	ID        uuid.UUID
+	UserID    int64
+	Target    Target
+	Body      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Attachment struct {
+	ID          uuid.UUID
+	UserID      int64
+	Target      Target
+	FileName    string
+	ContentType string
+	Size        int64
+	StorageKey  string
+	CreatedAt   time.Time
+}
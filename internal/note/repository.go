@@ -0,0 +1,215 @@
+package note
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	// TargetOwner returns the user owning the goal behind the target, or
+	// false when the target does not exist or its goal is deleted.
+	TargetOwner(ctx context.Context, t Target) (int64, bool, error)
+
+	CreateNote(ctx context.Context, n *Note) error
+	GetNote(ctx context.Context, id uuid.UUID) (*Note, error)
+	UpdateNote(ctx context.Context, n *Note) error
+	DeleteNote(ctx context.Context, id uuid.UUID) error
+	ListNotes(ctx context.Context, t Target) ([]Note, error)
+
+	CreateAttachment(ctx context.Context, a *Attachment) error
+	GetAttachment(ctx context.Context, id uuid.UUID) (*Attachment, error)
+	DeleteAttachment(ctx context.Context, id uuid.UUID) error
+	ListAttachments(ctx context.Context, t Target) ([]Attachment, error)
+}
+
+type repositoryImpl struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repositoryImpl{db: db}
+}
+
+func (r *repositoryImpl) TargetOwner(ctx context.Context, t Target) (int64, bool, error) {
+	var q string
+	switch t.Type {
+	case TargetGoal:
+		q = `SELECT g.user_id FROM goals g WHERE g.id = $1 AND g.deleted_at IS NULL`
+	case TargetPhase:
+		q = `SELECT g.user_id FROM phases p JOIN goals g ON g.id = p.goal_id
+		     WHERE p.id = $1 AND g.deleted_at IS NULL`
+	case TargetTask:
+		q = `SELECT g.user_id FROM tasks t JOIN goals g ON g.id = t.goal_id
+		     WHERE t.id = $1 AND g.deleted_at IS NULL`
+	default:
+		return 0, false, ErrInvalidTarget
+	}
+	var userID int64
+	err := r.db.QueryRowContext(ctx, q, t.ID).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to resolve target owner: %w", err)
+	}
+	return userID, true, nil
+}
+
+// targetWhere matches rows attached to t; the id goes into $1.
+func targetWhere(t Target) string {
+	switch t.Type {
+	case TargetGoal:
+		return "goal_id = $1"
+	case TargetPhase:
+		return "phase_id = $1"
+	default:
+		return "task_id = $1"
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const noteColumns = `id, user_id, goal_id, phase_id, task_id, body, created_at, updated_at`
+
+func scanNote(row rowScanner, n *Note) error {
+	var goalID, phaseID, taskID *uuid.UUID
+	if err := row.Scan(&n.ID, &n.UserID, &goalID, &phaseID, &taskID, &n.Body, &n.CreatedAt, &n.UpdatedAt); err != nil {
+		return err
+	}
+	n.Target = targetFromColumns(goalID, phaseID, taskID)
+	return nil
+}
+
+func (r *repositoryImpl) CreateNote(ctx context.Context, n *Note) error {
+	goalID, phaseID, taskID := n.Target.columns()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO notes (id, user_id, goal_id, phase_id, task_id, body, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		n.ID, n.UserID, goalID, phaseID, taskID, n.Body, n.CreatedAt, n.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert note: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetNote(ctx context.Context, id uuid.UUID) (*Note, error) {
+	var n Note
+	err := scanNote(r.db.QueryRowContext(ctx, `SELECT `+noteColumns+` FROM notes WHERE id = $1`, id), &n)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get note: %w", err)
+	}
+	return &n, nil
+}
+
+func (r *repositoryImpl) UpdateNote(ctx context.Context, n *Note) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE notes SET body = $2, updated_at = $3 WHERE id = $1`,
+		n.ID, n.Body, n.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update note: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) DeleteNote(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM notes WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete note: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) ListNotes(ctx context.Context, t Target) ([]Note, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+noteColumns+` FROM notes WHERE `+targetWhere(t)+` ORDER BY created_at DESC`, t.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notes: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Note
+	for rows.Next() {
+		var n Note
+		if err := scanNote(rows, &n); err != nil {
+			return nil, err
+		}
+		result = append(result, n)
+	}
+	return result, rows.Err()
+}
+
+const attachmentColumns = `id, user_id, goal_id, phase_id, task_id, file_name, content_type,
+	size_bytes, storage_key, created_at`
+
+func scanAttachment(row rowScanner, a *Attachment) error {
+	var goalID, phaseID, taskID *uuid.UUID
+	if err := row.Scan(
+		&a.ID, &a.UserID, &goalID, &phaseID, &taskID, &a.FileName, &a.ContentType,
+		&a.Size, &a.StorageKey, &a.CreatedAt,
+	); err != nil {
+		return err
+	}
+	a.Target = targetFromColumns(goalID, phaseID, taskID)
+	return nil
+}
+
+func (r *repositoryImpl) CreateAttachment(ctx context.Context, a *Attachment) error {
+	goalID, phaseID, taskID := a.Target.columns()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO attachments (
+			id, user_id, goal_id, phase_id, task_id, file_name, content_type,
+			size_bytes, storage_key, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		a.ID, a.UserID, goalID, phaseID, taskID, a.FileName, a.ContentType,
+		a.Size, a.StorageKey, a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert attachment: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetAttachment(ctx context.Context, id uuid.UUID) (*Attachment, error) {
+	var a Attachment
+	err := scanAttachment(r.db.QueryRowContext(ctx,
+		`SELECT `+attachmentColumns+` FROM attachments WHERE id = $1`, id), &a)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return &a, nil
+}
+
+func (r *repositoryImpl) DeleteAttachment(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM attachments WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) ListAttachments(ctx context.Context, t Target) ([]Attachment, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+attachmentColumns+` FROM attachments WHERE `+targetWhere(t)+` ORDER BY created_at DESC`, t.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Attachment
+	for rows.Next() {
+		var a Attachment
+		if err := scanAttachment(rows, &a); err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
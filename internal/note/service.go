@@ -0,0 +1,305 @@
+package note
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"task-planner/internal/note/dto"
+	"task-planner/pkg/config"
+	"task-planner/pkg/storage"
+	"time"
+)
+
+// maxNoteLength caps a note body, in characters.
+const maxNoteLength = 50000
+
+type Service interface {
+	ListNotes(ctx context.Context, userID int64, t Target) ([]dto.NoteResponse, error)
+	CreateNote(ctx context.Context, userID int64, req dto.CreateNoteRequest) (*dto.NoteResponse, error)
+	UpdateNote(ctx context.Context, userID int64, noteID uuid.UUID, req dto.UpdateNoteRequest) (*dto.NoteResponse, error)
+	DeleteNote(ctx context.Context, userID int64, noteID uuid.UUID) error
+
+	ListAttachments(ctx context.Context, userID int64, t Target) ([]dto.AttachmentResponse, error)
+	// UploadAttachment stores the file and records it. The content type is
+	// detected from the data, not taken from the client.
+	UploadAttachment(ctx context.Context, userID int64, t Target, fileName string, r io.Reader) (*dto.AttachmentResponse, error)
+	// OpenAttachment returns the attachment with its content; the caller
+	// closes the reader.
+	OpenAttachment(ctx context.Context, userID int64, attachmentID uuid.UUID) (*Attachment, io.ReadCloser, error)
+	DeleteAttachment(ctx context.Context, userID int64, attachmentID uuid.UUID) error
+}
+
+type service struct {
+	repo    Repository
+	storage storage.Storage
+	cfg     config.FilesConfig
+}
+
+func NewService(repo Repository, store storage.Storage, cfg config.FilesConfig) Service {
+	return &service{repo: repo, storage: store, cfg: cfg}
+}
+
+// ownedTarget checks that the target exists and belongs to the user.
+func (s *service) ownedTarget(ctx context.Context, userID int64, t Target) error {
+	if !t.valid() {
+		return ErrInvalidTarget
+	}
+	owner, ok, err := s.repo.TargetOwner(ctx, t)
+	if err != nil {
+		return err
+	}
+	if !ok || owner != userID {
+		return ErrTargetNotFound
+	}
+	return nil
+}
+
+func validBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", ErrEmptyNote
+	}
+	if len([]rune(body)) > maxNoteLength {
+		return "", ErrNoteTooLong
+	}
+	return body, nil
+}
+
+func (s *service) ListNotes(ctx context.Context, userID int64, t Target) ([]dto.NoteResponse, error) {
+	if err := s.ownedTarget(ctx, userID, t); err != nil {
+		return nil, err
+	}
+	notes, err := s.repo.ListNotes(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.NoteResponse, 0, len(notes))
+	for i := range notes {
+		result = append(result, toNoteResponse(&notes[i]))
+	}
+	return result, nil
+}
+
+func (s *service) CreateNote(ctx context.Context, userID int64, req dto.CreateNoteRequest) (*dto.NoteResponse, error) {
+	t := Target{Type: req.TargetType, ID: req.TargetID}
+	if err := s.ownedTarget(ctx, userID, t); err != nil {
+		return nil, err
+	}
+	body, err := validBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	n := &Note{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Target:    t,
+		Body:      body,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.CreateNote(ctx, n); err != nil {
+		return nil, err
+	}
+	resp := toNoteResponse(n)
+	return &resp, nil
+}
+
+func (s *service) UpdateNote(ctx context.Context, userID int64, noteID uuid.UUID, req dto.UpdateNoteRequest) (*dto.NoteResponse, error) {
+	n, err := s.ownedNote(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if n.Body, err = validBody(req.Body); err != nil {
+		return nil, err
+	}
+	n.UpdatedAt = time.Now()
+	if err := s.repo.UpdateNote(ctx, n); err != nil {
+		return nil, err
+	}
+	resp := toNoteResponse(n)
+	return &resp, nil
+}
+
+func (s *service) DeleteNote(ctx context.Context, userID int64, noteID uuid.UUID) error {
+	if _, err := s.ownedNote(ctx, userID, noteID); err != nil {
+		return err
+	}
+	return s.repo.DeleteNote(ctx, noteID)
+}
+
+func (s *service) ownedNote(ctx context.Context, userID int64, noteID uuid.UUID) (*Note, error) {
+	n, err := s.repo.GetNote(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil || n.UserID != userID {
+		return nil, ErrNoteNotFound
+	}
+	return n, nil
+}
+
+func (s *service) ListAttachments(ctx context.Context, userID int64, t Target) ([]dto.AttachmentResponse, error) {
+	if err := s.ownedTarget(ctx, userID, t); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListAttachments(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.AttachmentResponse, 0, len(list))
+	for i := range list {
+		result = append(result, toAttachmentResponse(&list[i]))
+	}
+	return result, nil
+}
+
+func (s *service) maxSize() int64 {
+	return int64(s.cfg.MaxSizeMB) << 20
+}
+
+func (s *service) allowedType(contentType string) bool {
+	for _, t := range s.cfg.AllowedTypes {
+		if strings.EqualFold(strings.TrimSpace(t), contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *service) UploadAttachment(
+	ctx context.Context, userID int64, t Target, fileName string, r io.Reader,
+) (*dto.AttachmentResponse, error) {
+	if err := s.ownedTarget(ctx, userID, t); err != nil {
+		return nil, err
+	}
+	fileName = filepath.Base(strings.TrimSpace(fileName))
+	if fileName == "" || fileName == "." || fileName == string(filepath.Separator) {
+		return nil, ErrInvalidFileName
+	}
+	if len(fileName) > 255 {
+		fileName = fileName[len(fileName)-255:]
+	}
+
+	br := bufio.NewReaderSize(r, 512)
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !s.allowedType(contentType) {
+		return nil, ErrUnsupportedType
+	}
+
+	a := &Attachment{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Target:      t,
+		FileName:    fileName,
+		ContentType: contentType,
+		CreatedAt:   time.Now(),
+	}
+	a.StorageKey = a.ID.String()
+
+	// Read one byte past the limit so an oversized file is detected
+	// without storing all of it.
+	limit := s.maxSize()
+	size, err := s.storage.Save(ctx, a.StorageKey, io.LimitReader(br, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if size > limit {
+		s.discard(ctx, a.StorageKey)
+		return nil, ErrFileTooLarge
+	}
+	a.Size = size
+
+	if err := s.repo.CreateAttachment(ctx, a); err != nil {
+		s.discard(ctx, a.StorageKey)
+		return nil, err
+	}
+	resp := toAttachmentResponse(a)
+	return &resp, nil
+}
+
+func (s *service) OpenAttachment(ctx context.Context, userID int64, attachmentID uuid.UUID) (*Attachment, io.ReadCloser, error) {
+	a, err := s.ownedAttachment(ctx, userID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, err := s.storage.Open(ctx, a.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, rc, nil
+}
+
+func (s *service) DeleteAttachment(ctx context.Context, userID int64, attachmentID uuid.UUID) error {
+	a, err := s.ownedAttachment(ctx, userID, attachmentID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteAttachment(ctx, a.ID); err != nil {
+		return err
+	}
+	s.discard(ctx, a.StorageKey)
+	return nil
+}
+
+// ownedAttachment checks access through the target, so only the owner of
+// the goal behind it can read or delete an attachment.
+func (s *service) ownedAttachment(ctx context.Context, userID int64, attachmentID uuid.UUID) (*Attachment, error) {
+	a, err := s.repo.GetAttachment(ctx, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		return nil, ErrAttachmentNotFound
+	}
+	if err := s.ownedTarget(ctx, userID, a.Target); err != nil {
+		return nil, ErrAttachmentNotFound
+	}
+	return a, nil
+}
+
+func (s *service) discard(ctx context.Context, key string) {
+	if err := s.storage.Delete(ctx, key); err != nil {
+		log.Printf("[ATTACHMENT] failed to delete %s: %v", key, err)
+	}
+}
+
+func toNoteResponse(n *Note) dto.NoteResponse {
+	return dto.NoteResponse{
+		ID:         n.ID,
+		TargetType: n.Target.Type,
+		TargetID:   n.Target.ID,
+		Body:       n.Body,
+		CreatedAt:  n.CreatedAt,
+		UpdatedAt:  n.UpdatedAt,
+	}
+}
+
+func toAttachmentResponse(a *Attachment) dto.AttachmentResponse {
+	return dto.AttachmentResponse{
+		ID:          a.ID,
+		TargetType:  a.Target.Type,
+		TargetID:    a.Target.ID,
+		FileName:    a.FileName,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		DownloadURL: "/api/attachments/" + a.ID.String() + "/download",
+		CreatedAt:   a.CreatedAt,
+	}
+}
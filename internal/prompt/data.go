@@ -0,0 +1,72 @@
+package prompt
+
+// DecomposeData fills the Decompose prompt.
+type DecomposeData struct {
+	Title        string
+	Description  string
+	HoursPerWeek int
+}
+
+// RefineData fills the Refine prompt, which wraps one instruction of the
+// user refining a generated plan.
+type RefineData struct {
+	Instruction string
+}
+
+// PhaseSummaryData fills the PhaseSummary prompt with the tasks done in
+// the phase recently.
+type PhaseSummaryData struct {
+	Goal  string
+	Phase string
+	Done  []TaskData
+}
+
+// ExtraTasksData fills the ExtraTasks prompt that tops up the current
+// phase with at most Count tasks.
+type ExtraTasksData struct {
+	Goal           string
+	Phase          string
+	RemainingHours int
+	PhaseHours     int
+	HoursPerWeek   int
+	Summary        string
+	Count          int
+}
+
+// PhaseTasksData fills the PhaseTasks prompt that plans a phase once the
+// ones before it are completed.
+type PhaseTasksData struct {
+	Goal             string
+	GoalDescription  string
+	HoursPerWeek     int
+	Completed        []PhaseData
+	Order            int
+	Phase            string
+	PhaseDescription string
+	PhaseHours       int
+	MaxMinutes       int
+}
+
+// PhaseData is a completed phase with the tasks done in it. More is set
+// when there were more tasks than listed.
+type PhaseData struct {
+	Order       int
+	Title       string
+	Description string
+	Tasks       []TaskData
+	More        bool
+}
+
+// TaskData is a task quoted to the model, with its estimate in hours and
+// the time tracked on it in minutes.
+type TaskData struct {
+	Title          string
+	EstimatedHours int
+	SpentMinutes   int
+}
+
+// MotivationData fills the Motivation prompt with the titles of the
+// user's tasks for today.
+type MotivationData struct {
+	Tasks []string
+}
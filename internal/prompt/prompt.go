@@ -0,0 +1,139 @@
+// Package prompt renders the prompts sent to language models from
+// versioned templates embedded in the binary.
+//
+// Templates live in templates/<locale>/<name>.v<N>.tmpl. The highest
+// version of a prompt is the one in use; older versions stay in the tree so
+// that Known still accepts the versions recorded on generated entities.
+package prompt
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Locales with prompt variants.
+const (
+	LocaleEN = "en"
+	LocaleRU = "ru"
+	// DefaultLocale is used for users without a supported locale.
+	DefaultLocale = LocaleRU
+)
+
+// Prompt names.
+const (
+	Decompose    = "decompose"
+	Refine       = "refine"
+	PhaseSummary = "phase_summary"
+	ExtraTasks   = "extra_tasks"
+	PhaseTasks   = "phase_tasks"
+	Motivation   = "motivation"
+)
+
+//go:embed templates
+var files embed.FS
+
+var fileName = regexp.MustCompile(`^([a-z_]+)\.v([0-9]+)\.tmpl$`)
+
+type key struct {
+	name, locale string
+}
+
+type entry struct {
+	version int
+	tmpl    *template.Template
+}
+
+// latest holds the highest version of every prompt per locale, and known
+// the name of every embedded version, such as "decompose.v1.ru".
+var latest, known = mustLoad()
+
+func mustLoad() (map[key]entry, map[string]bool) {
+	out := make(map[key]entry)
+	versions := make(map[string]bool)
+	err := fs.WalkDir(files, "templates", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		parts := strings.Split(path, "/")
+		m := fileName.FindStringSubmatch(d.Name())
+		if len(parts) != 3 || m == nil {
+			return fmt.Errorf("unexpected prompt file %s", path)
+		}
+		version, _ := strconv.Atoi(m[2])
+		k := key{name: m[1], locale: parts[1]}
+		versions[versionName(k, version)] = true
+		if cur, ok := out[k]; ok && cur.version >= version {
+			return nil
+		}
+		tmpl, err := template.New(d.Name()).Option("missingkey=error").ParseFS(files, path)
+		if err != nil {
+			return err
+		}
+		out[k] = entry{version: version, tmpl: tmpl}
+		return nil
+	})
+	if err != nil {
+		panic(fmt.Sprintf("prompt: %v", err))
+	}
+	return out, versions
+}
+
+func versionName(k key, version int) string {
+	return fmt.Sprintf("%s.v%d.%s", k.name, version, k.locale)
+}
+
+// Known reports whether version names an embedded prompt, current or not,
+// as recorded in Rendered.Version.
+func Known(version string) bool {
+	return known[version]
+}
+
+// Supported reports whether there are prompts for the locale.
+func Supported(locale string) bool {
+	return locale == LocaleEN || locale == LocaleRU
+}
+
+// Normalize maps a locale such as "en-US" to a supported one, falling back
+// to DefaultLocale.
+func Normalize(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	if Supported(locale) {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// Rendered is a prompt ready to send together with the version that
+// produced it, such as "decompose.v1.ru".
+type Rendered struct {
+	Text    string
+	Version string
+}
+
+// Render fills the latest version of the named prompt for the locale with
+// data, one of the types in data.go.
+func Render(name, locale string, data any) (Rendered, error) {
+	locale = Normalize(locale)
+	k := key{name: name, locale: locale}
+	e, ok := latest[k]
+	if !ok {
+		return Rendered{}, fmt.Errorf("prompt: no %q prompt for locale %q", name, locale)
+	}
+	var buf bytes.Buffer
+	if err := e.tmpl.Execute(&buf, data); err != nil {
+		return Rendered{}, fmt.Errorf("prompt: render %s: %w", name, err)
+	}
+	return Rendered{
+		Text:    strings.TrimSpace(buf.String()),
+		Version: versionName(k, e.version),
+	}, nil
+}
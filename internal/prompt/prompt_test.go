@@ -0,0 +1,97 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderEveryPrompt(t *testing.T) {
+	data := map[string]any{
+		Decompose:    DecomposeData{Title: "Learn Go", Description: "For work", HoursPerWeek: 5},
+		Refine:       RefineData{Instruction: "Fewer phases"},
+		PhaseSummary: PhaseSummaryData{Goal: "Learn Go", Phase: "Basics", Done: []TaskData{{Title: "Tour of Go", EstimatedHours: 3}}},
+		ExtraTasks:   ExtraTasksData{Goal: "Learn Go", Phase: "Basics", RemainingHours: 4, PhaseHours: 10, HoursPerWeek: 5, Summary: "Went well", Count: 2},
+		PhaseTasks: PhaseTasksData{
+			Goal: "Learn Go", HoursPerWeek: 5, Order: 2, Phase: "Concurrency", PhaseHours: 10, MaxMinutes: 600,
+			Completed: []PhaseData{{Order: 1, Title: "Basics", Tasks: []TaskData{{Title: "Tour of Go", EstimatedHours: 3, SpentMinutes: 200}}, More: true}},
+		},
+		Motivation: MotivationData{Tasks: []string{"Tour of Go"}},
+	}
+	for _, locale := range []string{LocaleEN, LocaleRU} {
+		for name, d := range data {
+			r, err := Render(name, locale, d)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", locale, name, err)
+			}
+			if want := name + ".v1." + locale; r.Version != want {
+				t.Errorf("%s/%s: version %q, want %q", locale, name, r.Version, want)
+			}
+			if strings.Contains(r.Text, "<no value>") {
+				t.Errorf("%s/%s: unfilled field:\n%s", locale, name, r.Text)
+			}
+		}
+	}
+}
+
+func TestKnown(t *testing.T) {
+	r, err := Render(Decompose, LocaleEN, DecomposeData{Title: "Learn Go", HoursPerWeek: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{r.Version, true},
+		{"phase_tasks.v1.ru", true},
+		{"decompose.v1.de", false},
+		{"decompose.v99.en", false},
+		{"decompose.v1", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := Known(tt.version); got != tt.want {
+			t.Errorf("Known(%q) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestRenderPhaseTasks(t *testing.T) {
+	r, err := Render(PhaseTasks, LocaleRU, PhaseTasksData{
+		Goal: "Learn Go", Order: 2, Phase: "Concurrency", PhaseHours: 10, MaxMinutes: 600,
+		Completed: []PhaseData{{Order: 1, Title: "Basics", Tasks: []TaskData{{Title: "Tour of Go", EstimatedHours: 3, SpentMinutes: 200}}, More: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Фаза 1 «Basics»", "  - Tour of Go (план 3 ч, факт 200 мин)\n  - …", `фаза 2 "Concurrency"`, "600 минут"} {
+		if !strings.Contains(r.Text, want) {
+			t.Errorf("prompt is missing %q:\n%s", want, r.Text)
+		}
+	}
+}
+
+func TestRenderMotivationWithoutTasks(t *testing.T) {
+	r, err := Render(Motivation, LocaleEN, MotivationData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(r.Text, "Cheer me up") {
+		t.Errorf("unexpected prompt %q", r.Text)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"en":    LocaleEN,
+		"en-US": LocaleEN,
+		"RU_ru": LocaleRU,
+		"de":    DefaultLocale,
+		"":      DefaultLocale,
+	}
+	for in, want := range cases {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
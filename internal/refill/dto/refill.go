@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type SetRefillRequest struct {
+	// Enabled opts the goal in to or out of the scheduled refill.
+	Enabled bool `json:"enabled"`
+}
+
+type RefillResponse struct {
+	GoalID  uuid.UUID `json:"goal_id"`
+	Enabled bool      `json:"enabled"`
+	// Runs are the most recent refills of the goal, newest first.
+	Runs []RunResponse `json:"runs"`
+}
+
+type RunResponse struct {
+	ID uuid.UUID `json:"id"`
+	// Trigger is scheduled or manual.
+	Trigger string `json:"trigger"`
+	// Status is running, succeeded or failed.
+	Status         string     `json:"status"`
+	TasksAdded     int        `json:"tasks_added"`
+	TasksScheduled int        `json:"tasks_scheduled"`
+	Error          string     `json:"error,omitempty"`
+	StartedAt      time.Time  `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
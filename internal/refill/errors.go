@@ -0,0 +1,7 @@
+package refill
+
+import "errors"
+
+var (
+	ErrRefillRunning = errors.New("a refill of this goal is already running")
+)
@@ -0,0 +1,140 @@
+package refill
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"task-planner/internal/auth"
+	"task-planner/internal/goal"
+	"task-planner/internal/refill/dto"
+	"task-planner/internal/usage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func requestParams(w http.ResponseWriter, r *http.Request) (int64, uuid.UUID, bool) {
+	goalID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return 0, uuid.Nil, false
+	}
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, uuid.Nil, false
+	}
+	return claims.UserID, goalID, true
+}
+
+func writeError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, goal.ErrGoalNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrRefillRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, usage.ErrQuotaExceeded):
+		usage.WriteQuotaError(w, err)
+	default:
+		log.Printf("[REFILL] %s failed: %v", action, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// @Summary      Автопополнение задач цели
+// @Description  Показывает, участвует ли цель в плановом пополнении задач, и последние запуски пополнения
+// @Tags         Refill
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "UUID цели"
+// @Success      200  {object}  dto.RefillResponse
+// @Failure      404  {object}  response.ErrorResponse  "Goal not found"
+// @Failure      500  {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/{id}/refill [get]
+func (h *Handler) GetRefill(w http.ResponseWriter, r *http.Request) {
+	userID, goalID, ok := requestParams(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.GetRefill(r.Context(), userID, goalID)
+	if err != nil {
+		writeError(w, "get refill", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Включить или выключить автопополнение
+// @Description  Когда в текущей фазе остаётся мало задач, плановое пополнение добавляет новые задачи и ставит их в расписание. Работает только для целей, где оно включено
+// @Tags         Refill
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id    path      string                true  "UUID цели"
+// @Param        body  body      dto.SetRefillRequest  true  "Настройка"
+// @Success      200   {object}  dto.RefillResponse
+// @Failure      400   {object}  response.ErrorResponse  "Invalid request body"
+// @Failure      404   {object}  response.ErrorResponse  "Goal not found"
+// @Failure      500   {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/{id}/refill [put]
+func (h *Handler) SetRefill(w http.ResponseWriter, r *http.Request) {
+	userID, goalID, ok := requestParams(w, r)
+	if !ok {
+		return
+	}
+	var req dto.SetRefillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.SetRefill(r.Context(), userID, goalID, req)
+	if err != nil {
+		writeError(w, "set refill", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Пополнить задачи сейчас
+// @Description  Сразу пополняет текущую фазу цели и ставит новые задачи в расписание, даже если автопополнение выключено. Неудачный запуск возвращается со статусом failed и сохраняется в истории
+// @Tags         Refill
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "UUID цели"
+// @Success      201  {object}  dto.RunResponse
+// @Failure      404  {object}  response.ErrorResponse  "Goal not found"
+// @Failure      409  {object}  response.ErrorResponse  "A refill of this goal is already running"
+// @Failure      429  {object}  response.ErrorResponse  "LLM usage quota exceeded"
+// @Failure      500  {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/goals/{id}/refill/run [post]
+func (h *Handler) RunRefill(w http.ResponseWriter, r *http.Request) {
+	userID, goalID, ok := requestParams(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.RunNow(r.Context(), userID, goalID)
+	if err != nil {
+		writeError(w, "run refill", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
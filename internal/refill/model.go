@@ -0,0 +1,48 @@
+package refill
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// What started a run.
+const (
+	TriggerScheduled = "scheduled"
+	TriggerManual    = "manual"
+)
+
+// Run statuses. A run is running until the refill and the scheduling that
+// follows it have both finished.
+const (
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+// Run is one refill of a goal.
+type Run struct {
+	ID             uuid.UUID
+	GoalID         uuid.UUID
+	Trigger        string
+	Status         string
+	TasksAdded     int
+	TasksScheduled int
+	Error          string
+	StartedAt      time.Time
+	FinishedAt     *time.Time
+
+	// cause is the error the run failed with, kept for the caller.
+	cause error
+}
+
+// finish records the outcome of the run.
+func (r *Run) finish(err error) {
+	now := time.Now()
+	r.FinishedAt = &now
+	r.Status = RunSucceeded
+	if err != nil {
+		r.Status = RunFailed
+		r.Error = err.Error()
+		r.cause = err
+	}
+}
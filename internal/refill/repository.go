@@ -0,0 +1,154 @@
+package refill
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/google/uuid"
+	"task-planner/internal/db"
+	"time"
+)
+
+type Repository interface {
+	// ListOptedInGoals returns the active goals that take part in the
+	// scheduled refill.
+	ListOptedInGoals(ctx context.Context) ([]uuid.UUID, error)
+	GetAutoRefill(ctx context.Context, goalID uuid.UUID) (bool, error)
+	SetAutoRefill(ctx context.Context, goalID uuid.UUID, enabled bool) error
+
+	CreateRun(ctx context.Context, run *Run) error
+	// FinishRun stores the outcome of a run created before.
+	FinishRun(ctx context.Context, run *Run) error
+	// ListRuns returns the goal's most recent runs, newest first.
+	ListRuns(ctx context.Context, goalID uuid.UUID, limit int) ([]Run, error)
+	// FailStaleRuns closes runs that were left running before since, for
+	// example by a restart, and returns how many there were.
+	FailStaleRuns(ctx context.Context, since time.Time) (int64, error)
+	// PurgeRuns deletes runs started before the given time.
+	PurgeRuns(ctx context.Context, before time.Time) (int64, error)
+}
+
+type repositoryImpl struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repositoryImpl{db: db}
+}
+
+// conn returns the transaction carried by ctx, if any.
+func (r *repositoryImpl) conn(ctx context.Context) db.Executor {
+	return db.Conn(ctx, r.db)
+}
+
+func (r *repositoryImpl) ListOptedInGoals(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT id FROM goals
+		WHERE auto_refill AND status = 'active'
+		  AND archived_at IS NULL AND deleted_at IS NULL
+		ORDER BY updated_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refill goals: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *repositoryImpl) GetAutoRefill(ctx context.Context, goalID uuid.UUID) (bool, error) {
+	var enabled bool
+	err := r.conn(ctx).QueryRowContext(ctx,
+		`SELECT auto_refill FROM goals WHERE id = $1`, goalID).Scan(&enabled)
+	if err != nil {
+		return false, fmt.Errorf("failed to get auto_refill: %w", err)
+	}
+	return enabled, nil
+}
+
+func (r *repositoryImpl) SetAutoRefill(ctx context.Context, goalID uuid.UUID, enabled bool) error {
+	_, err := r.conn(ctx).ExecContext(ctx,
+		`UPDATE goals SET auto_refill = $2 WHERE id = $1`, goalID, enabled)
+	if err != nil {
+		return fmt.Errorf("failed to set auto_refill: %w", err)
+	}
+	return nil
+}
+
+const runColumns = `id, goal_id, trigger, status, tasks_added, tasks_scheduled, error, started_at, finished_at`
+
+func (r *repositoryImpl) CreateRun(ctx context.Context, run *Run) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `
+		INSERT INTO refill_runs (`+runColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		run.ID, run.GoalID, run.Trigger, run.Status, run.TasksAdded, run.TasksScheduled,
+		run.Error, run.StartedAt, run.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert refill run: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) FinishRun(ctx context.Context, run *Run) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `
+		UPDATE refill_runs
+		SET status = $2, tasks_added = $3, tasks_scheduled = $4, error = $5, finished_at = $6
+		WHERE id = $1`,
+		run.ID, run.Status, run.TasksAdded, run.TasksScheduled, run.Error, run.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update refill run: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) ListRuns(ctx context.Context, goalID uuid.UUID, limit int) ([]Run, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT `+runColumns+` FROM refill_runs
+		WHERE goal_id = $1
+		ORDER BY started_at DESC
+		LIMIT $2`, goalID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refill runs: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Run
+	for rows.Next() {
+		var run Run
+		if err := rows.Scan(
+			&run.ID, &run.GoalID, &run.Trigger, &run.Status, &run.TasksAdded, &run.TasksScheduled,
+			&run.Error, &run.StartedAt, &run.FinishedAt,
+		); err != nil {
+			return nil, err
+		}
+		result = append(result, run)
+	}
+	return result, rows.Err()
+}
+
+func (r *repositoryImpl) FailStaleRuns(ctx context.Context, since time.Time) (int64, error) {
+	res, err := r.conn(ctx).ExecContext(ctx, `
+		UPDATE refill_runs
+		SET status = 'failed', error = 'interrupted', finished_at = NOW()
+		WHERE status = 'running' AND started_at < $1`, since)
+	if err != nil {
+		return 0, fmt.Errorf("failed to close stale refill runs: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (r *repositoryImpl) PurgeRuns(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.conn(ctx).ExecContext(ctx,
+		`DELETE FROM refill_runs WHERE started_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge refill runs: %w", err)
+	}
+	return res.RowsAffected()
+}
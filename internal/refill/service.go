@@ -0,0 +1,104 @@
+package refill
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"task-planner/internal/goal"
+	"task-planner/internal/refill/dto"
+	"task-planner/internal/usage"
+)
+
+// recentRuns is how many runs GetRefill returns.
+const recentRuns = 20
+
+type Service interface {
+	GetRefill(ctx context.Context, userID int64, goalID uuid.UUID) (*dto.RefillResponse, error)
+	SetRefill(ctx context.Context, userID int64, goalID uuid.UUID, req dto.SetRefillRequest) (*dto.RefillResponse, error)
+	// RunNow refills the goal right away, whether or not it opted in to the
+	// scheduled refill.
+	RunNow(ctx context.Context, userID int64, goalID uuid.UUID) (*dto.RunResponse, error)
+}
+
+type service struct {
+	repo     Repository
+	goalRepo goal.RepositoryAggregator
+	worker   *Worker
+}
+
+func NewService(repo Repository, goalRepo goal.RepositoryAggregator, worker *Worker) Service {
+	return &service{repo: repo, goalRepo: goalRepo, worker: worker}
+}
+
+func (s *service) GetRefill(ctx context.Context, userID int64, goalID uuid.UUID) (*dto.RefillResponse, error) {
+	if err := s.ownedGoal(ctx, userID, goalID); err != nil {
+		return nil, err
+	}
+	enabled, err := s.repo.GetAutoRefill(ctx, goalID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toRefillResponse(ctx, goalID, enabled)
+}
+
+func (s *service) SetRefill(ctx context.Context, userID int64, goalID uuid.UUID, req dto.SetRefillRequest) (*dto.RefillResponse, error) {
+	if err := s.ownedGoal(ctx, userID, goalID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetAutoRefill(ctx, goalID, req.Enabled); err != nil {
+		return nil, err
+	}
+	return s.toRefillResponse(ctx, goalID, req.Enabled)
+}
+
+func (s *service) RunNow(ctx context.Context, userID int64, goalID uuid.UUID) (*dto.RunResponse, error) {
+	if err := s.ownedGoal(ctx, userID, goalID); err != nil {
+		return nil, err
+	}
+	run, err := s.worker.RunGoal(ctx, goalID, TriggerManual)
+	if err != nil {
+		return nil, err
+	}
+	// The failed run is in the history; the user is told to wait.
+	if errors.Is(run.cause, usage.ErrQuotaExceeded) {
+		return nil, run.cause
+	}
+	resp := toRunResponse(run)
+	return &resp, nil
+}
+
+func (s *service) ownedGoal(ctx context.Context, userID int64, goalID uuid.UUID) error {
+	g, err := s.goalRepo.GetGoalByID(ctx, goalID)
+	if err != nil {
+		return err
+	}
+	if g == nil || g.UserId != userID {
+		return goal.ErrGoalNotFound
+	}
+	return nil
+}
+
+func (s *service) toRefillResponse(ctx context.Context, goalID uuid.UUID, enabled bool) (*dto.RefillResponse, error) {
+	runs, err := s.repo.ListRuns(ctx, goalID, recentRuns)
+	if err != nil {
+		return nil, err
+	}
+	resp := &dto.RefillResponse{GoalID: goalID, Enabled: enabled, Runs: make([]dto.RunResponse, 0, len(runs))}
+	for i := range runs {
+		resp.Runs = append(resp.Runs, toRunResponse(&runs[i]))
+	}
+	return resp, nil
+}
+
+func toRunResponse(run *Run) dto.RunResponse {
+	return dto.RunResponse{
+		ID:             run.ID,
+		Trigger:        run.Trigger,
+		Status:         run.Status,
+		TasksAdded:     run.TasksAdded,
+		TasksScheduled: run.TasksScheduled,
+		Error:          run.Error,
+		StartedAt:      run.StartedAt,
+		FinishedAt:     run.FinishedAt,
+	}
+}
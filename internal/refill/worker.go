@@ -2,37 +2,184 @@ package refill
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
 	"log"
+	"sync"
+	"sync/atomic"
+	"task-planner/internal/goal"
 	"task-planner/internal/schedule"
+	"task-planner/pkg/config"
 	"time"
+)
 
-	"task-planner/internal/goal"
+const (
+	// maxPhaseAttempts bounds how often StartPhases tries to generate the
+	// tasks of a phase before leaving it to the user.
+	maxPhaseAttempts = 4
+	// phaseRetryBackoff is the delay after the first failed attempt; it
+	// doubles with every further failure.
+	phaseRetryBackoff = time.Hour
 )
 
 type Worker struct {
 	goalRepo  goal.RepositoryAggregator
 	service   goal.Service
 	scheduler schedule.Service
+	repo      Repository
+	cfg       config.RefillConfig
+
+	// ticking is set while a scheduled pass is in progress, so a slow pass
+	// is not overlapped by the next one.
+	ticking atomic.Bool
+	mu      sync.Mutex
+	running map[uuid.UUID]bool
 }
 
-func NewWorker(repo goal.RepositoryAggregator, svc goal.Service, sch schedule.Service) *Worker {
-	return &Worker{goalRepo: repo, service: svc, scheduler: sch}
+func NewWorker(goalRepo goal.RepositoryAggregator, svc goal.Service, sch schedule.Service, repo Repository, cfg config.RefillConfig) *Worker {
+	return &Worker{
+		goalRepo:  goalRepo,
+		service:   svc,
+		scheduler: sch,
+		repo:      repo,
+		cfg:       cfg,
+		running:   make(map[uuid.UUID]bool),
+	}
 }
 
+// Tick refills every goal that opted in, at most cfg.Concurrency at a time.
 func (w *Worker) Tick(ctx context.Context) {
-	goals, err := w.goalRepo.ListActiveGoals(ctx)
+	if !w.ticking.CompareAndSwap(false, true) {
+		log.Printf("[Refill] previous pass still running, skipping")
+		return
+	}
+	defer w.ticking.Store(false)
+
+	// Runs are bounded by GoalTimeout, so anything still open after twice
+	// that was cut short by a restart.
+	if n, err := w.repo.FailStaleRuns(ctx, time.Now().Add(-2*w.cfg.GoalTimeout)); err != nil {
+		log.Printf("[Refill] close stale runs: %v", err)
+	} else if n > 0 {
+		log.Printf("[Refill] closed %d interrupted runs", n)
+	}
+
+	goals, err := w.repo.ListOptedInGoals(ctx)
 	if err != nil {
 		log.Printf("[Refill] list goals: %v", err)
 		return
 	}
-	for _, g := range goals {
-		added, err := w.service.AutoRefillTasks(ctx, g.ID)
+
+	sem := make(chan struct{}, w.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for _, goalID := range goals {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(goalID uuid.UUID) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			run, err := w.RunGoal(ctx, goalID, TriggerScheduled)
+			switch {
+			case errors.Is(err, ErrRefillRunning):
+			case err != nil:
+				log.Printf("[Refill] goal %s: %v", goalID, err)
+			case run.Status == RunFailed:
+				log.Printf("[Refill] goal %s: %s", goalID, run.Error)
+			}
+		}(goalID)
+	}
+	wg.Wait()
+}
+
+// RunGoal refills one goal and schedules whatever was added, recording the
+// run. The outcome is in the returned run; an error means the run could not
+// be started or recorded.
+func (w *Worker) RunGoal(ctx context.Context, goalID uuid.UUID, trigger string) (*Run, error) {
+	if !w.claim(goalID) {
+		return nil, ErrRefillRunning
+	}
+	defer w.release(goalID)
+
+	run := &Run{
+		ID:        uuid.New(),
+		GoalID:    goalID,
+		Trigger:   trigger,
+		Status:    RunRunning,
+		StartedAt: time.Now(),
+	}
+	if err := w.repo.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, w.cfg.GoalTimeout)
+	defer cancel()
+	added, err := w.service.AutoRefillTasks(runCtx, goalID)
+	run.TasksAdded = added
+	if err == nil && added > 0 {
+		run.TasksScheduled, err = w.scheduler.AutoScheduleForGoal(runCtx, goalID)
+		if err != nil {
+			err = fmt.Errorf("schedule: %w", err)
+		}
+	}
+	run.finish(err)
+
+	// The outcome is stored even when the run itself timed out.
+	if err := w.repo.FinishRun(context.WithoutCancel(ctx), run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (w *Worker) claim(goalID uuid.UUID) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.running[goalID] {
+		return false
+	}
+	w.running[goalID] = true
+	return true
+}
+
+func (w *Worker) release(goalID uuid.UUID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.running, goalID)
+}
+
+// StartPhases generates and schedules the tasks of phases that have become
+// current without any, which is how every phase after the first of an AI
+// decomposition starts out. Only goals opted in to refill are considered,
+// and a phase that keeps failing is retried with backoff, maxPhaseAttempts
+// times at most.
+func (w *Worker) StartPhases(ctx context.Context) {
+	phases, err := w.goalRepo.ListPhasesAwaitingTasks(ctx, maxPhaseAttempts)
+	if err != nil {
+		log.Printf("[Refill] list phases awaiting tasks: %v", err)
+		return
+	}
+	for _, ph := range phases {
+		added, err := w.service.GeneratePhaseTasks(ctx, ph.ID)
 		if err != nil {
-			log.Printf("[Refill] goal %s: %v", g.ID, err)
+			log.Printf("[Refill] phase %s: %v", ph.ID, err)
+			if err := w.goalRepo.RecordPhaseTasksFailure(context.WithoutCancel(ctx), ph.ID, phaseRetryBackoff); err != nil {
+				log.Printf("[Refill] phase %s: %v", ph.ID, err)
+			}
+			continue
 		}
 		if added > 0 {
-			_, _ = w.scheduler.AutoScheduleForGoal(ctx, g.ID)
+			if _, err := w.scheduler.AutoScheduleForGoal(ctx, ph.GoalId); err != nil {
+				log.Printf("[Refill] schedule goal %s: %v", ph.GoalId, err)
+			}
 		}
-		time.Sleep(2 * time.Second)
 	}
 }
+
+// PurgeRuns drops run history older than cfg.KeepRunsDays.
+func (w *Worker) PurgeRuns(ctx context.Context) (int64, error) {
+	return w.repo.PurgeRuns(ctx, time.Now().AddDate(0, 0, -w.cfg.KeepRunsDays))
+}
@@ -0,0 +1,133 @@
+package refill
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"sync"
+	"sync/atomic"
+	"task-planner/internal/goal"
+	"task-planner/internal/schedule"
+	"task-planner/pkg/config"
+	"testing"
+	"time"
+)
+
+type memRepo struct {
+	Repository
+	mu    sync.Mutex
+	goals []uuid.UUID
+	runs  map[uuid.UUID]Run
+}
+
+func (r *memRepo) ListOptedInGoals(context.Context) ([]uuid.UUID, error) { return r.goals, nil }
+
+func (r *memRepo) FailStaleRuns(context.Context, time.Time) (int64, error) { return 0, nil }
+
+func (r *memRepo) CreateRun(ctx context.Context, run *Run) error {
+	return r.FinishRun(ctx, run)
+}
+
+func (r *memRepo) FinishRun(_ context.Context, run *Run) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[run.ID] = *run
+	return nil
+}
+
+// refillService adds two tasks to every goal except failGoal, tracking how
+// many refills run at once.
+type refillService struct {
+	goal.Service
+	failGoal uuid.UUID
+	release  chan struct{}
+	active   atomic.Int32
+	peak     atomic.Int32
+}
+
+func (s *refillService) AutoRefillTasks(_ context.Context, goalID uuid.UUID) (int, error) {
+	n := s.active.Add(1)
+	defer s.active.Add(-1)
+	for {
+		p := s.peak.Load()
+		if n <= p || s.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+	if s.release != nil {
+		<-s.release
+	}
+	if goalID == s.failGoal {
+		return 0, errors.New("model unavailable")
+	}
+	return 2, nil
+}
+
+type countingScheduler struct{ schedule.Service }
+
+func (countingScheduler) AutoScheduleForGoal(context.Context, uuid.UUID) (int, error) { return 2, nil }
+
+func newTestWorker(repo *memRepo, svc goal.Service, concurrency int) *Worker {
+	cfg := config.RefillConfig{Concurrency: concurrency, GoalTimeout: time.Minute}
+	return NewWorker(nil, svc, countingScheduler{}, repo, cfg)
+}
+
+func TestTickRecordsRunsWithBoundedConcurrency(t *testing.T) {
+	repo := &memRepo{runs: make(map[uuid.UUID]Run)}
+	for i := 0; i < 6; i++ {
+		repo.goals = append(repo.goals, uuid.New())
+	}
+	svc := &refillService{failGoal: repo.goals[0]}
+	w := newTestWorker(repo, svc, 2)
+
+	w.Tick(context.Background())
+
+	if len(repo.runs) != 6 {
+		t.Fatalf("recorded %d runs, want 6", len(repo.runs))
+	}
+	for _, run := range repo.runs {
+		if run.Trigger != TriggerScheduled || run.FinishedAt == nil {
+			t.Errorf("run %+v is not a finished scheduled run", run)
+		}
+		switch {
+		case run.GoalID == svc.failGoal:
+			if run.Status != RunFailed || run.Error != "model unavailable" {
+				t.Errorf("failing goal run = %+v", run)
+			}
+		case run.Status != RunSucceeded || run.TasksAdded != 2 || run.TasksScheduled != 2:
+			t.Errorf("run = %+v, want succeeded with 2 added and scheduled", run)
+		}
+	}
+	if peak := svc.peak.Load(); peak > 2 {
+		t.Errorf("%d refills ran at once, want at most 2", peak)
+	}
+}
+
+func TestRunGoalRejectsOverlappingRun(t *testing.T) {
+	repo := &memRepo{runs: make(map[uuid.UUID]Run)}
+	svc := &refillService{release: make(chan struct{})}
+	w := newTestWorker(repo, svc, 1)
+	goalID := uuid.New()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := w.RunGoal(context.Background(), goalID, TriggerScheduled); err != nil {
+			t.Errorf("first run: %v", err)
+		}
+	}()
+	for svc.active.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := w.RunGoal(context.Background(), goalID, TriggerManual); !errors.Is(err, ErrRefillRunning) {
+		t.Errorf("overlapping run error = %v, want ErrRefillRunning", err)
+	}
+	close(svc.release)
+	<-done
+
+	run, err := w.RunGoal(context.Background(), goalID, TriggerManual)
+	if err != nil || run.Status != RunSucceeded {
+		t.Errorf("run after release = %+v, %v", run, err)
+	}
+}
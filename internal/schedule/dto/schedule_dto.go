@@ -56,3 +56,10 @@ type AutoScheduleResponse struct {
 type ToggleTaskRequest struct {
 	Done bool `json:"done"`
 }
+
+// BatchIntervalsRequest marks many scheduled intervals at once: done
+// completes them, undo returns them to scheduled and skip cancels them.
+type BatchIntervalsRequest struct {
+	Action string      `json:"action" validate:"required,oneof=done undo skip"`
+	IDs    []uuid.UUID `json:"ids" validate:"required,min=1,max=200"`
+}
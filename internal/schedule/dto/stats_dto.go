@@ -1,5 +1,7 @@
 package dto
 
+import "github.com/google/uuid"
+
 type DayStat struct {
 	Date      string `json:"date"`
 	Completed int    `json:"completed"`
@@ -9,3 +11,17 @@ type DayStat struct {
 type GetStatsResponse struct {
 	Week []DayStat `json:"week"`
 }
+
+type TagTimeStat struct {
+	TagID            uuid.UUID `json:"tag_id"`
+	Name             string    `json:"name"`
+	Color            string    `json:"color"`
+	CompletedMinutes int       `json:"completed_minutes"`
+	PlannedMinutes   int       `json:"planned_minutes"`
+}
+
+type GetTagStatsResponse struct {
+	StartDate string        `json:"start_date"`
+	EndDate   string        `json:"end_date"`
+	Tags      []TagTimeStat `json:"tags"`
+}
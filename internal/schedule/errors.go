@@ -0,0 +1,5 @@
+package schedule
+
+import "errors"
+
+var ErrIntervalNotFound = errors.New("scheduled interval not found")
@@ -2,9 +2,13 @@ package schedule
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"task-planner/internal/auth"
+	"task-planner/internal/goal"
 	"task-planner/internal/schedule/dto"
+	"task-planner/internal/tag"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -132,6 +136,7 @@ func (h *Handler) AutoSchedule(w http.ResponseWriter, r *http.Request) {
 // @Param        date        query     string  false  "Конкретный день YYYY-MM-DD"
 // @Param        start_date  query     string  false  "Начало диапазона YYYY-MM-DD"
 // @Param        end_date    query     string  false  "Конец диапазона YYYY-MM-DD"
+// @Param        tags        query     string  false  "UUID тегов через запятую: задачи с любым из тегов (у задачи или её цели)"
 // @Success      200         {object}  dto.GetScheduleForDayResponse    "Расписание за день"
 // @Success      200         {object}  dto.GetScheduleRangeResponse     "Расписание за диапазон"
 // @Failure      400         {object}  response.ErrorResponse                "Missing or invalid date parameters"
@@ -141,6 +146,11 @@ func (h *Handler) GetSchedule(w http.ResponseWriter, r *http.Request) {
 	dateStr := r.URL.Query().Get("date")
 	startDateStr := r.URL.Query().Get("start_date")
 	endDateStr := r.URL.Query().Get("end_date")
+	tagIDs, err := tag.ParseIDs(r.URL.Query().Get("tags"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	if dateStr != "" {
 		dt, err := time.Parse("2006-01-02", dateStr)
@@ -148,7 +158,7 @@ func (h *Handler) GetSchedule(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Invalid date format (YYYY-MM-DD)", http.StatusBadRequest)
 			return
 		}
-		resp, err := h.service.GetScheduleForDay(r.Context(), dt)
+		resp, err := h.service.GetScheduleForDay(r.Context(), dt, tagIDs)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -165,7 +175,7 @@ func (h *Handler) GetSchedule(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Invalid date format (YYYY-MM-DD)", http.StatusBadRequest)
 			return
 		}
-		resp, err := h.service.GetScheduleRange(r.Context(), sd, ed)
+		resp, err := h.service.GetScheduleRange(r.Context(), sd, ed, tagIDs)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -211,11 +221,73 @@ func (h *Handler) GetUpcomingTasks(w http.ResponseWriter, r *http.Request) {
 // @Tags         Schedule
 // @Security     ApiKeyAuth
 // @Produce      json
+// @Param        tags  query     string  false  "UUID тегов через запятую"
 // @Success      200  {object}  dto.GetStatsResponse   "Статистика по дням"
+// @Failure      400  {object}  response.ErrorResponse      "Invalid tags"
 // @Failure      500  {object}  response.ErrorResponse      "Internal Server Error"
 // @Router       /api/stats [get]
 func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
-	resp, err := h.service.GetStats(r.Context())
+	tagIDs, err := tag.ParseIDs(r.URL.Query().Get("tags"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.GetStats(r.Context(), tagIDs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Время по тегам
+// @Description  Возвращает запланированное и выполненное время (в минутах) по тегам пользователя. По умолчанию — за последние 7 дней
+// @Tags         Schedule
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        start_date  query     string  false  "Начало диапазона YYYY-MM-DD"
+// @Param        end_date    query     string  false  "Конец диапазона YYYY-MM-DD"
+// @Param        tags        query     string  false  "UUID тегов через запятую"
+// @Success      200         {object}  dto.GetTagStatsResponse  "Время по тегам"
+// @Failure      400         {object}  response.ErrorResponse  "Invalid date or tags"
+// @Failure      401         {object}  response.ErrorResponse  "Unauthorized"
+// @Failure      500         {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/stats/tags [get]
+func (h *Handler) GetTagStats(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	endDate := time.Now().UTC().Truncate(24 * time.Hour)
+	startDate := endDate.AddDate(0, 0, -6)
+	if s := r.URL.Query().Get("start_date"); s != "" {
+		if startDate, err = time.Parse("2006-01-02", s); err != nil {
+			http.Error(w, "Invalid date format (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+	}
+	if s := r.URL.Query().Get("end_date"); s != "" {
+		if endDate, err = time.Parse("2006-01-02", s); err != nil {
+			http.Error(w, "Invalid date format (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+	}
+	if endDate.Before(startDate) {
+		http.Error(w, "end_date must not be before start_date", http.StatusBadRequest)
+		return
+	}
+
+	tagIDs, err := tag.ParseIDs(r.URL.Query().Get("tags"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.GetTagStats(r.Context(), claims.UserID, startDate, endDate, tagIDs)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -255,3 +327,77 @@ func (h *Handler) ToggleInterval(w http.ResponseWriter, r *http.Request) {
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// @Summary      Массово отметить запланированные интервалы
+// @Description  Отмечает выполненными (done), возвращает в план (undo) или пропускает (skip) несколько интервалов за раз. Пакет применяется целиком: если хотя бы один интервал не найден, ничего не меняется и возвращается 422 с результатом по каждому интервалу
+// @Tags         Schedule
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.BatchIntervalsRequest  true  "Действие и UUID интервалов"
+// @Success      200   {object}  goaldto.BatchResponse
+// @Failure      400   {object}  response.ErrorResponse  "Invalid request"
+// @Failure      401   {object}  response.ErrorResponse  "Unauthorized"
+// @Failure      422   {object}  goaldto.BatchResponse   "Batch was not applied"
+// @Failure      500   {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/scheduled_tasks/batch [post]
+func (h *Handler) BatchIntervals(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req dto.BatchIntervalsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.BatchIntervals(r.Context(), claims.UserID, req)
+	switch {
+	case errors.Is(err, goal.ErrBatchFailed):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	case errors.Is(err, goal.ErrInvalidBatch):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("Error in BatchIntervals: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Запланировать входящие
+// @Description  Размещает незапланированные задачи из входящих в свободное время слотов всех целей пользователя на ближайшие четыре недели, сначала более важные. Задача занимает один непрерывный интервал
+// @Tags         Schedule
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Success      200  {object}  dto.AutoScheduleResponse  "Сообщение и число запланированных задач"
+// @Failure      401  {object}  response.ErrorResponse    "Unauthorized"
+// @Failure      500  {object}  response.ErrorResponse    "Internal Server Error"
+// @Router       /api/inbox/schedule [post]
+func (h *Handler) ScheduleInbox(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	count, err := h.service.ScheduleInbox(r.Context(), claims.UserID)
+	if err != nil {
+		log.Printf("Error in ScheduleInbox: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(dto.AutoScheduleResponse{
+		Message:        "Inbox scheduled",
+		ScheduledTasks: count,
+	})
+}
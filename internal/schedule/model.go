@@ -32,9 +32,22 @@ type ScheduledTask struct {
 	Status        string    `json:"status"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
+	// OccurrenceID is set when the interval belongs to one dated instance
+	// of a recurring task.
+	OccurrenceID *uuid.UUID `json:"occurrence_id,omitempty"`
 }
 
 type DayCounters struct {
 	Completed int
 	Pending   int
 }
+
+// TagMinutes is the scheduled time of one tag over a date range. Intervals
+// of a task count towards the task's own tags and the tags of its goal.
+type TagMinutes struct {
+	TagID            uuid.UUID
+	Name             string
+	Color            string
+	CompletedMinutes int
+	PlannedMinutes   int
+}
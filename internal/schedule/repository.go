@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"log"
+	"task-planner/internal/db"
 	"time"
 )
 
@@ -14,6 +15,9 @@ type Repository interface {
 	DeleteAvailabilityByGoal(ctx context.Context, goalID uuid.UUID) error
 	CreateAvailability(ctx context.Context, av *Availability) error
 	ListAvailabilityByGoal(ctx context.Context, goalID uuid.UUID) ([]Availability, error)
+	// ListAvailabilityByUser returns the availability of all the user's
+	// goals that are neither archived nor deleted.
+	ListAvailabilityByUser(ctx context.Context, userID int64) ([]Availability, error)
 
 	CreateTimeSlot(ctx context.Context, slot *TimeSlot) error
 	ListTimeSlotsByAvailabilityIDs(ctx context.Context, avIDs []uuid.UUID) ([]TimeSlot, error)
@@ -21,17 +25,24 @@ type Repository interface {
 	CreateScheduledTask(ctx context.Context, st *ScheduledTask) error
 	DeleteScheduledTasksByGoal(ctx context.Context, goalID uuid.UUID) error
 	ListScheduledTasksForDate(ctx context.Context, date time.Time) ([]ScheduledTask, error)
-	ListScheduledTasksInRange(ctx context.Context, startDate, endDate time.Time) ([]ScheduledTask, error)
+	ListScheduledTasksInRange(ctx context.Context, startDate, endDate time.Time, tagIDs []uuid.UUID) ([]ScheduledTask, error)
 	ListUpcomingTasks(ctx context.Context, limit int) ([]ScheduledTask, error)
 
 	ListScheduledTasksForGoalInRange(ctx context.Context, goalID uuid.UUID, startDate, endDate time.Time) ([]ScheduledTask, error)
+	// ListScheduledTasksForUserOnDate returns the intervals of all the
+	// user's goals on date, leaving out canceled ones.
+	ListScheduledTasksForUserOnDate(ctx context.Context, userID int64, date time.Time) ([]ScheduledTask, error)
 
 	// todo: дополнить для статы или выкинуть нафиг
-	CountTasksByDay(ctx context.Context, startDate, endDate time.Time) (map[time.Time]DayCounters, error)
+	CountTasksByDay(ctx context.Context, startDate, endDate time.Time, tagIDs []uuid.UUID) (map[time.Time]DayCounters, error)
+	SumMinutesByTag(ctx context.Context, userID int64, startDate, endDate time.Time, tagIDs []uuid.UUID) ([]TagMinutes, error)
 
 	UpdateScheduledTaskStatus(ctx context.Context, id uuid.UUID, newStatus string) error
 	GetScheduledTaskByID(ctx context.Context, id uuid.UUID) (*ScheduledTask, error)
 	SumDoneIntervalsForTask(ctx context.Context, taskID uuid.UUID) (int, error)
+
+	IsOccurrenceScheduled(ctx context.Context, occurrenceID uuid.UUID) (bool, error)
+	SumDoneIntervalsForOccurrence(ctx context.Context, occurrenceID uuid.UUID) (int, error)
 }
 
 type repositoryImpl struct {
@@ -42,9 +53,14 @@ func NewRepository(db *sql.DB) Repository {
 	return &repositoryImpl{db: db}
 }
 
+// conn returns the transaction carried by ctx, if any.
+func (r repositoryImpl) conn(ctx context.Context) db.Executor {
+	return db.Conn(ctx, r.db)
+}
+
 func (r *repositoryImpl) DeleteAvailabilityByGoal(ctx context.Context, goalID uuid.UUID) error {
 	query := `DELETE FROM availability WHERE goal_id = $1`
-	_, err := r.db.ExecContext(ctx, query, goalID)
+	_, err := r.conn(ctx).ExecContext(ctx, query, goalID)
 	if err != nil {
 		return fmt.Errorf("failed to delete old availability: %w", err)
 	}
@@ -55,7 +71,7 @@ func (r repositoryImpl) CreateAvailability(ctx context.Context, av *Availability
 	query := `INSERT INTO availability (id, goal_id, day_of_week, created_at, updated_at)
 				VALUES ($1, $2, $3, $4, $5)
 `
-	_, err := r.db.ExecContext(ctx, query, av.ID, av.GoalID, av.DayOfWeek, av.CreatedAt, av.UpdatedAt)
+	_, err := r.conn(ctx).ExecContext(ctx, query, av.ID, av.GoalID, av.DayOfWeek, av.CreatedAt, av.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create availability: %w", err)
 	}
@@ -64,7 +80,7 @@ func (r repositoryImpl) CreateAvailability(ctx context.Context, av *Availability
 
 func (r repositoryImpl) ListAvailabilityByGoal(ctx context.Context, goalID uuid.UUID) ([]Availability, error) {
 	query := `SELECT id, goal_id, day_of_week, created_at, updated_at FROM availability WHERE goal_id = $1 ORDER BY day_of_week ASC`
-	rows, err := r.db.QueryContext(ctx, query, goalID)
+	rows, err := r.conn(ctx).QueryContext(ctx, query, goalID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list availability: %w", err)
 	}
@@ -81,11 +97,34 @@ func (r repositoryImpl) ListAvailabilityByGoal(ctx context.Context, goalID uuid.
 	return result, nil
 }
 
+func (r repositoryImpl) ListAvailabilityByUser(ctx context.Context, userID int64) ([]Availability, error) {
+	query := `SELECT a.id, a.goal_id, a.day_of_week, a.created_at, a.updated_at
+		FROM availability a
+		JOIN goals g ON g.id = a.goal_id
+		WHERE g.user_id = $1 AND g.deleted_at IS NULL AND g.archived_at IS NULL
+		ORDER BY a.day_of_week ASC`
+	rows, err := r.conn(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user availability: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Availability
+	for rows.Next() {
+		var av Availability
+		if err := rows.Scan(&av.ID, &av.GoalID, &av.DayOfWeek, &av.CreatedAt, &av.UpdatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, av)
+	}
+	return result, rows.Err()
+}
+
 func (r repositoryImpl) CreateTimeSlot(ctx context.Context, slot *TimeSlot) error {
 	query := `INSERT INTO time_slot (id, availability_id, start_time, end_time, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6)
 `
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := r.conn(ctx).ExecContext(ctx, query,
 		slot.ID,
 		slot.AvailabilityID,
 		slot.StartTime,
@@ -112,7 +151,7 @@ func (r repositoryImpl) ListTimeSlotsByAvailabilityIDs(
       ORDER BY start_time
     `
 
-	rows, err := r.db.QueryContext(ctx, query, pq.Array(avIDs))
+	rows, err := r.conn(ctx).QueryContext(ctx, query, pq.Array(avIDs))
 	if err != nil {
 		return nil, fmt.Errorf("failed to list time_slots: %w", err)
 	}
@@ -137,10 +176,10 @@ func (r repositoryImpl) ListTimeSlotsByAvailabilityIDs(
 }
 
 func (r repositoryImpl) CreateScheduledTask(ctx context.Context, st *ScheduledTask) error {
-	query := `INSERT INTO scheduled_task (id, task_id, time_slot_id, scheduled_date, start_time, end_time, status, created_at, updated_at)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	query := `INSERT INTO scheduled_task (id, task_id, time_slot_id, scheduled_date, start_time, end_time, status, created_at, updated_at, occurrence_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 `
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := r.conn(ctx).ExecContext(ctx, query,
 		st.ID,
 		st.TaskID,
 		st.TimeSlotID,
@@ -150,6 +189,7 @@ func (r repositoryImpl) CreateScheduledTask(ctx context.Context, st *ScheduledTa
 		st.Status,
 		st.CreatedAt,
 		st.UpdatedAt,
+		st.OccurrenceID,
 	)
 
 	if err != nil {
@@ -163,7 +203,7 @@ func (r repositoryImpl) DeleteScheduledTasksByGoal(ctx context.Context, goalID u
 	query := `DELETE FROM scheduled_task USING tasks WHERE scheduled_task.task_id = tasks.id AND task.goal_id = $1
 
 `
-	_, err := r.db.ExecContext(ctx, query, goalID)
+	_, err := r.conn(ctx).ExecContext(ctx, query, goalID)
 	if err != nil {
 		return fmt.Errorf("failed to delete scheduled tasks by goal: %w", err)
 	}
@@ -172,9 +212,13 @@ func (r repositoryImpl) DeleteScheduledTasksByGoal(ctx context.Context, goalID u
 
 func (r repositoryImpl) ListScheduledTasksForDate(ctx context.Context, date time.Time) ([]ScheduledTask, error) {
 	query := `SELECT st.id, st.task_id, st.time_slot_id, st.scheduled_date, st.start_time, st.end_time, st.status, st.created_at, st.updated_at
-		FROM scheduled_task st WHERE st.scheduled_date = $1 ORDER BY st.start_time
+		FROM scheduled_task st
+		JOIN tasks t ON t.id = st.task_id
+		JOIN goals g ON g.id = t.goal_id
+		WHERE st.scheduled_date = $1 AND g.deleted_at IS NULL
+		ORDER BY st.start_time
 `
-	rows, err := r.db.QueryContext(ctx, query, date.Format("2006-01-02"))
+	rows, err := r.conn(ctx).QueryContext(ctx, query, date.Format("2006-01-02"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to list scheduled tasks for date: %w", err)
 	}
@@ -209,24 +253,37 @@ func (r repositoryImpl) ListScheduledTasksForDate(ctx context.Context, date time
 	return result, nil
 }
 
+// tagFilter matches scheduled intervals whose task or goal carries any of
+// the tags bound to placeholder p; an empty or NULL array matches all rows.
+func tagFilter(p string) string {
+	return `
+  AND (COALESCE(cardinality(` + p + `::uuid[]), 0) = 0
+       OR EXISTS (SELECT 1 FROM task_tags tt WHERE tt.task_id = t.id AND tt.tag_id = ANY(` + p + `))
+       OR EXISTS (SELECT 1 FROM goal_tags gt WHERE gt.goal_id = g.id AND gt.tag_id = ANY(` + p + `)))`
+}
+
 func combineDateTime(date, tm time.Time) time.Time {
 	return time.Date(date.Year(), date.Month(), date.Day(), tm.Hour(), tm.Minute(), tm.Second(), 0, time.UTC)
 }
 
-func (r repositoryImpl) ListScheduledTasksInRange(ctx context.Context, startDate, endDate time.Time) ([]ScheduledTask, error) {
+func (r repositoryImpl) ListScheduledTasksInRange(ctx context.Context, startDate, endDate time.Time, tagIDs []uuid.UUID) ([]ScheduledTask, error) {
 	query := `
 SELECT 
     st.id, st.task_id, st.time_slot_id,
     st.scheduled_date, st.start_time, st.end_time,
     st.status, st.created_at, st.updated_at
 FROM scheduled_task st
+JOIN tasks t ON t.id = st.task_id
+JOIN goals g ON g.id = t.goal_id
 WHERE st.scheduled_date >= $1
   AND st.scheduled_date <= $2
+  AND g.deleted_at IS NULL` + tagFilter("$3") + `
 ORDER BY st.scheduled_date, st.start_time
 `
-	rows, err := r.db.QueryContext(ctx, query,
+	rows, err := r.conn(ctx).QueryContext(ctx, query,
 		startDate.Format("2006-01-02"),
 		endDate.Format("2006-01-02"),
+		pq.Array(tagIDs),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list scheduled tasks in range: %w", err)
@@ -271,12 +328,16 @@ SELECT
     st.scheduled_date, st.start_time, st.end_time,
     st.status, st.created_at, st.updated_at
 FROM scheduled_task st
+JOIN tasks t ON t.id = st.task_id
+JOIN goals g ON g.id = t.goal_id
 WHERE st.scheduled_date >= $1
+  AND g.deleted_at IS NULL
+  AND g.archived_at IS NULL
 ORDER BY st.scheduled_date, st.start_time
 LIMIT %d
 `, limit)
 
-	rows, err := r.db.QueryContext(ctx, query, time.Now().Format("2006-01-02"))
+	rows, err := r.conn(ctx).QueryContext(ctx, query, time.Now().Format("2006-01-02"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to list upcoming tasks: %w", err)
 	}
@@ -312,29 +373,36 @@ LIMIT %d
 	return result, nil
 }
 
+// CountTasksByDay counts completed and pending intervals per day. Intervals
+// canceled by a batch "skip" count as neither.
 func (r *repositoryImpl) CountTasksByDay(
 	ctx context.Context,
 	startDate, endDate time.Time,
+	tagIDs []uuid.UUID,
 ) (map[time.Time]DayCounters, error) {
 	log.Printf("[CountTasksByDay] Params: start=%s, end=%s",
 		startDate.Format("2006-01-02"),
 		endDate.Format("2006-01-02"))
 
-	const query = `
+	query := `
 SELECT 
-    scheduled_date,
-    SUM(CASE WHEN status = 'completed' THEN 1 ELSE 0 END)   AS completed,
-    SUM(CASE WHEN status != 'completed' THEN 1 ELSE 0 END) AS pending
-FROM scheduled_task
-WHERE scheduled_date >= $1
-  AND scheduled_date <= $2
-GROUP BY scheduled_date
+    st.scheduled_date,
+    SUM(CASE WHEN st.status = 'completed' THEN 1 ELSE 0 END)   AS completed,
+    SUM(CASE WHEN st.status NOT IN ('completed', 'canceled') THEN 1 ELSE 0 END) AS pending
+FROM scheduled_task st
+JOIN tasks t ON t.id = st.task_id
+JOIN goals g ON g.id = t.goal_id
+WHERE st.scheduled_date >= $1
+  AND st.scheduled_date <= $2
+  AND g.deleted_at IS NULL` + tagFilter("$3") + `
+GROUP BY st.scheduled_date
 `
-	rows, err := r.db.QueryContext(
+	rows, err := r.conn(ctx).QueryContext(
 		ctx,
 		query,
 		startDate.Format("2006-01-02"),
 		endDate.Format("2006-01-02"),
+		pq.Array(tagIDs),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count tasks by day: %w", err)
@@ -385,7 +453,7 @@ WHERE scheduled_date >= $1
   )
 ORDER BY scheduled_date, start_time
 `
-	rows, err := r.db.QueryContext(ctx,
+	rows, err := r.conn(ctx).QueryContext(ctx,
 		query,
 		startDate.Format("2006-01-02"),
 		endDate.Format("2006-01-02"),
@@ -427,12 +495,49 @@ ORDER BY scheduled_date, start_time
 	return result, nil
 }
 
+func (r *repositoryImpl) ListScheduledTasksForUserOnDate(ctx context.Context, userID int64, date time.Time) ([]ScheduledTask, error) {
+	query := `
+SELECT st.id, st.task_id, st.time_slot_id, st.start_time, st.end_time, st.status
+FROM scheduled_task st
+JOIN tasks t ON t.id = st.task_id
+JOIN goals g ON g.id = t.goal_id
+WHERE g.user_id = $1
+  AND g.deleted_at IS NULL
+  AND st.scheduled_date = $2
+  AND st.status <> 'canceled'
+ORDER BY st.start_time
+`
+	rows, err := r.conn(ctx).QueryContext(ctx, query, userID, date.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user's scheduled tasks: %w", err)
+	}
+	defer rows.Close()
+
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	var result []ScheduledTask
+	for rows.Next() {
+		var st ScheduledTask
+		var startStr, endStr string
+		if err := rows.Scan(&st.ID, &st.TaskID, &st.TimeSlotID, &startStr, &endStr, &st.Status); err != nil {
+			return nil, err
+		}
+		stt, _ := time.Parse("15:04:05", startStr)
+		ett, _ := time.Parse("15:04:05", endStr)
+
+		st.ScheduledDate = day
+		st.StartTime = combineDateTime(day, stt)
+		st.EndTime = combineDateTime(day, ett)
+		result = append(result, st)
+	}
+	return result, rows.Err()
+}
+
 func (r *repositoryImpl) UpdateScheduledTaskStatus(ctx context.Context, id uuid.UUID, newStatus string) error {
 	query := ` UPDATE scheduled_task
 			 SET status = $2, updated_at = now()
 			 WHERE id = $1;
 `
-	_, err := r.db.ExecContext(ctx, query, id, newStatus)
+	_, err := r.conn(ctx).ExecContext(ctx, query, id, newStatus)
 	if err != nil {
 		return fmt.Errorf("failed to update scheduled tasks status: %w", err)
 	}
@@ -443,13 +548,13 @@ func (r repositoryImpl) GetScheduledTaskByID(
 	ctx context.Context, id uuid.UUID,
 ) (*ScheduledTask, error) {
 	q := `SELECT id, task_id, time_slot_id,
-	      scheduled_date, start_time, end_time, status, created_at, updated_at
+	      scheduled_date, start_time, end_time, status, created_at, updated_at, occurrence_id
 	      FROM scheduled_task WHERE id = $1`
 	var st ScheduledTask
 	var dateStr, stStr, etStr string
-	if err := r.db.QueryRowContext(ctx, q, id).Scan(
+	if err := r.conn(ctx).QueryRowContext(ctx, q, id).Scan(
 		&st.ID, &st.TaskID, &st.TimeSlotID,
-		&dateStr, &stStr, &etStr, &st.Status, &st.CreatedAt, &st.UpdatedAt); err != nil {
+		&dateStr, &stStr, &etStr, &st.Status, &st.CreatedAt, &st.UpdatedAt, &st.OccurrenceID); err != nil {
 		return nil, err
 	}
 	sd, _ := time.Parse("2006-01-02", dateStr)
@@ -467,12 +572,92 @@ func (r repositoryImpl) SumDoneIntervalsForTask(
 	q := `SELECT COALESCE(
 	          SUM( EXTRACT(EPOCH FROM (end_time - start_time))), 0)
 	      FROM scheduled_task
-	      WHERE task_id = $1 AND status = 'completed'`
+	      WHERE task_id = $1 AND status = 'completed' AND occurrence_id IS NULL`
 	var seconds float64
-	if err := r.db.QueryRowContext(ctx, q, taskID).Scan(&seconds); err != nil {
+	if err := r.conn(ctx).QueryRowContext(ctx, q, taskID).Scan(&seconds); err != nil {
 		return 0, err
 	}
 	minutes := int(seconds / 60)
 	log.Printf("[SumDoneIntervals] task=%s minutes=%d", taskID, minutes)
 	return minutes, nil
 }
+
+func (r repositoryImpl) IsOccurrenceScheduled(ctx context.Context, occurrenceID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.conn(ctx).QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM scheduled_task WHERE occurrence_id = $1)`,
+		occurrenceID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check occurrence schedule: %w", err)
+	}
+	return exists, nil
+}
+
+func (r repositoryImpl) SumDoneIntervalsForOccurrence(
+	ctx context.Context, occurrenceID uuid.UUID,
+) (int, error) {
+	q := `SELECT COALESCE(
+	          SUM( EXTRACT(EPOCH FROM (end_time - start_time))), 0)
+	      FROM scheduled_task
+	      WHERE occurrence_id = $1 AND status = 'completed'`
+	var seconds float64
+	if err := r.conn(ctx).QueryRowContext(ctx, q, occurrenceID).Scan(&seconds); err != nil {
+		return 0, err
+	}
+	return int(seconds / 60), nil
+}
+
+func (r *repositoryImpl) SumMinutesByTag(
+	ctx context.Context,
+	userID int64,
+	startDate, endDate time.Time,
+	tagIDs []uuid.UUID,
+) ([]TagMinutes, error) {
+	const query = `
+WITH tagged AS (
+    SELECT tt.task_id, tt.tag_id FROM task_tags tt
+    UNION
+    SELECT t.id, gt.tag_id FROM tasks t JOIN goal_tags gt ON gt.goal_id = t.goal_id
+)
+SELECT
+    tg.id, tg.name, tg.color,
+    COALESCE(SUM(EXTRACT(EPOCH FROM st.end_time - st.start_time) / 60)
+             FILTER (WHERE st.status = 'completed'), 0)::int AS completed,
+    COALESCE(SUM(EXTRACT(EPOCH FROM st.end_time - st.start_time) / 60), 0)::int AS planned
+FROM scheduled_task st
+JOIN tasks t ON t.id = st.task_id
+JOIN goals g ON g.id = t.goal_id
+JOIN tagged x ON x.task_id = t.id
+JOIN tags tg ON tg.id = x.tag_id
+WHERE st.scheduled_date >= $1
+  AND st.scheduled_date <= $2
+  AND g.deleted_at IS NULL
+  AND tg.user_id = $3
+  AND (COALESCE(cardinality($4::uuid[]), 0) = 0 OR tg.id = ANY($4))
+GROUP BY tg.id, tg.name, tg.color
+ORDER BY completed DESC, LOWER(tg.name)
+`
+	rows, err := r.conn(ctx).QueryContext(ctx, query,
+		startDate.Format("2006-01-02"),
+		endDate.Format("2006-01-02"),
+		userID,
+		pq.Array(tagIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum minutes by tag: %w", err)
+	}
+	defer rows.Close()
+
+	var result []TagMinutes
+	for rows.Next() {
+		var tm TagMinutes
+		if err := rows.Scan(&tm.TagID, &tm.Name, &tm.Color, &tm.CompletedMinutes, &tm.PlannedMinutes); err != nil {
+			return nil, err
+		}
+		result = append(result, tm)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return result, nil
+}
@@ -9,6 +9,8 @@ import (
 	"log"
 	"sort"
 	"task-planner/internal/goal"
+	goaldto "task-planner/internal/goal/dto"
+	"task-planner/internal/inbox"
 	"task-planner/internal/schedule/dto"
 	"time"
 )
@@ -19,24 +21,31 @@ type Service interface {
 
 	AutoScheduleForGoal(ctx context.Context, goalID uuid.UUID) (int, error)
 
-	GetScheduleForDay(ctx context.Context, date time.Time) (*dto.GetScheduleForDayResponse, error)
-	GetScheduleRange(ctx context.Context, startDate, endDate time.Time) (*dto.GetScheduleRangeResponse, error)
+	GetScheduleForDay(ctx context.Context, date time.Time, tagIDs []uuid.UUID) (*dto.GetScheduleForDayResponse, error)
+	GetScheduleRange(ctx context.Context, startDate, endDate time.Time, tagIDs []uuid.UUID) (*dto.GetScheduleRangeResponse, error)
 	GetUpcomingTasks(ctx context.Context, limit int) (*dto.GetUpcomingTasksResponse, error)
-	GetStats(ctx context.Context) (*dto.GetStatsResponse, error)
+	GetStats(ctx context.Context, tagIDs []uuid.UUID) (*dto.GetStatsResponse, error)
+	GetTagStats(ctx context.Context, userID int64, startDate, endDate time.Time, tagIDs []uuid.UUID) (*dto.GetTagStatsResponse, error)
 	ToggleScheduledTask(ctx context.Context, intervalID uuid.UUID, markDone bool) error
+	BatchIntervals(ctx context.Context, userID int64, req dto.BatchIntervalsRequest) (*goaldto.BatchResponse, error)
+	// ScheduleInbox books the user's unplanned inbox items into the time
+	// left free in the slots of their goals and returns how many it booked.
+	ScheduleInbox(ctx context.Context, userID int64) (int, error)
 }
 
 type service struct {
-	db       *sql.DB
-	repo     Repository
-	goalRepo goal.RepositoryAggregator
+	db        *sql.DB
+	repo      Repository
+	goalRepo  goal.RepositoryAggregator
+	inboxRepo inbox.Repository
 }
 
-func NewService(db *sql.DB, repo Repository, goalRepo goal.RepositoryAggregator) Service {
+func NewService(db *sql.DB, repo Repository, goalRepo goal.RepositoryAggregator, inboxRepo inbox.Repository) Service {
 	return &service{
-		db:       db,
-		repo:     repo,
-		goalRepo: goalRepo,
+		db:        db,
+		repo:      repo,
+		goalRepo:  goalRepo,
+		inboxRepo: inboxRepo,
 	}
 }
 
@@ -199,13 +208,48 @@ func (s *service) AutoScheduleForGoal(ctx context.Context, goalID uuid.UUID) (in
 		return 0, fmt.Errorf("list tasks: %w", err)
 	}
 
+	// Phases behind an unachieved milestone are not worked on yet.
+	locked, err := goal.LockedPhaseIDs(ctx, s.goalRepo, goalID)
+	if err != nil {
+		return 0, fmt.Errorf("list locked phases: %w", err)
+	}
+
+	g, err := s.goalRepo.GetGoalByID(ctx, goalID)
+	if err != nil {
+		return 0, fmt.Errorf("get goal: %w", err)
+	}
+	if g == nil {
+		err = goal.ErrGoalNotFound
+		return 0, err
+	}
+	factor, err := goal.CalibrationFactor(ctx, s.goalRepo, g.UserId)
+	if err != nil {
+		return 0, fmt.Errorf("get calibration: %w", err)
+	}
+
 	var tasksToSchedule []plannedTask
+	var recurring []recurringTask
 	for _, t := range tasks {
 		log.Printf("[AutoSchedule] task %s status=%q est=%d", t.ID, t.Status, t.EstimatedTime)
+		if t.PhaseId != nil && locked[*t.PhaseId] {
+			continue
+		}
+		if t.IsRecurring() {
+			if t.Status == goal.TaskStatusBlocked || t.Status == goal.TaskStatusCompleted {
+				continue
+			}
+			rule, err := goal.ParseRecurrence(*t.RecurrenceRule)
+			if err != nil {
+				log.Printf("[AutoSchedule] task %s has invalid recurrence %q: %v", t.ID, *t.RecurrenceRule, err)
+				continue
+			}
+			recurring = append(recurring, recurringTask{Task: t, Rule: rule})
+			continue
+		}
 		if t.Status != "todo" {
 			continue
 		}
-		toPlanMinutes := t.EstimatedTime * 60
+		toPlanMinutes := t.PlannedMinutes(factor)
 		if toPlanMinutes <= 0 {
 			continue
 		}
@@ -214,9 +258,14 @@ func (s *service) AutoScheduleForGoal(ctx context.Context, goalID uuid.UUID) (in
 			RemainingTime: toPlanMinutes,
 		})
 	}
-	if len(tasksToSchedule) == 0 {
+	if len(tasksToSchedule) == 0 && len(recurring) == 0 {
 		return 0, nil
 	}
+	// Higher priority work gets the earliest slots; tasks of equal priority
+	// keep their original order.
+	sort.SliceStable(tasksToSchedule, func(i, j int) bool {
+		return tasksToSchedule[i].Task.Priority > tasksToSchedule[j].Task.Priority
+	})
 
 	avList, err := s.repo.ListAvailabilityByGoal(ctx, goalID)
 	log.Printf("[AutoSchedule] availability for goal %s: %+v", goalID, avList)
@@ -269,6 +318,14 @@ OUTER:
 			continue
 		}
 
+		// Dated instances of recurring tasks only fit their own day, so they
+		// take their slots before one-off work is spread over the horizon.
+		placed, err := s.placeOccurrences(ctx, recurring, currentDate, freeIntervals)
+		if err != nil {
+			return totalScheduled, err
+		}
+		totalScheduled += placed
+
 		for fiIdx, fi := range freeIntervals {
 			if fi.duration() <= 0 {
 				continue
@@ -330,7 +387,7 @@ OUTER:
 						break
 					}
 				}
-				if allDone {
+				if allDone && len(recurring) == 0 {
 					break OUTER
 				}
 			}
@@ -356,6 +413,155 @@ type plannedTask struct {
 	RemainingTime int
 }
 
+type recurringTask struct {
+	Task goal.Task
+	Rule *goal.Recurrence
+}
+
+// placeOccurrences books the instances of recurring tasks due on day into
+// the first free interval long enough to hold them. Instances that are
+// already scheduled, done or skipped are left alone.
+func (s *service) placeOccurrences(
+	ctx context.Context, recurring []recurringTask, day time.Time, intervals []freeInterval,
+) (int, error) {
+	placed := 0
+	for _, rt := range recurring {
+		if !rt.Rule.Occurs(rt.Task.CreatedAt, day) {
+			continue
+		}
+		occ, err := s.goalRepo.EnsureOccurrence(ctx, rt.Task.ID, day)
+		if err != nil {
+			return placed, err
+		}
+		if occ.Status != goal.OccurrencePending {
+			continue
+		}
+		scheduled, err := s.repo.IsOccurrenceScheduled(ctx, occ.ID)
+		if err != nil {
+			return placed, err
+		}
+		if scheduled {
+			continue
+		}
+
+		needed := rt.Task.OccurrenceDuration()
+		for i := range intervals {
+			if intervals[i].duration() < needed {
+				continue
+			}
+			start := intervals[i].Start
+			end := start.Add(time.Duration(needed) * time.Minute)
+			occID := occ.ID
+			sch := &ScheduledTask{
+				ID:            uuid.New(),
+				TaskID:        rt.Task.ID,
+				TimeSlotID:    intervals[i].SlotID,
+				ScheduledDate: day,
+				StartTime:     start,
+				EndTime:       end,
+				Status:        "scheduled",
+				CreatedAt:     time.Now(),
+				OccurrenceID:  &occID,
+			}
+			if err := s.repo.CreateScheduledTask(ctx, sch); err != nil {
+				return placed, fmt.Errorf("create scheduled occurrence: %w", err)
+			}
+			intervals[i].Start = end
+			placed++
+			break
+		}
+	}
+	return placed, nil
+}
+
+// ScheduleInbox walks the next four weeks day by day, starting from now,
+// and gives each unplanned inbox item with an estimate the earliest free
+// interval long enough to hold it whole, highest priority first. Free time
+// is the user's, across all goals. Items that fit nowhere stay unplanned
+// until the next run.
+func (s *service) ScheduleInbox(ctx context.Context, userID int64) (int, error) {
+	placed := 0
+	err := s.goalRepo.InTx(ctx, func(ctx context.Context) error {
+		items, err := s.inboxRepo.ListItems(ctx, userID)
+		if err != nil {
+			return err
+		}
+		var pending []inbox.Item
+		for _, it := range items {
+			if it.Placement == nil && it.EstimatedMinutes > 0 {
+				pending = append(pending, it)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		sort.SliceStable(pending, func(i, j int) bool {
+			return pending[i].Priority > pending[j].Priority
+		})
+		factor, err := goal.CalibrationFactor(ctx, s.goalRepo, userID)
+		if err != nil {
+			return err
+		}
+
+		avList, err := s.repo.ListAvailabilityByUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		daySlotsMap, err := s.loadSlotsByDayOfWeek(ctx, avList)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		today := dateOnly(now)
+		// Nothing is booked into the part of today that has already passed.
+		earliest := combineDateTime(today, now).Truncate(time.Minute).Add(time.Minute)
+		horizon := 28
+		for dayOffset := 0; dayOffset < horizon && len(pending) > 0; dayOffset++ {
+			day := today.AddDate(0, 0, dayOffset)
+			notBefore := combineDateTime(day, time.Time{})
+			if dayOffset == 0 {
+				notBefore = earliest
+			}
+
+			// Free time is shared by all the user's goals and never
+			// overlaps, so each booking only shrinks the interval it took.
+			free, err := s.calcUserFreeIntervals(ctx, userID, day, daySlotsMap[int(day.Weekday())], notBefore)
+			if err != nil {
+				return err
+			}
+
+			var rest []inbox.Item
+			for _, it := range pending {
+				booked, ok := bookFreeInterval(free, goal.CalibrateMinutes(it.EstimatedMinutes, factor))
+				if !ok {
+					rest = append(rest, it)
+					continue
+				}
+				it.Placement = &inbox.Placement{
+					ItemID:     it.ID,
+					TimeSlotID: booked.SlotID,
+					Date:       day,
+					StartTime:  booked.Start,
+					EndTime:    booked.End,
+				}
+				it.UpdatedAt = time.Now()
+				if err := s.inboxRepo.UpdateItem(ctx, &it); err != nil {
+					return err
+				}
+				placed++
+			}
+			pending = rest
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("[ScheduleInbox] user=%d placed=%d", userID, placed)
+	return placed, nil
+}
+
 func (s *service) loadSlotsByDayOfWeek(ctx context.Context, avList []Availability) (map[int][]TimeSlot, error) {
 	dayMap := make(map[int][]TimeSlot)
 	if len(avList) == 0 {
@@ -376,6 +582,97 @@ func (s *service) loadSlotsByDayOfWeek(ctx context.Context, avList []Availabilit
 	return dayMap, nil
 }
 
+// calcUserFreeIntervals returns the user's free time on day across the
+// slots of all their goals, see userFreeIntervals. Intervals of every goal
+// and booked inbox items are busy.
+func (s *service) calcUserFreeIntervals(
+	ctx context.Context, userID int64, day time.Time, slots []TimeSlot, notBefore time.Time,
+) ([]freeInterval, error) {
+	if len(slots) == 0 {
+		return nil, nil
+	}
+	stList, err := s.repo.ListScheduledTasksForUserOnDate(ctx, userID, day)
+	if err != nil {
+		return nil, err
+	}
+	slotIDs := make([]uuid.UUID, 0, len(slots))
+	for _, slot := range slots {
+		slotIDs = append(slotIDs, slot.ID)
+	}
+	placements, err := s.inboxRepo.ListPlacements(ctx, slotIDs, day)
+	if err != nil {
+		return nil, err
+	}
+
+	var busy []timeRange
+	for _, st := range stList {
+		busy = append(busy, timeRange{start: st.StartTime, end: st.EndTime})
+	}
+	for _, p := range placements {
+		busy = append(busy, timeRange{start: p.StartTime, end: p.EndTime})
+	}
+	return userFreeIntervals(day, slots, busy, notBefore), nil
+}
+
+// userFreeIntervals returns the free time on day in slots, which may belong
+// to different goals and overlap, so each moment is offered once, under the
+// earliest slot covering it. Time in busy and anything before notBefore is
+// taken. The intervals are ordered by start.
+func userFreeIntervals(day time.Time, slots []TimeSlot, busy []timeRange, notBefore time.Time) []freeInterval {
+	busy = append([]timeRange{{start: combineDateTime(day, time.Time{}), end: notBefore}}, busy...)
+
+	ordered := append([]TimeSlot(nil), slots...)
+	sort.Slice(ordered, func(i, j int) bool {
+		return combineDateTime(day, ordered[i].StartTime).Before(combineDateTime(day, ordered[j].StartTime))
+	})
+
+	var result []freeInterval
+	for _, slot := range ordered {
+		slotStart := combineDateTime(day, slot.StartTime)
+		slotEnd := combineDateTime(day, slot.EndTime)
+
+		var occupied []timeRange
+		for _, rng := range busy {
+			if rng.start.Before(slotStart) {
+				rng.start = slotStart
+			}
+			if rng.end.After(slotEnd) {
+				rng.end = slotEnd
+			}
+			if rng.start.Before(rng.end) {
+				occupied = append(occupied, rng)
+			}
+		}
+		for _, f := range subtractTimeRanges(slotStart, slotEnd, mergeTimeRanges(occupied)) {
+			if f.end.After(f.start) {
+				result = append(result, freeInterval{SlotID: slot.ID, Start: f.start, End: f.end})
+			}
+		}
+		// Later slots only offer what this one does not cover.
+		busy = append(busy, timeRange{start: slotStart, end: slotEnd})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+	return result
+}
+
+// bookFreeInterval takes minutes from the start of the earliest interval in
+// free long enough to hold them whole, shrinking that interval, and returns
+// the part taken. It reports false when no interval is long enough.
+func bookFreeInterval(free []freeInterval, minutes int) (freeInterval, bool) {
+	for i := range free {
+		if free[i].duration() < minutes {
+			continue
+		}
+		booked := free[i]
+		booked.End = booked.Start.Add(time.Duration(minutes) * time.Minute)
+		free[i].Start = booked.End
+		return booked, true
+	}
+	return freeInterval{}, false
+}
+
 func (s *service) calcFreeIntervals(ctx context.Context, goalID uuid.UUID, day time.Time, slots []TimeSlot) ([]freeInterval, error) {
 	if len(slots) == 0 {
 		return nil, nil
@@ -391,27 +688,38 @@ func (s *service) calcFreeIntervals(ctx context.Context, goalID uuid.UUID, day t
 		}
 	}
 
+	// Inbox items booked into leftover time hold their part of the slot too.
+	slotIDs := make([]uuid.UUID, 0, len(slots))
+	for _, slot := range slots {
+		slotIDs = append(slotIDs, slot.ID)
+	}
+	placements, err := s.inboxRepo.ListPlacements(ctx, slotIDs, day)
+	if err != nil {
+		return nil, err
+	}
+	busy := make(map[uuid.UUID][]timeRange)
+	for _, st := range stSameDay {
+		busy[st.TimeSlotID] = append(busy[st.TimeSlotID], timeRange{start: st.StartTime, end: st.EndTime})
+	}
+	for _, p := range placements {
+		busy[p.TimeSlotID] = append(busy[p.TimeSlotID], timeRange{start: p.StartTime, end: p.EndTime})
+	}
+
 	var result []freeInterval
 	for _, slot := range slots {
 		slotStart := combineDateTime(day, slot.StartTime)
 		slotEnd := combineDateTime(day, slot.EndTime)
 
 		var occupied []timeRange
-		for _, st := range stSameDay {
-			if st.TimeSlotID == slot.ID {
-				rng := timeRange{
-					start: st.StartTime,
-					end:   st.EndTime,
-				}
-				if rng.start.Before(slotStart) {
-					rng.start = slotStart
-				}
-				if rng.end.After(slotEnd) {
-					rng.end = slotEnd
-				}
-				if rng.start.Before(rng.end) {
-					occupied = append(occupied, rng)
-				}
+		for _, rng := range busy[slot.ID] {
+			if rng.start.Before(slotStart) {
+				rng.start = slotStart
+			}
+			if rng.end.After(slotEnd) {
+				rng.end = slotEnd
+			}
+			if rng.start.Before(rng.end) {
+				occupied = append(occupied, rng)
 			}
 		}
 		merged := mergeTimeRanges(occupied)
@@ -496,8 +804,8 @@ func dateOnly(t time.Time) time.Time {
 	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
 }
 
-func (s *service) GetScheduleForDay(ctx context.Context, date time.Time) (*dto.GetScheduleForDayResponse, error) {
-	scheduledList, err := s.repo.ListScheduledTasksInRange(ctx, date, date)
+func (s *service) GetScheduleForDay(ctx context.Context, date time.Time, tagIDs []uuid.UUID) (*dto.GetScheduleForDayResponse, error) {
+	scheduledList, err := s.repo.ListScheduledTasksInRange(ctx, date, date, tagIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list scheduled tasks for day: %w", err)
 	}
@@ -529,8 +837,8 @@ func (s *service) GetScheduleForDay(ctx context.Context, date time.Time) (*dto.G
 	return resp, nil
 }
 
-func (s *service) GetScheduleRange(ctx context.Context, startDate, endDate time.Time) (*dto.GetScheduleRangeResponse, error) {
-	scheduledList, err := s.repo.ListScheduledTasksInRange(ctx, startDate, endDate)
+func (s *service) GetScheduleRange(ctx context.Context, startDate, endDate time.Time, tagIDs []uuid.UUID) (*dto.GetScheduleRangeResponse, error) {
+	scheduledList, err := s.repo.ListScheduledTasksInRange(ctx, startDate, endDate, tagIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list scheduled tasks in range: %w", err)
 	}
@@ -601,11 +909,11 @@ func (s *service) GetUpcomingTasks(ctx context.Context, limit int) (*dto.GetUpco
 	return &dto.GetUpcomingTasksResponse{Tasks: items}, nil
 }
 
-func (s *service) GetStats(ctx context.Context) (*dto.GetStatsResponse, error) {
+func (s *service) GetStats(ctx context.Context, tagIDs []uuid.UUID) (*dto.GetStatsResponse, error) {
 	today := time.Now().UTC().Truncate(24 * time.Hour)
 	weekStart := today.AddDate(0, 0, -6)
 
-	raw, err := s.repo.CountTasksByDay(ctx, weekStart, today)
+	raw, err := s.repo.CountTasksByDay(ctx, weekStart, today, tagIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -628,6 +936,32 @@ func (s *service) GetStats(ctx context.Context) (*dto.GetStatsResponse, error) {
 	return &dto.GetStatsResponse{Week: ds}, nil
 }
 
+func (s *service) GetTagStats(
+	ctx context.Context, userID int64, startDate, endDate time.Time, tagIDs []uuid.UUID,
+) (*dto.GetTagStatsResponse, error) {
+	rows, err := s.repo.SumMinutesByTag(ctx, userID, startDate, endDate, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]dto.TagTimeStat, 0, len(rows))
+	for _, r := range rows {
+		stats = append(stats, dto.TagTimeStat{
+			TagID:            r.TagID,
+			Name:             r.Name,
+			Color:            r.Color,
+			CompletedMinutes: r.CompletedMinutes,
+			PlannedMinutes:   r.PlannedMinutes,
+		})
+	}
+
+	return &dto.GetTagStatsResponse{
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+		Tags:      stats,
+	}, nil
+}
+
 func (s *service) loadTasksAndGoals(
 	ctx context.Context,
 	scheduledList []ScheduledTask,
@@ -682,88 +1016,155 @@ func (s *service) ToggleScheduledTask(ctx context.Context, intervalID uuid.UUID,
 	if markDone {
 		newStatus = "completed"
 	}
-	if err := s.repo.UpdateScheduledTaskStatus(ctx, intervalID, newStatus); err != nil {
-		return err
-	}
-	log.Printf("[ToggleScheduledTask] interval=%s status set to %s", intervalID, newStatus)
+	return s.goalRepo.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateScheduledTaskStatus(ctx, intervalID, newStatus); err != nil {
+			return err
+		}
+		log.Printf("[ToggleScheduledTask] interval=%s status set to %s", intervalID, newStatus)
 
-	st, err := s.repo.GetScheduledTaskByID(ctx, intervalID)
-	if err != nil {
-		return err
-	}
-	log.Printf("[ToggleScheduledTask] loaded ScheduledTask: taskID=%s date=%s start=%s end=%s", st.TaskID, st.ScheduledDate.Format("2006-01-02"), st.StartTime.Format("15:04"), st.EndTime.Format("15:04"))
+		st, err := s.repo.GetScheduledTaskByID(ctx, intervalID)
+		if err != nil {
+			return err
+		}
+		log.Printf("[ToggleScheduledTask] loaded ScheduledTask: taskID=%s date=%s start=%s end=%s", st.TaskID, st.ScheduledDate.Format("2006-01-02"), st.StartTime.Format("15:04"), st.EndTime.Format("15:04"))
 
-	totalSpent, err := s.repo.SumDoneIntervalsForTask(ctx, st.TaskID)
-	if err != nil {
-		return err
-	}
-	if err := s.goalRepo.UpdateTaskTimeSpent(ctx, st.TaskID, totalSpent); err != nil {
-		return err
-	}
+		t, err := s.goalRepo.GetTaskByID(ctx, st.TaskID)
+		if err != nil {
+			return err
+		}
+		details := map[string]interface{}{
+			"task_id": st.TaskID,
+			"done":    markDone,
+			"date":    st.ScheduledDate.Format("2006-01-02"),
+			"minutes": int(st.EndTime.Sub(st.StartTime).Minutes()),
+		}
+		if err := goal.RecordActivity(ctx, s.goalRepo, t.GoalId, goal.EntityInterval, st.ID, goal.ActionIntervalToggled, details); err != nil {
+			return err
+		}
 
-	return s.recalcProgressCascade(ctx, st.TaskID)
-}
+		// Time on a recurring task belongs to that day's instance, not the task.
+		if st.OccurrenceID != nil {
+			spent, err := s.repo.SumDoneIntervalsForOccurrence(ctx, *st.OccurrenceID)
+			if err != nil {
+				return err
+			}
+			return goal.RecordOccurrenceTime(ctx, s.goalRepo, *st.OccurrenceID, spent)
+		}
 
-func (s *service) recalcProgressCascade(ctx context.Context, taskID uuid.UUID) error {
+		totalSpent, err := s.repo.SumDoneIntervalsForTask(ctx, st.TaskID)
+		if err != nil {
+			return err
+		}
+		if err := s.goalRepo.UpdateTaskTimeSpent(ctx, st.TaskID, totalSpent); err != nil {
+			return err
+		}
 
-	log.Printf("[recalcProgressCascade] start for task %s", taskID)
-	t, err := s.goalRepo.GetTaskByID(ctx, taskID)
-	if err != nil {
-		return err
-	}
+		return s.recalcProgressCascade(ctx, st.TaskID)
+	})
+}
 
-	log.Printf("[recalcProgressCascade] before: TimeSpent=%d EstimatedTime=%d Status=%s", t.TimeSpent, t.EstimatedTime, t.Status)
-	progress := t.CalculateProgress()
-	log.Printf("[recalcProgressCascade] computed progress=%d%%", progress)
-	switch progress {
-	case 0:
-		t.Status = "todo"
-	case 100:
-		t.Status = "completed"
-	default:
-		t.Status = "in_progress"
-	}
-	if err := s.goalRepo.UpdateTask(ctx, t); err != nil {
-		return err
-	}
+var batchIntervalStatus = map[string]string{
+	"done": "completed",
+	"undo": "scheduled",
+	"skip": "canceled",
+}
 
-	allTasks, err := s.goalRepo.ListTasksByGoalID(ctx, t.GoalId)
-	if err != nil {
-		return err
-	}
+// BatchIntervals sets the status of many intervals in one transaction. Time
+// is re-summed once per affected task or occurrence and progress once per
+// goal. When any interval fails nothing is changed and the per-item results
+// come back with goal.ErrBatchFailed.
+func (s *service) BatchIntervals(
+	ctx context.Context, userID int64, req dto.BatchIntervalsRequest,
+) (*goaldto.BatchResponse, error) {
+	newStatus, ok := batchIntervalStatus[req.Action]
+	if !ok || len(req.IDs) == 0 || len(req.IDs) > goal.MaxBatchSize {
+		return nil, goal.ErrInvalidBatch
+	}
+
+	var resp *goaldto.BatchResponse
+	err := s.goalRepo.InTx(ctx, func(ctx context.Context) error {
+		tasks := make(map[uuid.UUID]bool)
+		occurrences := make(map[uuid.UUID]bool)
+		var err error
+		resp, err = goal.RunBatch(req.IDs, func(id uuid.UUID) error {
+			st, err := s.repo.GetScheduledTaskByID(ctx, id)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrIntervalNotFound
+			}
+			if err != nil {
+				return err
+			}
+			t, err := s.goalRepo.GetTaskByID(ctx, st.TaskID)
+			if err != nil {
+				return err
+			}
+			g, err := s.goalRepo.GetGoalByID(ctx, t.GoalId)
+			if err != nil {
+				return err
+			}
+			if g == nil || g.UserId != userID {
+				return ErrIntervalNotFound
+			}
 
-	if t.PhaseId != nil {
-		ph, err := s.goalRepo.GetPhaseByID(ctx, *t.PhaseId)
+			if err := s.repo.UpdateScheduledTaskStatus(ctx, st.ID, newStatus); err != nil {
+				return err
+			}
+			details := map[string]interface{}{
+				"task_id": st.TaskID,
+				"done":    newStatus == "completed",
+				"status":  newStatus,
+				"date":    st.ScheduledDate.Format("2006-01-02"),
+				"minutes": int(st.EndTime.Sub(st.StartTime).Minutes()),
+			}
+			if err := goal.RecordActivity(ctx, s.goalRepo, t.GoalId, goal.EntityInterval, st.ID, goal.ActionIntervalToggled, details); err != nil {
+				return err
+			}
+			if st.OccurrenceID != nil {
+				occurrences[*st.OccurrenceID] = true
+			} else {
+				tasks[st.TaskID] = true
+			}
+			return nil
+		}, func(err error) bool { return errors.Is(err, ErrIntervalNotFound) })
 		if err != nil {
 			return err
 		}
-		ph.Progress = ph.CalculateProgress(allTasks)
-		switch {
-		case ph.Progress == 0:
-			ph.Status = "not_started"
-		case ph.Progress == 100:
-			ph.Status = "completed"
-			ph.MarkCompleted()
-		default:
-			ph.Status = "in_progress"
-			ph.MarkStarted()
-		}
-		if err := s.goalRepo.UpdatePhase(ctx, ph); err != nil {
-			return err
+
+		for id := range occurrences {
+			spent, err := s.repo.SumDoneIntervalsForOccurrence(ctx, id)
+			if err != nil {
+				return err
+			}
+			if err := goal.RecordOccurrenceTime(ctx, s.goalRepo, id, spent); err != nil {
+				return err
+			}
 		}
+		touched := goal.ProgressBatch{}
+		for id := range tasks {
+			spent, err := s.repo.SumDoneIntervalsForTask(ctx, id)
+			if err != nil {
+				return err
+			}
+			if err := s.goalRepo.UpdateTaskTimeSpent(ctx, id, spent); err != nil {
+				return err
+			}
+			t, err := goal.RecalcTaskStatus(ctx, s.goalRepo, id)
+			if err != nil {
+				return err
+			}
+			touched.Touch(t.GoalId, t.PhaseId)
+		}
+		return touched.Recalc(ctx, s.goalRepo)
+	})
+	if errors.Is(err, goal.ErrBatchFailed) {
+		return resp, err
 	}
-	log.Printf("[recalcProgressCascade] after: Status=%s", t.Status)
-
-	g, err := s.goalRepo.GetGoalByID(ctx, t.GoalId)
 	if err != nil {
-		return err
-	}
-	g.Progress = g.CalculateProgress(allTasks)
-	switch {
-	case g.Progress == 100:
-		g.Status = "completed"
-	default:
-		g.Status = "active"
+		return nil, err
 	}
-	return s.goalRepo.UpdateGoal(ctx, g)
+	return resp, nil
+}
+
+func (s *service) recalcProgressCascade(ctx context.Context, taskID uuid.UUID) error {
+	return goal.RecalcTaskCascade(ctx, s.goalRepo, taskID)
 }
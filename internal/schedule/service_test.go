@@ -0,0 +1,166 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var testDay = time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+
+// clock returns the time of day h:m as a slot stores it.
+func clock(h, m int) time.Time {
+	return time.Date(0, 1, 1, h, m, 0, 0, time.UTC)
+}
+
+// at returns h:m on testDay.
+func at(h, m int) time.Time {
+	return combineDateTime(testDay, clock(h, m))
+}
+
+func slot(id uuid.UUID, fromH, fromM, toH, toM int) TimeSlot {
+	return TimeSlot{ID: id, StartTime: clock(fromH, fromM), EndTime: clock(toH, toM)}
+}
+
+func TestUserFreeIntervals(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	midnight := at(0, 0)
+	tests := []struct {
+		name      string
+		slots     []TimeSlot
+		busy      []timeRange
+		notBefore time.Time
+		want      []freeInterval
+	}{
+		{
+			name:      "no slots",
+			notBefore: midnight,
+		},
+		{
+			name:      "overlapping slots of two goals are offered once",
+			slots:     []TimeSlot{slot(b, 11, 0, 14, 0), slot(a, 9, 0, 12, 0)},
+			notBefore: midnight,
+			want: []freeInterval{
+				{SlotID: a, Start: at(9, 0), End: at(12, 0)},
+				{SlotID: b, Start: at(12, 0), End: at(14, 0)},
+			},
+		},
+		{
+			name:      "slot inside another adds nothing",
+			slots:     []TimeSlot{slot(a, 9, 0, 14, 0), slot(b, 10, 0, 11, 0)},
+			notBefore: midnight,
+			want:      []freeInterval{{SlotID: a, Start: at(9, 0), End: at(14, 0)}},
+		},
+		{
+			name:      "booking spanning two slots",
+			slots:     []TimeSlot{slot(a, 9, 0, 11, 0), slot(b, 11, 0, 13, 0)},
+			busy:      []timeRange{{start: at(10, 0), end: at(12, 0)}},
+			notBefore: midnight,
+			want: []freeInterval{
+				{SlotID: a, Start: at(9, 0), End: at(10, 0)},
+				{SlotID: b, Start: at(12, 0), End: at(13, 0)},
+			},
+		},
+		{
+			name:  "busy intervals and bookings split a slot",
+			slots: []TimeSlot{slot(a, 9, 0, 13, 0)},
+			busy: []timeRange{
+				{start: at(11, 0), end: at(11, 30)},
+				{start: at(9, 30), end: at(10, 0)},
+				{start: at(10, 45), end: at(11, 15)},
+			},
+			notBefore: midnight,
+			want: []freeInterval{
+				{SlotID: a, Start: at(9, 0), End: at(9, 30)},
+				{SlotID: a, Start: at(10, 0), End: at(10, 45)},
+				{SlotID: a, Start: at(11, 30), End: at(13, 0)},
+			},
+		},
+		{
+			name:      "time already passed today is taken",
+			slots:     []TimeSlot{slot(a, 7, 0, 8, 0), slot(b, 9, 0, 12, 0)},
+			notBefore: at(10, 31),
+			want:      []freeInterval{{SlotID: b, Start: at(10, 31), End: at(12, 0)}},
+		},
+		{
+			name:      "day already over",
+			slots:     []TimeSlot{slot(a, 9, 0, 12, 0)},
+			notBefore: at(23, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := userFreeIntervals(testDay, tt.slots, tt.busy, tt.notBefore)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("free = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBookFreeInterval(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	tests := []struct {
+		name     string
+		minutes  int
+		want     freeInterval
+		wantOK   bool
+		wantFree []freeInterval
+	}{
+		{
+			name:    "earliest interval that fits",
+			minutes: 30,
+			want:    freeInterval{SlotID: a, Start: at(9, 0), End: at(9, 30)},
+			wantOK:  true,
+			wantFree: []freeInterval{
+				{SlotID: a, Start: at(9, 30), End: at(9, 45)},
+				{SlotID: b, Start: at(13, 0), End: at(15, 0)},
+			},
+		},
+		{
+			name:    "skips intervals that are too short",
+			minutes: 60,
+			want:    freeInterval{SlotID: b, Start: at(13, 0), End: at(14, 0)},
+			wantOK:  true,
+			wantFree: []freeInterval{
+				{SlotID: a, Start: at(9, 0), End: at(9, 45)},
+				{SlotID: b, Start: at(14, 0), End: at(15, 0)},
+			},
+		},
+		{
+			name:    "exact fit leaves an empty interval",
+			minutes: 120,
+			want:    freeInterval{SlotID: b, Start: at(13, 0), End: at(15, 0)},
+			wantOK:  true,
+			wantFree: []freeInterval{
+				{SlotID: a, Start: at(9, 0), End: at(9, 45)},
+				{SlotID: b, Start: at(15, 0), End: at(15, 0)},
+			},
+		},
+		{
+			name:    "item too long for any interval",
+			minutes: 121,
+			wantFree: []freeInterval{
+				{SlotID: a, Start: at(9, 0), End: at(9, 45)},
+				{SlotID: b, Start: at(13, 0), End: at(15, 0)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			free := []freeInterval{
+				{SlotID: a, Start: at(9, 0), End: at(9, 45)},
+				{SlotID: b, Start: at(13, 0), End: at(15, 0)},
+			}
+			got, ok := bookFreeInterval(free, tt.minutes)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("booked %v, %v; want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+			if !reflect.DeepEqual(free, tt.wantFree) {
+				t.Errorf("free = %v, want %v", free, tt.wantFree)
+			}
+		})
+	}
+}
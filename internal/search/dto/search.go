@@ -0,0 +1,24 @@
+package dto
+
+import "github.com/google/uuid"
+
+type SearchResult struct {
+	Type      string     `json:"type"`
+	ID        uuid.UUID  `json:"id"`
+	GoalID    uuid.UUID  `json:"goal_id"`
+	GoalTitle string     `json:"goal_title"`
+	PhaseID   *uuid.UUID `json:"phase_id,omitempty"`
+	Title     string     `json:"title"`
+	Status    string     `json:"status"`
+	Rank      float64    `json:"rank"`
+	Snippet   string     `json:"snippet"`
+}
+
+type SearchResponse struct {
+	Results []SearchResult `json:"results"`
+	Meta    struct {
+		Total  int `json:"total"`
+		Limit  int `json:"limit"`
+		Offset int `json:"offset"`
+	} `json:"meta"`
+}
@@ -0,0 +1,8 @@
+package search
+
+import "errors"
+
+var (
+	ErrEmptyQuery  = errors.New("search query is required")
+	ErrInvalidKind = errors.New("type must be goal, phase or task")
+)
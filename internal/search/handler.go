@@ -0,0 +1,90 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"task-planner/internal/auth"
+
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// @Summary      Поиск
+// @Description  Полнотекстовый поиск по названиям и описаниям целей, фаз и задач пользователя. Результаты отсортированы по релевантности, совпадения в сниппете выделены тегом <mark>
+// @Tags         Search
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        q        query     string  true   "Поисковый запрос (поддерживает \"фразы\", OR и -исключения)"
+// @Param        type     query     string  false  "Типы через запятую: goal, phase, task"
+// @Param        status   query     string  false  "Статус найденной цели, фазы или задачи"
+// @Param        goal_id  query     string  false  "Искать только внутри цели"
+// @Param        limit    query     int     false  "Максимальное число результатов" default(20)
+// @Param        offset   query     int     false  "Смещение для пагинации" default(0)
+// @Success      200      {object}  dto.SearchResponse
+// @Failure      400      {object}  response.ErrorResponse  "Invalid query"
+// @Failure      401      {object}  response.ErrorResponse  "Unauthorized"
+// @Failure      500      {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/search [get]
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	f := Filter{
+		Query:  q.Get("q"),
+		Status: q.Get("status"),
+	}
+	if v := q.Get("type"); v != "" {
+		for _, k := range strings.Split(v, ",") {
+			f.Kinds = append(f.Kinds, strings.TrimSpace(k))
+		}
+	}
+	if v := q.Get("goal_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid goal_id", http.StatusBadRequest)
+			return
+		}
+		f.GoalID = &id
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if f.Offset, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+
+	resp, err := h.service.Search(r.Context(), claims.UserID, f)
+	switch {
+	case errors.Is(err, ErrEmptyQuery), errors.Is(err, ErrInvalidKind):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("[SEARCH] search failed: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,39 @@
+package search
+
+import "github.com/google/uuid"
+
+// Kinds of searchable entities.
+const (
+	KindGoal  = "goal"
+	KindPhase = "phase"
+	KindTask  = "task"
+)
+
+// Filter narrows a search. Status is matched against the status of the hit
+// itself, so goal, phase and task statuses can be mixed freely.
+type Filter struct {
+	Query  string
+	Status string
+	GoalID *uuid.UUID
+	Kinds  []string
+	Limit  int
+	Offset int
+}
+
+// Result is one ranked hit. Snippet is HTML: the stored text is escaped
+// and the matched words are wrapped in <mark>.
+type Result struct {
+	Kind      string
+	ID        uuid.UUID
+	GoalID    uuid.UUID
+	GoalTitle string
+	PhaseID   *uuid.UUID
+	Title     string
+	Status    string
+	Rank      float64
+	Snippet   string
+}
+
+func validKind(k string) bool {
+	return k == KindGoal || k == KindPhase || k == KindTask
+}
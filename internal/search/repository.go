@@ -0,0 +1,95 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+type Repository interface {
+	// Search returns one page of hits ordered by rank together with the
+	// total number of hits.
+	Search(ctx context.Context, userID int64, f Filter) ([]Result, int, error)
+}
+
+type repositoryImpl struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repositoryImpl{db: db}
+}
+
+// searchQuery ranks goals, phases and tasks in one pass. Highlighting is
+// done on the outer level so ts_headline only runs for the returned page.
+// The text is HTML-escaped before highlighting, so the <mark> tags are the
+// only markup in a snippet.
+//
+//	$1 user, $2 query, $3 status, $4 goal, $5 kinds, $6 limit, $7 offset
+const searchQuery = `
+WITH q AS (SELECT websearch_to_tsquery('simple', $2) AS query),
+hits AS (
+    SELECT 'goal' AS kind, g.id, g.id AS goal_id, g.title AS goal_title, NULL::uuid AS phase_id,
+           g.title, g.status, COALESCE(g.description, '') AS body,
+           ts_rank(g.search_vector, q.query) AS rank
+    FROM goals g, q
+    WHERE g.user_id = $1 AND g.deleted_at IS NULL AND g.search_vector @@ q.query
+    UNION ALL
+    SELECT 'phase', p.id, g.id, g.title, p.id,
+           p.title, p.status, COALESCE(p.description, ''),
+           ts_rank(p.search_vector, q.query)
+    FROM phases p JOIN goals g ON g.id = p.goal_id, q
+    WHERE g.user_id = $1 AND g.deleted_at IS NULL AND p.search_vector @@ q.query
+    UNION ALL
+    SELECT 'task', t.id, g.id, g.title, t.phase_id,
+           t.title, t.status, COALESCE(t.description, ''),
+           ts_rank(t.search_vector, q.query)
+    FROM tasks t JOIN goals g ON g.id = t.goal_id, q
+    WHERE g.user_id = $1 AND g.deleted_at IS NULL AND t.search_vector @@ q.query
+), filtered AS (
+    SELECT *, COUNT(*) OVER () AS total
+    FROM hits
+    WHERE ($3 = '' OR status = $3)
+      AND ($4::uuid IS NULL OR goal_id = $4)
+      AND (COALESCE(cardinality($5::text[]), 0) = 0 OR kind = ANY($5::text[]))
+    ORDER BY rank DESC, title
+    LIMIT $6 OFFSET $7
+)
+SELECT f.kind, f.id, f.goal_id, f.goal_title, f.phase_id, f.title, f.status, f.rank,
+       ts_headline('simple',
+                   replace(replace(replace(replace(replace(f.title || ' — ' || f.body,
+                       '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;'),
+                   q.query,
+                   'StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2'),
+       f.total
+FROM filtered f, q
+ORDER BY f.rank DESC, f.title`
+
+func (r *repositoryImpl) Search(ctx context.Context, userID int64, f Filter) ([]Result, int, error) {
+	rows, err := r.db.QueryContext(ctx, searchQuery,
+		userID, f.Query, f.Status, f.GoalID, pq.Array(f.Kinds), f.Limit, f.Offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		results []Result
+		total   int
+	)
+	for rows.Next() {
+		var res Result
+		if err := rows.Scan(
+			&res.Kind, &res.ID, &res.GoalID, &res.GoalTitle, &res.PhaseID,
+			&res.Title, &res.Status, &res.Rank, &res.Snippet, &total,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return results, total, nil
+}
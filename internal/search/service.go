@@ -0,0 +1,69 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"task-planner/internal/search/dto"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type Service interface {
+	Search(ctx context.Context, userID int64, f Filter) (*dto.SearchResponse, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) Search(ctx context.Context, userID int64, f Filter) (*dto.SearchResponse, error) {
+	f.Query = strings.TrimSpace(f.Query)
+	if f.Query == "" {
+		return nil, ErrEmptyQuery
+	}
+	for _, k := range f.Kinds {
+		if !validKind(k) {
+			return nil, ErrInvalidKind
+		}
+	}
+	if f.Limit <= 0 {
+		f.Limit = defaultLimit
+	}
+	if f.Limit > maxLimit {
+		f.Limit = maxLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+
+	results, total, err := s.repo.Search(ctx, userID, f)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.SearchResponse{Results: make([]dto.SearchResult, 0, len(results))}
+	for _, r := range results {
+		resp.Results = append(resp.Results, dto.SearchResult{
+			Type:      r.Kind,
+			ID:        r.ID,
+			GoalID:    r.GoalID,
+			GoalTitle: r.GoalTitle,
+			PhaseID:   r.PhaseID,
+			Title:     r.Title,
+			Status:    r.Status,
+			Rank:      r.Rank,
+			Snippet:   r.Snippet,
+		})
+	}
+	resp.Meta.Total = total
+	resp.Meta.Limit = f.Limit
+	resp.Meta.Offset = f.Offset
+	return resp, nil
+}
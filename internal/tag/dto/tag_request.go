@@ -0,0 +1,17 @@
+package dto
+
+import "github.com/google/uuid"
+
+type CreateTagRequest struct {
+	Name  string `json:"name" validate:"required,max=50"`
+	Color string `json:"color,omitempty" validate:"omitempty,hexcolor"`
+}
+
+type UpdateTagRequest struct {
+	Name  *string `json:"name,omitempty" validate:"omitempty,max=50"`
+	Color *string `json:"color,omitempty" validate:"omitempty,hexcolor"`
+}
+
+type SetTagsRequest struct {
+	TagIDs []uuid.UUID `json:"tag_ids"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+import "github.com/google/uuid"
+
+type TagResponse struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Color string    `json:"color"`
+}
@@ -0,0 +1,11 @@
+package tag
+
+import "errors"
+
+var (
+	ErrTagNotFound  = errors.New("tag not found")
+	ErrInvalidName  = errors.New("tag name is required and must be at most 50 characters")
+	ErrInvalidColor = errors.New("tag color must be a hex value like #1E88E5")
+	ErrDuplicateTag = errors.New("tag with this name already exists")
+	ErrUnknownTags  = errors.New("one or more tags do not exist")
+)
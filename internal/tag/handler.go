@@ -0,0 +1,173 @@
+package tag
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"task-planner/internal/auth"
+	"task-planner/internal/tag/dto"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// @Summary      Список тегов
+// @Description  Возвращает все теги пользователя, отсортированные по имени
+// @Tags         Tag
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {array}   dto.TagResponse
+// @Failure      401  {object}  response.ErrorResponse  "Unauthorized"
+// @Failure      500  {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tags [get]
+func (h *Handler) ListTags(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := h.service.ListTags(r.Context(), claims.UserID)
+	if err != nil {
+		WriteError(w, "list tags", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Создать тег
+// @Description  Создаёт тег пользователя. Цвет задаётся в формате #RRGGBB
+// @Tags         Tag
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body  body      dto.CreateTagRequest  true  "Имя и цвет тега"
+// @Success      201   {object}  dto.TagResponse
+// @Failure      400   {object}  response.ErrorResponse  "Invalid request"
+// @Failure      401   {object}  response.ErrorResponse  "Unauthorized"
+// @Failure      409   {object}  response.ErrorResponse  "Tag already exists"
+// @Failure      500   {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tags [post]
+func (h *Handler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.CreateTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.CreateTag(r.Context(), claims.UserID, req)
+	if err != nil {
+		WriteError(w, "create tag", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Изменить тег
+// @Tags         Tag
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        tag_id  path      string                true  "UUID тега"
+// @Param        body    body      dto.UpdateTagRequest  true  "Новые имя и/или цвет"
+// @Success      200     {object}  dto.TagResponse
+// @Failure      400     {object}  response.ErrorResponse  "Invalid request"
+// @Failure      404     {object}  response.ErrorResponse  "Tag not found"
+// @Failure      409     {object}  response.ErrorResponse  "Tag already exists"
+// @Failure      500     {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tags/{tag_id} [patch]
+func (h *Handler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	tagID, err := uuid.Parse(chi.URLParam(r, "tag_id"))
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.UpdateTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.UpdateTag(r.Context(), claims.UserID, tagID, req)
+	if err != nil {
+		WriteError(w, "update tag", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// @Summary      Удалить тег
+// @Description  Удаляет тег и снимает его со всех целей и задач
+// @Tags         Tag
+// @Security     ApiKeyAuth
+// @Param        tag_id  path      string  true  "UUID тега"
+// @Success      204     {string}  string  "No Content"
+// @Failure      400     {object}  response.ErrorResponse  "Invalid tag ID"
+// @Failure      404     {object}  response.ErrorResponse  "Tag not found"
+// @Failure      500     {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/tags/{tag_id} [delete]
+func (h *Handler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	tagID, err := uuid.Parse(chi.URLParam(r, "tag_id"))
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.DeleteTag(r.Context(), claims.UserID, tagID); err != nil {
+		WriteError(w, "delete tag", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WriteError maps tag errors to HTTP statuses. It is shared with the goal
+// handlers that attach tags to goals and tasks.
+func WriteError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, ErrTagNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrDuplicateTag):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrInvalidName),
+		errors.Is(err, ErrInvalidColor),
+		errors.Is(err, ErrUnknownTags):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("[TAG] %s failed: %v", action, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,30 @@
+package tag
+
+import (
+	"github.com/google/uuid"
+	"regexp"
+	"time"
+)
+
+const DefaultColor = "#9E9E9E"
+
+var colorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+type Tag struct {
+	ID        uuid.UUID
+	UserID    int64
+	Name      string
+	Color     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Assignment links a tag to the goal or task it is attached to.
+type Assignment struct {
+	OwnerID uuid.UUID
+	Tag     Tag
+}
+
+func validColor(c string) bool {
+	return colorPattern.MatchString(c)
+}
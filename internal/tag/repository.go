@@ -0,0 +1,210 @@
+package tag
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type Repository interface {
+	Create(ctx context.Context, t *Tag) error
+	GetByID(ctx context.Context, userID int64, id uuid.UUID) (*Tag, error)
+	Update(ctx context.Context, t *Tag) error
+	Delete(ctx context.Context, userID int64, id uuid.UUID) (bool, error)
+	ListByUser(ctx context.Context, userID int64) ([]Tag, error)
+	CountOwned(ctx context.Context, userID int64, ids []uuid.UUID) (int, error)
+
+	SetGoalTags(ctx context.Context, goalID uuid.UUID, tagIDs []uuid.UUID) error
+	SetTaskTags(ctx context.Context, taskID uuid.UUID, tagIDs []uuid.UUID) error
+	ListByGoalIDs(ctx context.Context, goalIDs []uuid.UUID) ([]Assignment, error)
+	ListByTaskIDs(ctx context.Context, taskIDs []uuid.UUID) ([]Assignment, error)
+}
+
+type repositoryImpl struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repositoryImpl{db: db}
+}
+
+const tagColumns = "tg.id, tg.user_id, tg.name, tg.color, tg.created_at, tg.updated_at"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTag(row rowScanner, t *Tag) error {
+	return row.Scan(&t.ID, &t.UserID, &t.Name, &t.Color, &t.CreatedAt, &t.UpdatedAt)
+}
+
+// isUniqueViolation reports whether err is a Postgres unique_violation.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func (r *repositoryImpl) Create(ctx context.Context, t *Tag) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO tags (id, user_id, name, color, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		t.ID, t.UserID, t.Name, t.Color, t.CreatedAt, t.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicateTag
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert tag: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetByID(ctx context.Context, userID int64, id uuid.UUID) (*Tag, error) {
+	row := r.db.QueryRowContext(ctx,
+		"SELECT "+tagColumns+" FROM tags tg WHERE tg.id = $1 AND tg.user_id = $2",
+		id, userID)
+	var t Tag
+	if err := scanTag(row, &t); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get tag: %w", err)
+	}
+	return &t, nil
+}
+
+func (r *repositoryImpl) Update(ctx context.Context, t *Tag) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE tags SET name = $3, color = $4, updated_at = $5
+		WHERE id = $1 AND user_id = $2`,
+		t.ID, t.UserID, t.Name, t.Color, t.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicateTag
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update tag: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) Delete(ctx context.Context, userID int64, id uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM tags WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete tag: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *repositoryImpl) ListByUser(ctx context.Context, userID int64) ([]Tag, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+tagColumns+" FROM tags tg WHERE tg.user_id = $1 ORDER BY LOWER(tg.name)",
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []Tag
+	for rows.Next() {
+		var t Tag
+		if err := scanTag(rows, &t); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+func (r *repositoryImpl) CountOwned(ctx context.Context, userID int64, ids []uuid.UUID) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM tags WHERE user_id = $1 AND id = ANY($2)`,
+		userID, pq.Array(ids)).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tags: %w", err)
+	}
+	return n, nil
+}
+
+func (r *repositoryImpl) SetGoalTags(ctx context.Context, goalID uuid.UUID, tagIDs []uuid.UUID) error {
+	return r.replaceLinks(ctx, "goal_tags", "goal_id", goalID, tagIDs)
+}
+
+func (r *repositoryImpl) SetTaskTags(ctx context.Context, taskID uuid.UUID, tagIDs []uuid.UUID) error {
+	return r.replaceLinks(ctx, "task_tags", "task_id", taskID, tagIDs)
+}
+
+// replaceLinks swaps the full tag set of one goal or task in a single
+// transaction so readers never observe a half-applied assignment.
+func (r *repositoryImpl) replaceLinks(
+	ctx context.Context, table, ownerColumn string, ownerID uuid.UUID, tagIDs []uuid.UUID,
+) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx,
+		"DELETE FROM "+table+" WHERE "+ownerColumn+" = $1", ownerID); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", table, err)
+	}
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	if _, err = tx.ExecContext(ctx,
+		"INSERT INTO "+table+" ("+ownerColumn+", tag_id) "+
+			"SELECT $1, UNNEST($2::uuid[]) ON CONFLICT DO NOTHING",
+		ownerID, pq.Array(tagIDs)); err != nil {
+		return fmt.Errorf("failed to insert %s: %w", table, err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) ListByGoalIDs(ctx context.Context, goalIDs []uuid.UUID) ([]Assignment, error) {
+	return r.listAssignments(ctx, "goal_tags", "goal_id", goalIDs)
+}
+
+func (r *repositoryImpl) ListByTaskIDs(ctx context.Context, taskIDs []uuid.UUID) ([]Assignment, error) {
+	return r.listAssignments(ctx, "task_tags", "task_id", taskIDs)
+}
+
+func (r *repositoryImpl) listAssignments(
+	ctx context.Context, table, ownerColumn string, ownerIDs []uuid.UUID,
+) ([]Assignment, error) {
+	if len(ownerIDs) == 0 {
+		return nil, nil
+	}
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT l."+ownerColumn+", "+tagColumns+" "+
+			"FROM "+table+" l JOIN tags tg ON tg.id = l.tag_id "+
+			"WHERE l."+ownerColumn+" = ANY($1) "+
+			"ORDER BY LOWER(tg.name)",
+		pq.Array(ownerIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var result []Assignment
+	for rows.Next() {
+		var a Assignment
+		if err := rows.Scan(&a.OwnerID,
+			&a.Tag.ID, &a.Tag.UserID, &a.Tag.Name, &a.Tag.Color, &a.Tag.CreatedAt, &a.Tag.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", table, err)
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,229 @@
+package tag
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+	"task-planner/internal/tag/dto"
+	"time"
+)
+
+type Service interface {
+	CreateTag(ctx context.Context, userID int64, req dto.CreateTagRequest) (*dto.TagResponse, error)
+	ListTags(ctx context.Context, userID int64) ([]dto.TagResponse, error)
+	UpdateTag(ctx context.Context, userID int64, tagID uuid.UUID, req dto.UpdateTagRequest) (*dto.TagResponse, error)
+	DeleteTag(ctx context.Context, userID int64, tagID uuid.UUID) error
+
+	// AssignToGoal and AssignToTask replace the tag set of a goal or task.
+	// Callers are responsible for checking that the goal or task belongs
+	// to userID; the tags themselves are checked here.
+	AssignToGoal(ctx context.Context, userID int64, goalID uuid.UUID, tagIDs []uuid.UUID) ([]dto.TagResponse, error)
+	AssignToTask(ctx context.Context, userID int64, taskID uuid.UUID, tagIDs []uuid.UUID) ([]dto.TagResponse, error)
+	TagsForGoals(ctx context.Context, goalIDs []uuid.UUID) (map[uuid.UUID][]dto.TagResponse, error)
+	TagsForTasks(ctx context.Context, taskIDs []uuid.UUID) (map[uuid.UUID][]dto.TagResponse, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) CreateTag(ctx context.Context, userID int64, req dto.CreateTagRequest) (*dto.TagResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len([]rune(name)) > 50 {
+		return nil, ErrInvalidName
+	}
+	color := req.Color
+	if color == "" {
+		color = DefaultColor
+	}
+	if !validColor(color) {
+		return nil, ErrInvalidColor
+	}
+
+	now := time.Now()
+	t := &Tag{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      name,
+		Color:     strings.ToUpper(color),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.Create(ctx, t); err != nil {
+		return nil, err
+	}
+	resp := ToResponse(t)
+	return &resp, nil
+}
+
+func (s *service) ListTags(ctx context.Context, userID int64) ([]dto.TagResponse, error) {
+	tags, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]dto.TagResponse, 0, len(tags))
+	for i := range tags {
+		resp = append(resp, ToResponse(&tags[i]))
+	}
+	return resp, nil
+}
+
+func (s *service) UpdateTag(
+	ctx context.Context, userID int64, tagID uuid.UUID, req dto.UpdateTagRequest,
+) (*dto.TagResponse, error) {
+	t, err := s.repo.GetByID(ctx, userID, tagID)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrTagNotFound
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len([]rune(name)) > 50 {
+			return nil, ErrInvalidName
+		}
+		t.Name = name
+	}
+	if req.Color != nil {
+		if !validColor(*req.Color) {
+			return nil, ErrInvalidColor
+		}
+		t.Color = strings.ToUpper(*req.Color)
+	}
+	t.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(ctx, t); err != nil {
+		return nil, err
+	}
+	resp := ToResponse(t)
+	return &resp, nil
+}
+
+func (s *service) DeleteTag(ctx context.Context, userID int64, tagID uuid.UUID) error {
+	ok, err := s.repo.Delete(ctx, userID, tagID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+func (s *service) AssignToGoal(
+	ctx context.Context, userID int64, goalID uuid.UUID, tagIDs []uuid.UUID,
+) ([]dto.TagResponse, error) {
+	ids, err := s.checkOwned(ctx, userID, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetGoalTags(ctx, goalID, ids); err != nil {
+		return nil, err
+	}
+	byGoal, err := s.TagsForGoals(ctx, []uuid.UUID{goalID})
+	if err != nil {
+		return nil, err
+	}
+	return byGoal[goalID], nil
+}
+
+func (s *service) AssignToTask(
+	ctx context.Context, userID int64, taskID uuid.UUID, tagIDs []uuid.UUID,
+) ([]dto.TagResponse, error) {
+	ids, err := s.checkOwned(ctx, userID, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetTaskTags(ctx, taskID, ids); err != nil {
+		return nil, err
+	}
+	byTask, err := s.TagsForTasks(ctx, []uuid.UUID{taskID})
+	if err != nil {
+		return nil, err
+	}
+	return byTask[taskID], nil
+}
+
+func (s *service) TagsForGoals(ctx context.Context, goalIDs []uuid.UUID) (map[uuid.UUID][]dto.TagResponse, error) {
+	assignments, err := s.repo.ListByGoalIDs(ctx, goalIDs)
+	if err != nil {
+		return nil, err
+	}
+	return groupAssignments(assignments), nil
+}
+
+func (s *service) TagsForTasks(ctx context.Context, taskIDs []uuid.UUID) (map[uuid.UUID][]dto.TagResponse, error) {
+	assignments, err := s.repo.ListByTaskIDs(ctx, taskIDs)
+	if err != nil {
+		return nil, err
+	}
+	return groupAssignments(assignments), nil
+}
+
+// checkOwned de-duplicates ids and verifies every one of them is a tag of
+// the user, so a caller can't attach someone else's tag by guessing its id.
+func (s *service) checkOwned(ctx context.Context, userID int64, ids []uuid.UUID) ([]uuid.UUID, error) {
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+	if len(unique) == 0 {
+		return unique, nil
+	}
+
+	n, err := s.repo.CountOwned(ctx, userID, unique)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(unique) {
+		return nil, fmt.Errorf("%w: %d of %d found", ErrUnknownTags, n, len(unique))
+	}
+	return unique, nil
+}
+
+func groupAssignments(assignments []Assignment) map[uuid.UUID][]dto.TagResponse {
+	result := make(map[uuid.UUID][]dto.TagResponse)
+	for i := range assignments {
+		a := &assignments[i]
+		result[a.OwnerID] = append(result[a.OwnerID], ToResponse(&a.Tag))
+	}
+	return result
+}
+
+func ToResponse(t *Tag) dto.TagResponse {
+	return dto.TagResponse{
+		ID:    t.ID,
+		Name:  t.Name,
+		Color: t.Color,
+	}
+}
+
+// ParseIDs parses a comma-separated list of tag ids as accepted by the
+// ?tags= query parameter of list and stats endpoints.
+func ParseIDs(raw string) ([]uuid.UUID, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	parts := strings.Split(raw, ",")
+	ids := make([]uuid.UUID, 0, len(parts))
+	for _, p := range parts {
+		id, err := uuid.Parse(strings.TrimSpace(p))
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag id %q: %w", p, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+type UsageResponse struct {
+	Daily   PeriodUsage `json:"daily"`
+	Monthly PeriodUsage `json:"monthly"`
+	// Features splits this month's usage by feature, most expensive first.
+	Features []FeatureUsage `json:"features"`
+}
+
+type PeriodUsage struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	// LimitTokens and RemainingTokens are omitted when the period has no
+	// quota.
+	LimitTokens     *int      `json:"limit_tokens,omitempty"`
+	RemainingTokens *int      `json:"remaining_tokens,omitempty"`
+	ResetsAt        time.Time `json:"resets_at"`
+}
+
+type FeatureUsage struct {
+	Feature          string  `json:"feature"`
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
@@ -0,0 +1,26 @@
+package usage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrQuotaExceeded = errors.New("llm usage quota exceeded")
+
+// QuotaError says which quota ran out and when it resets.
+type QuotaError struct {
+	Period  string
+	Limit   int
+	Used    int
+	ResetAt time.Time
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s quota of %d tokens used up (%d used), resets at %s",
+		e.Period, e.Limit, e.Used, e.ResetAt.Format(time.RFC3339))
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
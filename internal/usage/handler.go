@@ -0,0 +1,57 @@
+package usage
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"task-planner/internal/auth"
+	"time"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// WriteQuotaError answers 429 with a Retry-After header for a request that
+// ran out of quota.
+func WriteQuotaError(w http.ResponseWriter, err error) {
+	var qe *QuotaError
+	if errors.As(err, &qe) {
+		retry := int(time.Until(qe.ResetAt).Seconds()) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(max(retry, 1)))
+	}
+	http.Error(w, err.Error(), http.StatusTooManyRequests)
+}
+
+// @Summary      Расход LLM
+// @Description  Сколько запросов к модели, токенов и денег (оценочно, в USD) пользователь потратил сегодня и в этом месяце, лимиты и разбивка по функциям за месяц. Когда лимит исчерпан, генерация отвечает 429
+// @Tags         Usage
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object}  dto.UsageResponse
+// @Failure      401  {object}  response.ErrorResponse  "Unauthorized"
+// @Failure      500  {object}  response.ErrorResponse  "Internal Server Error"
+// @Router       /api/usage [get]
+func (h *Handler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := h.service.GetUsage(r.Context(), claims.UserID)
+	if err != nil {
+		log.Printf("[USAGE] get usage failed: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,166 @@
+package usage
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"log"
+	"strings"
+	"task-planner/internal/llm"
+	"task-planner/pkg/config"
+	"time"
+	"unicode/utf8"
+)
+
+type callerKey struct{}
+
+type caller struct {
+	userID  int64
+	feature string
+}
+
+// WithCaller attributes the model calls made with ctx to the user and
+// feature, so they are metered and count against the user's quota.
+func WithCaller(ctx context.Context, userID int64, feature string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller{userID: userID, feature: feature})
+}
+
+func callerFrom(ctx context.Context) (caller, bool) {
+	c, ok := ctx.Value(callerKey{}).(caller)
+	return c, ok
+}
+
+// meter records every call of the client it wraps and refuses calls for
+// users whose quota is used up.
+type meter struct {
+	next llm.Client
+	repo Repository
+	cfg  config.LLMConfig
+	now  func() time.Time
+}
+
+// NewMeter wraps c so that every call is recorded and quotas are enforced
+// before the model is called.
+func NewMeter(c llm.Client, repo Repository, cfg config.LLMConfig) llm.Client {
+	return &meter{next: c, repo: repo, cfg: cfg, now: time.Now}
+}
+
+func (m *meter) Complete(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	if err := m.checkQuota(ctx); err != nil {
+		return nil, err
+	}
+	start := m.now()
+	resp, err := m.next.Complete(ctx, req)
+	m.record(ctx, req, resp, err, start)
+	return resp, err
+}
+
+func (m *meter) Stream(ctx context.Context, req llm.Request, onDelta func(string) error) (*llm.Response, error) {
+	if err := m.checkQuota(ctx); err != nil {
+		return nil, err
+	}
+	start := m.now()
+	resp, err := m.next.Stream(ctx, req, onDelta)
+	m.record(ctx, req, resp, err, start)
+	return resp, err
+}
+
+// checkQuota fails with a *QuotaError once the caller has spent their
+// daily or monthly tokens.
+func (m *meter) checkQuota(ctx context.Context) error {
+	c, ok := callerFrom(ctx)
+	if !ok {
+		return nil
+	}
+	quotas := []struct {
+		period string
+		limit  int
+	}{
+		{PeriodDay, m.cfg.DailyTokenQuota},
+		{PeriodMonth, m.cfg.MonthlyTokenQuota},
+	}
+	now := m.now()
+	for _, q := range quotas {
+		if q.limit <= 0 {
+			continue
+		}
+		since, next := periodStart(q.period, now)
+		t, err := m.repo.Totals(ctx, c.userID, since)
+		if err != nil {
+			return err
+		}
+		if t.Tokens() >= q.limit {
+			return &QuotaError{Period: q.period, Limit: q.limit, Used: t.Tokens(), ResetAt: next}
+		}
+	}
+	return nil
+}
+
+// record stores the call. A failure to record is logged rather than
+// failing a call the model already answered. Usage arrives with the last
+// chunk of a stream, so for a stream cut short it is estimated from the
+// prompt and the reply received so far.
+func (m *meter) record(ctx context.Context, req llm.Request, resp *llm.Response, err error, start time.Time) {
+	call := &Call{
+		ID:        uuid.New(),
+		Feature:   FeatureUnknown,
+		Model:     req.Model,
+		Latency:   m.now().Sub(start),
+		Failed:    err != nil,
+		CreatedAt: start,
+	}
+	if c, ok := callerFrom(ctx); ok {
+		userID := c.userID
+		call.UserID = &userID
+		call.Feature = c.feature
+	}
+	if resp != nil {
+		if resp.Model != "" {
+			call.Model = resp.Model
+		}
+		u := resp.Usage
+		if u == (llm.Usage{}) {
+			u = estimateUsage(req, resp.Content)
+		}
+		call.PromptTokens = u.PromptTokens
+		call.CompletionTokens = u.CompletionTokens
+		call.CostUSD = EstimateCost(m.cfg.Prices, call.Model, u)
+	}
+	if err := m.repo.RecordCall(context.WithoutCancel(ctx), call); err != nil {
+		log.Printf("[USAGE] %v", err)
+	}
+}
+
+// charsPerToken is the rough length of a token in English text, used when
+// the provider reported no usage.
+const charsPerToken = 4
+
+// estimateUsage guesses the tokens of a call the provider did not report.
+func estimateUsage(req llm.Request, reply string) llm.Usage {
+	var u llm.Usage
+	for _, msg := range req.Messages {
+		u.PromptTokens += estimateTokens(msg.Content)
+	}
+	u.CompletionTokens = estimateTokens(reply)
+	return u
+}
+
+func estimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + charsPerToken - 1) / charsPerToken
+}
+
+// EstimateCost prices the tokens of one call. Providers report dated model
+// names such as gpt-4o-2024-08-06, so a model without a price of its own
+// takes the price of the longest configured name it starts with. Unknown
+// models cost nothing.
+func EstimateCost(prices map[string]config.LLMPrice, model string, u llm.Usage) float64 {
+	p, ok := prices[model]
+	if !ok {
+		best := ""
+		for name, price := range prices {
+			if strings.HasPrefix(model, name) && len(name) > len(best) {
+				best, p = name, price
+			}
+		}
+	}
+	return (float64(u.PromptTokens)*p.Prompt + float64(u.CompletionTokens)*p.Completion) / 1e6
+}
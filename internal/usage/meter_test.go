@@ -0,0 +1,136 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"task-planner/internal/llm"
+	"task-planner/pkg/config"
+	"testing"
+	"time"
+)
+
+type memRepo struct {
+	Repository
+	calls []Call
+}
+
+func (r *memRepo) RecordCall(_ context.Context, c *Call) error {
+	r.calls = append(r.calls, *c)
+	return nil
+}
+
+func (r *memRepo) Totals(_ context.Context, userID int64, since time.Time) (Totals, error) {
+	var t Totals
+	for _, c := range r.calls {
+		if c.UserID != nil && *c.UserID == userID && !c.CreatedAt.Before(since) {
+			t.Calls++
+			t.PromptTokens += c.PromptTokens
+			t.CompletionTokens += c.CompletionTokens
+			t.CostUSD += c.CostUSD
+		}
+	}
+	return t, nil
+}
+
+func TestMeterRecordsAttributedCalls(t *testing.T) {
+	repo := &memRepo{}
+	cfg := config.LLMConfig{Prices: map[string]config.LLMPrice{"fake": {Prompt: 1e6, Completion: 2e6}}}
+	m := NewMeter(llm.NewFake("three word reply"), repo, cfg)
+
+	ctx := WithCaller(context.Background(), 7, FeatureDecompose)
+	if _, err := llm.Ask(ctx, m, config.LLMModel{Name: "fake"}, "say hi"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := llm.Ask(context.Background(), m, config.LLMModel{Name: "fake"}, "again"); !errors.Is(err, llm.ErrNoFakeReply) {
+		t.Fatalf("second call error = %v, want ErrNoFakeReply", err)
+	}
+
+	if len(repo.calls) != 2 {
+		t.Fatalf("recorded %d calls, want 2", len(repo.calls))
+	}
+	c := repo.calls[0]
+	if c.UserID == nil || *c.UserID != 7 || c.Feature != FeatureDecompose || c.Failed {
+		t.Errorf("first call = %+v, want user 7, decompose, not failed", c)
+	}
+	want := float64(c.PromptTokens) + 2*float64(c.CompletionTokens)
+	if c.CompletionTokens == 0 || math.Abs(c.CostUSD-want) > 1e-9 {
+		t.Errorf("cost = %v for %d+%d tokens, want %v", c.CostUSD, c.PromptTokens, c.CompletionTokens, want)
+	}
+	if c := repo.calls[1]; c.UserID != nil || c.Feature != FeatureUnknown || !c.Failed {
+		t.Errorf("second call = %+v, want unattributed failed call", c)
+	}
+}
+
+func TestMeterEnforcesQuotaBeforeCalling(t *testing.T) {
+	repo := &memRepo{}
+	fake := llm.NewFake("one two three four five", "never sent")
+	m := NewMeter(fake, repo, config.LLMConfig{DailyTokenQuota: 5})
+	ctx := WithCaller(context.Background(), 7, FeatureRefill)
+
+	if _, err := llm.Ask(ctx, m, config.LLMModel{Name: "fake"}, "go"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := llm.Ask(ctx, m, config.LLMModel{Name: "fake"}, "go")
+	var qe *QuotaError
+	if !errors.As(err, &qe) || !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("error = %v, want QuotaError", err)
+	}
+	if qe.Period != PeriodDay || qe.Limit != 5 || !qe.ResetAt.After(time.Now()) {
+		t.Errorf("quota error = %+v", qe)
+	}
+	if n := len(fake.Requests()); n != 1 {
+		t.Errorf("model was called %d times, want 1", n)
+	}
+
+	// Other users and unattributed calls are not limited.
+	if _, err := llm.Ask(WithCaller(context.Background(), 8, FeatureRefill), m, config.LLMModel{Name: "fake"}, "go"); err != nil {
+		t.Errorf("another user: %v", err)
+	}
+}
+
+func TestMeterEstimatesAbortedStreams(t *testing.T) {
+	repo := &memRepo{}
+	reply := "a reply long enough to arrive in several chunks"
+	m := NewMeter(llm.NewFake(reply), repo, config.LLMConfig{})
+	ctx := WithCaller(context.Background(), 7, FeatureDecompose)
+
+	errGone := errors.New("client went away")
+	req := llm.Request{Model: "fake", Messages: []llm.Message{{Role: llm.RoleUser, Content: "plan my goal"}}}
+	resp, err := m.Stream(ctx, req, func(string) error { return errGone })
+	if !errors.Is(err, errGone) {
+		t.Fatalf("error = %v, want %v", err, errGone)
+	}
+	if resp == nil || resp.Content != reply[:llm.FakeChunkSize] {
+		t.Fatalf("response = %+v, want the first chunk", resp)
+	}
+
+	if len(repo.calls) != 1 {
+		t.Fatalf("recorded %d calls, want 1", len(repo.calls))
+	}
+	c := repo.calls[0]
+	if !c.Failed || c.PromptTokens != 3 || c.CompletionTokens != 4 {
+		t.Errorf("call = %+v, want a failed call with 3+4 estimated tokens", c)
+	}
+}
+
+func TestEstimateCostMatchesDatedModelNames(t *testing.T) {
+	prices := map[string]config.LLMPrice{
+		"gpt-4":  {Prompt: 30, Completion: 60},
+		"gpt-4o": {Prompt: 2.5, Completion: 10},
+	}
+	u := llm.Usage{PromptTokens: 1000, CompletionTokens: 500}
+	tests := []struct {
+		model string
+		want  float64
+	}{
+		{"gpt-4", 0.06},
+		{"gpt-4o-2024-08-06", 0.0075},
+		{"llama3", 0},
+	}
+	for _, tt := range tests {
+		if got := EstimateCost(prices, tt.model, u); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("EstimateCost(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package usage
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Features that call a language model.
+const (
+	FeatureDecompose  = "decompose"
+	FeatureRefine     = "refine"
+	FeatureRefill     = "refill"
+	FeaturePhaseTasks = "phase_tasks"
+	FeatureMotivation = "motivation"
+	// FeatureUnknown marks calls made without WithCaller.
+	FeatureUnknown = "unknown"
+)
+
+// Call is one request to a language model. UserID is nil for calls made on
+// nobody's behalf.
+type Call struct {
+	ID               uuid.UUID
+	UserID           *int64
+	Feature          string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	Latency          time.Duration
+	CostUSD          float64
+	Failed           bool
+	CreatedAt        time.Time
+}
+
+// Totals sums the calls of a period.
+type Totals struct {
+	Calls            int
+	PromptTokens     int
+	CompletionTokens int
+	CostUSD          float64
+}
+
+func (t Totals) Tokens() int {
+	return t.PromptTokens + t.CompletionTokens
+}
+
+type FeatureTotals struct {
+	Feature string
+	Totals
+}
+
+// Quota periods.
+const (
+	PeriodDay   = "daily"
+	PeriodMonth = "monthly"
+)
+
+// periodStart returns when the period containing now began, and when the
+// next one begins.
+func periodStart(period string, now time.Time) (start, next time.Time) {
+	y, m, d := now.Date()
+	if period == PeriodMonth {
+		start = time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0)
+	}
+	start = time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	return start, start.AddDate(0, 0, 1)
+}
@@ -0,0 +1,83 @@
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"task-planner/internal/db"
+	"time"
+)
+
+type Repository interface {
+	RecordCall(ctx context.Context, c *Call) error
+	// Totals sums the user's calls since the given time.
+	Totals(ctx context.Context, userID int64, since time.Time) (Totals, error)
+	// TotalsByFeature is Totals per feature, most expensive first.
+	TotalsByFeature(ctx context.Context, userID int64, since time.Time) ([]FeatureTotals, error)
+}
+
+type repositoryImpl struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repositoryImpl{db: db}
+}
+
+// conn returns the transaction carried by ctx, if any.
+func (r *repositoryImpl) conn(ctx context.Context) db.Executor {
+	return db.Conn(ctx, r.db)
+}
+
+func (r *repositoryImpl) RecordCall(ctx context.Context, c *Call) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `
+		INSERT INTO llm_calls (
+			id, user_id, feature, model, prompt_tokens, completion_tokens,
+			latency_ms, cost_usd, failed, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		c.ID, c.UserID, c.Feature, c.Model, c.PromptTokens, c.CompletionTokens,
+		c.Latency.Milliseconds(), c.CostUSD, c.Failed, c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record llm call: %w", err)
+	}
+	return nil
+}
+
+const totalsColumns = `COUNT(*), COALESCE(SUM(prompt_tokens), 0),
+	COALESCE(SUM(completion_tokens), 0), COALESCE(SUM(cost_usd), 0)`
+
+func (r *repositoryImpl) Totals(ctx context.Context, userID int64, since time.Time) (Totals, error) {
+	var t Totals
+	err := r.conn(ctx).QueryRowContext(ctx, `
+		SELECT `+totalsColumns+`
+		FROM llm_calls
+		WHERE user_id = $1 AND created_at >= $2`, userID, since).
+		Scan(&t.Calls, &t.PromptTokens, &t.CompletionTokens, &t.CostUSD)
+	if err != nil {
+		return t, fmt.Errorf("failed to sum llm usage: %w", err)
+	}
+	return t, nil
+}
+
+func (r *repositoryImpl) TotalsByFeature(ctx context.Context, userID int64, since time.Time) ([]FeatureTotals, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `
+		SELECT feature, `+totalsColumns+`
+		FROM llm_calls
+		WHERE user_id = $1 AND created_at >= $2
+		GROUP BY feature
+		ORDER BY SUM(cost_usd) DESC, feature`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum llm usage by feature: %w", err)
+	}
+	defer rows.Close()
+
+	var result []FeatureTotals
+	for rows.Next() {
+		var f FeatureTotals
+		if err := rows.Scan(&f.Feature, &f.Calls, &f.PromptTokens, &f.CompletionTokens, &f.CostUSD); err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,72 @@
+package usage
+
+import (
+	"context"
+	"task-planner/internal/usage/dto"
+	"task-planner/pkg/config"
+	"time"
+)
+
+type Service interface {
+	// GetUsage reports the user's model usage today and this month against
+	// their quotas.
+	GetUsage(ctx context.Context, userID int64) (*dto.UsageResponse, error)
+}
+
+type service struct {
+	repo Repository
+	cfg  config.LLMConfig
+}
+
+func NewService(repo Repository, cfg config.LLMConfig) Service {
+	return &service{repo: repo, cfg: cfg}
+}
+
+func (s *service) GetUsage(ctx context.Context, userID int64) (*dto.UsageResponse, error) {
+	now := time.Now()
+	daily, err := s.periodUsage(ctx, userID, PeriodDay, s.cfg.DailyTokenQuota, now)
+	if err != nil {
+		return nil, err
+	}
+	monthly, err := s.periodUsage(ctx, userID, PeriodMonth, s.cfg.MonthlyTokenQuota, now)
+	if err != nil {
+		return nil, err
+	}
+
+	since, _ := periodStart(PeriodMonth, now)
+	features, err := s.repo.TotalsByFeature(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	resp := &dto.UsageResponse{Daily: *daily, Monthly: *monthly, Features: make([]dto.FeatureUsage, 0, len(features))}
+	for _, f := range features {
+		resp.Features = append(resp.Features, dto.FeatureUsage{
+			Feature:          f.Feature,
+			Calls:            f.Calls,
+			PromptTokens:     f.PromptTokens,
+			CompletionTokens: f.CompletionTokens,
+			CostUSD:          f.CostUSD,
+		})
+	}
+	return resp, nil
+}
+
+func (s *service) periodUsage(ctx context.Context, userID int64, period string, limit int, now time.Time) (*dto.PeriodUsage, error) {
+	since, next := periodStart(period, now)
+	t, err := s.repo.Totals(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	u := &dto.PeriodUsage{
+		Calls:            t.Calls,
+		PromptTokens:     t.PromptTokens,
+		CompletionTokens: t.CompletionTokens,
+		CostUSD:          t.CostUSD,
+		ResetsAt:         next,
+	}
+	if limit > 0 {
+		remaining := max(limit-t.Tokens(), 0)
+		u.LimitTokens, u.RemainingTokens = &limit, &remaining
+	}
+	return u, nil
+}
@@ -4,4 +4,5 @@ import "errors"
 
 var (
 	ErrUserAlreadyExists = errors.New("user already exists")
+	ErrUnsupportedLocale = errors.New("unsupported locale")
 )
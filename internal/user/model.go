@@ -9,6 +9,7 @@ type User struct {
 	Name            string    `json:"name"`
 	IsEmailVerified bool      `json:"is_email_verified"`
 	GoogleID        string    `json:"google_id"`
+	Locale          string    `json:"locale"`
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 }
@@ -16,6 +16,7 @@ type Repository interface {
 	GetByGoogleID(ctx context.Context, googleID string) (*User, error)
 	CreateWithGoogle(ctx context.Context, email, googleID string) (int64, error)
 	LinkGoogleID(ctx context.Context, userID int64, googleID string) error
+	SetLocale(ctx context.Context, userID int64, locale string) error
 }
 
 type PGRepository struct {
@@ -61,14 +62,14 @@ func (r *PGRepository) CreateUser(ctx context.Context, user *User) (int64, error
 
 func (r *PGRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, email, password_hash, name, is_email_verified, created_at, updated_at
+		SELECT id, email, password_hash, name, is_email_verified, locale, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
 	user := &User{}
 	err := r.db.QueryRowContext(ctx, query, email).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.Name,
-		&user.IsEmailVerified, &user.CreatedAt, &user.UpdatedAt,
+		&user.IsEmailVerified, &user.Locale, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -94,7 +95,7 @@ func (r *PGRepository) MarkEmailAsVerified(ctx context.Context, userID int64) er
 
 func (r *PGRepository) GetByID(ctx context.Context, id int64) (*User, error) {
 	const q = `
-        SELECT id, email, password_hash, name, is_email_verified, google_id
+        SELECT id, email, password_hash, name, is_email_verified, google_id, locale
         FROM users
         WHERE id = $1
     `
@@ -106,6 +107,7 @@ func (r *PGRepository) GetByID(ctx context.Context, id int64) (*User, error) {
 		&u.Name,
 		&u.IsEmailVerified,
 		&u.GoogleID,
+		&u.Locale,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -118,7 +120,7 @@ func (r *PGRepository) GetByID(ctx context.Context, id int64) (*User, error) {
 
 func (r *PGRepository) GetByGoogleID(ctx context.Context, googleID string) (*User, error) {
 	const q = `
-        SELECT id, email, password_hash, name, is_email_verified, google_id
+        SELECT id, email, password_hash, name, is_email_verified, google_id, locale
         FROM users
         WHERE google_id = $1
     `
@@ -130,6 +132,7 @@ func (r *PGRepository) GetByGoogleID(ctx context.Context, googleID string) (*Use
 		&u.Name,
 		&u.IsEmailVerified,
 		&u.GoogleID,
+		&u.Locale,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -164,3 +167,15 @@ func (r *PGRepository) LinkGoogleID(ctx context.Context, userID int64, googleID
 	}
 	return nil
 }
+
+func (r *PGRepository) SetLocale(ctx context.Context, userID int64, locale string) error {
+	const q = `
+        UPDATE users
+        SET locale = $1, updated_at = NOW()
+        WHERE id = $2
+    `
+	if _, err := r.db.ExecContext(ctx, q, locale, userID); err != nil {
+		return fmt.Errorf("repository.SetLocale: %w", err)
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package user
 
-import "context"
+import (
+	"context"
+	"task-planner/internal/prompt"
+)
 
 type Service interface {
 	CreateUser(ctx context.Context, email, passwordHash, name string) (int64, error)
@@ -12,6 +15,9 @@ type Service interface {
 	GetUserByGoogleID(ctx context.Context, googleID string) (*User, error)
 	LinkGoogleID(ctx context.Context, userID int64, googleID string) error
 	CreateUserWithGoogle(ctx context.Context, email, googleID string) (int64, error)
+
+	// SetLocale sets the language the user's prompts are rendered in.
+	SetLocale(ctx context.Context, userID int64, locale string) error
 }
 
 type service struct {
@@ -72,3 +78,10 @@ func (s *service) LinkGoogleID(ctx context.Context, userID int64, googleID strin
 func (s *service) CreateUserWithGoogle(ctx context.Context, email, googleID string) (int64, error) {
 	return s.repo.CreateWithGoogle(ctx, email, googleID)
 }
+
+func (s *service) SetLocale(ctx context.Context, userID int64, locale string) error {
+	if !prompt.Supported(locale) {
+		return ErrUnsupportedLocale
+	}
+	return s.repo.SetLocale(ctx, userID, locale)
+}
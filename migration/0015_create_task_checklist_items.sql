@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS task_checklist_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    is_done BOOLEAN NOT NULL DEFAULT FALSE,
+    "order" INT NOT NULL DEFAULT 1,
+    completed_at TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_checklist_items_task_id ON task_checklist_items(task_id, "order");
+
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS progress_mode VARCHAR(20) NOT NULL DEFAULT 'time';
@@ -0,0 +1,6 @@
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 2,
+    ADD COLUMN IF NOT EXISTS blocked_reason TEXT,
+    ADD COLUMN IF NOT EXISTS status_manual BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_goal_priority ON tasks(goal_id, priority DESC);
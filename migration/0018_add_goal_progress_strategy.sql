@@ -0,0 +1,3 @@
+ALTER TABLE goals
+    ADD COLUMN IF NOT EXISTS progress_strategy VARCHAR(20) NOT NULL DEFAULT 'time',
+    ADD COLUMN IF NOT EXISTS manual_progress INT CHECK (manual_progress BETWEEN 0 AND 100);
@@ -0,0 +1,23 @@
+ALTER TABLE goals
+    ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'project';
+
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS recurrence_rule TEXT,
+    ADD COLUMN IF NOT EXISTS occurrence_minutes INT;
+
+CREATE TABLE IF NOT EXISTS task_occurrences (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    occurrence_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, completed, skipped
+    time_spent INT NOT NULL DEFAULT 0,
+    completed_at TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (task_id, occurrence_date)
+);
+
+ALTER TABLE scheduled_task
+    ADD COLUMN IF NOT EXISTS occurrence_id UUID REFERENCES task_occurrences(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_task_occurrence_id ON scheduled_task(occurrence_id);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS goal_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source_goal_id UUID REFERENCES goals(id) ON DELETE SET NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    hours_per_week INT NOT NULL DEFAULT 0,
+    estimated_time INT NOT NULL DEFAULT 0,
+    kind VARCHAR(20) NOT NULL DEFAULT 'project',
+    progress_strategy VARCHAR(20) NOT NULL DEFAULT 'time',
+    is_public BOOLEAN NOT NULL DEFAULT FALSE,
+    structure JSONB NOT NULL DEFAULT '[]', -- phases with their tasks, no progress
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_goal_templates_user_id ON goal_templates(user_id);
+CREATE INDEX IF NOT EXISTS idx_goal_templates_public ON goal_templates(is_public) WHERE is_public;
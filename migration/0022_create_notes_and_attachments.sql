@@ -0,0 +1,35 @@
+-- Notes and attachments belong to exactly one goal, phase or task and go
+-- away together with it.
+CREATE TABLE IF NOT EXISTS notes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    goal_id UUID REFERENCES goals(id) ON DELETE CASCADE,
+    phase_id UUID REFERENCES phases(id) ON DELETE CASCADE,
+    task_id UUID REFERENCES tasks(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (num_nonnulls(goal_id, phase_id, task_id) = 1)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notes_goal_id ON notes(goal_id) WHERE goal_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_notes_phase_id ON notes(phase_id) WHERE phase_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_notes_task_id ON notes(task_id) WHERE task_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    goal_id UUID REFERENCES goals(id) ON DELETE CASCADE,
+    phase_id UUID REFERENCES phases(id) ON DELETE CASCADE,
+    task_id UUID REFERENCES tasks(id) ON DELETE CASCADE,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (num_nonnulls(goal_id, phase_id, task_id) = 1)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_goal_id ON attachments(goal_id) WHERE goal_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_attachments_phase_id ON attachments(phase_id) WHERE phase_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_attachments_task_id ON attachments(task_id) WHERE task_id IS NOT NULL;
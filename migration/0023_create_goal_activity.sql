@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS goal_activity (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    goal_id UUID NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    actor_id BIGINT REFERENCES users(id) ON DELETE SET NULL, -- NULL for background jobs
+    entity_type VARCHAR(20) NOT NULL, -- goal, phase, task, checklist_item, occurrence, interval
+    entity_id UUID NOT NULL,
+    action VARCHAR(40) NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_goal_activity_goal_created ON goal_activity(goal_id, created_at DESC);
+
+-- The log is append-only: entries are only ever removed together with
+-- their goal. The one change allowed is clearing actor_id, which the
+-- foreign key does when the user is deleted.
+CREATE OR REPLACE FUNCTION goal_activity_append_only() RETURNS trigger AS $$
+BEGIN
+    IF NEW.actor_id IS NULL AND to_jsonb(NEW) - 'actor_id' = to_jsonb(OLD) - 'actor_id' THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'goal_activity is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS goal_activity_no_update ON goal_activity;
+CREATE TRIGGER goal_activity_no_update
+    BEFORE UPDATE ON goal_activity
+    FOR EACH ROW EXECUTE FUNCTION goal_activity_append_only();
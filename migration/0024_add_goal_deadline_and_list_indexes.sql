@@ -0,0 +1,12 @@
+ALTER TABLE goals
+    ADD COLUMN IF NOT EXISTS deadline DATE;
+
+-- ListGoals always filters by owner and live goals, then sorts by one of
+-- these columns.
+CREATE INDEX IF NOT EXISTS idx_goals_user_updated ON goals(user_id, updated_at DESC) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_goals_user_created ON goals(user_id, created_at DESC) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_goals_user_progress ON goals(user_id, progress) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_goals_user_deadline ON goals(user_id, deadline) WHERE deleted_at IS NULL;
+
+-- Next task of each goal on a page: the highest-priority todo task.
+CREATE INDEX IF NOT EXISTS idx_tasks_goal_next ON tasks(goal_id, priority DESC, created_at) WHERE status = 'todo';
@@ -0,0 +1,11 @@
+-- Optimistic concurrency: every write bumps the row version, and updates
+-- made on behalf of a client only apply when the version it read is still
+-- current.
+ALTER TABLE goals
+    ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE phases
+    ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
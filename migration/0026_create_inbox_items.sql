@@ -0,0 +1,42 @@
+-- Quick-captured tasks that do not belong to a goal yet. Triage turns an
+-- item into a task of a goal and removes it from the inbox.
+CREATE TABLE IF NOT EXISTS inbox_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    estimated_minutes INT NOT NULL DEFAULT 0 CHECK (estimated_minutes >= 0),
+    priority SMALLINT NOT NULL DEFAULT 2,
+    -- Where the scheduler booked the item into a free part of one of the
+    -- user's time slots; all four are set or none is.
+    time_slot_id UUID,
+    scheduled_date DATE,
+    start_time TIME,
+    end_time TIME,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_inbox_time_slot FOREIGN KEY (time_slot_id) REFERENCES time_slot(id),
+    CHECK (num_nonnulls(time_slot_id, scheduled_date, start_time, end_time) IN (0, 4)),
+    CHECK (start_time < end_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbox_items_user_created ON inbox_items(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_inbox_items_slot_date ON inbox_items(time_slot_id, scheduled_date)
+    WHERE time_slot_id IS NOT NULL;
+
+-- Deleting a time slot drops the bookings made in it. The foreign key
+-- cannot clear all four columns itself, as only time_slot_id is part of it.
+CREATE OR REPLACE FUNCTION inbox_items_unbook_time_slot() RETURNS trigger AS $$
+BEGIN
+    UPDATE inbox_items
+       SET time_slot_id = NULL, scheduled_date = NULL, start_time = NULL, end_time = NULL,
+           updated_at = NOW()
+     WHERE time_slot_id = OLD.id;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS time_slot_unbook_inbox_items ON time_slot;
+CREATE TRIGGER time_slot_unbook_inbox_items
+    BEFORE DELETE ON time_slot
+    FOR EACH ROW EXECUTE FUNCTION inbox_items_unbook_time_slot();
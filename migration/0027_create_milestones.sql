@@ -0,0 +1,25 @@
+-- A phase may end in a milestone: acceptance criteria the user confirms by
+-- hand. The phase only completes, and later phases only unlock, once every
+-- criterion is confirmed.
+CREATE TABLE IF NOT EXISTS milestones (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    phase_id UUID NOT NULL UNIQUE REFERENCES phases(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    achieved_at TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS milestone_criteria (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    milestone_id UUID NOT NULL REFERENCES milestones(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    is_met BOOLEAN NOT NULL DEFAULT FALSE,
+    met_at TIMESTAMP WITHOUT TIME ZONE,
+    "order" INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_milestone_criteria_milestone ON milestone_criteria(milestone_id, "order");
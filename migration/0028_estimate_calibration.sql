@@ -0,0 +1,15 @@
+-- Tasks whose estimate already had the user's calibration factor applied
+-- when they were created (AI decomposition and refill). The scheduler does
+-- not scale them a second time, and they are left out when suggesting a
+-- new factor.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS estimate_calibrated BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Optional per-user factor applied to new estimates: 1.5 means tasks take
+-- half again as long as estimated. No row means no calibration.
+CREATE TABLE IF NOT EXISTS estimate_calibration (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    factor NUMERIC(4, 2) NOT NULL CHECK (factor BETWEEN 0.25 AND 5),
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_completed_at ON tasks(completed_at) WHERE status = 'completed';
@@ -0,0 +1,16 @@
+-- A generated plan the user is still refining with the model before it
+-- becomes a goal. The conversation is kept so every instruction builds on
+-- the previous ones.
+CREATE TABLE IF NOT EXISTS plan_drafts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    draft JSONB NOT NULL,
+    messages JSONB NOT NULL DEFAULT '[]',
+    turns INT NOT NULL DEFAULT 0,
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_plan_drafts_user_id ON plan_drafts(user_id);
+CREATE INDEX IF NOT EXISTS idx_plan_drafts_updated_at ON plan_drafts(updated_at);
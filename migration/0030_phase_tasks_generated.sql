@@ -0,0 +1,4 @@
+-- Set once tasks have been generated for a phase that started without any
+-- (every phase after the first in an AI decomposition), so the phase is not
+-- generated again when the user later removes its tasks.
+ALTER TABLE phases ADD COLUMN IF NOT EXISTS tasks_generated_at TIMESTAMP WITHOUT TIME ZONE;
@@ -0,0 +1,19 @@
+-- Goals opt in to the scheduled refill job, which tops up the current phase
+-- with AI-generated tasks when it runs low.
+ALTER TABLE goals ADD COLUMN IF NOT EXISTS auto_refill BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- One row per refill of a goal, scheduled or started by the user.
+CREATE TABLE IF NOT EXISTS refill_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    goal_id UUID NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    trigger VARCHAR(16) NOT NULL CHECK (trigger IN ('scheduled', 'manual')),
+    status VARCHAR(16) NOT NULL CHECK (status IN ('running', 'succeeded', 'failed')),
+    tasks_added INT NOT NULL DEFAULT 0,
+    tasks_scheduled INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP WITHOUT TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_refill_runs_goal_started ON refill_runs(goal_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_goals_auto_refill ON goals(id) WHERE auto_refill;
@@ -0,0 +1,8 @@
+-- The language prompts are rendered in, and the prompt version that
+-- produced each generated goal, task and motivation message. Existing rows
+-- were produced by prompts that were not versioned yet.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(8) NOT NULL DEFAULT 'ru';
+
+ALTER TABLE goals ADD COLUMN IF NOT EXISTS prompt_version VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS prompt_version VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE motivation ADD COLUMN IF NOT EXISTS prompt_version VARCHAR(64) NOT NULL DEFAULT '';
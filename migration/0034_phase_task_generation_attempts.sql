@@ -0,0 +1,5 @@
+-- Failed attempts at generating the tasks of a phase that has become
+-- current, and when the next one is due, so a phase the model keeps failing
+-- on is retried with a growing delay and then given up on.
+ALTER TABLE phases ADD COLUMN IF NOT EXISTS task_generation_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE phases ADD COLUMN IF NOT EXISTS task_generation_retry_at TIMESTAMP WITHOUT TIME ZONE;
@@ -5,6 +5,7 @@ import (
 	_ "github.com/lib/pq"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,10 @@ type Config struct {
 	DB      DBConfig
 	SMTP    SMTPConfig
 	JWT     JWTConfig
+	Goal    GoalConfig
+	Files   FilesConfig
+	LLM     LLMConfig
+	Refill  RefillConfig
 }
 
 type DBConfig struct {
@@ -40,6 +45,83 @@ type JWTConfig struct {
 	GoogleClientID string `mapstructure:"GOOGLE_CLIENT_ID"`
 }
 
+type GoalConfig struct {
+	// PurgeAfterDays is how long a soft-deleted goal can still be restored
+	// before the purge job removes it for good.
+	PurgeAfterDays int
+}
+
+type FilesConfig struct {
+	// Dir is where the local storage keeps uploaded attachments.
+	Dir string
+	// MaxSizeMB caps the size of a single attachment.
+	MaxSizeMB int
+	// AllowedTypes lists the content types accepted for upload, as detected
+	// from the file itself.
+	AllowedTypes []string
+}
+
+// LLMProviderOpenAI is the only provider: any OpenAI-compatible endpoint,
+// including a local Ollama through BaseURL. The fake client has no replies
+// of its own and is only built by tests.
+const LLMProviderOpenAI = "openai"
+
+type LLMConfig struct {
+	// Provider must be LLMProviderOpenAI or empty.
+	Provider string
+	APIKey   string
+	// BaseURL points the client at another OpenAI-compatible backend, for
+	// example http://localhost:11434/v1 for a local Ollama.
+	BaseURL string
+	// MaxAttempts caps how often a prompt is retried when the reply is not
+	// the JSON it asked for.
+	MaxAttempts int
+
+	Decompose  LLMModel
+	Refill     LLMModel
+	Motivation LLMModel
+
+	// DailyTokenQuota and MonthlyTokenQuota cap the tokens one user may
+	// spend per calendar day and month; zero means no limit.
+	DailyTokenQuota   int
+	MonthlyTokenQuota int
+	// Prices are used to estimate the cost of each call, by model name.
+	Prices map[string]LLMPrice
+}
+
+type LLMModel struct {
+	Name        string
+	Temperature float32
+}
+
+// LLMPrice is the price in USD per million prompt and completion tokens.
+type LLMPrice struct {
+	Prompt     float64
+	Completion float64
+}
+
+// defaultLLMPrices are list prices of the default models; LLM_PRICES
+// overrides and extends them.
+var defaultLLMPrices = map[string]LLMPrice{
+	"gpt-4":       {Prompt: 30, Completion: 60},
+	"gpt-4o":      {Prompt: 2.5, Completion: 10},
+	"gpt-4o-mini": {Prompt: 0.15, Completion: 0.6},
+}
+
+type RefillConfig struct {
+	// Enabled turns on the scheduled refill of goals that opted in. The
+	// "refill now" endpoint works either way.
+	Enabled bool
+	// Schedule is a cron spec with a leading seconds field.
+	Schedule string
+	// Concurrency caps how many goals are refilled at once.
+	Concurrency int
+	// GoalTimeout bounds a single goal's refill and scheduling.
+	GoalTimeout time.Duration
+	// KeepRunsDays is how long the run history is kept.
+	KeepRunsDays int
+}
+
 func LoadConfig() (*Config, error) {
 	var c Config
 
@@ -69,5 +151,134 @@ func LoadConfig() (*Config, error) {
 	c.JWT.RefreshTTL = 24 * time.Hour * 7
 	c.JWT.GoogleClientID = os.Getenv("GOOGLE_CLIENT_ID")
 
+	c.Goal.PurgeAfterDays, err = getEnvInt("GOAL_PURGE_AFTER_DAYS", 30)
+	if err != nil {
+		return nil, err
+	}
+	if c.Goal.PurgeAfterDays < 1 {
+		return nil, fmt.Errorf("invalid GOAL_PURGE_AFTER_DAYS: must be at least 1")
+	}
+
+	c.Files.Dir = os.Getenv("FILES_DIR")
+	if c.Files.Dir == "" {
+		c.Files.Dir = "data/attachments"
+	}
+	c.Files.MaxSizeMB, err = getEnvInt("FILES_MAX_SIZE_MB", 10)
+	if err != nil {
+		return nil, err
+	}
+	c.Files.AllowedTypes = []string{
+		"image/png", "image/jpeg", "image/gif", "image/webp",
+		"application/pdf", "text/plain", "application/zip",
+	}
+	if raw := os.Getenv("FILES_ALLOWED_TYPES"); raw != "" {
+		c.Files.AllowedTypes = strings.Split(raw, ",")
+	}
+
+	c.LLM.Provider = getEnv("LLM_PROVIDER", LLMProviderOpenAI)
+	c.LLM.APIKey = os.Getenv("OPENAI_API_KEY")
+	c.LLM.BaseURL = os.Getenv("LLM_BASE_URL")
+	c.LLM.MaxAttempts, err = getEnvInt("LLM_MAX_ATTEMPTS", 3)
+	if err != nil {
+		return nil, err
+	}
+	if c.LLM.Decompose, err = getEnvModel("LLM_DECOMPOSE", "gpt-4", 0.7); err != nil {
+		return nil, err
+	}
+	if c.LLM.Refill, err = getEnvModel("LLM_REFILL", "gpt-4o", 0.7); err != nil {
+		return nil, err
+	}
+	if c.LLM.Motivation, err = getEnvModel("LLM_MOTIVATION", "gpt-4", 0.8); err != nil {
+		return nil, err
+	}
+	if c.LLM.DailyTokenQuota, err = getEnvInt("LLM_DAILY_TOKEN_QUOTA", 0); err != nil {
+		return nil, err
+	}
+	if c.LLM.MonthlyTokenQuota, err = getEnvInt("LLM_MONTHLY_TOKEN_QUOTA", 0); err != nil {
+		return nil, err
+	}
+	if c.LLM.Prices, err = getEnvPrices("LLM_PRICES", defaultLLMPrices); err != nil {
+		return nil, err
+	}
+
+	c.Refill.Enabled = os.Getenv("REFILL_ENABLED") == "true"
+	c.Refill.Schedule = getEnv("REFILL_SCHEDULE", "0 0 */4 * * *")
+	if c.Refill.Concurrency, err = getEnvInt("REFILL_CONCURRENCY", 2); err != nil {
+		return nil, err
+	}
+	if c.Refill.Concurrency < 1 {
+		return nil, fmt.Errorf("invalid REFILL_CONCURRENCY: must be at least 1")
+	}
+	timeout, err := getEnvInt("REFILL_GOAL_TIMEOUT_SECONDS", 120)
+	if err != nil {
+		return nil, err
+	}
+	c.Refill.GoalTimeout = time.Duration(timeout) * time.Second
+	if c.Refill.KeepRunsDays, err = getEnvInt("REFILL_KEEP_RUNS_DAYS", 90); err != nil {
+		return nil, err
+	}
+
 	return &c, nil
 }
+
+func getEnvInt(key string, def int) (int, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return v, nil
+}
+
+func getEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// getEnvModel reads <prefix>_MODEL and <prefix>_TEMPERATURE.
+func getEnvModel(prefix, defModel string, defTemp float32) (LLMModel, error) {
+	m := LLMModel{Name: getEnv(prefix+"_MODEL", defModel), Temperature: defTemp}
+	if raw := os.Getenv(prefix + "_TEMPERATURE"); raw != "" {
+		t, err := strconv.ParseFloat(raw, 32)
+		if err != nil {
+			return m, fmt.Errorf("invalid %s_TEMPERATURE: %w", prefix, err)
+		}
+		m.Temperature = float32(t)
+	}
+	return m, nil
+}
+
+// getEnvPrices reads a list like "gpt-4o=2.5:10,llama3=0:0" of prompt and
+// completion prices per million tokens on top of the defaults.
+func getEnvPrices(key string, def map[string]LLMPrice) (map[string]LLMPrice, error) {
+	prices := make(map[string]LLMPrice, len(def))
+	for model, p := range def {
+		prices[model] = p
+	}
+	raw := os.Getenv(key)
+	if raw == "" {
+		return prices, nil
+	}
+	for _, entry := range strings.Split(raw, ",") {
+		model, pair, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		prompt, completion, ok2 := strings.Cut(pair, ":")
+		if !ok || !ok2 || model == "" {
+			return nil, fmt.Errorf("invalid %s entry %q: want model=prompt:completion", key, entry)
+		}
+		var p LLMPrice
+		var err error
+		if p.Prompt, err = strconv.ParseFloat(prompt, 64); err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", key, entry, err)
+		}
+		if p.Completion, err = strconv.ParseFloat(completion, 64); err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", key, entry, err)
+		}
+		prices[model] = p
+	}
+	return prices, nil
+}